@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/beamly/go-gocd/gocd"
 	"github.com/urfave/cli"
+	"time"
 )
 
 // List of command name and descriptions
@@ -22,12 +24,20 @@ const (
 	UpdateAgentsCommandUsage = "Bulk Update Agents"
 	DeleteAgentsCommandName  = "delete-agents"
 	DeleteAgentsCommandUsage = "Bulk Delete Agents"
+	GetAgentJobHistoryName   = "get-agent-job-history"
+	GetAgentJobHistoryUsage  = "Get the history of jobs run on an Agent"
 	agentCategory            = "Agents"
+	agentSelectorUsage       = "Select agents matching an expression, eg: 'resources=docker,env!=prod,state=Idle,free_space<10GB'."
 )
 
 // ListAgentsAction gets a list of agents and return them.
 func listAgentsAction(client *gocd.Client, c *cli.Context) (r interface{}, resp *gocd.APIResponse, err error) {
-	agents, resp, err := client.Agents.List(context.Background())
+	selector, err := gocd.ParseAgentSelector(c.String("selector"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	agents, resp, err := client.Agents.Select(context.Background(), selector)
 	if err == nil {
 		for _, agent := range agents {
 			agent.RemoveLinks()
@@ -91,9 +101,9 @@ func updateAgentsAction(client *gocd.Client, c *cli.Context) (v interface{}, res
 		u.Operations = &op
 	}
 
-	var uuids []string
-	if uuids = c.StringSlice("uuid"); len(uuids) == 0 {
-		return nil, nil, NewFlagError("uuid")
+	uuids, resp, err := selectAgentUUIDs(client, c)
+	if err != nil {
+		return nil, resp, err
 	}
 	u.Uuids = uuids
 
@@ -108,9 +118,75 @@ func updateAgentsAction(client *gocd.Client, c *cli.Context) (v interface{}, res
 	return updateResponse, r, err
 }
 
-// DeleteAgentsAction must be implemented.
+// DeleteAgentsAction deletes multiple agents. Note: The agents must be disabled.
 func deleteAgentsAction(client *gocd.Client, c *cli.Context) (r interface{}, resp *gocd.APIResponse, err error) {
-	return nil, nil, nil
+	uuids, resp, err := selectAgentUUIDs(client, c)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	deleteResponse, resp, err := client.Agents.BulkDelete(context.Background(), uuids)
+	if resp.HTTP.StatusCode == 406 {
+		err = errors.New(deleteResponse)
+	}
+	return deleteResponse, resp, err
+}
+
+// getAgentJobHistoryAction retrieves a page of jobs run on an agent, or with `--all` every job.
+func getAgentJobHistoryAction(client *gocd.Client, c *cli.Context) (v interface{}, resp *gocd.APIResponse, err error) {
+	var uuid string
+	if uuid = c.String("uuid"); uuid == "" {
+		return nil, nil, NewFlagError("uuid")
+	}
+
+	if !c.Bool("all") {
+		return client.Agents.JobRunHistoryPage(context.Background(), uuid, c.Int("offset"))
+	}
+
+	var since int
+	if d := c.Duration("since"); d > 0 {
+		since = int(time.Now().Add(-d).UnixNano() / int64(time.Millisecond))
+	}
+
+	jobs := []*gocd.Job{}
+	it := client.Agents.IterateJobRunHistory(uuid, c.Int("max"))
+	for it.Next(context.Background()) {
+		// Jobs are returned most recent first, so we can stop as soon as we reach one older than `--since`.
+		if since > 0 && it.Job().ScheduledDate < since {
+			break
+		}
+		jobs = append(jobs, it.Job())
+	}
+	return jobs, it.Response(), it.Err()
+}
+
+// selectAgentUUIDs returns the uuids provided with `--uuid`, or the uuids of the agents matching `--selector`.
+func selectAgentUUIDs(client *gocd.Client, c *cli.Context) (uuids []string, resp *gocd.APIResponse, err error) {
+	if uuids = c.StringSlice("uuid"); len(uuids) > 0 {
+		return
+	}
+
+	if c.String("selector") == "" {
+		return nil, nil, errors.New("'--uuid' or '--selector' is missing")
+	}
+
+	selector, err := gocd.ParseAgentSelector(c.String("selector"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	agents, resp, err := client.Agents.Select(context.Background(), selector)
+	if err != nil {
+		return nil, resp, err
+	}
+	if len(agents) == 0 {
+		return nil, resp, fmt.Errorf("no agents matched selector '%s'", selector)
+	}
+
+	for _, agent := range agents {
+		uuids = append(uuids, agent.UUID)
+	}
+	return
 }
 
 // ListAgentsCommand checks a template-name is provided and that the response is a 2xx response.
@@ -120,6 +196,9 @@ func listAgentsCommand() *cli.Command {
 		Usage:    ListAgentsCommandUsage,
 		Action:   ActionWrapper(listAgentsAction),
 		Category: agentCategory,
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "selector, s", Usage: agentSelectorUsage},
+		},
 	}
 }
 
@@ -163,6 +242,24 @@ func deleteAgentCommand() *cli.Command {
 	}
 }
 
+// GetAgentJobHistoryCommand handles the interaction between the cli flags and the action handler for
+// get-agent-job-history
+func getAgentJobHistoryCommand() *cli.Command {
+	return &cli.Command{
+		Name:     GetAgentJobHistoryName,
+		Usage:    GetAgentJobHistoryUsage,
+		Action:   ActionWrapper(getAgentJobHistoryAction),
+		Category: agentCategory,
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "uuid, u", Usage: "GoCD Agent UUID"},
+			cli.IntFlag{Name: "offset", Usage: "Number of jobs to skip. Ignored if '--all' is provided."},
+			cli.BoolFlag{Name: "all", Usage: "Walk through every page of the job history."},
+			cli.IntFlag{Name: "max", Usage: "Maximum number of jobs to return with '--all'."},
+			cli.DurationFlag{Name: "since", Usage: "Only return jobs scheduled within this duration with '--all', eg: '720h'."},
+		},
+	}
+}
+
 // UpdateAgentsCommand handles the interaction between the cli flags and the action handler for update-agents
 func updateAgentsCommand() *cli.Command {
 	return &cli.Command{
@@ -172,6 +269,7 @@ func updateAgentsCommand() *cli.Command {
 		Category: agentCategory,
 		Flags: []cli.Flag{
 			cli.StringSliceFlag{Name: "uuid", Usage: "GoCD Agent UUIDs"},
+			cli.StringFlag{Name: "selector, s", Usage: agentSelectorUsage + " Ignored if '--uuid' is provided."},
 			cli.StringFlag{Name: "state", Usage: "Whether agents are enabled or disabled. Allowed values 'Enabled','Disabled'."},
 			cli.StringFlag{Name: "operations", Usage: "JSON encoded config for bulk operation updates."},
 		},
@@ -187,6 +285,7 @@ func deleteAgentsCommand() *cli.Command {
 		Category: agentCategory,
 		Flags: []cli.Flag{
 			cli.StringSliceFlag{Name: "uuid", Usage: "GoCD Agent UUIDs"},
+			cli.StringFlag{Name: "selector, s", Usage: agentSelectorUsage + " Ignored if '--uuid' is provided."},
 		},
 	}
 }
@@ -10,6 +10,7 @@ func TestAgent(t *testing.T) {
 	for _, envCmd := range []cli.Command{
 		*listAgentsCommand(),
 		*getAgentCommand(),
+		*getAgentJobHistoryCommand(),
 		*updateAgentCommand(),
 		*deleteAgentCommand(),
 		*updateAgentsCommand(),
@@ -16,6 +16,7 @@ func GetCliCommands() []cli.Command {
 		*listAgentsCommand(),
 		*listPipelineTemplatesCommand(),
 		*getAgentCommand(),
+		*getAgentJobHistoryCommand(),
 		*getPipelineTemplateCommand(),
 		*createPipelineTemplateCommand(),
 		*updateAgentCommand(),
@@ -37,10 +38,16 @@ func GetCliCommands() []cli.Command {
 		*getConfigurationCommand(),
 		*encryptCommand(),
 		*getVersionCommand(),
+		*exportCommand(),
+		*importCommand(),
+		*planCommand(),
+		*applyCommand(),
 		*listPluginsCommand(),
 		*getPluginCommand(),
 		*listScheduledJobsCommand(),
 		*getPipelineConfigCommand(),
+		*diffPipelineConfigCommand(),
+		*exportPipelineCommand(),
 		*listEnvironmentsCommand(),
 		*getEnvironmentCommand(),
 		*patchEnvironmentCommand(),
@@ -86,7 +93,21 @@ func NewCliClient(c *cli.Context) (cl *gocd.Client, err error) {
 
 	cfg.SkipSslCheck = cfg.SkipSslCheck || c.Bool("skip_ssl_check")
 
-	return cfg.Client(), nil
+	setStringFromContext(&cfg.CAFile, "ca_file", c)
+	setStringFromContext(&cfg.ClientCert, "client_cert", c)
+	setStringFromContext(&cfg.ClientKey, "client_key", c)
+	setStringFromContext(&cfg.ProxyURL, "proxy_url", c)
+	setStringFromContext(&cfg.NoProxy, "no_proxy", c)
+	if timeout := c.Duration("timeout"); timeout != 0 {
+		cfg.Timeout = timeout
+	}
+
+	httpClient, err := cfg.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return gocd.NewClient(cfg, httpClient), nil
 }
 
 func setStringFromContext(dest *string, key string, c *cli.Context) {
@@ -96,7 +117,15 @@ func setStringFromContext(dest *string, key string, c *cli.Context) {
 	}
 }
 
+// textOutput is returned by actions to print their result as it is, rather than as JSON.
+type textOutput string
+
 func handleOutput(r interface{}, reqType string) cli.ExitCoder {
+	if text, isText := r.(textOutput); isText {
+		fmt.Println(string(text))
+		return nil
+	}
+
 	o := map[string]interface{}{
 		fmt.Sprintf("%s-response", reqType): r,
 	}
@@ -16,7 +16,12 @@ const (
 
 // GetConfigurationAction gets a list of agents and return them.
 func getConfigurationAction(client *gocd.Client, c *cli.Context) (r interface{}, resp *gocd.APIResponse, err error) {
-	return client.Configuration.Get(context.Background())
+	cfg, resp, err := client.Configuration.Get(context.Background())
+	if err != nil || !c.Bool("pipelines") {
+		return cfg, resp, err
+	}
+	pipelines, err := cfg.Pipelines()
+	return pipelines, resp, err
 }
 
 // GetVersionAction returns version information about GoCD
@@ -31,6 +36,9 @@ func getConfigurationCommand() *cli.Command {
 		Usage:    GetConfigurationCommandUsage,
 		Action:   ActionWrapper(getConfigurationAction),
 		Category: "Configuration",
+		Flags: []cli.Flag{
+			cli.BoolFlag{Name: "pipelines", Usage: "Return the pipelines of every group, as pipeline configs"},
+		},
 	}
 }
 
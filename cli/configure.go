@@ -90,6 +90,26 @@ func generateConfig() (cfg *gocd.Configuration, err error) {
 			Name:   "skip_ssl_check",
 			Prompt: &survey.Confirm{Message: "Skip SSL certificate validation"},
 		},
+		{
+			Name:   "ca_file",
+			Prompt: &survey.Input{Message: "Trusted CA bundle (PEM, optional)"},
+		},
+		{
+			Name:   "client_cert",
+			Prompt: &survey.Input{Message: "Client certificate (PEM, optional)"},
+		},
+		{
+			Name:   "client_key",
+			Prompt: &survey.Input{Message: "Client key (PEM, optional)"},
+		},
+		{
+			Name:   "proxy_url",
+			Prompt: &survey.Input{Message: "Proxy URL (optional)"},
+		},
+		{
+			Name:   "no_proxy",
+			Prompt: &survey.Input{Message: "Hosts not to proxy (optional)"},
+		},
 	}
 
 	err = survey.Ask(qs, cfg)
@@ -19,11 +19,27 @@ func NewFlagError(flag string) (err error) {
 	return fmt.Errorf("'--%s' is missing", flag)
 }
 
+// detailedError is an error returned from a cli action with details, such as the changes made before it, which are
+// output with the error.
+type detailedError struct {
+	err     error
+	details dataJSONCliError
+}
+
+func (e *detailedError) Error() string {
+	return e.err.Error()
+}
+
 // NewCliError creates an error which can be returned from a cli action
 func NewCliError(reqType string, hr *gocd.APIResponse, err error) (jerr JSONCliError) {
 	data := dataJSONCliError{
 		"error": err.Error(),
 	}
+	if de, isDetailed := err.(*detailedError); isDetailed {
+		for key, value := range de.details {
+			data[key] = value
+		}
+	}
 	if hr != nil {
 		data["status"] = hr.HTTP.StatusCode
 		data["response-body"] = hr.Body
@@ -15,6 +15,27 @@ func TestError(t *testing.T) {
 	t.Run("Basic", testErrorBasic)
 	t.Run("Type", testErrorType)
 	t.Run("UnexpectedError", testErrorUnexpectedError)
+	t.Run("Details", testErrorDetails)
+}
+
+func testErrorDetails(t *testing.T) {
+	err := NewCliError("import", nil, &detailedError{
+		err: errors.New("test-error"),
+		details: dataJSONCliError{"applied": []*gocd.SnapshotChange{
+			{Kind: "role", Name: "admins", Action: gocd.SnapshotChangeCreate},
+		}},
+	})
+	assert.Equal(t, `{
+  "applied": [
+    {
+      "kind": "role",
+      "name": "admins",
+      "action": "create"
+    }
+  ],
+  "error": "test-error",
+  "request": "import"
+}`, err.Error())
 }
 
 func testErrorType(t *testing.T) {
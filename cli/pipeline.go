@@ -28,7 +28,7 @@ func getPipelineStatusAction(client *gocd.Client, c *cli.Context) (r interface{}
 	if name = c.String("name"); name == "" {
 		return nil, nil, NewFlagError("name")
 	}
-	return client.Pipelines.GetStatus(context.Background(), name, -1)
+	return client.Pipelines.Status(context.Background(), name)
 }
 
 // GetPipelineAction handles the business logic between the command objects and the go-gocd library.
@@ -48,7 +48,16 @@ func getPipelineHistoryAction(client *gocd.Client, c *cli.Context) (r interface{
 		return nil, nil, NewFlagError("name")
 	}
 
-	return client.Pipelines.GetHistory(context.Background(), c.String("name"), -1)
+	if !c.Bool("all") {
+		return client.Pipelines.GetHistory(context.Background(), name, c.Int("offset"))
+	}
+
+	instances := []*gocd.PipelineInstance{}
+	it := client.Pipelines.IterateHistory(name, c.Int("max"))
+	for it.Next(context.Background()) {
+		instances = append(instances, it.Instance())
+	}
+	return instances, it.Response(), it.Err()
 }
 
 // PausePipelineAction handles the business logic between the command objects and the go-gocd library.
@@ -160,6 +169,9 @@ func getPipelineHistoryCommand() *cli.Command {
 		Category: "Pipelines",
 		Flags: []cli.Flag{
 			cli.StringFlag{Name: "name"},
+			cli.IntFlag{Name: "offset", Usage: "Number of pipeline instances to skip. Ignored if '--all' is provided."},
+			cli.BoolFlag{Name: "all", Usage: "Walk through every page of the pipeline history."},
+			cli.IntFlag{Name: "max", Usage: "Maximum number of pipeline instances to return with '--all'."},
 		},
 		Action: ActionWrapper(getPipelineHistoryAction),
 	}
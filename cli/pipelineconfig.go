@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"github.com/beamly/go-gocd/gocd"
 	"github.com/urfave/cli"
@@ -20,6 +22,10 @@ const (
 	DeletePipelineConfigCommandUsage = "Remove Pipeline. This will not delete the pipeline history, which will be stored in the database"
 	GetPipelineConfigCommandName     = "get-pipeline-config"
 	GetPipelineConfigCommandUsage    = "Get a Pipeline Configuration"
+	DiffPipelineConfigCommandName    = "diff-pipeline-config"
+	DiffPipelineConfigCommandUsage   = "Compare a Pipeline Configuration on the server with a JSON file"
+	ExportPipelineCommandName        = "export-pipeline"
+	ExportPipelineCommandUsage       = "Export a Pipeline Configuration, with its group and environments, for a config repository"
 )
 
 // CreatePipelineConfigAction handles the interaction between the cli flags and the action handler for
@@ -130,6 +136,108 @@ func getPipelineConfigAction(client *gocd.Client, c *cli.Context) (r interface{}
 	return getResponse, resp, err
 }
 
+// DiffPipelineConfigAction handles the interaction between the cli flags and the action handler for
+// diff-pipeline-config
+func diffPipelineConfigAction(client *gocd.Client, c *cli.Context) (r interface{}, resp *gocd.APIResponse, err error) {
+	pipelineFile := c.String("file")
+	if pipelineFile == "" {
+		return nil, nil, NewFlagError("file")
+	}
+
+	format := c.String("format")
+	if format != "text" && format != "json" {
+		return nil, nil, fmt.Errorf("'--format' must be 'text' or 'json', not '%s'", format)
+	}
+
+	pf, err := ioutil.ReadFile(pipelineFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	local := &gocd.Pipeline{}
+	if err = json.Unmarshal(pf, local); err != nil {
+		return nil, nil, err
+	}
+
+	name := c.String("name")
+	if name == "" {
+		name = local.Name
+	}
+	if name == "" {
+		return nil, nil, NewFlagError("name")
+	}
+
+	server, resp, err := client.PipelineConfigs.Get(context.Background(), name)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	changes, err := gocd.DiffPipelines(server, local)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	if format == "json" {
+		return changes, resp, nil
+	}
+	if len(changes) == 0 {
+		return textOutput(fmt.Sprintf("Pipeline '%s' has no changes.", name)), resp, nil
+	}
+	return textOutput(gocd.FormatPipelineChanges(changes)), resp, nil
+}
+
+// ExportPipelineAction handles the interaction between the cli flags and the action handler for export-pipeline
+func exportPipelineAction(client *gocd.Client, c *cli.Context) (r interface{}, resp *gocd.APIResponse, err error) {
+	name := c.String("name")
+	if name == "" {
+		return nil, nil, NewFlagError("name")
+	}
+
+	if format := c.String("format"); format != "yaml" {
+		return nil, nil, fmt.Errorf("'--format' must be 'yaml', not '%s'", format)
+	}
+
+	ctx := context.Background()
+	export := &gocd.PipelineExport{}
+	if export.Pipeline, resp, err = client.PipelineConfigs.Get(ctx, name); err != nil {
+		return nil, resp, err
+	}
+
+	groups, resp, err := client.PipelineGroups.List(ctx, "")
+	if err != nil {
+		return nil, resp, err
+	}
+	if group := groups.GetGroupByPipelineName(name); group != nil {
+		export.Group = group.Name
+	}
+
+	environments, resp, err := client.Environments.List(ctx)
+	if err != nil {
+		return nil, resp, err
+	}
+	if environments.Embedded != nil {
+		for _, env := range environments.Embedded.Environments {
+			for _, p := range env.Pipelines {
+				if p.Name == name {
+					export.Environments = append(export.Environments, env)
+					break
+				}
+			}
+		}
+	}
+
+	if template := export.Pipeline.Template; template != "" && c.Bool("inline-template") {
+		if export.Template, resp, err = client.PipelineTemplates.Get(ctx, template); err != nil {
+			return nil, resp, err
+		}
+	}
+
+	b, err := export.YAML()
+	if err != nil {
+		return nil, resp, err
+	}
+	return textOutput(strings.TrimSuffix(string(b), "\n")), resp, nil
+}
+
 // CreatePipelineConfigCommand handles the interaction between the cli flags and the action handler for create-pipeline-config
 func createPipelineConfigCommand() *cli.Command {
 	return &cli.Command{
@@ -186,3 +294,33 @@ func getPipelineConfigCommand() *cli.Command {
 		},
 	}
 }
+
+// DiffPipelineConfigCommand handles the interaction between the cli flags and the action handler for diff-pipeline-config
+func diffPipelineConfigCommand() *cli.Command {
+	return &cli.Command{
+		Name:     DiffPipelineConfigCommandName,
+		Usage:    DiffPipelineConfigCommandUsage,
+		Action:   ActionWrapper(diffPipelineConfigAction),
+		Category: "Pipeline Configs",
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "file", Usage: "Path to a JSON file describing the pipeline configuration"},
+			cli.StringFlag{Name: "name", Usage: "Name of the pipeline on the server. Defaults to the name in the file"},
+			cli.StringFlag{Name: "format", Value: "text", Usage: "Output format, 'text' or 'json'"},
+		},
+	}
+}
+
+// ExportPipelineCommand handles the interaction between the cli flags and the action handler for export-pipeline
+func exportPipelineCommand() *cli.Command {
+	return &cli.Command{
+		Name:     ExportPipelineCommandName,
+		Usage:    ExportPipelineCommandUsage,
+		Action:   ActionWrapper(exportPipelineAction),
+		Category: "Pipeline Configs",
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "name"},
+			cli.StringFlag{Name: "format", Value: "yaml", Usage: "Output format. Only 'yaml', for the gocd-yaml-config-plugin, is supported"},
+			cli.BoolFlag{Name: "inline-template", Usage: "Write the stages of the pipeline's template in the pipeline, rather than referring to the template"},
+		},
+	}
+}
@@ -12,6 +12,8 @@ func TestPipelineConfig(t *testing.T) {
 		*updatePipelineConfigCommand(),
 		*deletePipelineConfigCommand(),
 		*getPipelineConfigCommand(),
+		*diffPipelineConfigCommand(),
+		*exportPipelineCommand(),
 	} {
 		assert.Equal(t, envCmd.Category, "Pipeline Configs")
 		assert.NotEmpty(t, envCmd.Name)
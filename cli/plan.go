@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"github.com/beamly/go-gocd/gocd"
+	"github.com/urfave/cli"
+)
+
+// List of command name and descriptions
+const (
+	PlanCommandName   = "plan"
+	PlanCommandUsage  = "Compare the pipelines and templates of a directory written by export with the GoCD server, and list the changes apply would make"
+	ApplyCommandName  = "apply"
+	ApplyCommandUsage = "Create and update the pipelines and templates of the GoCD server to match a directory written by export, and optionally delete the others"
+)
+
+// planPipelines reads the desired pipelines and templates from the directory, and plans the changes to the server.
+func planPipelines(client *gocd.Client, c *cli.Context) (*gocd.Plan, error) {
+	dir := c.String("dir")
+	if dir == "" {
+		return nil, NewFlagError("dir")
+	}
+
+	desired, err := gocd.ReadSnapshot(dir)
+	if err != nil {
+		return nil, err
+	}
+	prune := c.Bool("prune")
+	if prune && len(desired.Templates) == 0 && len(desired.Pipelines) == 0 && !c.Bool("allow-empty") {
+		return nil, fmt.Errorf("'%s' holds no pipelines or templates, so pruning would delete them all, "+
+			"run again with --allow-empty to allow it", dir)
+	}
+	return client.PlanPipelines(context.Background(), desired.Templates, desired.Pipelines, prune)
+}
+
+// PlanAction lists the changes which would reconcile the server with the directory.
+func planAction(client *gocd.Client, c *cli.Context) (r interface{}, resp *gocd.APIResponse, err error) {
+	plan, err := planPipelines(client, c)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.String("format") == "json" {
+		return plan, nil, nil
+	}
+	return textOutput(plan.String()), nil, nil
+}
+
+// ApplyAction reconciles the server with the directory. Pipelines and templates are only deleted with
+// --confirm-delete, as the plan is made again when applying it and may delete more than the plan which was reviewed.
+func applyAction(client *gocd.Client, c *cli.Context) (r interface{}, resp *gocd.APIResponse, err error) {
+	plan, err := planPipelines(client, c)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !plan.HasChanges() {
+		return textOutput(plan.String() + "\nNo changes to apply."), nil, nil
+	}
+	if deletes := countDeletes(plan); deletes > 0 && !c.Bool("confirm-delete") {
+		return nil, nil, &detailedError{
+			err: fmt.Errorf("the plan deletes %d pipelines and templates, "+
+				"run apply again with --confirm-delete to delete them", deletes),
+			details: dataJSONCliError{"plan": plan},
+		}
+	}
+	if err = client.ApplyPlan(context.Background(), plan); err != nil {
+		return nil, nil, err
+	}
+	return textOutput(plan.String() + "\nApplied."), nil, nil
+}
+
+func countDeletes(plan *gocd.Plan) (deletes int) {
+	for _, change := range plan.Changes {
+		if change.Action == gocd.PlanChangeDelete {
+			deletes++
+		}
+	}
+	return deletes
+}
+
+// PlanCommand handles the interaction between the cli flags and the action handler for plan
+func planCommand() *cli.Command {
+	return &cli.Command{
+		Name:     PlanCommandName,
+		Usage:    PlanCommandUsage,
+		Action:   ActionWrapper(planAction),
+		Category: "Configuration",
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "dir", Usage: "Directory to read the desired pipelines and templates from"},
+			cli.BoolFlag{Name: "prune", Usage: "Delete the pipelines and templates missing from the directory"},
+			cli.BoolFlag{Name: "allow-empty", Usage: "Allow pruning with a directory holding no pipelines or templates"},
+			cli.StringFlag{Name: "format", Value: "text", Usage: "Output format, 'text' or 'json'"},
+		},
+	}
+}
+
+// ApplyCommand handles the interaction between the cli flags and the action handler for apply
+func applyCommand() *cli.Command {
+	return &cli.Command{
+		Name:     ApplyCommandName,
+		Usage:    ApplyCommandUsage,
+		Action:   ActionWrapper(applyAction),
+		Category: "Configuration",
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "dir", Usage: "Directory to read the desired pipelines and templates from"},
+			cli.BoolFlag{Name: "prune", Usage: "Delete the pipelines and templates missing from the directory"},
+			cli.BoolFlag{Name: "allow-empty", Usage: "Allow pruning with a directory holding no pipelines or templates"},
+			cli.BoolFlag{Name: "confirm-delete", Usage: "Confirm the deletion of the pipelines and templates pruned"},
+		},
+	}
+}
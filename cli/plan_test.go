@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	for _, planCmd := range []cli.Command{
+		*planCommand(),
+		*applyCommand(),
+	} {
+		assert.Equal(t, planCmd.Category, "Configuration")
+		assert.NotEmpty(t, planCmd.Name)
+		assert.NotEmpty(t, planCmd.Usage)
+	}
+}
+
+func TestPlanDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plan")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	context := func(args ...string) *cli.Context {
+		set := flag.NewFlagSet("apply", flag.ContinueOnError)
+		for _, f := range applyCommand().Flags {
+			f.Apply(set)
+		}
+		assert.NoError(t, set.Parse(args))
+		return cli.NewContext(nil, set, nil)
+	}
+
+	_, err = planPipelines(nil, context("--dir", filepath.Join(dir, "typo"), "--prune"))
+	assert.True(t, os.IsNotExist(err), "a missing directory is not read as an empty desired state")
+
+	_, err = planPipelines(nil, context("--dir", dir, "--prune"))
+	assert.EqualError(t, err, "'"+dir+"' holds no pipelines or templates, so pruning would delete them all, "+
+		"run again with --allow-empty to allow it")
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"github.com/beamly/go-gocd/gocd"
+	"github.com/urfave/cli"
+)
+
+// List of command name and descriptions
+const (
+	ExportCommandName  = "export"
+	ExportCommandUsage = "Export the configuration of the GoCD server to a directory of JSON files, to keep it as code"
+	ImportCommandName  = "import"
+	ImportCommandUsage = "Import the configuration of a GoCD server from a directory written by export, creating and updating objects as needed"
+)
+
+// ExportAction writes a snapshot of the server configuration to a directory.
+func exportAction(client *gocd.Client, c *cli.Context) (r interface{}, resp *gocd.APIResponse, err error) {
+	dir := c.String("dir")
+	if dir == "" {
+		return nil, nil, NewFlagError("dir")
+	}
+
+	s, err := client.ExportSnapshot(context.Background())
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = s.Write(dir); err != nil {
+		return nil, nil, err
+	}
+	return textOutput(fmt.Sprintf("Exported %d pipelines, %d templates and %d environments to '%s'.",
+		len(s.Pipelines), len(s.Templates), len(s.Environments), dir)), nil, nil
+}
+
+// ImportAction applies a snapshot read from a directory to the server. If the server refuses a change, the changes
+// made before it are output with the error.
+func importAction(client *gocd.Client, c *cli.Context) (r interface{}, resp *gocd.APIResponse, err error) {
+	dir := c.String("dir")
+	if dir == "" {
+		return nil, nil, NewFlagError("dir")
+	}
+
+	s, err := gocd.ReadSnapshot(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	dryRun := c.Bool("dry-run")
+	changes, err := client.ImportSnapshot(context.Background(), s, dryRun)
+	if err != nil && len(changes) > 0 && !dryRun {
+		// The changes made before the error are not undone, so they are output with it.
+		return nil, nil, &detailedError{err: err, details: dataJSONCliError{"applied": changes}}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return textOutput(gocd.FormatSnapshotChanges(changes)), nil, nil
+}
+
+// ExportCommand handles the interaction between the cli flags and the action handler for export
+func exportCommand() *cli.Command {
+	return &cli.Command{
+		Name:     ExportCommandName,
+		Usage:    ExportCommandUsage,
+		Action:   ActionWrapper(exportAction),
+		Category: "Configuration",
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "dir", Usage: "Directory to write the configuration to"},
+		},
+	}
+}
+
+// ImportCommand handles the interaction between the cli flags and the action handler for import
+func importCommand() *cli.Command {
+	return &cli.Command{
+		Name:     ImportCommandName,
+		Usage:    ImportCommandUsage,
+		Action:   ActionWrapper(importAction),
+		Category: "Configuration",
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "dir", Usage: "Directory to read the configuration from"},
+			cli.BoolFlag{Name: "dry-run", Usage: "List the changes without making them"},
+		},
+	}
+}
@@ -0,0 +1,18 @@
+package cli
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	for _, snapshotCmd := range []cli.Command{
+		*exportCommand(),
+		*importCommand(),
+	} {
+		assert.Equal(t, snapshotCmd.Category, "Configuration")
+		assert.NotEmpty(t, snapshotCmd.Name)
+		assert.NotEmpty(t, snapshotCmd.Usage)
+	}
+}
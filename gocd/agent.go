@@ -46,6 +46,11 @@ type AgentBulkUpdate struct {
 	AgentConfigState string                     `json:"agent_config_state,omitempty"`
 }
 
+// AgentBulkDelete describes the structure for the DELETE payload when removing multiple agents
+type AgentBulkDelete struct {
+	Uuids []string `json:"uuids"`
+}
+
 // AgentBulkOperationsUpdate describes the structure for a single Operation in AgentBulkUpdate the PUT payload when
 // updating multiple agents
 type AgentBulkOperationsUpdate struct {
@@ -69,12 +74,19 @@ type BuildDetails struct {
 
 // List will retrieve all agents, their status, and metadata from the GoCD Server.
 func (s *AgentsService) List(ctx context.Context) (agents []*Agent, resp *APIResponse, err error) {
+	apiVersion, err := s.client.getAPIVersion(ctx, "agents")
+	if err != nil {
+		return nil, nil, err
+	}
+
 	r := AgentsResponse{}
-	_, resp, err = s.client.getAction(ctx, &APIClientRequest{
+	if _, resp, err = s.client.getAction(ctx, &APIClientRequest{
 		Path:         "agents",
 		ResponseBody: &r,
-		APIVersion:   apiV4,
-	})
+		APIVersion:   apiVersion,
+	}); err != nil {
+		return nil, resp, err
+	}
 
 	for _, agent := range r.Embedded.Agents {
 		agent.client = s.client
@@ -84,6 +96,15 @@ func (s *AgentsService) List(ctx context.Context) (agents []*Agent, resp *APIRes
 	return
 }
 
+// Select will retrieve all agents matching the provided selector.
+func (s *AgentsService) Select(ctx context.Context, selector *AgentSelector) (agents []*Agent, resp *APIResponse, err error) {
+	if agents, resp, err = s.List(ctx); err != nil {
+		return
+	}
+	agents = selector.Filter(agents)
+	return
+}
+
 // Get will retrieve a single agent based on the provided UUID.
 func (s *AgentsService) Get(ctx context.Context, uuid string) (*Agent, *APIResponse, error) {
 	return s.handleAgentRequest(ctx, "GET", uuid, nil)
@@ -96,15 +117,25 @@ func (s *AgentsService) Update(ctx context.Context, uuid string, agent *Agent) (
 
 // Delete will remove an existing agent. Note: The agent must be disabled, and not currently building to be deleted.
 func (s *AgentsService) Delete(ctx context.Context, uuid string) (string, *APIResponse, error) {
-	return s.client.deleteAction(ctx, "agents/"+uuid, apiV4)
+	apiVersion, err := s.client.getAPIVersion(ctx, "agents/:agent_uuid")
+	if err != nil {
+		return "", nil, err
+	}
+
+	return s.client.deleteAction(ctx, "agents/"+uuid, apiVersion)
 }
 
 // BulkUpdate will change the configuration for multiple agents in a single request.
 func (s *AgentsService) BulkUpdate(ctx context.Context, agents AgentBulkUpdate) (message string, resp *APIResponse, err error) {
+	apiVersion, err := s.client.getAPIVersion(ctx, "agents")
+	if err != nil {
+		return "", nil, err
+	}
+
 	a := StringResponse{}
 	_, resp, err = s.client.patchAction(ctx, &APIClientRequest{
 		Path:         "agents",
-		APIVersion:   apiV4,
+		APIVersion:   apiVersion,
 		ResponseBody: &a,
 		RequestBody:  agents,
 	})
@@ -112,24 +143,135 @@ func (s *AgentsService) BulkUpdate(ctx context.Context, agents AgentBulkUpdate)
 	return
 }
 
+// BulkDelete will remove multiple agents in a single request. Note: The agents must be disabled, and not currently
+// building to be deleted.
+func (s *AgentsService) BulkDelete(ctx context.Context, uuids []string) (message string, resp *APIResponse, err error) {
+	apiVersion, err := s.client.getAPIVersion(ctx, "agents")
+	if err != nil {
+		return "", nil, err
+	}
+
+	a := StringResponse{}
+	_, resp, err = s.client.httpAction(ctx, &APIClientRequest{
+		Method:       "DELETE",
+		Path:         "agents",
+		APIVersion:   apiVersion,
+		ResponseBody: &a,
+		RequestBody:  AgentBulkDelete{Uuids: uuids},
+	})
+	message = a.Message
+	return
+}
+
 // JobRunHistory will return a list of Jobs run on the agent identified by `uuid`.
 func (s *AgentsService) JobRunHistory(ctx context.Context, uuid string) (jobs []*Job, resp *APIResponse, err error) {
-	a := JobRunHistoryResponse{}
+	page, resp, err := s.JobRunHistoryPage(ctx, uuid, 0)
+	jobs = page.Jobs
+	return
+}
+
+// JobRunHistoryPage will return a single page of Jobs run on the agent identified by `uuid`, starting at `offset`,
+// along with the pagination details required to retrieve the next page.
+func (s *AgentsService) JobRunHistoryPage(ctx context.Context, uuid string, offset int) (page *JobRunHistoryResponse, resp *APIResponse, err error) {
+	page = &JobRunHistoryResponse{}
+	path := fmt.Sprintf("agents/%s/job_run_history", uuid)
+	endpoint := "agents/:agent_uuid/job_run_history"
+	if offset > 0 {
+		endpoint += "/:offset"
+	}
+
+	apiVersion, err := s.client.getAPIVersion(ctx, endpoint)
+	if err != nil {
+		return page, nil, err
+	}
+	path += jobRunHistoryOffset(apiVersion, offset)
+
 	_, resp, err = s.client.getAction(ctx, &APIClientRequest{
-		Path:         fmt.Sprintf("agents/%s/job_run_history", uuid),
-		APIVersion:   apiV4,
-		ResponseBody: &a,
+		Path:         path,
+		APIVersion:   apiVersion,
+		ResponseBody: page,
 	})
-	jobs = a.Jobs
 	return
 }
 
+// jobRunHistoryOffset returns the suffix of the job run history path requesting the page at `offset`. The first
+// version of the API served by GoCD 20.1.0 takes the offset as a query parameter, where older ones took it in the path.
+func jobRunHistoryOffset(apiVersion string, offset int) string {
+	switch {
+	case offset <= 0:
+		return ""
+	case apiVersion == apiV1:
+		return fmt.Sprintf("?offset=%d", offset)
+	default:
+		return fmt.Sprintf("/%d", offset)
+	}
+}
+
+// JobRunHistoryIterator walks through every page of the job run history for an agent. Pages are only requested from
+// the server as the iterator advances.
+//
+//	it := client.Agents.IterateJobRunHistory(uuid, 100)
+//	for it.Next(ctx) {
+//		fmt.Println(it.Job().Name)
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type JobRunHistoryIterator struct {
+	*PageIterator
+}
+
+// IterateJobRunHistory creates an iterator over the jobs run on the agent identified by `uuid`. If `maxItems` is
+// greater than zero, the iterator will stop after that many jobs.
+func (s *AgentsService) IterateJobRunHistory(uuid string, maxItems int) *JobRunHistoryIterator {
+	return &JobRunHistoryIterator{
+		PageIterator: NewPageIterator(func(ctx context.Context, cursor *PageCursor) (*Page, error) {
+			page, resp, err := s.JobRunHistoryPage(ctx, uuid, cursor.Offset)
+			if err != nil {
+				return nil, err
+			}
+
+			items := make([]interface{}, len(page.Jobs))
+			for i, job := range page.Jobs {
+				items[i] = job
+			}
+			return &Page{
+				Items:    items,
+				Next:     nextOffsetCursor(cursor, page.Pagination, len(items)),
+				Response: resp,
+			}, nil
+		}, maxItems),
+	}
+}
+
+// AllJobRunHistory will return every Job run on the agent identified by `uuid`, up to `maxItems` if it is greater than
+// zero.
+func (s *AgentsService) AllJobRunHistory(ctx context.Context, uuid string, maxItems int) (jobs []*Job, resp *APIResponse, err error) {
+	jobs = []*Job{}
+	it := s.IterateJobRunHistory(uuid, maxItems)
+	for it.Next(ctx) {
+		jobs = append(jobs, it.Job())
+	}
+	return jobs, it.Response(), it.Err()
+}
+
+// Job returns the job the iterator is currently positioned on.
+func (it *JobRunHistoryIterator) Job() *Job {
+	job, _ := it.Item().(*Job)
+	return job
+}
+
 func (s *AgentsService) handleAgentRequest(ctx context.Context, action string, uuid string, agent *Agent) (a *Agent, resp *APIResponse, err error) {
+	apiVersion, err := s.client.getAPIVersion(ctx, "agents/:agent_uuid")
+	if err != nil {
+		return nil, nil, err
+	}
+
 	a = &Agent{}
 	_, resp, err = s.client.httpAction(ctx, &APIClientRequest{
 		Method:       action,
 		Path:         "agents/" + uuid,
-		APIVersion:   apiV4,
+		APIVersion:   apiVersion,
 		RequestBody:  agent,
 		ResponseBody: a,
 	})
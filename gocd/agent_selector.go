@@ -0,0 +1,288 @@
+package gocd
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Agent selector operators
+const (
+	SelectorOpEqual          = "="
+	SelectorOpNotEqual       = "!="
+	SelectorOpLessThan       = "<"
+	SelectorOpLessOrEqual    = "<="
+	SelectorOpGreaterThan    = ">"
+	SelectorOpGreaterOrEqual = ">="
+)
+
+// selectorOperators are ordered so that two character operators are matched before their single character prefixes.
+var selectorOperators = []string{
+	SelectorOpNotEqual,
+	SelectorOpLessOrEqual,
+	SelectorOpGreaterOrEqual,
+	SelectorOpEqual,
+	SelectorOpLessThan,
+	SelectorOpGreaterThan,
+}
+
+// agentSelectorKeys maps the keys (and their aliases) which can be used in a selector to the agent attribute they
+// filter on.
+var agentSelectorKeys = map[string]string{
+	"uuid":             "uuid",
+	"hostname":         "hostname",
+	"host":             "hostname",
+	"ip":               "ip_address",
+	"ip_address":       "ip_address",
+	"os":               "operating_system",
+	"operating_system": "operating_system",
+	"state":            "agent_state",
+	"agent_state":      "agent_state",
+	"build_state":      "build_state",
+	"config_state":     "agent_config_state",
+	"resource":         "resources",
+	"resources":        "resources",
+	"env":              "environments",
+	"environment":      "environments",
+	"environments":     "environments",
+	"free_space":       "free_space",
+}
+
+var freeSpaceUnits = map[string]int{
+	"":   1,
+	"B":  1,
+	"KB": 1 << 10,
+	"MB": 1 << 20,
+	"GB": 1 << 30,
+	"TB": 1 << 40,
+}
+
+// AgentSelector describes a set of requirements an agent must satisfy to be selected. Selectors are built from
+// comma separated expressions, eg: `resources=docker,env!=prod,state=Idle,free_space<10GB,hostname=build-*`.
+//
+// String attributes (uuid, hostname, ip, os, state, build_state, config_state) support `=` and `!=`, and the value
+// may be a glob pattern. List attributes (resources, environments) match when the agent has (`=`) or does not have
+// (`!=`) the value. Alternatives can be separated by `|`, eg: `os=Linux|Mac OS X`. `free_space` supports all
+// comparison operators and the units B, KB, MB, GB and TB.
+type AgentSelector struct {
+	Requirements []*AgentRequirement
+}
+
+// AgentRequirement describes a single expression in an AgentSelector.
+type AgentRequirement struct {
+	Key      string
+	Operator string
+	Values   []string
+}
+
+// ParseAgentSelector from its string representation. An empty selector matches every agent.
+func ParseAgentSelector(selector string) (s *AgentSelector, err error) {
+	s = &AgentSelector{}
+	for _, expression := range strings.Split(selector, ",") {
+		if expression = strings.TrimSpace(expression); expression == "" {
+			continue
+		}
+
+		var requirement *AgentRequirement
+		if requirement, err = parseAgentRequirement(expression); err != nil {
+			return nil, err
+		}
+		s.Requirements = append(s.Requirements, requirement)
+	}
+	return
+}
+
+func parseAgentRequirement(expression string) (r *AgentRequirement, err error) {
+	i := strings.IndexAny(expression, "!=<>")
+	if i < 1 {
+		return nil, fmt.Errorf("could not find an operator in agent selector expression '%s'", expression)
+	}
+
+	var op string
+	for _, op = range selectorOperators {
+		if strings.HasPrefix(expression[i:], op) {
+			break
+		}
+	}
+	if !strings.HasPrefix(expression[i:], op) {
+		return nil, fmt.Errorf("could not find an operator in agent selector expression '%s'", expression)
+	}
+
+	key := strings.ToLower(strings.TrimSpace(expression[:i]))
+	attribute, isKnown := agentSelectorKeys[key]
+	if !isKnown {
+		return nil, fmt.Errorf("unknown agent selector key '%s'", key)
+	}
+
+	r = &AgentRequirement{
+		Key:      attribute,
+		Operator: op,
+	}
+	for _, value := range strings.Split(expression[i+len(op):], "|") {
+		r.Values = append(r.Values, strings.TrimSpace(value))
+	}
+
+	err = r.validate()
+	return
+}
+
+func (r *AgentRequirement) validate() error {
+	if r.Key == "free_space" {
+		if len(r.Values) != 1 {
+			return fmt.Errorf("'free_space' only accepts a single value")
+		}
+		_, err := parseFreeSpace(r.Values[0])
+		return err
+	}
+
+	if r.Operator != SelectorOpEqual && r.Operator != SelectorOpNotEqual {
+		return fmt.Errorf("operator '%s' is not supported for '%s'", r.Operator, r.Key)
+	}
+
+	for _, value := range r.Values {
+		if _, err := path.Match(value, ""); err != nil {
+			return fmt.Errorf("invalid pattern '%s' for '%s': %s", value, r.Key, err)
+		}
+	}
+	return nil
+}
+
+// Matches returns true if the agent satisfies every requirement of the selector.
+func (s *AgentSelector) Matches(a *Agent) bool {
+	for _, r := range s.Requirements {
+		if !r.Matches(a) {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter a list of agents, returning only those matching the selector.
+func (s *AgentSelector) Filter(agents []*Agent) (selected []*Agent) {
+	selected = []*Agent{}
+	for _, agent := range agents {
+		if s.Matches(agent) {
+			selected = append(selected, agent)
+		}
+	}
+	return
+}
+
+// String representation of the selector.
+func (s *AgentSelector) String() string {
+	expressions := make([]string, len(s.Requirements))
+	for i, r := range s.Requirements {
+		expressions[i] = r.String()
+	}
+	return strings.Join(expressions, ",")
+}
+
+// String representation of the requirement.
+func (r *AgentRequirement) String() string {
+	return r.Key + r.Operator + strings.Join(r.Values, "|")
+}
+
+// Matches returns true if the agent satisfies this requirement.
+func (r *AgentRequirement) Matches(a *Agent) bool {
+	switch r.Key {
+	case "free_space":
+		return r.matchFreeSpace(a.FreeSpace)
+	case "resources":
+		return r.matchList(a.Resources)
+	case "environments":
+		return r.matchList(a.Environments)
+	}
+
+	matched := r.matchAny(a.selectorAttribute(r.Key))
+	if r.Operator == SelectorOpNotEqual {
+		return !matched
+	}
+	return matched
+}
+
+func (r *AgentRequirement) matchList(values []string) bool {
+	matched := false
+	for _, value := range values {
+		if r.matchAny(value) {
+			matched = true
+			break
+		}
+	}
+	if r.Operator == SelectorOpNotEqual {
+		return !matched
+	}
+	return matched
+}
+
+func (r *AgentRequirement) matchAny(value string) bool {
+	for _, pattern := range r.Values {
+		if matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(value)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *AgentRequirement) matchFreeSpace(freeSpace int) bool {
+	limit, _ := parseFreeSpace(r.Values[0])
+	switch r.Operator {
+	case SelectorOpEqual:
+		return freeSpace == limit
+	case SelectorOpNotEqual:
+		return freeSpace != limit
+	case SelectorOpLessThan:
+		return freeSpace < limit
+	case SelectorOpLessOrEqual:
+		return freeSpace <= limit
+	case SelectorOpGreaterThan:
+		return freeSpace > limit
+	case SelectorOpGreaterOrEqual:
+		return freeSpace >= limit
+	}
+	return false
+}
+
+// selectorAttribute returns the value of a string attribute for an agent selector key.
+func (a *Agent) selectorAttribute(key string) string {
+	switch key {
+	case "uuid":
+		return a.UUID
+	case "hostname":
+		return a.Hostname
+	case "ip_address":
+		return a.IPAddress
+	case "operating_system":
+		return a.OperatingSystem
+	case "agent_state":
+		return a.AgentState
+	case "build_state":
+		return a.BuildState
+	case "agent_config_state":
+		return a.AgentConfigState
+	}
+	return ""
+}
+
+// parseFreeSpace turns a human readable size (eg, "10GB") into a number of bytes.
+func parseFreeSpace(value string) (int, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	i := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(value)
+	}
+
+	unit, hasUnit := freeSpaceUnits[strings.TrimSpace(value[i:])]
+	if !hasUnit {
+		return 0, fmt.Errorf("unknown size unit in '%s'", value)
+	}
+
+	size, err := strconv.ParseFloat(value[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse size '%s'", value)
+	}
+
+	return int(size * float64(unit)), nil
+}
@@ -0,0 +1,101 @@
+package gocd
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAgentSelector(t *testing.T) {
+	t.Run("Parse", testAgentSelectorParse)
+	t.Run("ParseFail", testAgentSelectorParseFail)
+	t.Run("Filter", testAgentSelectorFilter)
+	t.Run("FreeSpace", testAgentSelectorFreeSpace)
+}
+
+func testAgentSelectorParse(t *testing.T) {
+	s, err := ParseAgentSelector("resources=docker, env!=prod,state=Idle|Building,free_space<10GB,hostname=build-*")
+	assert.NoError(t, err)
+	assert.Len(t, s.Requirements, 5)
+
+	assert.Equal(t, &AgentRequirement{Key: "environments", Operator: SelectorOpNotEqual, Values: []string{"prod"}}, s.Requirements[1])
+	assert.Equal(t, &AgentRequirement{Key: "agent_state", Operator: SelectorOpEqual, Values: []string{"Idle", "Building"}}, s.Requirements[2])
+	assert.Equal(t, &AgentRequirement{Key: "free_space", Operator: SelectorOpLessThan, Values: []string{"10GB"}}, s.Requirements[3])
+	assert.Equal(t, "resources=docker,environments!=prod,agent_state=Idle|Building,free_space<10GB,hostname=build-*", s.String())
+
+	s, err = ParseAgentSelector("")
+	assert.NoError(t, err)
+	assert.Empty(t, s.Requirements)
+}
+
+func testAgentSelectorParseFail(t *testing.T) {
+	for _, test := range []struct {
+		selector string
+		err      string
+	}{
+		{"docker", "could not find an operator in agent selector expression 'docker'"},
+		{"=docker", "could not find an operator in agent selector expression '=docker'"},
+		{"colour=red", "unknown agent selector key 'colour'"},
+		{"hostname<abc", "operator '<' is not supported for 'hostname'"},
+		{"free_space>10XB", "unknown size unit in '10XB'"},
+		{"free_space>GB", "could not parse size 'GB'"},
+		{"free_space>1GB|2GB", "'free_space' only accepts a single value"},
+		{"hostname=[", "invalid pattern '[' for 'hostname': syntax error in pattern"},
+	} {
+		_, err := ParseAgentSelector(test.selector)
+		assert.EqualError(t, err, test.err, test.selector)
+	}
+}
+
+func testAgentSelectorFilter(t *testing.T) {
+	agents := []*Agent{
+		{UUID: "1", Hostname: "build-01", AgentState: "Idle", Resources: []string{"docker", "linux"}, Environments: []string{"dev"}},
+		{UUID: "2", Hostname: "build-02", AgentState: "Building", Resources: []string{"docker"}, Environments: []string{"prod"}},
+		{UUID: "3", Hostname: "deploy-01", AgentState: "Idle", Resources: []string{"java"}},
+	}
+
+	for _, test := range []struct {
+		selector string
+		uuids    []string
+	}{
+		{"", []string{"1", "2", "3"}},
+		{"resources=docker", []string{"1", "2"}},
+		{"resources=docker,env!=prod", []string{"1"}},
+		{"resource!=docker", []string{"3"}},
+		{"state=idle", []string{"1", "3"}},
+		{"hostname=build-*", []string{"1", "2"}},
+		{"hostname!=build-*", []string{"3"}},
+		{"resources=java|linux", []string{"1", "3"}},
+		{"uuid=4", []string{}},
+	} {
+		s, err := ParseAgentSelector(test.selector)
+		assert.NoError(t, err)
+
+		uuids := []string{}
+		for _, agent := range s.Filter(agents) {
+			uuids = append(uuids, agent.UUID)
+		}
+		assert.Equal(t, test.uuids, uuids, test.selector)
+	}
+}
+
+func testAgentSelectorFreeSpace(t *testing.T) {
+	agent := &Agent{FreeSpace: 5 << 30}
+
+	for _, test := range []struct {
+		selector string
+		matches  bool
+	}{
+		{"free_space<10GB", true},
+		{"free_space>10GB", false},
+		{"free_space>=5GB", true},
+		{"free_space<=5gb", true},
+		{"free_space=5120MB", true},
+		{"free_space!=5120MB", false},
+		{"free_space>4.5GB", true},
+		{"free_space<1024", false},
+	} {
+		s, err := ParseAgentSelector(test.selector)
+		assert.NoError(t, err)
+		assert.Equal(t, test.matches, s.Matches(agent), test.selector)
+	}
+}
@@ -2,10 +2,12 @@ package gocd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 )
 
@@ -14,12 +16,17 @@ func TestAgent(t *testing.T) {
 	defer teardown()
 
 	t.Run("JobRunHistory", testAgentJobRunHistory)
+	t.Run("JobRunHistoryIterator", testAgentJobRunHistoryIterator)
+	t.Run("JobRunHistoryQueryOffset", testAgentJobRunHistoryQueryOffset)
 	t.Run("BulkUpdate", testAgentBulkUpdate)
 	t.Run("Delete", testAgentDelete)
 	t.Run("Get", testAgentGet)
 	t.Run("Update", testAgentUpdate)
 	t.Run("RemoveLinks", testAgentRemoveLinks)
 	t.Run("List", testAgentList)
+	t.Run("Select", testAgentSelect)
+	t.Run("BulkDelete", testAgentBulkDelete)
+	t.Run("UnmarshalEnvironments", testAgentUnmarshalEnvironments)
 }
 
 func testAgentJobRunHistory(t *testing.T) {
@@ -59,6 +66,91 @@ func testAgentJobRunHistory(t *testing.T) {
 
 }
 
+func testAgentJobRunHistoryQueryOffset(t *testing.T) {
+	uuid := "testAgentJobRunHistoryQueryOffset-e6d3-4299-9120-7faff6e6030b"
+	v := &ServerVersion{Version: "20.1.0"}
+	assert.NoError(t, v.parseVersion())
+	client.setCachedServerVersion(v)
+	defer client.setCachedServerVersion(nil)
+
+	mux.HandleFunc("/api/agents/"+uuid+"/job_run_history", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, apiV1, r.Header.Get("Accept"))
+		assert.Equal(t, "offset=10", r.URL.RawQuery)
+
+		b, _ := json.Marshal(JobRunHistoryResponse{
+			Jobs:       []*Job{{Name: "job-10", ID: 10}},
+			Pagination: &PaginationResponse{Offset: 10, Total: 11, PageSize: 10},
+		})
+		fmt.Fprint(w, string(b))
+	})
+
+	page, _, err := client.Agents.JobRunHistoryPage(context.Background(), uuid, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, page.Jobs, 1) {
+		assert.Equal(t, "job-10", page.Jobs[0].Name)
+	}
+}
+
+func testAgentJobRunHistoryIterator(t *testing.T) {
+	uuid := "testAgentJobRunHistoryIterator-e6d3-4299-9120-7faff6e6030b"
+	requests := 0
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Method, "GET", "Unexpected HTTP method")
+		requests++
+
+		offset := 0
+		fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/api/agents/"+uuid+"/job_run_history/"), "%d", &offset)
+
+		jobs := []*Job{}
+		for i := offset; i < offset+10 && i < 25; i++ {
+			jobs = append(jobs, &Job{Name: fmt.Sprintf("job-%d", i), ID: i})
+		}
+		b, _ := json.Marshal(JobRunHistoryResponse{
+			Jobs:       jobs,
+			Pagination: &PaginationResponse{Offset: offset, Total: 25, PageSize: 10},
+		})
+		fmt.Fprint(w, string(b))
+	}
+	mux.HandleFunc("/api/agents/"+uuid+"/job_run_history", handler)
+	mux.HandleFunc("/api/agents/"+uuid+"/job_run_history/", handler)
+
+	t.Run("All", func(t *testing.T) {
+		requests = 0
+		jobs, _, err := client.Agents.AllJobRunHistory(context.Background(), uuid, 0)
+		assert.Nil(t, err)
+		assert.Len(t, jobs, 25)
+		assert.Equal(t, 3, requests)
+		for i, job := range jobs {
+			assert.Equal(t, i, job.ID)
+		}
+	})
+
+	t.Run("MaxItems", func(t *testing.T) {
+		requests = 0
+		jobs, _, err := client.Agents.AllJobRunHistory(context.Background(), uuid, 12)
+		assert.Nil(t, err)
+		assert.Len(t, jobs, 12)
+		assert.Equal(t, 2, requests)
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		requests = 0
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		it := client.Agents.IterateJobRunHistory(uuid, 0)
+		count := 0
+		for it.Next(ctx) {
+			if count++; count == 5 {
+				cancel()
+			}
+		}
+		assert.Equal(t, context.Canceled, it.Err())
+		assert.Equal(t, 5, count)
+		assert.Equal(t, 1, requests)
+		assert.Nil(t, it.Job())
+	})
+}
+
 func testAgentBulkUpdate(t *testing.T) {
 
 	mux.HandleFunc("/api/agents", func(w http.ResponseWriter, r *http.Request) {
@@ -180,6 +272,70 @@ func testAgentList(t *testing.T) {
 	testAgent(t, agents[0])
 }
 
+func testAgentSelect(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/agents", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Method, "GET", "Unexpected HTTP method")
+		j, _ := ioutil.ReadFile("test/resources/agents.1.json")
+		fmt.Fprint(w, string(j))
+	})
+
+	selector, err := ParseAgentSelector("resources=java,env=UAT")
+	assert.Nil(t, err)
+	agents, _, err := client.Agents.Select(context.Background(), selector)
+	assert.Nil(t, err)
+	assert.Len(t, agents, 1)
+
+	selector, err = ParseAgentSelector("resources=java,env!=UAT")
+	assert.Nil(t, err)
+	agents, _, err = client.Agents.Select(context.Background(), selector)
+	assert.Nil(t, err)
+	assert.Len(t, agents, 0)
+}
+
+func testAgentBulkDelete(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/agents", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Method, "DELETE", "Unexpected HTTP method")
+		bdy, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, `{
+  "uuids": [
+    "adb9540a-b954-4571-9d9b-2f330739d4da",
+    "adb528b2-b954-1234-9d9b-b27ag4h568e1"
+  ]
+}
+`, string(bdy))
+		fmt.Fprint(w, `{"message":"Deleted 2 agent(s)."}`)
+	})
+
+	message, _, err := client.Agents.BulkDelete(context.Background(), []string{
+		"adb9540a-b954-4571-9d9b-2f330739d4da",
+		"adb528b2-b954-1234-9d9b-b27ag4h568e1",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "Deleted 2 agent(s).", message)
+}
+
+func testAgentUnmarshalEnvironments(t *testing.T) {
+	for _, body := range []string{
+		`{"uuid": "agent-1", "environments": ["perf", "UAT"]}`,
+		`{"uuid": "agent-1", "environments": [{"name": "perf", "origin": {"type": "gocd"}}, {"name": "UAT"}]}`,
+	} {
+		agent := &Agent{}
+		assert.NoError(t, json.Unmarshal([]byte(body), agent))
+		assert.Equal(t, "agent-1", agent.UUID)
+		assert.Equal(t, []string{"perf", "UAT"}, agent.Environments)
+	}
+
+	err := json.Unmarshal([]byte(`{"environments": [1]}`), &Agent{})
+	assert.EqualError(t, err, "unexpected agent environment: 1")
+}
+
 func testAgent(t *testing.T, agent *Agent) {
 
 	for _, attribute := range []EqualityTest{
@@ -2,8 +2,9 @@ package gocd
 
 // Approval represents a request/response object describing the approval configuration for a GoCD Job
 type Approval struct {
-	Type          string         `json:"type"`
-	Authorization *Authorization `json:"authorization"`
+	Type               string         `json:"type"`
+	AllowOnlyOnSuccess bool           `json:"allow_only_on_success,omitempty"`
+	Authorization      *Authorization `json:"authorization"`
 }
 
 // Authorization describes the access control for a "manual" approval type. Specifies who (role or users) can approve
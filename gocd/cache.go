@@ -0,0 +1,211 @@
+package gocd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResponseCache describes a backend storing responses for conditional GET requests. Implementations must be safe for
+// concurrent use.
+type ResponseCache interface {
+	Get(key string) (response *CachedResponse, ok bool)
+	Set(key string, response *CachedResponse)
+	Delete(key string)
+}
+
+// CachedResponse describes a response stored in a ResponseCache.
+type CachedResponse struct {
+	ETag       string
+	Accept     string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	StoredAt   time.Time
+}
+
+// CacheOptions describe how the client caches responses from the GoCD server.
+//
+// Every GET request to a cached endpoint is sent with an `If-None-Match` header, and `304 Not Modified` responses are
+// served from the cache. Responses younger than the TTL for their endpoint are served from the cache without contacting
+// the server at all.
+type CacheOptions struct {
+	// Cache is the backend the responses are stored in. Defaults to a MemoryCache.
+	Cache ResponseCache
+	// DefaultTTL is used for endpoints without an entry in TTLs.
+	DefaultTTL time.Duration
+	// TTLs by endpoint, as listed in the server version lookup, eg: `/api/admin/pipelines/:pipeline_name` or
+	// `/api/admin/environments`.
+	TTLs map[string]time.Duration
+}
+
+// MemoryCache is a ResponseCache storing responses in memory.
+type MemoryCache struct {
+	mu        sync.RWMutex
+	responses map[string]*CachedResponse
+}
+
+// NewMemoryCache creates an empty in-memory response cache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		responses: map[string]*CachedResponse{},
+	}
+}
+
+// Get a response from the cache.
+func (mc *MemoryCache) Get(key string) (response *CachedResponse, ok bool) {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	response, ok = mc.responses[key]
+	return
+}
+
+// Set a response in the cache.
+func (mc *MemoryCache) Set(key string, response *CachedResponse) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.responses[key] = response
+}
+
+// Delete a response from the cache.
+func (mc *MemoryCache) Delete(key string) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	delete(mc.responses, key)
+}
+
+// EnableCache turns on conditional GET requests and response caching for this client. Passing nil disables caching.
+func (c *Client) EnableCache(options *CacheOptions) {
+	c.Lock()
+	defer c.Unlock()
+
+	if options != nil && options.Cache == nil {
+		options.Cache = NewMemoryCache()
+	}
+	c.cache = options
+}
+
+// ttl returns how long a response for the endpoint matching the path can be served without revalidation.
+func (co *CacheOptions) ttl(path string) time.Duration {
+	if endpoint, hasEndpoint := serverVersionLookup.MatchEndpoint(path); hasEndpoint {
+		if ttl, hasTTL := co.TTLs[endpoint]; hasTTL {
+			return ttl
+		}
+	}
+	return co.DefaultTTL
+}
+
+// doCached performs the request, using the cache if it is enabled. It returns true if the response was served from the
+// cache. The cache and the http client are read once, under the lock, so that the client can be reconfigured while
+// requests are made.
+func (c *Client) doCached(req *http.Request) (resp *http.Response, fromCache bool, err error) {
+	c.Lock()
+	cache, httpClient := c.cache, c.client
+	c.Unlock()
+
+	if cache == nil {
+		resp, err = httpClient.Do(req)
+		return
+	}
+
+	key := req.URL.String()
+	if req.Method != "GET" {
+		if resp, err = httpClient.Do(req); err == nil && resp.StatusCode < 400 {
+			cache.invalidate(req.URL)
+		}
+		return
+	}
+
+	accept := req.Header.Get("Accept")
+	cached, isCached := cache.Cache.Get(key)
+	if isCached && cached.Accept != accept {
+		isCached = false
+	}
+
+	if isCached {
+		if path, pathErr := c.relativePath(req.URL); pathErr == nil && time.Since(cached.StoredAt) < cache.ttl(path) {
+			return cached.response(req), true, nil
+		}
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	if resp, err = httpClient.Do(req); err != nil {
+		return
+	}
+
+	if resp.StatusCode == http.StatusNotModified && isCached {
+		resp.Body.Close()
+		refreshed := *cached
+		refreshed.StoredAt = time.Now()
+		cache.Cache.Set(key, &refreshed)
+		return refreshed.response(req), true, nil
+	}
+
+	etag := resp.Header.Get("Etag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return
+	}
+
+	var body []byte
+	if body, err = ioutil.ReadAll(resp.Body); err != nil {
+		return
+	}
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	cache.Cache.Set(key, &CachedResponse{
+		ETag:       etag,
+		Accept:     accept,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		StoredAt:   time.Now(),
+	})
+	return
+}
+
+// invalidate removes the cached responses for a resource which has been modified, and for the collection it belongs
+// to.
+func (co *CacheOptions) invalidate(u *url.URL) {
+	resource := *u
+	resource.Path = strings.TrimSuffix(resource.Path, "/")
+	withSlash := resource
+	withSlash.Path += "/"
+	co.Cache.Delete(resource.String())
+	co.Cache.Delete(withSlash.String())
+
+	if i := strings.LastIndex(resource.Path, "/"); i > 0 {
+		collection := resource
+		collection.Path = resource.Path[:i]
+		collection.RawQuery = ""
+		co.Cache.Delete(collection.String())
+	}
+}
+
+// response builds a net/http.Response from the cached response.
+func (cr *CachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", cr.StatusCode, http.StatusText(cr.StatusCode)),
+		StatusCode:    cr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cloneHeader(cr.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader(cr.Body)),
+		ContentLength: int64(len(cr.Body)),
+		Request:       req,
+	}
+}
+
+func cloneHeader(header http.Header) http.Header {
+	clone := make(http.Header, len(header))
+	for key, values := range header {
+		clone[key] = append([]string(nil), values...)
+	}
+	return clone
+}
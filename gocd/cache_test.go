@@ -0,0 +1,189 @@
+package gocd
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	t.Run("ConditionalGet", testCacheConditionalGet)
+	t.Run("TTL", testCacheTTL)
+	t.Run("Invalidate", testCacheInvalidate)
+	t.Run("Disabled", testCacheDisabled)
+	t.Run("MemoryCache", testCacheMemoryCache)
+	t.Run("Reconfigure", testCacheReconfigure)
+}
+
+// testCacheReconfigure turns the cache on and off while requests are made, for `go test -race` to check.
+func testCacheReconfigure(t *testing.T) {
+	setup()
+	defer teardown()
+
+	etag := `"etag-1"`
+	requests, notModified := 0, 0
+	mux.HandleFunc("/api/admin/environments", cachedEnvironmentHandler(t, &etag, &requests, &notModified))
+	mux.HandleFunc("/api/version", cacheServerVersionHandler)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			client.EnableCache(&CacheOptions{})
+			client.EnableCache(nil)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		_, _, err := client.Environments.List(context.Background())
+		assert.NoError(t, err)
+	}
+	<-done
+}
+
+// cachedEnvironmentHandler serves the environment list with an ETag, and counts the requests and 304 responses.
+func cachedEnvironmentHandler(t *testing.T, etag *string, requests, notModified *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		assert.Equal(t, apiV2, r.Header.Get("Accept"))
+		if r.Header.Get("If-None-Match") == *etag {
+			*notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", *etag)
+		j, _ := ioutil.ReadFile("test/resources/environment.0.json")
+		fmt.Fprint(w, string(j))
+	}
+}
+
+func cacheServerVersionHandler(w http.ResponseWriter, r *http.Request) {
+	j, _ := ioutil.ReadFile("test/resources/version.2.json")
+	fmt.Fprint(w, string(j))
+}
+
+func testCacheConditionalGet(t *testing.T) {
+	setup()
+	defer teardown()
+
+	etag := `"etag-1"`
+	requests, notModified := 0, 0
+	mux.HandleFunc("/api/admin/environments", cachedEnvironmentHandler(t, &etag, &requests, &notModified))
+	mux.HandleFunc("/api/version", cacheServerVersionHandler)
+
+	client.EnableCache(&CacheOptions{})
+
+	envs, resp, err := client.Environments.List(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, resp.FromCache)
+	assert.Len(t, envs.Embedded.Environments, 1)
+
+	envs, resp, err = client.Environments.List(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, resp.FromCache)
+	assert.Equal(t, 200, resp.HTTP.StatusCode)
+	assert.Len(t, envs.Embedded.Environments, 1)
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 1, notModified)
+
+	etag = `"etag-2"`
+	_, resp, err = client.Environments.List(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, resp.FromCache)
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, 1, notModified)
+}
+
+func testCacheTTL(t *testing.T) {
+	setup()
+	defer teardown()
+
+	etag := `"etag-1"`
+	requests, notModified := 0, 0
+	mux.HandleFunc("/api/admin/environments", cachedEnvironmentHandler(t, &etag, &requests, &notModified))
+	mux.HandleFunc("/api/version", cacheServerVersionHandler)
+
+	client.EnableCache(&CacheOptions{
+		TTLs: map[string]time.Duration{
+			"/api/admin/environments": time.Hour,
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		_, _, err := client.Environments.List(context.Background())
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, 0, notModified)
+}
+
+func testCacheInvalidate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	etag := `"etag-1"`
+	requests, notModified := 0, 0
+	mux.HandleFunc("/api/admin/environments", cachedEnvironmentHandler(t, &etag, &requests, &notModified))
+	mux.HandleFunc("/api/admin/environments/my_environment", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "DELETE", r.Method)
+		fmt.Fprint(w, `{"message": "Environment 'my_environment' was deleted successfully."}`)
+	})
+	mux.HandleFunc("/api/version", cacheServerVersionHandler)
+
+	client.EnableCache(&CacheOptions{DefaultTTL: time.Hour})
+
+	_, _, err := client.Environments.List(context.Background())
+	assert.NoError(t, err)
+	_, resp, err := client.Environments.List(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, resp.FromCache)
+	assert.Equal(t, 1, requests)
+
+	_, _, err = client.Environments.Delete(context.Background(), "my_environment")
+	assert.NoError(t, err)
+
+	_, resp, err = client.Environments.List(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, resp.FromCache)
+	assert.Equal(t, 2, requests)
+}
+
+func testCacheDisabled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	etag := `"etag-1"`
+	requests, notModified := 0, 0
+	mux.HandleFunc("/api/admin/environments", cachedEnvironmentHandler(t, &etag, &requests, &notModified))
+	mux.HandleFunc("/api/version", cacheServerVersionHandler)
+
+	client.EnableCache(&CacheOptions{DefaultTTL: time.Hour})
+	client.EnableCache(nil)
+
+	for i := 0; i < 2; i++ {
+		_, resp, err := client.Environments.List(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, resp.FromCache)
+	}
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 0, notModified)
+}
+
+func testCacheMemoryCache(t *testing.T) {
+	c := NewMemoryCache()
+
+	_, ok := c.Get("key")
+	assert.False(t, ok)
+
+	c.Set("key", &CachedResponse{ETag: "etag"})
+	r, ok := c.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, "etag", r.ETag)
+
+	c.Delete("key")
+	_, ok = c.Get("key")
+	assert.False(t, ok)
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/user"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -20,6 +21,12 @@ const (
 	EnvVarUsername       = "GOCD_USERNAME"
 	EnvVarPassword       = "GOCD_PASSWORD"
 	EnvVarSkipSsl        = "GOCD_SKIP_SSL_CHECK"
+	EnvVarCAFile         = "GOCD_CA_FILE"
+	EnvVarClientCert     = "GOCD_CLIENT_CERT"
+	EnvVarClientKey      = "GOCD_CLIENT_KEY"
+	EnvVarProxyURL       = "GOCD_PROXY_URL"
+	EnvVarNoProxy        = "GOCD_NO_PROXY"
+	EnvVarTimeout        = "GOCD_TIMEOUT"
 )
 
 // Configuration describes a single connection to a GoCD server
@@ -28,6 +35,18 @@ type Configuration struct {
 	Username     string `yaml:"username,omitempty"`
 	Password     string `yaml:"password,omitempty"`
 	SkipSslCheck bool   `yaml:"skip_ssl_check,omitempty" survey:"skip_ssl_check"`
+
+	// CAFile is the path to a PEM encoded bundle of certificate authorities trusted in addition to the system ones.
+	CAFile string `yaml:"ca_file,omitempty" survey:"ca_file"`
+	// ClientCert and ClientKey are paths to a PEM encoded certificate and key presented to the server for mutual TLS.
+	ClientCert string `yaml:"client_cert,omitempty" survey:"client_cert"`
+	ClientKey  string `yaml:"client_key,omitempty" survey:"client_key"`
+	// ProxyURL overrides the proxy taken from the HTTP_PROXY/HTTPS_PROXY environment variables.
+	ProxyURL string `yaml:"proxy_url,omitempty" survey:"proxy_url"`
+	// NoProxy is a comma separated list of hosts, domains and CIDR ranges which are not reached through ProxyURL.
+	NoProxy string `yaml:"no_proxy,omitempty" survey:"no_proxy"`
+	// Timeout for a whole request, eg: "30s". No timeout is applied if empty.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
 }
 
 // LoadConfigByName loads configurations from yaml at the default file location
@@ -57,6 +76,29 @@ func LoadConfigByName(name string, cfg *Configuration) (err error) {
 		cfg.Password = password
 	}
 
+	return cfg.loadTransportFromEnv()
+}
+
+// loadTransportFromEnv overrides the TLS, proxy and timeout settings with the values from the environment.
+func (c *Configuration) loadTransportFromEnv() (err error) {
+	for envVar, dest := range map[string]*string{
+		EnvVarCAFile:     &c.CAFile,
+		EnvVarClientCert: &c.ClientCert,
+		EnvVarClientKey:  &c.ClientKey,
+		EnvVarProxyURL:   &c.ProxyURL,
+		EnvVarNoProxy:    &c.NoProxy,
+	} {
+		if value := os.Getenv(envVar); value != "" {
+			*dest = value
+		}
+	}
+
+	if timeout := os.Getenv(EnvVarTimeout); timeout != "" {
+		if c.Timeout, err = time.ParseDuration(timeout); err != nil {
+			return fmt.Errorf("invalid %s '%s': %s", EnvVarTimeout, timeout, err)
+		}
+	}
+
 	return nil
 }
 
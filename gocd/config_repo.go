@@ -39,12 +39,19 @@ type ConfigRepoProperty struct {
 // List returns all available config repos, these are config repositories that
 // are present in the in `cruise-config.xml`
 func (crs *ConfigRepoService) List(ctx context.Context) (repos []*ConfigRepo, resp *APIResponse, err error) {
+	apiVersion, err := crs.client.getAPIVersion(ctx, "admin/config_repos")
+	if err != nil {
+		return nil, nil, err
+	}
+
 	r := &ConfigReposListResponse{}
-	_, resp, err = crs.client.getAction(ctx, &APIClientRequest{
+	if _, resp, err = crs.client.getAction(ctx, &APIClientRequest{
 		Path:         "admin/config_repos",
 		ResponseBody: r,
-		APIVersion:   apiV1,
-	})
+		APIVersion:   apiVersion,
+	}); err != nil {
+		return nil, resp, err
+	}
 
 	for _, repos := range r.Embedded.Repos {
 		repos.client = crs.client
@@ -56,11 +63,16 @@ func (crs *ConfigRepoService) List(ctx context.Context) (repos []*ConfigRepo, re
 
 // Get fetches the config repo object for a specified id
 func (crs *ConfigRepoService) Get(ctx context.Context, id string) (out *ConfigRepo, resp *APIResponse, err error) {
+	apiVersion, err := crs.client.getAPIVersion(ctx, "admin/config_repos/:id")
+	if err != nil {
+		return nil, nil, err
+	}
+
 	out = &ConfigRepo{}
 	_, resp, err = crs.client.getAction(ctx, &APIClientRequest{
 		Path:         fmt.Sprintf("admin/config_repos/%s", id),
 		ResponseBody: out,
-		APIVersion:   apiV1,
+		APIVersion:   apiVersion,
 	})
 
 	out.client = crs.client
@@ -69,12 +81,17 @@ func (crs *ConfigRepoService) Get(ctx context.Context, id string) (out *ConfigRe
 
 // Create a config repo
 func (crs *ConfigRepoService) Create(ctx context.Context, cr *ConfigRepo) (out *ConfigRepo, resp *APIResponse, err error) {
+	apiVersion, err := crs.client.getAPIVersion(ctx, "admin/config_repos")
+	if err != nil {
+		return nil, nil, err
+	}
+
 	out = &ConfigRepo{}
 	_, resp, err = crs.client.postAction(ctx, &APIClientRequest{
 		Path:         "admin/config_repos",
 		RequestBody:  cr,
 		ResponseBody: out,
-		APIVersion:   apiV1,
+		APIVersion:   apiVersion,
 	})
 
 	out.client = crs.client
@@ -83,12 +100,17 @@ func (crs *ConfigRepoService) Create(ctx context.Context, cr *ConfigRepo) (out *
 
 // Update config repos for specified config repo id
 func (crs *ConfigRepoService) Update(ctx context.Context, id string, cr *ConfigRepo) (out *ConfigRepo, resp *APIResponse, err error) {
+	apiVersion, err := crs.client.getAPIVersion(ctx, "admin/config_repos/:id")
+	if err != nil {
+		return nil, nil, err
+	}
+
 	out = &ConfigRepo{}
 	_, resp, err = crs.client.putAction(ctx, &APIClientRequest{
 		Path:         fmt.Sprintf("admin/config_repos/%s", id),
 		RequestBody:  cr,
 		ResponseBody: out,
-		APIVersion:   apiV1,
+		APIVersion:   apiVersion,
 	})
 
 	out.client = crs.client
@@ -97,5 +119,10 @@ func (crs *ConfigRepoService) Update(ctx context.Context, id string, cr *ConfigR
 
 // Delete the specified config repo
 func (crs *ConfigRepoService) Delete(ctx context.Context, id string) (string, *APIResponse, error) {
-	return crs.client.deleteAction(ctx, fmt.Sprintf("admin/config_repos/%s", id), apiV1)
+	apiVersion, err := crs.client.getAPIVersion(ctx, "admin/config_repos/:id")
+	if err != nil {
+		return "", nil, err
+	}
+
+	return crs.client.deleteAction(ctx, fmt.Sprintf("admin/config_repos/%s", id), apiVersion)
 }
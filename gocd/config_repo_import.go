@@ -0,0 +1,336 @@
+package gocd
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// JSONFormatVersion is the newest `format_version` of the files read for the gocd-json-config-plugin.
+const JSONFormatVersion = 10
+
+// ParseConfigRepoFile reads the pipelines and environments defined in a file of a config repository. The format is
+// chosen by the name of the file:
+//
+//	*.gocd.yaml, *.gocd.yml        gocd-yaml-config-plugin
+//	*.gocd.json                    gocd-json-config-plugin, holding `pipelines` and `environments`
+//	*.gopipeline.json              gocd-json-config-plugin, holding a single pipeline
+//	*.goenvironment.json           gocd-json-config-plugin, holding a single environment
+//
+// Files written for older format versions use fields which have since been replaced, eg: `locking` rather than
+// `lock_behavior`, or fetch tasks without an `artifact_origin`. Those are read as their current equivalent, so that
+// the pipelines can be submitted with PipelineConfigsService. A file with a format version newer than the plugins
+// supported by the library is rejected.
+//
+// Materials of type `configrepo` refer to the repository holding the file, and are replaced with a copy of the
+// material of `repo`. They can not be read when `repo` is nil.
+func ParseConfigRepoFile(name string, content []byte, repo *ConfigRepo) (pipelines []*Pipeline, environments []*Environment, err error) {
+	base := path.Base(name)
+	switch {
+	case strings.HasSuffix(base, ".gocd.yaml"), strings.HasSuffix(base, ".gocd.yml"):
+		pipelines, environments, err = parseYAMLConfigFile(content, repo)
+	case strings.HasSuffix(base, ".gocd.json"):
+		pipelines, environments, err = parseJSONConfigFile(content, repo)
+	case strings.HasSuffix(base, ".gopipeline.json"):
+		pipelines, err = parseJSONPipelineFile(content, repo)
+	case strings.HasSuffix(base, ".goenvironment.json"):
+		environments, err = parseJSONEnvironmentFile(content)
+	default:
+		return nil, nil, fmt.Errorf("'%s' is not a config repository file", name)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %s", name, err)
+	}
+
+	sort.Slice(pipelines, func(i, j int) bool { return pipelines[i].Name < pipelines[j].Name })
+	sort.Slice(environments, func(i, j int) bool { return environments[i].Name < environments[j].Name })
+	return pipelines, environments, nil
+}
+
+// checkFormatVersion rejects the files written for plugins newer than the library. Files without a format version
+// were written for the first one.
+func checkFormatVersion(version int, newest int) error {
+	if version < 0 || version > newest {
+		return fmt.Errorf("format_version %d is not supported, it must be between 1 and %d", version, newest)
+	}
+	return nil
+}
+
+// configRepoMaterial holds the attributes of a material of any type, as the plugins describe them.
+// codebeat:disable[TOO_MANY_IVARS]
+type configRepoMaterial struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+
+	URL            string `json:"url"`
+	Branch         string `json:"branch"`
+	ShallowClone   bool   `json:"shallow_clone"`
+	CheckExternals bool   `json:"check_externals"`
+	Port           string `json:"port"`
+	UseTickets     bool   `json:"use_tickets"`
+	View           string `json:"view"`
+	Domain         string `json:"domain"`
+	Project        string `json:"project"`
+
+	Username          string `json:"username"`
+	Password          string `json:"password"`
+	EncryptedPassword string `json:"encrypted_password"`
+
+	Pipeline  string `json:"pipeline"`
+	Stage     string `json:"stage"`
+	PackageID string `json:"package_id"`
+	SCMID     string `json:"scm_id"`
+
+	Destination string                    `json:"destination"`
+	AutoUpdate  *bool                     `json:"auto_update"`
+	Filter      *configRepoMaterialFilter `json:"filter"`
+}
+
+// codebeat:enable[TOO_MANY_IVARS]
+
+type configRepoMaterialFilter struct {
+	Ignore    []string `json:"ignore"`
+	Whitelist []string `json:"whitelist"`
+}
+
+// filter returns the ignored, or included when inverted, files of the material.
+func (crm *configRepoMaterial) filter() (filter *MaterialFilter, invert bool, err error) {
+	if crm.Filter == nil {
+		return nil, false, nil
+	}
+	if len(crm.Filter.Ignore) > 0 && len(crm.Filter.Whitelist) > 0 {
+		return nil, false, fmt.Errorf("material '%s' can not both ignore and include files", crm.Name)
+	}
+	if len(crm.Filter.Whitelist) > 0 {
+		return &MaterialFilter{Ignore: crm.Filter.Whitelist}, true, nil
+	}
+	if len(crm.Filter.Ignore) > 0 {
+		return &MaterialFilter{Ignore: crm.Filter.Ignore}, false, nil
+	}
+	return nil, false, nil
+}
+
+// codebeat:disable[ABC,CYCLO,LOC]
+func (crm *configRepoMaterial) material(repo *ConfigRepo) (m Material, err error) {
+	filter, invert, err := crm.filter()
+	if err != nil {
+		return m, err
+	}
+	autoUpdate := crm.AutoUpdate == nil || *crm.AutoUpdate
+
+	m.Type = crm.Type
+	switch crm.Type {
+	case "git":
+		m.Attributes = &MaterialAttributesGit{
+			Name: crm.Name, URL: crm.URL, Branch: crm.Branch, ShallowClone: crm.ShallowClone,
+			Destination: crm.Destination, Filter: filter, InvertFilter: invert, AutoUpdate: autoUpdate,
+		}
+	case "svn":
+		m.Attributes = &MaterialAttributesSvn{
+			Name: crm.Name, URL: crm.URL, CheckExternals: crm.CheckExternals,
+			Username: crm.Username, Password: crm.Password, EncryptedPassword: crm.EncryptedPassword,
+			Destination: crm.Destination, Filter: filter, InvertFilter: invert, AutoUpdate: autoUpdate,
+		}
+	case "hg":
+		m.Attributes = &MaterialAttributesHg{
+			Name: crm.Name, URL: crm.URL,
+			Destination: crm.Destination, Filter: filter, InvertFilter: invert, AutoUpdate: autoUpdate,
+		}
+	case "p4":
+		m.Attributes = &MaterialAttributesP4{
+			Name: crm.Name, Port: crm.Port, UseTickets: crm.UseTickets, View: crm.View,
+			Username: crm.Username, Password: crm.Password, EncryptedPassword: crm.EncryptedPassword,
+			Destination: crm.Destination, Filter: filter, InvertFilter: invert, AutoUpdate: autoUpdate,
+		}
+	case "tfs":
+		m.Attributes = &MaterialAttributesTfs{
+			Name: crm.Name, URL: crm.URL, Domain: crm.Domain, ProjectPath: crm.Project,
+			Username: crm.Username, Password: crm.Password, EncryptedPassword: crm.EncryptedPassword,
+			Destination: crm.Destination, Filter: filter, InvertFilter: invert, AutoUpdate: autoUpdate,
+		}
+	case "dependency":
+		m.Attributes = &MaterialAttributesDependency{Name: crm.Name, Pipeline: crm.Pipeline, Stage: crm.Stage, AutoUpdate: true}
+	case "package":
+		m.Attributes = &MaterialAttributesPackage{Ref: crm.PackageID}
+	case "plugin":
+		m.Attributes = &MaterialAttributesPlugin{Ref: crm.SCMID, Destination: crm.Destination, Filter: filter, InvertFilter: invert}
+	case "configrepo":
+		return crm.configRepoMaterial(repo, filter, invert)
+	case "":
+		return m, fmt.Errorf("material '%s' has no type", crm.Name)
+	default:
+		return m, fmt.Errorf("material '%s' has an unknown type '%s'", crm.Name, crm.Type)
+	}
+	return m, nil
+}
+
+// codebeat:enable[ABC,CYCLO,LOC]
+
+// configRepoMaterial copies the material of the config repository, with the name, destination and filter of the
+// `configrepo` material.
+func (crm *configRepoMaterial) configRepoMaterial(repo *ConfigRepo, filter *MaterialFilter, invert bool) (m Material, err error) {
+	if repo == nil || repo.Material.Attributes == nil {
+		return m, fmt.Errorf("material '%s' refers to the config repository, which is not known", crm.Name)
+	}
+
+	m.Type = repo.Material.Type
+	switch a := repo.Material.Attributes.(type) {
+	case *MaterialAttributesGit:
+		c := *a
+		c.Name, c.Destination, c.Filter, c.InvertFilter = crm.Name, crm.Destination, filter, invert
+		m.Attributes = &c
+	case *MaterialAttributesSvn:
+		c := *a
+		c.Name, c.Destination, c.Filter, c.InvertFilter = crm.Name, crm.Destination, filter, invert
+		m.Attributes = &c
+	case *MaterialAttributesHg:
+		c := *a
+		c.Name, c.Destination, c.Filter, c.InvertFilter = crm.Name, crm.Destination, filter, invert
+		m.Attributes = &c
+	case *MaterialAttributesP4:
+		c := *a
+		c.Name, c.Destination, c.Filter, c.InvertFilter = crm.Name, crm.Destination, filter, invert
+		m.Attributes = &c
+	case *MaterialAttributesTfs:
+		c := *a
+		c.Name, c.Destination, c.Filter, c.InvertFilter = crm.Name, crm.Destination, filter, invert
+		m.Attributes = &c
+	default:
+		return m, fmt.Errorf("material '%s' refers to a config repository with a '%s' material", crm.Name, repo.Material.Type)
+	}
+	return m, nil
+}
+
+// configRepoTask holds the attributes of a task of any type, as the plugins describe them.
+// codebeat:disable[TOO_MANY_IVARS]
+type configRepoTask struct {
+	Type  string `json:"type"`
+	RunIf string `json:"run_if"`
+
+	Command          string   `json:"command"`
+	Arguments        []string `json:"arguments"`
+	BuildFile        string   `json:"build_file"`
+	Target           string   `json:"target"`
+	NantPath         string   `json:"nant_path"`
+	WorkingDirectory string   `json:"working_directory"`
+
+	ArtifactOrigin string `json:"artifact_origin"`
+	Pipeline       string `json:"pipeline"`
+	Stage          string `json:"stage"`
+	Job            string `json:"job"`
+	Source         string `json:"source"`
+	IsSourceAFile  bool   `json:"is_source_a_file"`
+	Destination    string `json:"destination"`
+	ArtifactID     string `json:"artifact_id"`
+
+	PluginConfiguration *TaskPluginConfiguration    `json:"plugin_configuration"`
+	Configuration       []PluginConfigurationKVPair `json:"configuration"`
+
+	OnCancel *configRepoTask `json:"on_cancel"`
+}
+
+// codebeat:enable[TOO_MANY_IVARS]
+
+// codebeat:disable[ABC,CYCLO]
+func (crt *configRepoTask) task() (*Task, error) {
+	runIf := []string{"passed"}
+	switch crt.RunIf {
+	case "":
+	case "passed", "failed", "any":
+		runIf = []string{crt.RunIf}
+	default:
+		return nil, fmt.Errorf("'run_if' must be one of 'passed', 'failed' or 'any', not '%s'", crt.RunIf)
+	}
+
+	var onCancel *Task
+	if crt.OnCancel != nil {
+		cancel, err := crt.OnCancel.task()
+		if err != nil {
+			return nil, fmt.Errorf("on_cancel: %s", err)
+		}
+		onCancel = cancel
+	}
+
+	task := &Task{Type: crt.Type}
+	switch crt.Type {
+	case "exec":
+		task.Attributes = &TaskAttributesExec{
+			RunIf: runIf, Command: crt.Command, Arguments: crt.Arguments, WorkingDirectory: crt.WorkingDirectory,
+			OnCancel: onCancel,
+		}
+	case "ant":
+		task.Attributes = &TaskAttributesAnt{
+			RunIf: runIf, BuildFile: crt.BuildFile, Target: crt.Target, WorkingDirectory: crt.WorkingDirectory,
+			OnCancel: onCancel,
+		}
+	case "nant":
+		task.Attributes = &TaskAttributesNant{
+			RunIf: runIf, BuildFile: crt.BuildFile, Target: crt.Target, WorkingDirectory: crt.WorkingDirectory,
+			NantPath: crt.NantPath, OnCancel: onCancel,
+		}
+	case "rake":
+		task.Attributes = &TaskAttributesRake{
+			RunIf: runIf, BuildFile: crt.BuildFile, Target: crt.Target, WorkingDirectory: crt.WorkingDirectory,
+			OnCancel: onCancel,
+		}
+	case "fetch":
+		fetch := &TaskAttributesFetch{
+			ArtifactOrigin: crt.ArtifactOrigin, RunIf: runIf, Pipeline: crt.Pipeline, Stage: crt.Stage, Job: crt.Job,
+			OnCancel: onCancel,
+		}
+		if crt.ArtifactOrigin == "external" {
+			fetch.ArtifactID, fetch.Configuration = crt.ArtifactID, crt.Configuration
+		} else {
+			// Fetch tasks written before external artifacts were supported have no origin.
+			fetch.ArtifactOrigin = "gocd"
+			fetch.Source, fetch.IsSourceAFile, fetch.Destination = crt.Source, crt.IsSourceAFile, crt.Destination
+		}
+		task.Attributes = fetch
+	case "plugin", "pluggable_task":
+		task.Type = "pluggable_task"
+		task.Attributes = &TaskAttributesPluggable{
+			RunIf: runIf, PluginConfiguration: crt.PluginConfiguration, Configuration: crt.Configuration,
+			OnCancel: onCancel,
+		}
+	case "":
+		return nil, fmt.Errorf("task has no type")
+	default:
+		return nil, fmt.Errorf("task has an unknown type '%s'", crt.Type)
+	}
+	return task, nil
+}
+
+// codebeat:enable[ABC,CYCLO]
+
+// configRepoVariables lists the plain and secure variables of a pipeline, stage, job or environment by name.
+func configRepoVariables(plain map[string]string, secure map[string]string) (envs []*EnvironmentVariable) {
+	for _, name := range sortedKeys(plain) {
+		envs = append(envs, &EnvironmentVariable{Name: name, Value: plain[name]})
+	}
+	for _, name := range sortedKeys(secure) {
+		envs = append(envs, &EnvironmentVariable{Name: name, EncryptedValue: secure[name], Secure: true})
+	}
+	return
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// configRepoEnvironment builds an environment from the names of its agents and pipelines.
+func configRepoEnvironment(name string, envs []*EnvironmentVariable, agents []string, pipelines []string) *Environment {
+	env := &Environment{Name: name, EnvironmentVariables: envs}
+	for _, uuid := range agents {
+		env.Agents = append(env.Agents, &Agent{UUID: uuid})
+	}
+	for _, pipeline := range pipelines {
+		env.Pipelines = append(env.Pipelines, &Pipeline{Name: pipeline})
+	}
+	return env
+}
@@ -0,0 +1,351 @@
+package gocd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConfigRepoFile(t *testing.T) {
+	t.Run("YAMLRoundTrip", testParseConfigRepoFileYAMLRoundTrip)
+	t.Run("YAMLOlderFormats", testParseConfigRepoFileYAMLOlderFormats)
+	t.Run("YAMLEnvironments", testParseConfigRepoFileYAMLEnvironments)
+	t.Run("JSON", testParseConfigRepoFileJSON)
+	t.Run("JSONSingleFiles", testParseConfigRepoFileJSONSingleFiles)
+	t.Run("ConfigRepoMaterial", testParseConfigRepoFileConfigRepoMaterial)
+	t.Run("Errors", testParseConfigRepoFileErrors)
+}
+
+func testParseConfigRepoFileYAMLRoundTrip(t *testing.T) {
+	exported, err := NewPipeline("my-pipeline").
+		Group("my-group").
+		LabelTemplate("${COUNT}-${gocd[:7]}").
+		LockBehavior(LockBehaviorNone).
+		Timer("0 0 22 ? * MON-FRI", false).
+		Parameter("env", "dev").
+		Material(Material{Type: "git", Attributes: &MaterialAttributesGit{Name: "gocd", URL: "https://github.com/gocd/gocd", Branch: "master",
+			AutoUpdate: true, Filter: &MaterialFilter{Ignore: []string{"docs/**"}}}}).
+		Material(Material{Type: "dependency", Attributes: &MaterialAttributesDependency{Name: "upstream", Pipeline: "upstream", Stage: "build", AutoUpdate: true}}).
+		SecureEnv("TOKEN", "").
+		Stage("build").Env("DEBUG", "1").
+		Job("compile").Timeout(10).Resources("linux").Artifact("bin/", "dist").
+		Exec("make", "all").
+		Exec("cleanup").RunIf("failed").
+		Stage("deploy").Manual().
+		Job("deploy").Fetch("", "build", "compile", "bin/").
+		Task(&Task{Type: "pluggable_task", Attributes: &TaskAttributesPluggable{RunIf: []string{"passed"},
+			PluginConfiguration: &TaskPluginConfiguration{ID: "script-executor", Version: "1"},
+			Configuration:       []PluginConfigurationKVPair{{Key: "script", Value: "./deploy.sh"}}}}).
+		Build()
+	assert.NoError(t, err)
+	exported.EnvironmentVariables[0].EncryptedValue = "AES:abc"
+	exported.Stages[1].Jobs[0].Tasks[0].Attributes.(*TaskAttributesFetch).ArtifactOrigin = "gocd"
+	exported.Stages[0].Jobs[0].Tasks[0].Attributes.(*TaskAttributesExec).OnCancel = &Task{Type: "exec",
+		Attributes: &TaskAttributesExec{RunIf: []string{"passed"}, Command: "pkill", Arguments: []string{"make"}}}
+	exported.Stages[1].Jobs[0].Tasks[0].Attributes.(*TaskAttributesFetch).OnCancel = &Task{Type: "rake",
+		Attributes: &TaskAttributesRake{RunIf: []string{"passed"}, Target: "cleanup"}}
+
+	b, err := (&PipelineExport{Pipeline: exported}).YAML()
+	assert.NoError(t, err)
+
+	pipelines, environments, err := ParseConfigRepoFile("pipelines/my-pipeline.gocd.yaml", b, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, environments)
+	if assert.Len(t, pipelines, 1) {
+		assert.Equal(t, "my-group", pipelines[0].Group)
+		changes, err := DiffPipelines(exported, pipelines[0])
+		assert.NoError(t, err)
+		assert.Empty(t, changes, FormatPipelineChanges(changes))
+		assert.Equal(t, exported.Stages[0].Jobs[0].Tasks[0], pipelines[0].Stages[0].Jobs[0].Tasks[0])
+		assert.Equal(t, exported.Stages[1].Jobs[0].Tasks[0], pipelines[0].Stages[1].Jobs[0].Tasks[0])
+	}
+}
+
+func testParseConfigRepoFileYAMLOlderFormats(t *testing.T) {
+	pipelines, _, err := ParseConfigRepoFile("ci.gocd.yml", []byte(`
+pipelines:
+  legacy:
+    group: old
+    locking: on
+    materials:
+      repo:
+        type: git
+        url: https://github.com/gocd/gocd
+        auto_update: false
+    stages:
+      - build:
+          approval: manual
+          jobs:
+            test:
+              run_instances: "3"
+              tasks:
+                - fetch:
+                    pipeline: upstream
+                    stage: build
+                    job: compile
+                    source: app.jar
+                    is_file: yes
+                - exec:
+                    command: ./test.sh
+                    run_if: any
+`), nil)
+	assert.NoError(t, err)
+	if !assert.Len(t, pipelines, 1) {
+		return
+	}
+	p := pipelines[0]
+
+	assert.Equal(t, LockBehaviorLockOnFailure, p.LockBehavior)
+	assert.Equal(t, []Material{{
+		Type:       "git",
+		Attributes: &MaterialAttributesGit{Name: "repo", URL: "https://github.com/gocd/gocd"},
+	}}, p.Materials)
+	assert.Equal(t, "manual", p.Stages[0].Approval.Type)
+	assert.Equal(t, &Authorization{Users: []string{}, Roles: []string{}}, p.Stages[0].Approval.Authorization)
+	assert.True(t, p.Stages[0].FetchMaterials)
+
+	job := p.Stages[0].Jobs[0]
+	assert.Equal(t, 3, job.RunInstanceCount)
+	assert.Equal(t, []*Task{{
+		Type: "fetch",
+		Attributes: &TaskAttributesFetch{ArtifactOrigin: "gocd", RunIf: []string{"passed"}, Pipeline: "upstream", Stage: "build",
+			Job: "compile", Source: "app.jar", IsSourceAFile: true},
+	}, {
+		Type:       "exec",
+		Attributes: &TaskAttributesExec{RunIf: []string{"any"}, Command: "./test.sh"},
+	}}, job.Tasks)
+	assert.NoError(t, p.Validate())
+}
+
+func testParseConfigRepoFileYAMLEnvironments(t *testing.T) {
+	_, environments, err := ParseConfigRepoFile("environments.gocd.yaml", []byte(`format_version: 10
+environments:
+  staging:
+    environment_variables:
+      REGION: eu
+    secure_variables:
+      TOKEN: AES:abc
+    agents:
+      - agent-1
+    pipelines:
+      - my-pipeline
+  production: {}
+`), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Environment{
+		{Name: "production"},
+		{
+			Name:      "staging",
+			Pipelines: []*Pipeline{{Name: "my-pipeline"}},
+			Agents:    []*Agent{{UUID: "agent-1"}},
+			EnvironmentVariables: []*EnvironmentVariable{
+				{Name: "REGION", Value: "eu"},
+				{Name: "TOKEN", EncryptedValue: "AES:abc", Secure: true},
+			},
+		},
+	}, environments)
+}
+
+func testParseConfigRepoFileJSON(t *testing.T) {
+	pipelines, environments, err := ParseConfigRepoFile("ci.gocd.json", []byte(`{
+  "format_version": 3,
+  "environments": [{"name": "staging", "pipelines": ["my-pipeline"]}],
+  "pipelines": [{
+    "name": "my-pipeline",
+    "group": "my-group",
+    "lock_behavior": "unlockWhenFinished",
+    "environment_variables": [{"name": "GO_ENV", "value": "dev"}, {"name": "TOKEN", "encrypted_value": "AES:abc"}],
+    "tracking_tool": {"link": "https://jira/${ID}", "regex": "[A-Z]+-[0-9]+"},
+    "materials": [
+      {"type": "git", "name": "gocd", "url": "https://github.com/gocd/gocd", "filter": {"whitelist": ["src/**"]}},
+      {"type": "svn", "name": "svn", "url": "https://svn.example.com", "encrypted_password": "AES:svn", "check_externals": true},
+      {"type": "hg", "name": "hg", "url": "https://hg.example.com"},
+      {"type": "p4", "name": "p4", "port": "p4:1666", "use_tickets": true, "view": "//depot/..."},
+      {"type": "tfs", "name": "tfs", "url": "https://tfs.example.com", "domain": "corp", "project": "$/project"},
+      {"type": "dependency", "name": "up", "pipeline": "upstream", "stage": "build"},
+      {"type": "package", "package_id": "pkg-1"},
+      {"type": "plugin", "scm_id": "scm-1", "destination": "scm"}
+    ],
+    "stages": [{
+      "name": "build",
+      "fetch_materials": false,
+      "approval": {"type": "manual", "roles": ["admins"]},
+      "jobs": [{
+        "name": "compile",
+        "run_instance_count": 2,
+        "timeout": 5,
+        "tasks": [
+          {"type": "exec", "command": "make", "arguments": ["all"],
+           "on_cancel": {"type": "exec", "command": "pkill", "arguments": ["make"]}},
+          {"type": "nant", "build_file": "default.build", "nant_path": "/opt/nant", "run_if": "failed",
+           "on_cancel": {"type": "nant", "target": "clean"}},
+          {"type": "fetch", "artifact_origin": "external", "pipeline": "upstream", "stage": "build", "job": "compile",
+           "artifact_id": "image", "configuration": [{"key": "tag", "value": "latest"}]},
+          {"type": "plugin", "plugin_configuration": {"id": "script-executor", "version": "1"},
+           "configuration": [{"key": "script", "value": "./notify.sh"}]}
+        ]
+      }]
+    }]
+  }]
+}`), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Environment{{Name: "staging", Pipelines: []*Pipeline{{Name: "my-pipeline"}}}}, environments)
+	if !assert.Len(t, pipelines, 1) {
+		return
+	}
+	p := pipelines[0]
+
+	assert.Equal(t, "my-group", p.Group)
+	assert.Equal(t, LockBehaviorUnlockWhenFinished, p.LockBehavior)
+	assert.Equal(t, []*EnvironmentVariable{
+		{Name: "GO_ENV", Value: "dev"},
+		{Name: "TOKEN", EncryptedValue: "AES:abc", Secure: true},
+	}, p.EnvironmentVariables)
+	assert.Equal(t, &TrackingTool{Type: "generic", Attributes: TrackingToolAttributes{URLPattern: "https://jira/${ID}", Regex: "[A-Z]+-[0-9]+"}}, p.TrackingTool)
+	assert.Equal(t, []Material{
+		{Type: "git", Attributes: &MaterialAttributesGit{Name: "gocd", URL: "https://github.com/gocd/gocd",
+			Filter: &MaterialFilter{Ignore: []string{"src/**"}}, InvertFilter: true, AutoUpdate: true}},
+		{Type: "svn", Attributes: &MaterialAttributesSvn{Name: "svn", URL: "https://svn.example.com", EncryptedPassword: "AES:svn",
+			CheckExternals: true, AutoUpdate: true}},
+		{Type: "hg", Attributes: &MaterialAttributesHg{Name: "hg", URL: "https://hg.example.com", AutoUpdate: true}},
+		{Type: "p4", Attributes: &MaterialAttributesP4{Name: "p4", Port: "p4:1666", UseTickets: true, View: "//depot/...", AutoUpdate: true}},
+		{Type: "tfs", Attributes: &MaterialAttributesTfs{Name: "tfs", URL: "https://tfs.example.com", Domain: "corp",
+			ProjectPath: "$/project", AutoUpdate: true}},
+		{Type: "dependency", Attributes: &MaterialAttributesDependency{Name: "up", Pipeline: "upstream", Stage: "build", AutoUpdate: true}},
+		{Type: "package", Attributes: &MaterialAttributesPackage{Ref: "pkg-1"}},
+		{Type: "plugin", Attributes: &MaterialAttributesPlugin{Ref: "scm-1", Destination: "scm"}},
+	}, p.Materials)
+
+	stage := p.Stages[0]
+	assert.False(t, stage.FetchMaterials)
+	assert.Equal(t, &Approval{Type: "manual", Authorization: &Authorization{Users: []string{}, Roles: []string{"admins"}}}, stage.Approval)
+	job := stage.Jobs[0]
+	assert.Equal(t, 2, job.RunInstanceCount)
+	assert.Equal(t, TimeoutField(5), job.Timeout)
+	assert.Equal(t, []*Task{
+		{Type: "exec", Attributes: &TaskAttributesExec{RunIf: []string{"passed"}, Command: "make", Arguments: []string{"all"},
+			OnCancel: &Task{Type: "exec", Attributes: &TaskAttributesExec{RunIf: []string{"passed"}, Command: "pkill", Arguments: []string{"make"}}}}},
+		{Type: "nant", Attributes: &TaskAttributesNant{RunIf: []string{"failed"}, BuildFile: "default.build", NantPath: "/opt/nant",
+			OnCancel: &Task{Type: "nant", Attributes: &TaskAttributesNant{RunIf: []string{"passed"}, Target: "clean"}}}},
+		{Type: "fetch", Attributes: &TaskAttributesFetch{ArtifactOrigin: "external", RunIf: []string{"passed"}, Pipeline: "upstream",
+			Stage: "build", Job: "compile", ArtifactID: "image", Configuration: []PluginConfigurationKVPair{{Key: "tag", Value: "latest"}}}},
+		{Type: "pluggable_task", Attributes: &TaskAttributesPluggable{RunIf: []string{"passed"},
+			PluginConfiguration: &TaskPluginConfiguration{ID: "script-executor", Version: "1"},
+			Configuration:       []PluginConfigurationKVPair{{Key: "script", Value: "./notify.sh"}}}},
+	}, job.Tasks)
+}
+
+func testParseConfigRepoFileJSONSingleFiles(t *testing.T) {
+	pipelines, environments, err := ParseConfigRepoFile("legacy.gopipeline.json", []byte(`{
+  "name": "legacy",
+  "enable_pipeline_locking": true,
+  "materials": [{"type": "git", "url": "https://github.com/gocd/gocd", "auto_update": false}],
+  "stages": [{"name": "build", "jobs": [{"name": "compile", "run_instance_count": null, "timeout": "never",
+    "tasks": [{"type": "rake"}]}]}]
+}`), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, environments)
+	if assert.Len(t, pipelines, 1) {
+		p := pipelines[0]
+		assert.Equal(t, LockBehaviorLockOnFailure, p.LockBehavior)
+		assert.Equal(t, &MaterialAttributesGit{URL: "https://github.com/gocd/gocd"}, p.Materials[0].Attributes)
+		assert.True(t, p.Stages[0].FetchMaterials)
+		assert.Equal(t, &Approval{Type: "success", Authorization: &Authorization{Users: []string{}, Roles: []string{}}}, p.Stages[0].Approval)
+		assert.Equal(t, &Task{Type: "rake", Attributes: &TaskAttributesRake{RunIf: []string{"passed"}}}, p.Stages[0].Jobs[0].Tasks[0])
+	}
+
+	pipelines, environments, err = ParseConfigRepoFile("staging.goenvironment.json", []byte(`{
+  "name": "staging",
+  "environment_variables": [{"name": "TOKEN", "encrypted_value": "AES:abc"}],
+  "agents": ["agent-1"]
+}`), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, pipelines)
+	assert.Equal(t, []*Environment{{
+		Name:                 "staging",
+		Agents:               []*Agent{{UUID: "agent-1"}},
+		EnvironmentVariables: []*EnvironmentVariable{{Name: "TOKEN", EncryptedValue: "AES:abc", Secure: true}},
+	}}, environments)
+}
+
+func testParseConfigRepoFileConfigRepoMaterial(t *testing.T) {
+	content := []byte(`format_version: 10
+pipelines:
+  my-pipeline:
+    materials:
+      self:
+        type: configrepo
+        destination: config
+        blacklist: ["*.md"]
+    stages:
+      - build:
+          jobs:
+            compile:
+              tasks:
+                - exec:
+                    command: make
+`)
+	repo := &ConfigRepo{
+		ID: "my-repo",
+		Material: Material{
+			Type:       "git",
+			Attributes: &MaterialAttributesGit{URL: "https://github.com/gocd/config", Branch: "main", AutoUpdate: true},
+		},
+	}
+
+	pipelines, _, err := ParseConfigRepoFile("ci.gocd.yaml", content, repo)
+	assert.NoError(t, err)
+	if assert.Len(t, pipelines, 1) {
+		assert.Equal(t, []Material{{
+			Type: "git",
+			Attributes: &MaterialAttributesGit{Name: "self", URL: "https://github.com/gocd/config", Branch: "main", AutoUpdate: true,
+				Destination: "config", Filter: &MaterialFilter{Ignore: []string{"*.md"}}},
+		}}, pipelines[0].Materials)
+	}
+	assert.Equal(t, "main", repo.Material.Attributes.(*MaterialAttributesGit).Branch, "the material of the repository is copied")
+	assert.Empty(t, repo.Material.Attributes.(*MaterialAttributesGit).Name)
+
+	_, _, err = ParseConfigRepoFile("ci.gocd.yaml", content, nil)
+	assert.EqualError(t, err, "ci.gocd.yaml: pipeline 'my-pipeline': material 'self' refers to the config repository, which is not known")
+}
+
+func testParseConfigRepoFileErrors(t *testing.T) {
+	for want, file := range map[string]struct {
+		name    string
+		content string
+	}{
+		"'pipeline.json' is not a config repository file":                               {"pipeline.json", `{}`},
+		"ci.gocd.yaml: format_version 11 is not supported, it must be between 1 and 10": {"ci.gocd.yaml", `format_version: 11`},
+		"ci.gocd.json: format_version 11 is not supported, it must be between 1 and 10": {"ci.gocd.json", `{"format_version": 11}`},
+		"ci.gocd.yaml: pipeline 'p': material 'm' has no type":                          {"ci.gocd.yaml", "pipelines: {p: {materials: {m: {branch: master}}}}"},
+		"ci.gocd.yaml: pipeline 'p': stage 's': job 'j': task 1: task has an unknown type 'script'": {"ci.gocd.yaml",
+			"pipelines: {p: {stages: [{s: {jobs: {j: {tasks: [{script: {}}]}}}}]}}"},
+		"ci.gocd.yaml: pipeline 'p': stage 's': job 'j': task 1: 'run_if' must be one of 'passed', 'failed' or 'any', not 'always'": {"ci.gocd.yaml",
+			"pipelines: {p: {stages: [{s: {jobs: {j: {tasks: [{exec: {command: make, run_if: always}}]}}}}]}}"},
+		"ci.gocd.yaml: pipeline 'p': stage 's': job 'j': task 1: on_cancel must be a map with a single type, not 2": {"ci.gocd.yaml",
+			"pipelines: {p: {stages: [{s: {jobs: {j: {tasks: [{exec: {command: make, on_cancel: {exec: {}, rake: {}}}}]}}}}]}}"},
+		"ci.gocd.json: pipeline 'p': stage 's': job 'j': task 1: on_cancel: task has an unknown type 'script'": {"ci.gocd.json",
+			`{"pipelines": [{"name": "p", "stages": [{"name": "s", "jobs": [{"name": "j", "tasks": [{"type": "exec", "on_cancel": {"type": "script"}}]}]}]}]}`},
+		"ci.gocd.json: pipeline 'p': material 'm' has an unknown type 'cvs'": {"ci.gocd.json",
+			`{"pipelines": [{"name": "p", "materials": [{"type": "cvs", "name": "m"}]}]}`},
+		"ci.gocd.json: running 'all' instances of a job is not supported": {"ci.gocd.json",
+			`{"pipelines": [{"name": "p", "stages": [{"name": "s", "jobs": [{"name": "j", "run_instance_count": "all"}]}]}]}`},
+	} {
+		_, _, err := ParseConfigRepoFile(file.name, []byte(file.content), nil)
+		assert.EqualError(t, err, want)
+	}
+
+	pipelines, environments, err := ParseConfigRepoFile("ci.gocd.json", []byte(`{
+  "environments": [null, {"name": "staging", "environment_variables": [null], "pipelines": ["p"]}],
+  "pipelines": [null, {"name": "p", "environment_variables": [null], "materials": [null],
+    "stages": [null, {"name": "s", "environment_variables": [null],
+      "jobs": [null, {"name": "j", "environment_variables": [null], "tasks": [null, {"type": "exec", "command": "make"}]}]}]}]
+}`), nil)
+	assert.NoError(t, err, "null entries are left out")
+	assert.Len(t, environments, 1)
+	if assert.Len(t, pipelines, 1) && assert.Len(t, pipelines[0].Stages, 1) && assert.Len(t, pipelines[0].Stages[0].Jobs, 1) {
+		assert.Empty(t, pipelines[0].EnvironmentVariables)
+		assert.Empty(t, pipelines[0].Materials)
+		assert.Len(t, pipelines[0].Stages[0].Jobs[0].Tasks, 1)
+	}
+}
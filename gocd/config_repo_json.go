@@ -0,0 +1,265 @@
+package gocd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// jsonConfigFile describes a `*.gocd.json` file read by the gocd-json-config-plugin.
+type jsonConfigFile struct {
+	FormatVersion int                `json:"format_version"`
+	Pipelines     []*jsonPipeline    `json:"pipelines"`
+	Environments  []*jsonEnvironment `json:"environments"`
+}
+
+type jsonEnvironment struct {
+	Name                 string                 `json:"name"`
+	EnvironmentVariables []*EnvironmentVariable `json:"environment_variables"`
+	Agents               []string               `json:"agents"`
+	Pipelines            []string               `json:"pipelines"`
+}
+
+// codebeat:disable[TOO_MANY_IVARS]
+type jsonPipeline struct {
+	FormatVersion         int                    `json:"format_version"`
+	Name                  string                 `json:"name"`
+	Group                 string                 `json:"group"`
+	LabelTemplate         string                 `json:"label_template"`
+	LockBehavior          string                 `json:"lock_behavior"`
+	EnablePipelineLocking bool                   `json:"enable_pipeline_locking"`
+	Template              string                 `json:"template"`
+	Parameters            []*Parameter           `json:"parameters"`
+	EnvironmentVariables  []*EnvironmentVariable `json:"environment_variables"`
+	TrackingTool          *yamlTrackingTool      `json:"tracking_tool"`
+	Timer                 *Timer                 `json:"timer"`
+	Materials             []*configRepoMaterial  `json:"materials"`
+	Stages                []*jsonStage           `json:"stages"`
+}
+
+// codebeat:enable[TOO_MANY_IVARS]
+
+type jsonStage struct {
+	Name                  string                 `json:"name"`
+	FetchMaterials        *bool                  `json:"fetch_materials"`
+	NeverCleanupArtifacts bool                   `json:"never_cleanup_artifacts"`
+	CleanWorkingDirectory bool                   `json:"clean_working_directory"`
+	Approval              *yamlApproval          `json:"approval"`
+	EnvironmentVariables  []*EnvironmentVariable `json:"environment_variables"`
+	Jobs                  []*jsonJob             `json:"jobs"`
+}
+
+// codebeat:disable[TOO_MANY_IVARS]
+type jsonJob struct {
+	Name                 string                 `json:"name"`
+	RunInstanceCount     runInstances           `json:"run_instance_count"`
+	Timeout              TimeoutField           `json:"timeout"`
+	ElasticProfileID     string                 `json:"elastic_profile_id"`
+	Resources            []string               `json:"resources"`
+	EnvironmentVariables []*EnvironmentVariable `json:"environment_variables"`
+	Tabs                 []*Tab                 `json:"tabs"`
+	Properties           []*JobProperty         `json:"properties"`
+	Artifacts            []*Artifact            `json:"artifacts"`
+	Tasks                []*configRepoTask      `json:"tasks"`
+}
+
+// codebeat:enable[TOO_MANY_IVARS]
+
+// runInstances is the number of instances of a job to run. The plugins also accept `all`, to run an instance on each
+// agent, which can not be represented by Job.
+type runInstances int
+
+// UnmarshalJSON accepts a number of instances, as a number or a string.
+func (ri *runInstances) UnmarshalJSON(b []byte) error {
+	var count interface{}
+	if err := json.Unmarshal(b, &count); err != nil {
+		return err
+	}
+	return ri.set(count)
+}
+
+// UnmarshalYAML accepts a number of instances, as a number or a string.
+func (ri *runInstances) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var count interface{}
+	if err := unmarshal(&count); err != nil {
+		return err
+	}
+	return ri.set(count)
+}
+
+func (ri *runInstances) set(count interface{}) (err error) {
+	n := 0
+	switch c := count.(type) {
+	case nil:
+	case float64:
+		n = int(c)
+	case int:
+		n = c
+	case string:
+		if n, err = strconv.Atoi(c); err != nil {
+			return fmt.Errorf("running '%s' instances of a job is not supported", c)
+		}
+	default:
+		return fmt.Errorf("unexpected number of job instances: %v", count)
+	}
+	*ri = runInstances(n)
+	return nil
+}
+
+// parseJSONConfigFile reads the pipelines and environments of a file. The `null` entries of its lists, and of the
+// lists of its pipelines, are left out.
+func parseJSONConfigFile(content []byte, repo *ConfigRepo) (pipelines []*Pipeline, environments []*Environment, err error) {
+	file := &jsonConfigFile{}
+	if err = json.Unmarshal(content, file); err != nil {
+		return nil, nil, err
+	}
+	if err = checkFormatVersion(file.FormatVersion, JSONFormatVersion); err != nil {
+		return nil, nil, err
+	}
+
+	for _, jp := range file.Pipelines {
+		if jp == nil {
+			continue
+		}
+		p, err := jp.pipeline(repo)
+		if err != nil {
+			return nil, nil, err
+		}
+		pipelines = append(pipelines, p)
+	}
+	for _, je := range file.Environments {
+		if je == nil {
+			continue
+		}
+		environments = append(environments, je.environment())
+	}
+	return pipelines, environments, nil
+}
+
+func parseJSONPipelineFile(content []byte, repo *ConfigRepo) ([]*Pipeline, error) {
+	jp := &jsonPipeline{}
+	if err := json.Unmarshal(content, jp); err != nil {
+		return nil, err
+	}
+	if err := checkFormatVersion(jp.FormatVersion, JSONFormatVersion); err != nil {
+		return nil, err
+	}
+
+	p, err := jp.pipeline(repo)
+	if err != nil {
+		return nil, err
+	}
+	return []*Pipeline{p}, nil
+}
+
+func parseJSONEnvironmentFile(content []byte) ([]*Environment, error) {
+	je := &jsonEnvironment{}
+	if err := json.Unmarshal(content, je); err != nil {
+		return nil, err
+	}
+	return []*Environment{je.environment()}, nil
+}
+
+func (je *jsonEnvironment) environment() *Environment {
+	return configRepoEnvironment(je.Name, jsonVariables(je.EnvironmentVariables), je.Agents, je.Pipelines)
+}
+
+// jsonVariables marks the variables given with an encrypted value as secure, as the plugin does not require it.
+// `null` variables are left out.
+func jsonVariables(envs []*EnvironmentVariable) (variables []*EnvironmentVariable) {
+	for _, env := range envs {
+		if env == nil {
+			continue
+		}
+		if env.EncryptedValue != "" {
+			env.Secure = true
+		}
+		variables = append(variables, env)
+	}
+	return variables
+}
+
+func (jp *jsonPipeline) pipeline(repo *ConfigRepo) (p *Pipeline, err error) {
+	p = &Pipeline{
+		Name:                 jp.Name,
+		Group:                jp.Group,
+		LabelTemplate:        jp.LabelTemplate,
+		LockBehavior:         jp.LockBehavior,
+		Template:             jp.Template,
+		Parameters:           jp.Parameters,
+		EnvironmentVariables: jsonVariables(jp.EnvironmentVariables),
+		Timer:                jp.Timer,
+	}
+	if p.LockBehavior == "" && jp.EnablePipelineLocking {
+		p.LockBehavior = LockBehaviorLockOnFailure
+	}
+	if jp.TrackingTool != nil {
+		p.TrackingTool = &TrackingTool{
+			Type:       "generic",
+			Attributes: TrackingToolAttributes{URLPattern: jp.TrackingTool.Link, Regex: jp.TrackingTool.Regex},
+		}
+	}
+
+	for _, jm := range jp.Materials {
+		if jm == nil {
+			continue
+		}
+		m, err := jm.material(repo)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline '%s': %s", jp.Name, err)
+		}
+		p.Materials = append(p.Materials, m)
+	}
+
+	for _, js := range jp.Stages {
+		if js == nil {
+			continue
+		}
+		stage, err := js.stage()
+		if err != nil {
+			return nil, fmt.Errorf("pipeline '%s': stage '%s': %s", jp.Name, js.Name, err)
+		}
+		p.Stages = append(p.Stages, stage)
+	}
+	return p, nil
+}
+
+func (js *jsonStage) stage() (*Stage, error) {
+	stage := &Stage{
+		Name:                  js.Name,
+		FetchMaterials:        js.FetchMaterials == nil || *js.FetchMaterials,
+		NeverCleanupArtifacts: js.NeverCleanupArtifacts,
+		CleanWorkingDirectory: js.CleanWorkingDirectory,
+		Approval:              js.Approval.approval(),
+		EnvironmentVariables:  jsonVariables(js.EnvironmentVariables),
+	}
+
+	for _, jj := range js.Jobs {
+		if jj == nil {
+			continue
+		}
+		job := &Job{
+			Name:                 jj.Name,
+			RunInstanceCount:     int(jj.RunInstanceCount),
+			Timeout:              jj.Timeout,
+			ElasticProfileID:     jj.ElasticProfileID,
+			Resources:            jj.Resources,
+			EnvironmentVariables: jsonVariables(jj.EnvironmentVariables),
+			Tabs:                 jj.Tabs,
+			Properties:           jj.Properties,
+			Artifacts:            jj.Artifacts,
+		}
+		for i, jt := range jj.Tasks {
+			if jt == nil {
+				continue
+			}
+			task, err := jt.task()
+			if err != nil {
+				return nil, fmt.Errorf("job '%s': task %d: %s", jj.Name, i+1, err)
+			}
+			job.Tasks = append(job.Tasks, task)
+		}
+		stage.Jobs = append(stage.Jobs, job)
+	}
+	return stage, nil
+}
@@ -0,0 +1,866 @@
+package gocd
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// YAMLFormatVersion is the `format_version` of the files written for the gocd-yaml-config-plugin.
+const YAMLFormatVersion = 10
+
+// PipelineExport holds a pipeline config, and the settings kept outside of it, to be exported to a config repository.
+type PipelineExport struct {
+	Pipeline *Pipeline
+	// Group is not returned with the pipeline config, and is taken from `Pipeline.Group` when empty.
+	Group string
+	// Template, when set, has its stages written in the pipeline, so that it does not depend on a template defined on
+	// the server. Otherwise the pipeline refers to its template by name.
+	Template *PipelineTemplate
+	// Environments to list the pipeline in. Only the membership of the pipeline is exported, as the variables and
+	// agents of an environment are shared with the pipelines outside of the config repository.
+	Environments []*Environment
+}
+
+// yamlConfigFile describes a `*.gocd.yaml` file read by the gocd-yaml-config-plugin.
+type yamlConfigFile struct {
+	FormatVersion int                         `yaml:"format_version"`
+	Environments  map[string]*yamlEnvironment `yaml:"environments,omitempty"`
+	Pipelines     map[string]*yamlPipeline    `yaml:"pipelines,omitempty"`
+}
+
+type yamlEnvironment struct {
+	EnvironmentVariables map[string]string `yaml:"environment_variables,omitempty"`
+	SecureVariables      map[string]string `yaml:"secure_variables,omitempty"`
+	Agents               []string          `yaml:"agents,omitempty"`
+	Pipelines            []string          `yaml:"pipelines,omitempty"`
+}
+
+// codebeat:disable[TOO_MANY_IVARS]
+type yamlPipeline struct {
+	Group                string                   `yaml:"group,omitempty"`
+	LabelTemplate        string                   `yaml:"label_template,omitempty"`
+	LockBehavior         string                   `yaml:"lock_behavior,omitempty"`
+	Locking              *bool                    `yaml:"locking,omitempty"`
+	Template             string                   `yaml:"template,omitempty"`
+	Parameters           map[string]string        `yaml:"parameters,omitempty"`
+	EnvironmentVariables map[string]string        `yaml:"environment_variables,omitempty"`
+	SecureVariables      map[string]string        `yaml:"secure_variables,omitempty"`
+	TrackingTool         *yamlTrackingTool        `yaml:"tracking_tool,omitempty"`
+	Timer                *yamlTimer               `yaml:"timer,omitempty"`
+	Materials            map[string]*yamlMaterial `yaml:"materials,omitempty"`
+	Stages               []map[string]*yamlStage  `yaml:"stages,omitempty"`
+}
+
+// codebeat:enable[TOO_MANY_IVARS]
+
+type yamlTrackingTool struct {
+	Link  string `yaml:"link" json:"link"`
+	Regex string `yaml:"regex" json:"regex"`
+}
+
+type yamlTimer struct {
+	Spec          string `yaml:"spec"`
+	OnlyOnChanges bool   `yaml:"only_on_changes,omitempty"`
+}
+
+// yamlMaterial holds the attributes of every type of material. The type is given by the key holding the location of
+// the material, eg: `git: https://github.com/gocd/gocd`, or `pipeline` and `stage` for a dependency.
+// codebeat:disable[TOO_MANY_IVARS]
+type yamlMaterial struct {
+	Type string `yaml:"type,omitempty"`
+	URL  string `yaml:"url,omitempty"`
+	Port string `yaml:"port,omitempty"`
+
+	Git      string `yaml:"git,omitempty"`
+	Svn      string `yaml:"svn,omitempty"`
+	Hg       string `yaml:"hg,omitempty"`
+	P4       string `yaml:"p4,omitempty"`
+	Tfs      string `yaml:"tfs,omitempty"`
+	Pipeline string `yaml:"pipeline,omitempty"`
+	Stage    string `yaml:"stage,omitempty"`
+	Package  string `yaml:"package,omitempty"`
+	SCM      string `yaml:"scm,omitempty"`
+
+	Branch         string `yaml:"branch,omitempty"`
+	ShallowClone   bool   `yaml:"shallow_clone,omitempty"`
+	CheckExternals bool   `yaml:"check_externals,omitempty"`
+	UseTickets     bool   `yaml:"use_tickets,omitempty"`
+	View           string `yaml:"view,omitempty"`
+	Domain         string `yaml:"domain,omitempty"`
+	Project        string `yaml:"project,omitempty"`
+
+	Username          string `yaml:"username,omitempty"`
+	Password          string `yaml:"password,omitempty"`
+	EncryptedPassword string `yaml:"encrypted_password,omitempty"`
+
+	Destination string   `yaml:"destination,omitempty"`
+	AutoUpdate  *bool    `yaml:"auto_update,omitempty"`
+	Blacklist   []string `yaml:"blacklist,omitempty"`
+	Whitelist   []string `yaml:"whitelist,omitempty"`
+}
+
+// codebeat:enable[TOO_MANY_IVARS]
+
+type yamlStage struct {
+	FetchMaterials       *bool               `yaml:"fetch_materials,omitempty"`
+	KeepArtifacts        bool                `yaml:"keep_artifacts,omitempty"`
+	CleanWorkspace       bool                `yaml:"clean_workspace,omitempty"`
+	Approval             *yamlApproval       `yaml:"approval,omitempty"`
+	EnvironmentVariables map[string]string   `yaml:"environment_variables,omitempty"`
+	SecureVariables      map[string]string   `yaml:"secure_variables,omitempty"`
+	Jobs                 map[string]*yamlJob `yaml:"jobs"`
+}
+
+// yamlApproval describes the approval of a stage for both plugins.
+type yamlApproval struct {
+	Type  string   `yaml:"type" json:"type"`
+	Users []string `yaml:"users,omitempty" json:"users"`
+	Roles []string `yaml:"roles,omitempty" json:"roles"`
+}
+
+// codebeat:disable[TOO_MANY_IVARS]
+type yamlJob struct {
+	Timeout              int                        `yaml:"timeout,omitempty"`
+	RunInstances         runInstances               `yaml:"run_instances,omitempty"`
+	Resources            []string                   `yaml:"resources,omitempty"`
+	ElasticProfileID     string                     `yaml:"elastic_profile_id,omitempty"`
+	EnvironmentVariables map[string]string          `yaml:"environment_variables,omitempty"`
+	SecureVariables      map[string]string          `yaml:"secure_variables,omitempty"`
+	Tabs                 map[string]string          `yaml:"tabs,omitempty"`
+	Properties           map[string]*yamlProperty   `yaml:"properties,omitempty"`
+	Artifacts            []map[string]*yamlArtifact `yaml:"artifacts,omitempty"`
+	Tasks                []map[string]*yamlTask     `yaml:"tasks,omitempty"`
+}
+
+// codebeat:enable[TOO_MANY_IVARS]
+
+type yamlProperty struct {
+	Source string `yaml:"source"`
+	XPath  string `yaml:"xpath"`
+}
+
+type yamlArtifact struct {
+	Source      string `yaml:"source"`
+	Destination string `yaml:"destination,omitempty"`
+}
+
+// yamlTask holds the attributes of every type of task. The type is given by the key holding the task.
+// codebeat:disable[TOO_MANY_IVARS]
+type yamlTask struct {
+	RunIf string `yaml:"run_if,omitempty"`
+
+	Command          string   `yaml:"command,omitempty"`
+	Arguments        []string `yaml:"arguments,omitempty"`
+	BuildFile        string   `yaml:"build_file,omitempty"`
+	Target           string   `yaml:"target,omitempty"`
+	NantPath         string   `yaml:"nant_path,omitempty"`
+	WorkingDirectory string   `yaml:"working_directory,omitempty"`
+
+	ArtifactOrigin string `yaml:"artifact_origin,omitempty"`
+	Pipeline       string `yaml:"pipeline,omitempty"`
+	Stage          string `yaml:"stage,omitempty"`
+	Job            string `yaml:"job,omitempty"`
+	Source         string `yaml:"source,omitempty"`
+	IsFile         bool   `yaml:"is_file,omitempty"`
+	Destination    string `yaml:"destination,omitempty"`
+	ArtifactID     string `yaml:"artifact_id,omitempty"`
+
+	Configuration *yamlPluginConfiguration `yaml:"configuration,omitempty"`
+	Options       map[string]string        `yaml:"options,omitempty"`
+
+	// OnCancel is a task, held by its type like the tasks of a job.
+	OnCancel map[string]*yamlTask `yaml:"on_cancel,omitempty"`
+}
+
+// codebeat:enable[TOO_MANY_IVARS]
+
+// yamlPluginConfiguration identifies the plugin of a `plugin` task, or holds the options of an external artifact.
+type yamlPluginConfiguration struct {
+	ID      string            `yaml:"id,omitempty"`
+	Version string            `yaml:"version,omitempty"`
+	Options map[string]string `yaml:"options,omitempty"`
+}
+
+// YAML formats the pipeline for the gocd-yaml-config-plugin. Secure variables are exported with their encrypted value,
+// so that they can only be read by the GoCD server which encrypted them.
+func (pe *PipelineExport) YAML() ([]byte, error) {
+	if pe.Pipeline == nil {
+		return nil, fmt.Errorf("no pipeline to export")
+	}
+	p := pe.Pipeline
+
+	yp, err := yamlFromPipeline(p)
+	if err != nil {
+		return nil, err
+	}
+	if yp.Group = pe.Group; yp.Group == "" {
+		yp.Group = p.Group
+	}
+	if pe.Template != nil {
+		if p.Template != "" && p.Template != pe.Template.Name {
+			return nil, fmt.Errorf("pipeline '%s' uses template '%s', not '%s'", p.Name, p.Template, pe.Template.Name)
+		}
+		yp.Template = ""
+		if yp.Stages, err = yamlFromStages(pe.Template.Stages); err != nil {
+			return nil, err
+		}
+	}
+
+	file := &yamlConfigFile{
+		FormatVersion: YAMLFormatVersion,
+		Pipelines:     map[string]*yamlPipeline{p.Name: yp},
+	}
+	for _, env := range pe.Environments {
+		if env == nil {
+			continue
+		}
+		if file.Environments == nil {
+			file.Environments = map[string]*yamlEnvironment{}
+		}
+		file.Environments[env.Name] = &yamlEnvironment{Pipelines: []string{p.Name}}
+	}
+
+	return yaml.Marshal(file)
+}
+
+func yamlFromPipeline(p *Pipeline) (yp *yamlPipeline, err error) {
+	yp = &yamlPipeline{
+		LabelTemplate: p.LabelTemplate,
+		LockBehavior:  p.LockBehavior,
+		Template:      p.Template,
+	}
+	if yp.LockBehavior == "" && p.EnablePipelineLocking {
+		yp.LockBehavior = LockBehaviorLockOnFailure
+	}
+
+	if len(p.Parameters) > 0 {
+		yp.Parameters = map[string]string{}
+		for _, param := range p.Parameters {
+			yp.Parameters[param.Name] = param.Value
+		}
+	}
+	if yp.EnvironmentVariables, yp.SecureVariables, err = yamlFromEnvironmentVariables(p.EnvironmentVariables); err != nil {
+		return nil, err
+	}
+	if p.TrackingTool != nil && p.TrackingTool.Attributes.URLPattern != "" {
+		yp.TrackingTool = &yamlTrackingTool{
+			Link:  p.TrackingTool.Attributes.URLPattern,
+			Regex: p.TrackingTool.Attributes.Regex,
+		}
+	}
+	if p.Timer != nil && p.Timer.Spec != "" {
+		yp.Timer = &yamlTimer{Spec: p.Timer.Spec, OnlyOnChanges: p.Timer.OnlyOnChanges}
+	}
+	if yp.Materials, err = yamlFromMaterials(p.Materials); err != nil {
+		return nil, err
+	}
+	if yp.Stages, err = yamlFromStages(p.Stages); err != nil {
+		return nil, err
+	}
+	return yp, nil
+}
+
+// yamlFromEnvironmentVariables splits variables in plain and secure values, which must be encrypted.
+func yamlFromEnvironmentVariables(envs []*EnvironmentVariable) (plain map[string]string, secure map[string]string, err error) {
+	for _, env := range envs {
+		if env == nil {
+			continue
+		}
+		if !env.Secure {
+			if plain == nil {
+				plain = map[string]string{}
+			}
+			plain[env.Name] = env.Value
+			continue
+		}
+		if env.EncryptedValue == "" {
+			return nil, nil, fmt.Errorf("secure variable '%s' must be encrypted before it is exported", env.Name)
+		}
+		if secure == nil {
+			secure = map[string]string{}
+		}
+		secure[env.Name] = env.EncryptedValue
+	}
+	return
+}
+
+// yamlFromMaterials keys the materials by name. As the plugin names materials after their key, unnamed materials are
+// keyed after their location, eg: the upstream pipeline of a dependency.
+func yamlFromMaterials(materials []Material) (yms map[string]*yamlMaterial, err error) {
+	for i, m := range materials {
+		if yms == nil {
+			yms = map[string]*yamlMaterial{}
+		}
+		ym, key, err := yamlFromMaterial(m)
+		if err != nil {
+			return nil, fmt.Errorf("material %d: %s", i+1, err)
+		}
+
+		unique := key
+		for n := 2; yms[unique] != nil; n++ {
+			unique = fmt.Sprintf("%s-%d", key, n)
+		}
+		yms[unique] = ym
+	}
+	return
+}
+
+// codebeat:disable[ABC,CYCLO,LOC]
+func yamlFromMaterial(m Material) (ym *yamlMaterial, key string, err error) {
+	ym = &yamlMaterial{}
+	autoUpdate := true
+	switch a := m.Attributes.(type) {
+	case *MaterialAttributesGit:
+		ym.Git, ym.Branch, ym.ShallowClone = a.URL, a.Branch, a.ShallowClone
+		ym.setFilter(a.Destination, a.Filter, a.InvertFilter)
+		autoUpdate, key = a.AutoUpdate, a.Name
+	case *MaterialAttributesSvn:
+		ym.Svn, ym.CheckExternals = a.URL, a.CheckExternals
+		ym.Username, ym.Password, ym.EncryptedPassword = a.Username, a.Password, a.EncryptedPassword
+		ym.setFilter(a.Destination, a.Filter, a.InvertFilter)
+		autoUpdate, key = a.AutoUpdate, a.Name
+	case *MaterialAttributesHg:
+		ym.Hg = a.URL
+		ym.setFilter(a.Destination, a.Filter, a.InvertFilter)
+		autoUpdate, key = a.AutoUpdate, a.Name
+	case *MaterialAttributesP4:
+		ym.P4, ym.UseTickets, ym.View = a.Port, a.UseTickets, a.View
+		ym.Username, ym.Password, ym.EncryptedPassword = a.Username, a.Password, a.EncryptedPassword
+		ym.setFilter(a.Destination, a.Filter, a.InvertFilter)
+		autoUpdate, key = a.AutoUpdate, a.Name
+	case *MaterialAttributesTfs:
+		ym.Tfs, ym.Domain, ym.Project = a.URL, a.Domain, a.ProjectPath
+		ym.Username, ym.Password, ym.EncryptedPassword = a.Username, a.Password, a.EncryptedPassword
+		ym.setFilter(a.Destination, a.Filter, a.InvertFilter)
+		autoUpdate, key = a.AutoUpdate, a.Name
+	case *MaterialAttributesDependency:
+		ym.Pipeline, ym.Stage = a.Pipeline, a.Stage
+		autoUpdate, key = true, a.Name
+		if key == "" {
+			key = a.Pipeline
+		}
+	case *MaterialAttributesPackage:
+		ym.Package, key = a.Ref, a.Ref
+	case *MaterialAttributesPlugin:
+		ym.SCM, key = a.Ref, a.Ref
+		ym.setFilter(a.Destination, a.Filter, a.InvertFilter)
+	case nil:
+		return nil, "", fmt.Errorf("'%s' material has no attributes", m.Type)
+	default:
+		return nil, "", fmt.Errorf("can not export '%s' material with %T attributes", m.Type, m.Attributes)
+	}
+
+	if !autoUpdate {
+		ym.AutoUpdate = &autoUpdate
+	}
+	if key == "" {
+		key = yamlMaterialKey(m.Type, ym.Git+ym.Svn+ym.Hg+ym.Tfs)
+	}
+	return ym, key, nil
+}
+
+// codebeat:enable[ABC,CYCLO,LOC]
+
+func (ym *yamlMaterial) setFilter(destination string, filter *MaterialFilter, invert bool) {
+	ym.Destination = destination
+	if filter == nil || len(filter.Ignore) == 0 {
+		return
+	}
+	if invert {
+		ym.Whitelist = filter.Ignore
+	} else {
+		ym.Blacklist = filter.Ignore
+	}
+}
+
+// yamlMaterialKey names a material after the last element of its url, eg: `gocd` for https://github.com/gocd/gocd.git.
+func yamlMaterialKey(materialType string, url string) string {
+	name := strings.TrimSuffix(path.Base(strings.TrimRight(url, "/")), ".git")
+	if name == "" || name == "." || name == "/" || !validName.MatchString(name) {
+		return materialType
+	}
+	return name
+}
+
+func yamlFromStages(stages []*Stage) (yss []map[string]*yamlStage, err error) {
+	for _, stage := range stages {
+		if stage == nil {
+			continue
+		}
+		ys, err := yamlFromStage(stage)
+		if err != nil {
+			return nil, fmt.Errorf("stage '%s': %s", stage.Name, err)
+		}
+		yss = append(yss, map[string]*yamlStage{stage.Name: ys})
+	}
+	return
+}
+
+func yamlFromStage(stage *Stage) (ys *yamlStage, err error) {
+	ys = &yamlStage{
+		KeepArtifacts:  stage.NeverCleanupArtifacts,
+		CleanWorkspace: stage.CleanWorkingDirectory,
+		Jobs:           map[string]*yamlJob{},
+	}
+	if !stage.FetchMaterials {
+		ys.FetchMaterials = &stage.FetchMaterials
+	}
+	if a := stage.Approval; a != nil {
+		ya := &yamlApproval{Type: a.Type}
+		if a.Authorization != nil {
+			ya.Users, ya.Roles = a.Authorization.Users, a.Authorization.Roles
+		}
+		if ya.Type != "success" || len(ya.Users) > 0 || len(ya.Roles) > 0 {
+			ys.Approval = ya
+		}
+	}
+	if ys.EnvironmentVariables, ys.SecureVariables, err = yamlFromEnvironmentVariables(stage.EnvironmentVariables); err != nil {
+		return nil, err
+	}
+
+	for _, job := range stage.Jobs {
+		if job == nil {
+			continue
+		}
+		if ys.Jobs[job.Name], err = yamlFromJob(job); err != nil {
+			return nil, fmt.Errorf("job '%s': %s", job.Name, err)
+		}
+	}
+	return ys, nil
+}
+
+func yamlFromJob(job *Job) (yj *yamlJob, err error) {
+	yj = &yamlJob{
+		Timeout:          int(job.Timeout),
+		RunInstances:     runInstances(job.RunInstanceCount),
+		Resources:        job.Resources,
+		ElasticProfileID: job.ElasticProfileID,
+	}
+	if yj.EnvironmentVariables, yj.SecureVariables, err = yamlFromEnvironmentVariables(job.EnvironmentVariables); err != nil {
+		return nil, err
+	}
+	for _, tab := range job.Tabs {
+		if yj.Tabs == nil {
+			yj.Tabs = map[string]string{}
+		}
+		yj.Tabs[tab.Name] = tab.Path
+	}
+	for _, property := range job.Properties {
+		if yj.Properties == nil {
+			yj.Properties = map[string]*yamlProperty{}
+		}
+		yj.Properties[property.Name] = &yamlProperty{Source: property.Source, XPath: property.XPath}
+	}
+	for _, artifact := range job.Artifacts {
+		yj.Artifacts = append(yj.Artifacts, map[string]*yamlArtifact{
+			artifact.Type: {Source: artifact.Source, Destination: artifact.Destination},
+		})
+	}
+	for i, task := range job.Tasks {
+		key, yt, err := yamlFromTask(task)
+		if err != nil {
+			return nil, fmt.Errorf("task %d: %s", i+1, err)
+		}
+		yj.Tasks = append(yj.Tasks, map[string]*yamlTask{key: yt})
+	}
+	return yj, nil
+}
+
+// codebeat:disable[ABC,CYCLO]
+func yamlFromTask(task *Task) (key string, yt *yamlTask, err error) {
+	if task == nil {
+		return "", nil, fmt.Errorf("task is nil")
+	}
+	if err = task.validateType(); err != nil {
+		return "", nil, err
+	}
+
+	yt = &yamlTask{}
+	key = task.Type
+	var onCancel *Task
+	switch a := task.Attributes.(type) {
+	case *TaskAttributesExec:
+		yt.RunIf, onCancel = yamlRunIf(a.RunIf), a.OnCancel
+		yt.Command, yt.Arguments, yt.WorkingDirectory = a.Command, a.Arguments, a.WorkingDirectory
+	case *TaskAttributesAnt:
+		yt.RunIf, onCancel = yamlRunIf(a.RunIf), a.OnCancel
+		yt.BuildFile, yt.Target, yt.WorkingDirectory = a.BuildFile, a.Target, a.WorkingDirectory
+	case *TaskAttributesNant:
+		yt.RunIf, onCancel = yamlRunIf(a.RunIf), a.OnCancel
+		yt.BuildFile, yt.Target, yt.WorkingDirectory = a.BuildFile, a.Target, a.WorkingDirectory
+		yt.NantPath = a.NantPath
+	case *TaskAttributesRake:
+		yt.RunIf, onCancel = yamlRunIf(a.RunIf), a.OnCancel
+		yt.BuildFile, yt.Target, yt.WorkingDirectory = a.BuildFile, a.Target, a.WorkingDirectory
+	case *TaskAttributesFetch:
+		yt.RunIf, onCancel = yamlRunIf(a.RunIf), a.OnCancel
+		yt.ArtifactOrigin, yt.Pipeline, yt.Stage, yt.Job = a.ArtifactOrigin, a.Pipeline, a.Stage, a.Job
+		if a.ArtifactOrigin == "external" {
+			yt.ArtifactID = a.ArtifactID
+			yt.Configuration = &yamlPluginConfiguration{Options: yamlOptions(a.Configuration)}
+		} else {
+			yt.Source, yt.IsFile, yt.Destination = a.Source, a.IsSourceAFile, a.Destination
+		}
+	case *TaskAttributesPluggable:
+		key = "plugin"
+		yt.RunIf, onCancel = yamlRunIf(a.RunIf), a.OnCancel
+		yt.Options = yamlOptions(a.Configuration)
+		if a.PluginConfiguration != nil {
+			yt.Configuration = &yamlPluginConfiguration{ID: a.PluginConfiguration.ID, Version: a.PluginConfiguration.Version}
+		}
+	}
+
+	if onCancel != nil {
+		cancelKey, cancel, err := yamlFromTask(onCancel)
+		if err != nil {
+			return "", nil, fmt.Errorf("on_cancel: %s", err)
+		}
+		yt.OnCancel = map[string]*yamlTask{cancelKey: cancel}
+	}
+	return key, yt, nil
+}
+
+// codebeat:enable[ABC,CYCLO]
+
+// yamlRunIf reduces the `run_if` values of a task to the single value of the plugin. Tasks run when the previous tasks
+// passed by default.
+func yamlRunIf(runIf []string) string {
+	conditions := map[string]bool{}
+	for _, condition := range runIf {
+		conditions[condition] = true
+	}
+	switch {
+	case conditions["any"] || conditions["passed"] && conditions["failed"]:
+		return "any"
+	case conditions["failed"]:
+		return "failed"
+	default:
+		return ""
+	}
+}
+
+func yamlOptions(configuration []PluginConfigurationKVPair) (options map[string]string) {
+	for _, kv := range configuration {
+		if options == nil {
+			options = map[string]string{}
+		}
+		options[kv.Key] = kv.Value
+	}
+	return
+}
+
+// UnmarshalYAML accepts the type of approval alone, eg: `approval: manual`, as well as its attributes.
+func (ya *yamlApproval) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var approvalType string
+	if err := unmarshal(&approvalType); err == nil {
+		ya.Type = approvalType
+		return nil
+	}
+
+	type rawApproval yamlApproval
+	return unmarshal((*rawApproval)(ya))
+}
+
+// approval of a stage, which runs when the previous stage passes unless described otherwise.
+func (ya *yamlApproval) approval() *Approval {
+	approval := &Approval{Type: "success", Authorization: &Authorization{Users: []string{}, Roles: []string{}}}
+	if ya == nil {
+		return approval
+	}
+	if ya.Type != "" {
+		approval.Type = ya.Type
+	}
+	if ya.Users != nil {
+		approval.Authorization.Users = ya.Users
+	}
+	if ya.Roles != nil {
+		approval.Authorization.Roles = ya.Roles
+	}
+	return approval
+}
+
+func parseYAMLConfigFile(content []byte, repo *ConfigRepo) (pipelines []*Pipeline, environments []*Environment, err error) {
+	file := &yamlConfigFile{}
+	if err = yaml.Unmarshal(content, file); err != nil {
+		return nil, nil, err
+	}
+	if err = checkFormatVersion(file.FormatVersion, YAMLFormatVersion); err != nil {
+		return nil, nil, err
+	}
+
+	for name, yp := range file.Pipelines {
+		if yp == nil {
+			yp = &yamlPipeline{}
+		}
+		p, err := yp.pipeline(name, repo)
+		if err != nil {
+			return nil, nil, fmt.Errorf("pipeline '%s': %s", name, err)
+		}
+		pipelines = append(pipelines, p)
+	}
+	for name, ye := range file.Environments {
+		if ye == nil {
+			ye = &yamlEnvironment{}
+		}
+		envs := configRepoVariables(ye.EnvironmentVariables, ye.SecureVariables)
+		environments = append(environments, configRepoEnvironment(name, envs, ye.Agents, ye.Pipelines))
+	}
+	return pipelines, environments, nil
+}
+
+func (yp *yamlPipeline) pipeline(name string, repo *ConfigRepo) (p *Pipeline, err error) {
+	p = &Pipeline{
+		Name:                 name,
+		Group:                yp.Group,
+		LabelTemplate:        yp.LabelTemplate,
+		LockBehavior:         yp.LockBehavior,
+		Template:             yp.Template,
+		EnvironmentVariables: configRepoVariables(yp.EnvironmentVariables, yp.SecureVariables),
+	}
+	// Files written before GoCD 17.12 lock pipelines with `locking: on`.
+	if p.LockBehavior == "" && yp.Locking != nil && *yp.Locking {
+		p.LockBehavior = LockBehaviorLockOnFailure
+	}
+	for _, param := range sortedKeys(yp.Parameters) {
+		p.Parameters = append(p.Parameters, &Parameter{Name: param, Value: yp.Parameters[param]})
+	}
+	if yp.TrackingTool != nil {
+		p.TrackingTool = &TrackingTool{
+			Type:       "generic",
+			Attributes: TrackingToolAttributes{URLPattern: yp.TrackingTool.Link, Regex: yp.TrackingTool.Regex},
+		}
+	}
+	if yp.Timer != nil {
+		p.Timer = &Timer{Spec: yp.Timer.Spec, OnlyOnChanges: yp.Timer.OnlyOnChanges}
+	}
+
+	keys := make([]string, 0, len(yp.Materials))
+	for key := range yp.Materials {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		ym := yp.Materials[key]
+		if ym == nil {
+			return nil, fmt.Errorf("material '%s' has no type", key)
+		}
+		m, err := ym.configRepoMaterial(key).material(repo)
+		if err != nil {
+			return nil, err
+		}
+		p.Materials = append(p.Materials, m)
+	}
+
+	for _, stages := range yp.Stages {
+		if len(stages) != 1 {
+			return nil, fmt.Errorf("each stage must be a map with a single name, not %d", len(stages))
+		}
+		for stageName, ys := range stages {
+			if ys == nil {
+				ys = &yamlStage{}
+			}
+			stage, err := ys.stage(stageName)
+			if err != nil {
+				return nil, fmt.Errorf("stage '%s': %s", stageName, err)
+			}
+			p.Stages = append(p.Stages, stage)
+		}
+	}
+	return p, nil
+}
+
+// configRepoMaterial finds the type of the material from the key holding its location, unless it is set explicitly,
+// eg: `type: configrepo`.
+func (ym *yamlMaterial) configRepoMaterial(name string) *configRepoMaterial {
+	crm := &configRepoMaterial{
+		Type:              ym.Type,
+		Name:              name,
+		URL:               ym.URL,
+		Branch:            ym.Branch,
+		ShallowClone:      ym.ShallowClone,
+		CheckExternals:    ym.CheckExternals,
+		Port:              ym.Port,
+		UseTickets:        ym.UseTickets,
+		View:              ym.View,
+		Domain:            ym.Domain,
+		Project:           ym.Project,
+		Username:          ym.Username,
+		Password:          ym.Password,
+		EncryptedPassword: ym.EncryptedPassword,
+		Pipeline:          ym.Pipeline,
+		Stage:             ym.Stage,
+		PackageID:         ym.Package,
+		SCMID:             ym.SCM,
+		Destination:       ym.Destination,
+		AutoUpdate:        ym.AutoUpdate,
+	}
+	if len(ym.Blacklist) > 0 || len(ym.Whitelist) > 0 {
+		crm.Filter = &configRepoMaterialFilter{Ignore: ym.Blacklist, Whitelist: ym.Whitelist}
+	}
+
+	for _, location := range []struct {
+		materialType string
+		value        string
+		field        *string
+	}{
+		{"git", ym.Git, &crm.URL},
+		{"svn", ym.Svn, &crm.URL},
+		{"hg", ym.Hg, &crm.URL},
+		{"p4", ym.P4, &crm.Port},
+		{"tfs", ym.Tfs, &crm.URL},
+		{"dependency", ym.Pipeline, &crm.Pipeline},
+		{"package", ym.Package, &crm.PackageID},
+		{"plugin", ym.SCM, &crm.SCMID},
+	} {
+		if location.value == "" {
+			continue
+		}
+		*location.field = location.value
+		if crm.Type == "" {
+			crm.Type = location.materialType
+		}
+	}
+	return crm
+}
+
+func (ys *yamlStage) stage(name string) (*Stage, error) {
+	stage := &Stage{
+		Name:                  name,
+		FetchMaterials:        ys.FetchMaterials == nil || *ys.FetchMaterials,
+		NeverCleanupArtifacts: ys.KeepArtifacts,
+		CleanWorkingDirectory: ys.CleanWorkspace,
+		Approval:              ys.Approval.approval(),
+		EnvironmentVariables:  configRepoVariables(ys.EnvironmentVariables, ys.SecureVariables),
+	}
+
+	names := make([]string, 0, len(ys.Jobs))
+	for jobName := range ys.Jobs {
+		names = append(names, jobName)
+	}
+	sort.Strings(names)
+	for _, jobName := range names {
+		yj := ys.Jobs[jobName]
+		if yj == nil {
+			yj = &yamlJob{}
+		}
+		job, err := yj.job(jobName)
+		if err != nil {
+			return nil, fmt.Errorf("job '%s': %s", jobName, err)
+		}
+		stage.Jobs = append(stage.Jobs, job)
+	}
+	return stage, nil
+}
+
+func (yj *yamlJob) job(name string) (*Job, error) {
+	job := &Job{
+		Name:                 name,
+		Timeout:              TimeoutField(yj.Timeout),
+		RunInstanceCount:     int(yj.RunInstances),
+		Resources:            yj.Resources,
+		ElasticProfileID:     yj.ElasticProfileID,
+		EnvironmentVariables: configRepoVariables(yj.EnvironmentVariables, yj.SecureVariables),
+	}
+	for _, tab := range sortedKeys(yj.Tabs) {
+		job.Tabs = append(job.Tabs, &Tab{Name: tab, Path: yj.Tabs[tab]})
+	}
+
+	properties := make([]string, 0, len(yj.Properties))
+	for property := range yj.Properties {
+		properties = append(properties, property)
+	}
+	sort.Strings(properties)
+	for _, property := range properties {
+		if yproperty := yj.Properties[property]; yproperty != nil {
+			job.Properties = append(job.Properties, &JobProperty{Name: property, Source: yproperty.Source, XPath: yproperty.XPath})
+		}
+	}
+
+	for _, artifacts := range yj.Artifacts {
+		for artifactType, ya := range artifacts {
+			if ya == nil {
+				ya = &yamlArtifact{}
+			}
+			job.Artifacts = append(job.Artifacts, &Artifact{Type: artifactType, Source: ya.Source, Destination: ya.Destination})
+		}
+	}
+
+	for i, tasks := range yj.Tasks {
+		if len(tasks) != 1 {
+			return nil, fmt.Errorf("task %d must be a map with a single type, not %d", i+1, len(tasks))
+		}
+		for taskType, yt := range tasks {
+			if yt == nil {
+				yt = &yamlTask{}
+			}
+			crt, err := yt.configRepoTask(taskType)
+			if err != nil {
+				return nil, fmt.Errorf("task %d: %s", i+1, err)
+			}
+			task, err := crt.task()
+			if err != nil {
+				return nil, fmt.Errorf("task %d: %s", i+1, err)
+			}
+			job.Tasks = append(job.Tasks, task)
+		}
+	}
+	return job, nil
+}
+
+func (yt *yamlTask) configRepoTask(taskType string) (*configRepoTask, error) {
+	crt := &configRepoTask{
+		Type:             taskType,
+		RunIf:            yt.RunIf,
+		Command:          yt.Command,
+		Arguments:        yt.Arguments,
+		BuildFile:        yt.BuildFile,
+		Target:           yt.Target,
+		NantPath:         yt.NantPath,
+		WorkingDirectory: yt.WorkingDirectory,
+		ArtifactOrigin:   yt.ArtifactOrigin,
+		Pipeline:         yt.Pipeline,
+		Stage:            yt.Stage,
+		Job:              yt.Job,
+		Source:           yt.Source,
+		IsSourceAFile:    yt.IsFile,
+		Destination:      yt.Destination,
+		ArtifactID:       yt.ArtifactID,
+		Configuration:    configurationFromOptions(yt.Options),
+	}
+	if c := yt.Configuration; c != nil {
+		if c.ID != "" || c.Version != "" {
+			crt.PluginConfiguration = &TaskPluginConfiguration{ID: c.ID, Version: c.Version}
+		}
+		if len(c.Options) > 0 {
+			crt.Configuration = configurationFromOptions(c.Options)
+		}
+	}
+
+	if yt.OnCancel != nil {
+		if len(yt.OnCancel) != 1 {
+			return nil, fmt.Errorf("on_cancel must be a map with a single type, not %d", len(yt.OnCancel))
+		}
+		for cancelType, cancel := range yt.OnCancel {
+			if cancel == nil {
+				cancel = &yamlTask{}
+			}
+			onCancel, err := cancel.configRepoTask(cancelType)
+			if err != nil {
+				return nil, fmt.Errorf("on_cancel: %s", err)
+			}
+			crt.OnCancel = onCancel
+		}
+	}
+	return crt, nil
+}
+
+func configurationFromOptions(options map[string]string) (configuration []PluginConfigurationKVPair) {
+	for _, key := range sortedKeys(options) {
+		configuration = append(configuration, PluginConfigurationKVPair{Key: key, Value: options[key]})
+	}
+	return
+}
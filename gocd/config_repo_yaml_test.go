@@ -0,0 +1,281 @@
+package gocd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipelineExportYAML(t *testing.T) {
+	t.Run("Pipeline", testPipelineExportYAMLPipeline)
+	t.Run("Materials", testPipelineExportYAMLMaterials)
+	t.Run("Tasks", testPipelineExportYAMLTasks)
+	t.Run("Template", testPipelineExportYAMLTemplate)
+	t.Run("SecureVariables", testPipelineExportYAMLSecureVariables)
+}
+
+func testPipelineExportYAMLPipeline(t *testing.T) {
+	p := validPipeline()
+	p.Group = "ignored"
+	p.Parameters = []*Parameter{{Name: "env", Value: "dev"}}
+	p.EnvironmentVariables = []*EnvironmentVariable{
+		{Name: "GO_ENV", Value: "dev"},
+		{Name: "TOKEN", EncryptedValue: "AES:abc", Secure: true},
+	}
+	p.EnablePipelineLocking = true
+	p.TrackingTool = &TrackingTool{Type: "generic", Attributes: TrackingToolAttributes{URLPattern: "https://jira/${ID}", Regex: "[A-Z]+-[0-9]+"}}
+	p.Stages[0].FetchMaterials = true
+	p.Stages[0].Jobs[0].Timeout = 10
+	p.Stages[0].Jobs[0].Resources = []string{"linux"}
+	p.Stages[0].Jobs[0].Artifacts = []*Artifact{{Type: "build", Source: "bin/", Destination: "dist"}, {Type: "test", Source: "reports/"}}
+	p.Stages[0].Jobs[0].Tabs = []*Tab{{Name: "coverage", Path: "reports/coverage.html"}}
+	p.Stages[1].CleanWorkingDirectory = true
+	p.Stages[1].Approval = &Approval{Type: "manual", Authorization: &Authorization{Roles: []string{"admins"}}}
+	p.Stages[1].EnvironmentVariables = []*EnvironmentVariable{{Name: "DEBUG", Value: "1"}}
+
+	b, err := (&PipelineExport{
+		Pipeline:     p,
+		Group:        "my-group",
+		Environments: []*Environment{{Name: "staging"}},
+	}).YAML()
+	assert.NoError(t, err)
+	assert.Equal(t, `format_version: 10
+environments:
+  staging:
+    pipelines:
+    - my-pipeline
+pipelines:
+  my-pipeline:
+    group: my-group
+    label_template: ${COUNT}-${my-repo[:7]}
+    lock_behavior: lockOnFailure
+    parameters:
+      env: dev
+    environment_variables:
+      GO_ENV: dev
+    secure_variables:
+      TOKEN: AES:abc
+    tracking_tool:
+      link: https://jira/${ID}
+      regex: '[A-Z]+-[0-9]+'
+    timer:
+      spec: 0 0 22 ? * MON-FRI
+    materials:
+      my-repo:
+        git: https://github.com/gocd/gocd
+        auto_update: false
+      upstream:
+        pipeline: upstream
+        stage: build
+    stages:
+    - build:
+        jobs:
+          compile:
+            timeout: 10
+            resources:
+            - linux
+            tabs:
+              coverage: reports/coverage.html
+            artifacts:
+            - build:
+                source: bin/
+                destination: dist
+            - test:
+                source: reports/
+            tasks:
+            - exec:
+                command: make
+            - exec:
+                run_if: failed
+                command: cleanup
+          lint: {}
+    - test:
+        fetch_materials: false
+        clean_workspace: true
+        approval:
+          type: manual
+          roles:
+          - admins
+        environment_variables:
+          DEBUG: "1"
+        jobs:
+          compile: {}
+`, string(b))
+}
+
+func testPipelineExportYAMLMaterials(t *testing.T) {
+	p := &Pipeline{Name: "my-pipeline", Materials: []Material{
+		{Type: "git", Attributes: &MaterialAttributesGit{URL: "https://github.com/gocd/gocd.git", Branch: "release", ShallowClone: true, AutoUpdate: true,
+			Destination: "gocd", Filter: &MaterialFilter{Ignore: []string{"docs/**"}}}},
+		{Type: "git", Attributes: &MaterialAttributesGit{URL: "https://github.com/gocd/gocd/", AutoUpdate: true,
+			Filter: &MaterialFilter{Ignore: []string{"src/**"}}, InvertFilter: true}},
+		{Type: "svn", Attributes: &MaterialAttributesSvn{Name: "svn-repo", URL: "https://svn.example.com/trunk", Username: "user",
+			EncryptedPassword: "AES:svn", CheckExternals: true, AutoUpdate: true}},
+		{Type: "hg", Attributes: &MaterialAttributesHg{URL: "https://hg.example.com/repo", AutoUpdate: true}},
+		{Type: "p4", Attributes: &MaterialAttributesP4{Name: "p4-repo", Port: "p4.example.com:1666", UseTickets: true,
+			View: "//depot/... //ws/...", Username: "user", Password: "secret", AutoUpdate: true}},
+		{Type: "tfs", Attributes: &MaterialAttributesTfs{Name: "tfs-repo", URL: "https://tfs.example.com", Domain: "corp",
+			ProjectPath: "$/project", Username: "user", EncryptedPassword: "AES:tfs", AutoUpdate: true}},
+		{Type: "dependency", Attributes: &MaterialAttributesDependency{Name: "up", Pipeline: "upstream", Stage: "build"}},
+		{Type: "package", Attributes: &MaterialAttributesPackage{Ref: "pkg-1"}},
+		{Type: "plugin", Attributes: &MaterialAttributesPlugin{Ref: "scm-1", Destination: "scm"}},
+	}}
+
+	b, err := (&PipelineExport{Pipeline: p}).YAML()
+	assert.NoError(t, err)
+	assert.Equal(t, `format_version: 10
+pipelines:
+  my-pipeline:
+    materials:
+      gocd:
+        git: https://github.com/gocd/gocd.git
+        branch: release
+        shallow_clone: true
+        destination: gocd
+        blacklist:
+        - docs/**
+      gocd-2:
+        git: https://github.com/gocd/gocd/
+        whitelist:
+        - src/**
+      p4-repo:
+        p4: p4.example.com:1666
+        use_tickets: true
+        view: //depot/... //ws/...
+        username: user
+        password: secret
+      pkg-1:
+        package: pkg-1
+      repo:
+        hg: https://hg.example.com/repo
+      scm-1:
+        scm: scm-1
+        destination: scm
+      svn-repo:
+        svn: https://svn.example.com/trunk
+        check_externals: true
+        username: user
+        encrypted_password: AES:svn
+      tfs-repo:
+        tfs: https://tfs.example.com
+        domain: corp
+        project: $/project
+        username: user
+        encrypted_password: AES:tfs
+      up:
+        pipeline: upstream
+        stage: build
+`, string(b))
+
+	p.Materials = append(p.Materials, Material{Type: "svn"})
+	_, err = (&PipelineExport{Pipeline: p}).YAML()
+	assert.EqualError(t, err, "material 10: 'svn' material has no attributes")
+}
+
+func testPipelineExportYAMLTasks(t *testing.T) {
+	p, err := NewPipeline("my-pipeline").
+		Git("https://github.com/gocd/gocd", "").
+		Stage("build").
+		Job("compile").
+		Task(&Task{Type: "ant", Attributes: &TaskAttributesAnt{RunIf: []string{"passed", "failed"}, BuildFile: "build.xml", Target: "all", WorkingDirectory: "src",
+			OnCancel: &Task{Type: "exec", Attributes: &TaskAttributesExec{Command: "pkill", Arguments: []string{"java"},
+				OnCancel: &Task{Type: "rake", Attributes: &TaskAttributesRake{Target: "cleanup"}}}}}}).
+		Task(&Task{Type: "nant", Attributes: &TaskAttributesNant{RunIf: []string{"any"}, BuildFile: "default.build", Target: "all", WorkingDirectory: "src", NantPath: "/opt/nant"}}).
+		Task(&Task{Type: "rake", Attributes: &TaskAttributesRake{RunIf: []string{"passed"}, Target: "spec"}}).
+		Fetch("upstream", "build", "compile", "bin/app").
+		Task(&Task{Type: "fetch", Attributes: &TaskAttributesFetch{ArtifactOrigin: "external", RunIf: []string{"passed"}, Pipeline: "upstream",
+			Stage: "build", Job: "compile", ArtifactID: "image", Configuration: []PluginConfigurationKVPair{{Key: "tag", Value: "latest"}}}}).
+		Task(&Task{Type: "pluggable_task", Attributes: &TaskAttributesPluggable{RunIf: []string{"failed"},
+			PluginConfiguration: &TaskPluginConfiguration{ID: "script-executor", Version: "1"},
+			Configuration:       []PluginConfigurationKVPair{{Key: "script", Value: "./notify.sh"}}}}).
+		Build()
+	assert.NoError(t, err)
+	p.Stages[0].Jobs[0].Tasks[3].Attributes.(*TaskAttributesFetch).IsSourceAFile = true
+
+	b, err := (&PipelineExport{Pipeline: p}).YAML()
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `            tasks:
+            - ant:
+                run_if: any
+                build_file: build.xml
+                target: all
+                working_directory: src
+                on_cancel:
+                  exec:
+                    command: pkill
+                    arguments:
+                    - java
+                    on_cancel:
+                      rake:
+                        target: cleanup
+            - nant:
+                run_if: any
+                build_file: default.build
+                target: all
+                nant_path: /opt/nant
+                working_directory: src
+            - rake:
+                target: spec
+            - fetch:
+                pipeline: upstream
+                stage: build
+                job: compile
+                source: bin/app
+                is_file: true
+            - fetch:
+                artifact_origin: external
+                pipeline: upstream
+                stage: build
+                job: compile
+                artifact_id: image
+                configuration:
+                  options:
+                    tag: latest
+            - plugin:
+                run_if: failed
+                configuration:
+                  id: script-executor
+                  version: "1"
+                options:
+                  script: ./notify.sh
+`)
+}
+
+func testPipelineExportYAMLTemplate(t *testing.T) {
+	p, err := NewPipeline("my-pipeline").Git("https://github.com/gocd/gocd", "").UseTemplate("my-template").Build()
+	assert.NoError(t, err)
+	template, err := NewTemplate("my-template").Stage("build").Job("compile").Exec("make").BuildTemplate()
+	assert.NoError(t, err)
+
+	b, err := (&PipelineExport{Pipeline: p}).YAML()
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "    template: my-template\n")
+	assert.NotContains(t, string(b), "stages:")
+
+	b, err = (&PipelineExport{Pipeline: p, Template: template}).YAML()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "template:")
+	assert.Contains(t, string(b), `    stages:
+    - build:
+        jobs:
+          compile:
+            tasks:
+            - exec:
+                command: make
+`)
+
+	template.Name = "other-template"
+	_, err = (&PipelineExport{Pipeline: p, Template: template}).YAML()
+	assert.EqualError(t, err, "pipeline 'my-pipeline' uses template 'my-template', not 'other-template'")
+}
+
+func testPipelineExportYAMLSecureVariables(t *testing.T) {
+	p := validPipeline()
+	p.Stages[1].Jobs[0].EnvironmentVariables = []*EnvironmentVariable{{Name: "TOKEN", Value: "plain", Secure: true}}
+
+	_, err := (&PipelineExport{Pipeline: p}).YAML()
+	assert.EqualError(t, err, "stage 'test': job 'compile': secure variable 'TOKEN' must be encrypted before it is exported")
+
+	_, err = (&PipelineExport{}).YAML()
+	assert.EqualError(t, err, "no pipeline to export")
+}
@@ -1,215 +1,316 @@
 package gocd
 
 import (
+	"bytes"
 	"context"
+	"encoding/xml"
 )
 
 // ConfigurationService describes the HAL _link resource for the api response object for a pipelineconfig
 type ConfigurationService service
 
-// ConfigXML part of cruise-control.xml. @TODO better documentation
+// ConfigXML is the root `<cruise>` element of cruise-config.xml, the configuration file of a GoCD server. The model
+// follows cruise-config.xsd. Elements and attributes it does not know, for example ones added by a newer schema
+// version, are kept in the `Unknown` and `UnknownAttrs` fields of the closest known element, so that a configuration
+// which is read and written again does not lose anything. Optional boolean and numeric attributes are pointers, so
+// that an attribute which is not set can be told apart from one set to its zero value.
+// codebeat:disable[TOO_MANY_IVARS]
 type ConfigXML struct {
-	Repositories       []ConfigMaterialRepository `xml:"repositories>repository"`
-	Server             ConfigServer               `xml:"server"`
-	SCMS               []ConfigSCM                `xml:"scms>scm"`
-	ConfigRepositories []ConfigRepository         `xml:"config-repos>config-repo"`
+	XMLName            xml.Name                   `xml:"cruise" json:"-"`
+	SchemaVersion      int                        `xml:"schemaVersion,attr,omitempty"`
+	Server             *ConfigServer              `xml:"server"`
+	Elastic            *ConfigElastic             `xml:"elastic" json:",omitempty"`
+	ArtifactStores     []ConfigArtifactStore      `xml:"artifactStores>artifactStore,omitempty" json:",omitempty"`
+	SecretConfigs      []ConfigSecretConfig       `xml:"secretConfigs>secretConfig,omitempty" json:",omitempty"`
+	Repositories       []ConfigMaterialRepository `xml:"repositories>repository,omitempty"`
+	SCMS               []ConfigSCM                `xml:"scms>scm,omitempty"`
+	ConfigRepositories []ConfigRepository         `xml:"config-repos>config-repo,omitempty"`
 	PipelineGroups     []ConfigPipelineGroup      `xml:"pipelines"`
+	Templates          []ConfigTemplate           `xml:"templates>pipeline,omitempty" json:",omitempty"`
+	Environments       []ConfigEnvironment        `xml:"environments>environment,omitempty" json:",omitempty"`
+	Agents             []ConfigAgent              `xml:"agents>agent,omitempty" json:",omitempty"`
+	UnknownAttrs       []xml.Attr                 `xml:",any,attr" json:",omitempty"`
+	Unknown            []ConfigElement            `xml:",any" json:",omitempty"`
+
+	// order is the order of the elements read by ParseConfigXML.
+	order *xmlOrder
 }
 
-// ConfigPipelineGroup contains a single pipeline groups
-type ConfigPipelineGroup struct {
-	Name      string           `xml:"group,attr"`
-	Pipelines []ConfigPipeline `xml:"pipeline"`
+// codebeat:enable[TOO_MANY_IVARS]
+
+// ConfigServer is the `<server>` element, holding the settings of the server itself.
+// codebeat:disable[TOO_MANY_IVARS]
+type ConfigServer struct {
+	Security                  *ConfigSecurity        `xml:"security"`
+	MailHost                  *MailHost              `xml:"mailhost"`
+	Backup                    *ConfigBackup          `xml:"backup" json:",omitempty"`
+	Artifacts                 *ConfigServerArtifacts `xml:"artifacts" json:",omitempty"`
+	SiteURLs                  *ConfigSiteURLs        `xml:"siteUrls" json:",omitempty"`
+	Elastic                   *ConfigElastic         `xml:"elastic"`
+	ArtifactsDir              string                 `xml:"artifactsdir,attr,omitempty"`
+	SiteURL                   string                 `xml:"siteUrl,attr,omitempty"`
+	SecureSiteURL             string                 `xml:"secureSiteUrl,attr,omitempty"`
+	PurgeStart                string                 `xml:"purgeStart,attr,omitempty"`
+	PurgeUpTo                 string                 `xml:"purgeUpto,attr,omitempty"`
+	JobTimeout                *int                   `xml:"jobTimeout,attr,omitempty"`
+	AgentAutoRegisterKey      string                 `xml:"agentAutoRegisterKey,attr,omitempty"`
+	WebhookSecret             string                 `xml:"webhookSecret,attr,omitempty"`
+	CommandRepositoryLocation string                 `xml:"commandRepositoryLocation,attr,omitempty"`
+	ServerID                  string                 `xml:"serverId,attr,omitempty"`
+	TokenGenerationKey        string                 `xml:"tokenGenerationKey,attr,omitempty"`
+	UnknownAttrs              []xml.Attr             `xml:",any,attr" json:",omitempty"`
+	Unknown                   []ConfigElement        `xml:",any" json:",omitempty"`
 }
 
-// ConfigPipeline part of cruise-control.xml. @TODO better documentation
+// codebeat:enable[TOO_MANY_IVARS]
+
+// MailHost is the SMTP server used to send notifications.
 // codebeat:disable[TOO_MANY_IVARS]
-type ConfigPipeline struct {
-	Name                 string                      `xml:"name,attr"`
-	LabelTemplate        string                      `xml:"labeltemplate,attr"`
-	Params               []ConfigParam               `xml:"params>param"`
-	GitMaterials         []GitRepositoryMaterial     `xml:"materials>git,omitempty"`
-	PipelineMaterials    []PipelineMaterial          `xml:"materials>pipeline,omitempty"`
-	Timer                string                      `xml:"timer"`
-	EnvironmentVariables []ConfigEnvironmentVariable `xml:"environmentvariables>variable"`
-	Stages               []ConfigStage               `xml:"stage"`
+type MailHost struct {
+	Hostname          string          `xml:"hostname,attr"`
+	Port              *int            `xml:"port,attr,omitempty"`
+	Username          string          `xml:"username,attr,omitempty"`
+	Password          string          `xml:"password,attr,omitempty"`
+	EncryptedPassword string          `xml:"encryptedPassword,attr,omitempty"`
+	TLS               *bool           `xml:"tls,attr,omitempty"`
+	From              string          `xml:"from,attr,omitempty"`
+	Admin             string          `xml:"admin,attr,omitempty"`
+	UnknownAttrs      []xml.Attr      `xml:",any,attr" json:",omitempty"`
+	Unknown           []ConfigElement `xml:",any" json:",omitempty"`
 }
 
 // codebeat:enable[TOO_MANY_IVARS]
 
-// ConfigStage part of cruise-control.xml. @TODO better documentation
-type ConfigStage struct {
-	Name     string         `xml:"name,attr"`
-	Approval ConfigApproval `xml:"approval,omitempty" json:",omitempty"`
-	Jobs     []ConfigJob    `xml:"jobs>job"`
+// ConfigBackup schedules the backups of the server.
+type ConfigBackup struct {
+	Schedule         string          `xml:"schedule,attr,omitempty"`
+	PostBackupScript string          `xml:"postBackupScript,attr,omitempty"`
+	EmailOnSuccess   *bool           `xml:"emailOnSuccess,attr,omitempty"`
+	EmailOnFailure   *bool           `xml:"emailOnFailure,attr,omitempty"`
+	UnknownAttrs     []xml.Attr      `xml:",any,attr" json:",omitempty"`
+	Unknown          []ConfigElement `xml:",any" json:",omitempty"`
 }
 
-// ConfigJob part of cruise-control.xml. @TODO better documentation
-type ConfigJob struct {
-	Name                 string                      `xml:"name,attr"`
-	EnvironmentVariables []ConfigEnvironmentVariable `xml:"environmentvariables>variable" json:",omitempty"`
-	Tasks                ConfigTasks                 `xml:"tasks"`
-	Resources            []string                    `xml:"resources>resource" json:",omitempty"`
-	Artifacts            []ConfigArtifact            `xml:"artifacts>artifact" json:",omitempty"`
+// ConfigServerArtifacts is where the server stores artifacts, and when it purges them.
+type ConfigServerArtifacts struct {
+	ArtifactsDir  string               `xml:"artifactsDir,omitempty"`
+	PurgeSettings *ConfigPurgeSettings `xml:"purgeSettings"`
+	UnknownAttrs  []xml.Attr           `xml:",any,attr" json:",omitempty"`
+	Unknown       []ConfigElement      `xml:",any" json:",omitempty"`
 }
 
-// ConfigArtifact part of cruise-control.xml. @TODO better documentation
-type ConfigArtifact struct {
-	Src         string `xml:"src,attr"`
-	Destination string `xml:"dest,attr,omitempty" json:",omitempty"`
+// ConfigPurgeSettings are the disk space thresholds, in GB, which start and stop purging artifacts.
+type ConfigPurgeSettings struct {
+	PurgeStartDiskSpace string          `xml:"purgeStartDiskSpace,omitempty"`
+	PurgeUptoDiskSpace  string          `xml:"purgeUptoDiskSpace,omitempty"`
+	UnknownAttrs        []xml.Attr      `xml:",any,attr" json:",omitempty"`
+	Unknown             []ConfigElement `xml:",any" json:",omitempty"`
 }
 
-// ConfigApproval part of cruise-control.xml. @TODO better documentation
-type ConfigApproval struct {
-	Type string `xml:"type,attr,omitempty" json:",omitempty"`
+// ConfigSiteURLs are the URLs the server is reached at, used in links it generates.
+type ConfigSiteURLs struct {
+	SiteURL       string          `xml:"siteUrl,omitempty"`
+	SecureSiteURL string          `xml:"secureSiteUrl,omitempty"`
+	UnknownAttrs  []xml.Attr      `xml:",any,attr" json:",omitempty"`
+	Unknown       []ConfigElement `xml:",any" json:",omitempty"`
 }
 
-// ConfigEnvironmentVariable part of cruise-control.xml. @TODO better documentation
-type ConfigEnvironmentVariable struct {
-	Name  string `xml:"name,attr"`
-	Value string `xml:"value"`
+// ConfigSecurity holds the authentication and authorization settings of the server.
+// codebeat:disable[TOO_MANY_IVARS]
+type ConfigSecurity struct {
+	AllowOnlyKnownUsersToLogin *bool              `xml:"allowOnlyKnownUsersToLogin,attr,omitempty"`
+	AuthConfigs                []ConfigAuthConfig `xml:"authConfigs>authConfig,omitempty"`
+	Roles                      []ConfigRole       `xml:"roles>role,omitempty"`
+	PluginRoles                []ConfigPluginRole `xml:"roles>pluginRole,omitempty" json:",omitempty"`
+	Admins                     []string           `xml:"admins>user,omitempty"`
+	AdminRoles                 []string           `xml:"admins>role,omitempty" json:",omitempty"`
+	PasswordFile               *PasswordFilePath  `xml:"passwordFile"`
+	UnknownAttrs               []xml.Attr         `xml:",any,attr" json:",omitempty"`
+	Unknown                    []ConfigElement    `xml:",any" json:",omitempty"`
 }
 
-// PipelineMaterial part of cruise-control.xml. @TODO better documentation
-type PipelineMaterial struct {
-	Name         string `xml:"pipelineName,attr"`
-	StageName    string `xml:"stageName,attr"`
-	MaterialName string `xml:"materialName,attr"`
+// codebeat:enable[TOO_MANY_IVARS]
+
+// PasswordFilePath describes the location to set of user/passwords on disk
+type PasswordFilePath struct {
+	Path         string          `xml:"path,attr"`
+	UnknownAttrs []xml.Attr      `xml:",any,attr" json:",omitempty"`
+	Unknown      []ConfigElement `xml:",any" json:",omitempty"`
 }
 
-// GitRepositoryMaterial part of cruise-control.xml. @TODO better documentation
-type GitRepositoryMaterial struct {
-	URL     string         `xml:"url,attr"`
-	Filters []ConfigFilter `xml:"filter>ignore,omitempty"`
+// ConfigRole is a role whose users are listed in the configuration.
+type ConfigRole struct {
+	Name         string          `xml:"name,attr"`
+	Users        []string        `xml:"users>user,omitempty"`
+	UnknownAttrs []xml.Attr      `xml:",any,attr" json:",omitempty"`
+	Unknown      []ConfigElement `xml:",any" json:",omitempty"`
 }
 
-// ConfigFilter part of cruise-control.xml. @TODO better documentation
-type ConfigFilter struct {
-	Ignore string `xml:"pattern,attr,omitempty"`
+// ConfigPluginRole is a role whose users are looked up by an authorization plugin.
+type ConfigPluginRole struct {
+	Name         string           `xml:"name,attr"`
+	AuthConfigID string           `xml:"authConfigId,attr"`
+	Properties   []ConfigProperty `xml:"property"`
+	UnknownAttrs []xml.Attr       `xml:",any,attr" json:",omitempty"`
+	Unknown      []ConfigElement  `xml:",any" json:",omitempty"`
 }
 
-// ConfigParam part of cruise-control.xml. @TODO better documentation
-type ConfigParam struct {
-	Name  string `xml:"name,attr"`
-	Value string `xml:",chardata"`
+// ConfigAuthConfig configures an authorization plugin.
+type ConfigAuthConfig struct {
+	ID           string           `xml:"id,attr"`
+	PluginID     string           `xml:"pluginId,attr"`
+	Properties   []ConfigProperty `xml:"property"`
+	UnknownAttrs []xml.Attr       `xml:",any,attr" json:",omitempty"`
+	Unknown      []ConfigElement  `xml:",any" json:",omitempty"`
 }
 
-// ConfigRepository part of cruise-control.xml. @TODO better documentation
-type ConfigRepository struct {
-	Plugin string              `xml:"plugin,attr"`
-	ID     string              `xml:"id,attr"`
-	Git    ConfigRepositoryGit `xml:"git"`
+// ConfigElastic holds the profiles of elastic agents. Older servers keep it in `<server>`, and use `Profiles`, newer
+// ones keep it in `<cruise>`, and use `AgentProfiles` and `ClusterProfiles`.
+type ConfigElastic struct {
+	JobStarvationTimeout *int                   `xml:"jobStarvationTimeout,attr,omitempty"`
+	Profiles             []ConfigElasticProfile `xml:"profiles>profile,omitempty"`
+	AgentProfiles        []ConfigElasticProfile `xml:"agentProfiles>agentProfile,omitempty" json:",omitempty"`
+	ClusterProfiles      []ConfigClusterProfile `xml:"clusterProfiles>clusterProfile,omitempty" json:",omitempty"`
+	UnknownAttrs         []xml.Attr             `xml:",any,attr" json:",omitempty"`
+	Unknown              []ConfigElement        `xml:",any" json:",omitempty"`
 }
 
-// ConfigRepositoryGit part of cruise-control.xml. @TODO better documentation
-type ConfigRepositoryGit struct {
-	URL string `xml:"url,attr"`
+// ConfigElasticProfile describes the elastic agents a job can be run on.
+type ConfigElasticProfile struct {
+	ID               string           `xml:"id,attr"`
+	PluginID         string           `xml:"pluginId,attr,omitempty"`
+	ClusterProfileID string           `xml:"clusterProfileId,attr,omitempty"`
+	Properties       []ConfigProperty `xml:"property"`
+	UnknownAttrs     []xml.Attr       `xml:",any,attr" json:",omitempty"`
+	Unknown          []ConfigElement  `xml:",any" json:",omitempty"`
 }
 
-// ConfigSCM part of cruise-control.xml. @TODO better documentation
-type ConfigSCM struct {
-	ID                  string                    `xml:"id,attr"`
-	Name                string                    `xml:"name,attr"`
-	PluginConfiguration ConfigPluginConfiguration `xml:"pluginConfiguration"`
-	Configuration       []ConfigProperty          `xml:"configuration>property"`
+// ConfigClusterProfile configures an elastic agent plugin for a cluster elastic agents are started in.
+type ConfigClusterProfile struct {
+	ID           string           `xml:"id,attr"`
+	PluginID     string           `xml:"pluginId,attr"`
+	Properties   []ConfigProperty `xml:"property"`
+	UnknownAttrs []xml.Attr       `xml:",any,attr" json:",omitempty"`
+	Unknown      []ConfigElement  `xml:",any" json:",omitempty"`
 }
 
-// ConfigMaterialRepository part of cruise-control.xml. @TODO better documentation
-type ConfigMaterialRepository struct {
-	ID                  string                    `xml:"id,attr"`
-	Name                string                    `xml:"name,attr"`
-	PluginConfiguration ConfigPluginConfiguration `xml:"pluginConfiguration"`
-	Configuration       []ConfigProperty          `xml:"configuration>property"`
-	Packages            []ConfigPackage           `xml:"packages>package"`
+// ConfigArtifactStore configures an artifact plugin, which stores artifacts outside of the server.
+type ConfigArtifactStore struct {
+	ID           string           `xml:"id,attr"`
+	PluginID     string           `xml:"pluginId,attr"`
+	Properties   []ConfigProperty `xml:"property"`
+	UnknownAttrs []xml.Attr       `xml:",any,attr" json:",omitempty"`
+	Unknown      []ConfigElement  `xml:",any" json:",omitempty"`
 }
 
-// ConfigPackage part of cruise-control.xml. @TODO better documentation
-type ConfigPackage struct {
+// ConfigSecretConfig configures a secrets plugin, which resolves secret params.
+type ConfigSecretConfig struct {
 	ID            string           `xml:"id,attr"`
-	Name          string           `xml:"name,attr"`
-	Configuration []ConfigProperty `xml:"configuration>property"`
+	PluginID      string           `xml:"pluginId,attr"`
+	Description   string           `xml:"description,omitempty"`
+	Configuration []ConfigProperty `xml:"configuration>property,omitempty"`
+	Rules         *ConfigRules     `xml:"rules"`
+	UnknownAttrs  []xml.Attr       `xml:",any,attr" json:",omitempty"`
+	Unknown       []ConfigElement  `xml:",any" json:",omitempty"`
 }
 
-// ConfigPluginConfiguration part of cruise-control.xml. @TODO better documentation
-type ConfigPluginConfiguration struct {
-	ID      string `xml:"id,attr"`
-	Version string `xml:"version,attr"`
+// ConfigRules lists the entities a secret config or a config repository may refer to, in the order they apply.
+type ConfigRules struct {
+	Rules        []ConfigRule `xml:",any"`
+	UnknownAttrs []xml.Attr   `xml:",any,attr" json:",omitempty"`
 }
 
-// ConfigServer part of cruise-control.xml. @TODO better documentation
+// ConfigRule allows or denies an action on the resources matching a pattern. The name of the element, `allow` or
+// `deny`, is in XMLName.
+type ConfigRule struct {
+	XMLName      xml.Name
+	Action       string     `xml:"action,attr,omitempty"`
+	Type         string     `xml:"type,attr,omitempty"`
+	Resource     string     `xml:",chardata"`
+	UnknownAttrs []xml.Attr `xml:",any,attr" json:",omitempty"`
+}
+
+// ConfigRepository is a repository holding pipeline definitions, read by a config repository plugin. It has one
+// material, in the field named after its type.
 // codebeat:disable[TOO_MANY_IVARS]
-type ConfigServer struct {
-	MailHost                  MailHost       `xml:"mailhost"`
-	Security                  ConfigSecurity `xml:"security"`
-	Elastic                   ConfigElastic  `xml:"elastic"`
-	ArtifactsDir              string         `xml:"artifactsdir,attr"`
-	SiteURL                   string         `xml:"siteUrl,attr"`
-	SecureSiteURL             string         `xml:"secureSiteUrl,attr"`
-	PurgeStart                string         `xml:"purgeStart,attr"`
-	PurgeUpTo                 string         `xml:"purgeUpto,attr"`
-	JobTimeout                int            `xml:"jobTimeout,attr"`
-	AgentAutoRegisterKey      string         `xml:"agentAutoRegisterKey,attr"`
-	WebhookSecret             string         `xml:"webhookSecret,attr"`
-	CommandRepositoryLocation string         `xml:"commandRepositoryLocation,attr"`
-	ServerID                  string         `xml:"serverId,attr"`
+type ConfigRepository struct {
+	Plugin        string           `xml:"plugin,attr,omitempty"`
+	PluginID      string           `xml:"pluginId,attr,omitempty"`
+	ID            string           `xml:"id,attr"`
+	Git           *ConfigMaterial  `xml:"git"`
+	Svn           *ConfigMaterial  `xml:"svn" json:",omitempty"`
+	Hg            *ConfigMaterial  `xml:"hg" json:",omitempty"`
+	P4            *ConfigMaterial  `xml:"p4" json:",omitempty"`
+	Tfs           *ConfigMaterial  `xml:"tfs" json:",omitempty"`
+	SCM           *ConfigMaterial  `xml:"scm" json:",omitempty"`
+	Configuration []ConfigProperty `xml:"configuration>property,omitempty" json:",omitempty"`
+	Rules         *ConfigRules     `xml:"rules" json:",omitempty"`
+	UnknownAttrs  []xml.Attr       `xml:",any,attr" json:",omitempty"`
+	Unknown       []ConfigElement  `xml:",any" json:",omitempty"`
 }
 
 // codebeat:enable[TOO_MANY_IVARS]
 
-// MailHost part of cruise-control.xml. @TODO better documentation
-type MailHost struct {
-	Hostname string `xml:"hostname,attr"`
-	Port     int    `xml:"port,attr"`
-	TLS      bool   `xml:"tls,attr"`
-	From     string `xml:"from,attr"`
-	Admin    string `xml:"admin,attr"`
-}
-
-// ConfigSecurity part of cruise-control.xml. @TODO better documentation
-type ConfigSecurity struct {
-	AuthConfigs  []ConfigAuthConfig `xml:"authConfigs>authConfig"`
-	Roles        []ConfigRole       `xml:"roles>role"`
-	Admins       []string           `xml:"admins>user"`
-	PasswordFile PasswordFilePath   `xml:"passwordFile"`
+// ConfigRepositoryGit is the git material of a config repository.
+//
+// Deprecated: Use ConfigMaterial, which ConfigRepository holds in the field named after the type of its material.
+type ConfigRepositoryGit struct {
+	URL string `xml:"url,attr"`
 }
 
-// PasswordFilePath describes the location to set of user/passwords on disk
-type PasswordFilePath struct {
-	Path string `xml:"path,attr"`
+// ConfigSCM is a pluggable SCM, which pipelines refer to with an `<scm ref="..."/>` material.
+type ConfigSCM struct {
+	ID                  string                    `xml:"id,attr"`
+	Name                string                    `xml:"name,attr"`
+	AutoUpdate          *bool                     `xml:"autoUpdate,attr,omitempty"`
+	PluginConfiguration ConfigPluginConfiguration `xml:"pluginConfiguration"`
+	Configuration       []ConfigProperty          `xml:"configuration>property,omitempty"`
+	UnknownAttrs        []xml.Attr                `xml:",any,attr" json:",omitempty"`
+	Unknown             []ConfigElement           `xml:",any" json:",omitempty"`
 }
 
-// ConfigRole part of cruise-control.xml. @TODO better documentation
-type ConfigRole struct {
-	Name  string   `xml:"name,attr"`
-	Users []string `xml:"users>user"`
+// ConfigMaterialRepository is a package repository, holding the packages pipelines refer to with a
+// `<package ref="..."/>` material.
+// codebeat:disable[TOO_MANY_IVARS]
+type ConfigMaterialRepository struct {
+	ID                  string                    `xml:"id,attr"`
+	Name                string                    `xml:"name,attr"`
+	PluginConfiguration ConfigPluginConfiguration `xml:"pluginConfiguration"`
+	Configuration       []ConfigProperty          `xml:"configuration>property,omitempty"`
+	Packages            []ConfigPackage           `xml:"packages>package,omitempty"`
+	UnknownAttrs        []xml.Attr                `xml:",any,attr" json:",omitempty"`
+	Unknown             []ConfigElement           `xml:",any" json:",omitempty"`
 }
 
-// ConfigAuthConfig part of cruise-control.xml. @TODO better documentation
-type ConfigAuthConfig struct {
-	ID         string           `xml:"id,attr"`
-	PluginID   string           `xml:"pluginId,attr"`
-	Properties []ConfigProperty `xml:"property"`
-}
+// codebeat:enable[TOO_MANY_IVARS]
 
-// ConfigElastic part of cruise-control.xml. @TODO better documentation
-type ConfigElastic struct {
-	Profiles []ConfigElasticProfile `xml:"profiles>profile"`
+// ConfigPackage is a package in a package repository.
+type ConfigPackage struct {
+	ID            string           `xml:"id,attr"`
+	Name          string           `xml:"name,attr"`
+	AutoUpdate    *bool            `xml:"autoUpdate,attr,omitempty"`
+	Configuration []ConfigProperty `xml:"configuration>property,omitempty"`
+	UnknownAttrs  []xml.Attr       `xml:",any,attr" json:",omitempty"`
+	Unknown       []ConfigElement  `xml:",any" json:",omitempty"`
 }
 
-// ConfigElasticProfile part of cruise-control.xml. @TODO better documentation
-type ConfigElasticProfile struct {
-	ID         string           `xml:"id,attr"`
-	PluginID   string           `xml:"pluginId,attr"`
-	Properties []ConfigProperty `xml:"property"`
+// ConfigPluginConfiguration identifies the plugin, and the version of it, an entity is configured for.
+type ConfigPluginConfiguration struct {
+	ID           string     `xml:"id,attr"`
+	Version      string     `xml:"version,attr"`
+	UnknownAttrs []xml.Attr `xml:",any,attr" json:",omitempty"`
 }
 
-// ConfigProperty part of cruise-control.xml. @TODO better documentation
+// ConfigProperty is a key and a value, either plain or encrypted, configuring a plugin.
 type ConfigProperty struct {
-	Key   string `xml:"key"`
-	Value string `xml:"value"`
+	Key            string          `xml:"key"`
+	Value          string          `xml:"value,omitempty"`
+	EncryptedValue string          `xml:"encryptedValue,omitempty" json:",omitempty"`
+	UnknownAttrs   []xml.Attr      `xml:",any,attr" json:",omitempty"`
+	Unknown        []ConfigElement `xml:",any" json:",omitempty"`
 }
 
-// Version part of cruise-control.xml. @TODO better documentation
+// Version describes the software version of a GoCD server.
 type Version struct {
 	Links       *HALLinks `json:"_links"`
 	Version     string    `json:"version"`
@@ -221,16 +322,27 @@ type Version struct {
 
 // Get the config.xml document from the server and... render it as JSON... 'cause... eyugh.
 func (cs *ConfigurationService) Get(ctx context.Context) (cx *ConfigXML, resp *APIResponse, err error) {
-	cx = &ConfigXML{}
+	apiVersion, err := cs.client.getAPIVersion(ctx, "admin/config.xml")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b := &bytes.Buffer{}
 	_, resp, err = cs.client.getAction(ctx, &APIClientRequest{
 		Path:         "admin/config.xml",
-		ResponseBody: cx,
+		APIVersion:   apiVersion,
+		ResponseBody: b,
 		ResponseType: responseTypeXML,
 	})
+	if err != nil {
+		return nil, resp, err
+	}
+	cx, err = ParseConfigXML(b.Bytes())
 	return
 }
 
-// GetVersion of the GoCD server and other metadata about the software version.
+// GetVersion of the GoCD server and other metadata about the software version. The API version of this endpoint is not
+// negotiated, as negotiating API versions relies on it.
 func (cs *ConfigurationService) GetVersion(ctx context.Context) (v *Version, resp *APIResponse, err error) {
 	v = &Version{}
 	_, resp, err = cs.client.getAction(ctx, &APIClientRequest{
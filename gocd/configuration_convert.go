@@ -0,0 +1,759 @@
+package gocd
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// The conversions between the model of cruise-config.xml and the structures of the pipeline config API do not drop
+// anything. A setting one side can not hold, such as an element unknown to the model, is reported as an error.
+
+// Pipelines converts the pipelines of every group into pipeline configs, with their group set.
+func (cx *ConfigXML) Pipelines() (pipelines []*Pipeline, err error) {
+	for _, group := range cx.PipelineGroups {
+		for i := range group.Pipelines {
+			p, err := group.Pipelines[i].Pipeline()
+			if err != nil {
+				return nil, err
+			}
+			p.Group = group.Name
+			pipelines = append(pipelines, p)
+		}
+	}
+	return pipelines, nil
+}
+
+// Pipeline converts the pipeline into a pipeline config, as the pipeline config API returns it.
+// codebeat:disable[ABC,CYCLO]
+func (cp *ConfigPipeline) Pipeline() (*Pipeline, error) {
+	if err := configConvertible(cp.UnknownAttrs, cp.Unknown); err != nil {
+		return nil, cp.errorf(err)
+	}
+	p := &Pipeline{
+		Name:                  cp.Name,
+		LabelTemplate:         cp.LabelTemplate,
+		EnablePipelineLocking: configBool(cp.IsLocked, false),
+		LockBehavior:          cp.LockBehavior,
+		Template:              cp.Template,
+	}
+
+	for _, param := range cp.Params {
+		if err := configConvertible(param.UnknownAttrs, nil); err != nil {
+			return nil, cp.errorf(fmt.Errorf("parameter '%s': %s", param.Name, err))
+		}
+		p.Parameters = append(p.Parameters, &Parameter{Name: param.Name, Value: param.Value})
+	}
+	if tt := cp.TrackingTool; tt != nil {
+		if err := configConvertible(tt.UnknownAttrs, tt.Unknown); err != nil {
+			return nil, cp.errorf(fmt.Errorf("tracking tool: %s", err))
+		}
+		p.TrackingTool = &TrackingTool{Type: "generic", Attributes: TrackingToolAttributes{URLPattern: tt.Link, Regex: tt.Regex}}
+	}
+	if timer := cp.Timer; timer != nil {
+		if err := configConvertible(timer.UnknownAttrs, nil); err != nil {
+			return nil, cp.errorf(fmt.Errorf("timer: %s", err))
+		}
+		p.Timer = &Timer{Spec: timer.Spec, OnlyOnChanges: configBool(timer.OnlyOnChanges, false)}
+	}
+
+	var err error
+	if p.EnvironmentVariables, err = configEnvironmentVariables(cp.EnvironmentVariables); err != nil {
+		return nil, cp.errorf(err)
+	}
+	if err = configConvertible(cp.Materials.UnknownAttrs, nil); err != nil {
+		return nil, cp.errorf(fmt.Errorf("materials: %s", err))
+	}
+	for i := range cp.Materials.Materials {
+		m, err := cp.Materials.Materials[i].Material()
+		if err != nil {
+			return nil, cp.errorf(fmt.Errorf("material %d: %s", i+1, err))
+		}
+		p.Materials = append(p.Materials, m)
+	}
+	for i := range cp.Stages {
+		stage, err := cp.Stages[i].Stage()
+		if err != nil {
+			return nil, cp.errorf(err)
+		}
+		p.Stages = append(p.Stages, stage)
+	}
+	return p, nil
+}
+
+// codebeat:enable[ABC,CYCLO]
+
+func (cp *ConfigPipeline) errorf(err error) error {
+	return fmt.Errorf("pipeline '%s': %s", cp.Name, err)
+}
+
+// NewConfigPipeline converts a pipeline config into the `<pipeline>` element of cruise-config.xml. The group of the
+// pipeline is not part of the element.
+// codebeat:disable[ABC,CYCLO]
+func NewConfigPipeline(p *Pipeline) (*ConfigPipeline, error) {
+	cp := &ConfigPipeline{
+		Name:          p.Name,
+		LabelTemplate: p.LabelTemplate,
+		IsLocked:      newConfigBool(p.EnablePipelineLocking, false),
+		LockBehavior:  p.LockBehavior,
+		Template:      p.Template,
+	}
+
+	for _, param := range p.Parameters {
+		cp.Params = append(cp.Params, ConfigParam{Name: param.Name, Value: param.Value})
+	}
+	if tt := p.TrackingTool; tt != nil {
+		if tt.Type != "generic" {
+			return nil, cp.errorf(fmt.Errorf("'%s' tracking tools can not be converted", tt.Type))
+		}
+		cp.TrackingTool = &ConfigTrackingTool{Link: tt.Attributes.URLPattern, Regex: tt.Attributes.Regex}
+	}
+	if p.Timer != nil {
+		cp.Timer = &ConfigTimer{Spec: p.Timer.Spec, OnlyOnChanges: newConfigBool(p.Timer.OnlyOnChanges, false)}
+	}
+	cp.EnvironmentVariables = newConfigEnvironmentVariables(p.EnvironmentVariables)
+
+	for i, m := range p.Materials {
+		cm, err := NewConfigMaterial(m)
+		if err != nil {
+			return nil, cp.errorf(fmt.Errorf("material %d: %s", i+1, err))
+		}
+		cp.Materials.Materials = append(cp.Materials.Materials, *cm)
+	}
+	for _, stage := range p.Stages {
+		cs, err := NewConfigStage(stage)
+		if err != nil {
+			return nil, cp.errorf(err)
+		}
+		cp.Stages = append(cp.Stages, *cs)
+	}
+	return cp, nil
+}
+
+// codebeat:enable[ABC,CYCLO]
+
+// Stage converts the stage into the structure the pipeline config API uses. A stage without an approval is approved on
+// success.
+func (cs *ConfigStage) Stage() (*Stage, error) {
+	if err := configConvertible(cs.UnknownAttrs, cs.Unknown); err != nil {
+		return nil, cs.errorf(err)
+	}
+	stage := &Stage{
+		Name:                  cs.Name,
+		FetchMaterials:        configBool(cs.FetchMaterials, true),
+		CleanWorkingDirectory: configBool(cs.CleanWorkingDir, false),
+		NeverCleanupArtifacts: configBool(cs.ArtifactCleanupProhibited, false),
+		Approval:              &Approval{Type: "success", Authorization: &Authorization{Users: []string{}, Roles: []string{}}},
+	}
+
+	if ca := cs.Approval; ca != nil {
+		if err := configConvertible(ca.UnknownAttrs, ca.Unknown); err != nil {
+			return nil, cs.errorf(fmt.Errorf("approval: %s", err))
+		}
+		if ca.Type != "" {
+			stage.Approval.Type = ca.Type
+		}
+		stage.Approval.AllowOnlyOnSuccess = configBool(ca.AllowOnlyOnSuccess, false)
+		if auth := ca.Authorization; auth != nil {
+			if err := configConvertible(auth.UnknownAttrs, auth.Unknown); err != nil {
+				return nil, cs.errorf(fmt.Errorf("approval: %s", err))
+			}
+			stage.Approval.Authorization.Users = append(stage.Approval.Authorization.Users, auth.Users...)
+			stage.Approval.Authorization.Roles = append(stage.Approval.Authorization.Roles, auth.Roles...)
+		}
+	}
+
+	var err error
+	if stage.EnvironmentVariables, err = configEnvironmentVariables(cs.EnvironmentVariables); err != nil {
+		return nil, cs.errorf(err)
+	}
+	for i := range cs.Jobs {
+		job, err := cs.Jobs[i].Job()
+		if err != nil {
+			return nil, cs.errorf(err)
+		}
+		stage.Jobs = append(stage.Jobs, job)
+	}
+	return stage, nil
+}
+
+func (cs *ConfigStage) errorf(err error) error {
+	return fmt.Errorf("stage '%s': %s", cs.Name, err)
+}
+
+// NewConfigStage converts a stage of a pipeline config into the `<stage>` element of cruise-config.xml.
+func NewConfigStage(stage *Stage) (*ConfigStage, error) {
+	cs := &ConfigStage{
+		Name:                      stage.Name,
+		FetchMaterials:            newConfigBool(stage.FetchMaterials, true),
+		ArtifactCleanupProhibited: newConfigBool(stage.NeverCleanupArtifacts, false),
+		CleanWorkingDir:           newConfigBool(stage.CleanWorkingDirectory, false),
+		EnvironmentVariables:      newConfigEnvironmentVariables(stage.EnvironmentVariables),
+	}
+
+	if a := stage.Approval; a != nil {
+		ca := &ConfigApproval{Type: a.Type, AllowOnlyOnSuccess: newConfigBool(a.AllowOnlyOnSuccess, false)}
+		if a.Authorization != nil && (len(a.Authorization.Users) > 0 || len(a.Authorization.Roles) > 0) {
+			ca.Authorization = &ConfigAuthorization{Users: a.Authorization.Users, Roles: a.Authorization.Roles}
+		}
+		if ca.Type != "success" || ca.AllowOnlyOnSuccess != nil || ca.Authorization != nil {
+			cs.Approval = ca
+		}
+	}
+
+	for _, job := range stage.Jobs {
+		cj, err := NewConfigJob(job)
+		if err != nil {
+			return nil, cs.errorf(err)
+		}
+		cs.Jobs = append(cs.Jobs, *cj)
+	}
+	return cs, nil
+}
+
+// Job converts the job into the structure the pipeline config API uses. A job run on every agent can not be converted,
+// as Job can only hold a number of instances, and neither can a job which never times out, as Job can not tell it
+// apart from a job using the default timeout of the server.
+// codebeat:disable[ABC,CYCLO,LOC]
+func (cj *ConfigJob) Job() (*Job, error) {
+	if err := configConvertible(cj.UnknownAttrs, cj.Unknown); err != nil {
+		return nil, cj.errorf(err)
+	}
+	job := &Job{Name: cj.Name, ElasticProfileID: cj.ElasticProfileID, Resources: cj.Resources}
+
+	if cj.Timeout != "" {
+		timeout, err := strconv.Atoi(cj.Timeout)
+		if err != nil {
+			return nil, cj.errorf(fmt.Errorf("timeout '%s' is not a number of minutes", cj.Timeout))
+		}
+		if timeout == 0 {
+			return nil, cj.errorf(errors.New("a timeout of 0, never timing out, can not be converted"))
+		}
+		job.Timeout = TimeoutField(timeout)
+	}
+	if cj.RunInstanceCount != "" {
+		count, err := strconv.Atoi(cj.RunInstanceCount)
+		if err != nil {
+			return nil, cj.errorf(fmt.Errorf("running '%s' instances of a job can not be converted", cj.RunInstanceCount))
+		}
+		job.RunInstanceCount = count
+	}
+
+	var err error
+	if job.EnvironmentVariables, err = configEnvironmentVariables(cj.EnvironmentVariables); err != nil {
+		return nil, cj.errorf(err)
+	}
+	if err = configConvertible(cj.Tasks.UnknownAttrs, nil); err != nil {
+		return nil, cj.errorf(fmt.Errorf("tasks: %s", err))
+	}
+	for i := range cj.Tasks.Tasks {
+		task, err := cj.Tasks.Tasks[i].Task()
+		if err != nil {
+			return nil, cj.errorf(fmt.Errorf("task %d: %s", i+1, err))
+		}
+		job.Tasks = append(job.Tasks, task)
+	}
+
+	for _, tab := range cj.Tabs {
+		if err = configConvertible(tab.UnknownAttrs, nil); err != nil {
+			return nil, cj.errorf(fmt.Errorf("tab '%s': %s", tab.Name, err))
+		}
+		job.Tabs = append(job.Tabs, &Tab{Name: tab.Name, Path: tab.Path})
+	}
+	for _, property := range cj.Properties {
+		if err = configConvertible(property.UnknownAttrs, nil); err != nil {
+			return nil, cj.errorf(fmt.Errorf("property '%s': %s", property.Name, err))
+		}
+		job.Properties = append(job.Properties, &JobProperty{Name: property.Name, Source: property.Src, XPath: property.XPath})
+	}
+
+	for _, artifact := range cj.Artifacts {
+		a, err := artifact.artifact("build")
+		if err != nil {
+			return nil, cj.errorf(err)
+		}
+		job.Artifacts = append(job.Artifacts, a)
+	}
+	for _, artifact := range cj.Tests {
+		a, err := artifact.artifact("test")
+		if err != nil {
+			return nil, cj.errorf(err)
+		}
+		job.Artifacts = append(job.Artifacts, a)
+	}
+	for _, artifact := range cj.PluggableArtifacts {
+		if err = configConvertible(artifact.UnknownAttrs, artifact.Unknown); err != nil {
+			return nil, cj.errorf(fmt.Errorf("artifact '%s': %s", artifact.ID, err))
+		}
+		configuration, err := configPluginConfiguration(artifact.Configuration)
+		if err != nil {
+			return nil, cj.errorf(fmt.Errorf("artifact '%s': %s", artifact.ID, err))
+		}
+		job.Artifacts = append(job.Artifacts, &Artifact{Type: "external", ID: artifact.ID, StoreID: artifact.StoreID, Configuration: configuration})
+	}
+	return job, nil
+}
+
+// codebeat:enable[ABC,CYCLO,LOC]
+
+func (cj *ConfigJob) errorf(err error) error {
+	return fmt.Errorf("job '%s': %s", cj.Name, err)
+}
+
+// artifact converts an `<artifact>` element, or a `<test>` one of older servers, which have no type.
+func (ca *ConfigArtifact) artifact(defaultType string) (*Artifact, error) {
+	if err := configConvertible(ca.UnknownAttrs, ca.Unknown); err != nil {
+		return nil, fmt.Errorf("artifact '%s': %s", ca.Src, err)
+	}
+	a := &Artifact{Type: ca.Type, Source: ca.Src, Destination: ca.Destination}
+	if a.Type == "" {
+		a.Type = defaultType
+	}
+	return a, nil
+}
+
+// NewConfigJob converts a job of a pipeline config into the `<job>` element of cruise-config.xml. This is lossy for
+// the timeout: a Timeout of 0 is written without a timeout, so the job uses the default timeout of the server, but Job
+// also reads a job which never times out, `"never"` in the pipeline config API, as a Timeout of 0.
+// codebeat:disable[ABC,CYCLO]
+func NewConfigJob(job *Job) (*ConfigJob, error) {
+	cj := &ConfigJob{
+		Name:                 job.Name,
+		ElasticProfileID:     job.ElasticProfileID,
+		EnvironmentVariables: newConfigEnvironmentVariables(job.EnvironmentVariables),
+		Resources:            job.Resources,
+	}
+	if job.Timeout != 0 {
+		cj.Timeout = strconv.Itoa(int(job.Timeout))
+	}
+	if job.RunInstanceCount != 0 {
+		cj.RunInstanceCount = strconv.Itoa(job.RunInstanceCount)
+	}
+
+	for i, task := range job.Tasks {
+		ct, err := NewConfigTask(task)
+		if err != nil {
+			return nil, cj.errorf(fmt.Errorf("task %d: %s", i+1, err))
+		}
+		cj.Tasks.Tasks = append(cj.Tasks.Tasks, *ct)
+	}
+	for _, tab := range job.Tabs {
+		cj.Tabs = append(cj.Tabs, ConfigTab{Name: tab.Name, Path: tab.Path})
+	}
+	for _, property := range job.Properties {
+		cj.Properties = append(cj.Properties, ConfigJobProperty{Name: property.Name, Src: property.Source, XPath: property.XPath})
+	}
+	for _, a := range job.Artifacts {
+		switch a.Type {
+		case "build", "test":
+			cj.Artifacts = append(cj.Artifacts, ConfigArtifact{Type: a.Type, Src: a.Source, Destination: a.Destination})
+		case "external":
+			cj.PluggableArtifacts = append(cj.PluggableArtifacts, ConfigPluggableArtifact{
+				ID: a.ID, StoreID: a.StoreID, Configuration: newConfigProperties(a.Configuration),
+			})
+		default:
+			return nil, cj.errorf(fmt.Errorf("'%s' artifacts can not be converted", a.Type))
+		}
+	}
+	return cj, nil
+}
+
+// codebeat:enable[ABC,CYCLO]
+
+var configTaskTypes = map[string]bool{
+	"exec": true, "ant": true, "nant": true, "rake": true, "fetchartifact": true, "fetchPluggableArtifact": true,
+	"task": true,
+}
+
+// Task converts the task into the structure the pipeline config API uses. `fetchartifact` and `fetchPluggableArtifact`
+// tasks become `fetch` tasks, and `task` ones `pluggable_task` tasks.
+// codebeat:disable[ABC,CYCLO,LOC]
+func (ct *ConfigTask) Task() (*Task, error) {
+	if !configTaskTypes[ct.XMLName.Local] {
+		return nil, fmt.Errorf("<%s> tasks can not be converted", ct.XMLName.Local)
+	}
+	if err := configConvertible(ct.UnknownAttrs, ct.Unknown); err != nil {
+		return nil, err
+	}
+	if ct.Type != "" {
+		return nil, errors.New("element <type> can not be converted")
+	}
+	runIf, err := ct.runIf()
+	if err != nil {
+		return nil, err
+	}
+	configuration, err := configPluginConfiguration(ct.Configuration)
+	if err != nil {
+		return nil, err
+	}
+	var onCancel *Task
+	if ct.OnCancel != nil {
+		if onCancel, err = ct.OnCancel.onCancel(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch ct.XMLName.Local {
+	case "exec":
+		return &Task{Type: "exec", Attributes: &TaskAttributesExec{
+			RunIf: runIf, Command: ct.Command, Arguments: ct.Args, WorkingDirectory: ct.WorkingDir, OnCancel: onCancel,
+		}}, nil
+	case "ant":
+		return &Task{Type: "ant", Attributes: &TaskAttributesAnt{
+			RunIf: runIf, BuildFile: ct.BuildFile, Target: ct.Target, WorkingDirectory: ct.WorkingDir, OnCancel: onCancel,
+		}}, nil
+	case "nant":
+		return &Task{Type: "nant", Attributes: &TaskAttributesNant{
+			RunIf: runIf, BuildFile: ct.BuildFile, Target: ct.Target, WorkingDirectory: ct.WorkingDir, NantPath: ct.NantPath,
+			OnCancel: onCancel,
+		}}, nil
+	case "rake":
+		return &Task{Type: "rake", Attributes: &TaskAttributesRake{
+			RunIf: runIf, BuildFile: ct.BuildFile, Target: ct.Target, WorkingDirectory: ct.WorkingDir, OnCancel: onCancel,
+		}}, nil
+	case "fetchartifact":
+		fetch := &TaskAttributesFetch{
+			ArtifactOrigin: ct.ArtifactOrigin, RunIf: runIf, Pipeline: ct.Pipeline, Stage: ct.Stage, Job: ct.Job,
+			Source: ct.SrcDir, Destination: ct.Dest, OnCancel: onCancel,
+		}
+		if ct.SrcFile != "" {
+			fetch.Source, fetch.IsSourceAFile = ct.SrcFile, true
+		}
+		return &Task{Type: "fetch", Attributes: fetch}, nil
+	case "fetchPluggableArtifact":
+		return &Task{Type: "fetch", Attributes: &TaskAttributesFetch{
+			ArtifactOrigin: "external", RunIf: runIf, Pipeline: ct.Pipeline, Stage: ct.Stage, Job: ct.Job,
+			ArtifactID: ct.ArtifactID, Configuration: configuration, OnCancel: onCancel,
+		}}, nil
+	case "task":
+		pluggable := &TaskAttributesPluggable{RunIf: runIf, Configuration: configuration, OnCancel: onCancel}
+		if pc := ct.PluginConfiguration; pc != nil {
+			if err = configConvertible(pc.UnknownAttrs, nil); err != nil {
+				return nil, err
+			}
+			pluggable.PluginConfiguration = &TaskPluginConfiguration{ID: pc.ID, Version: pc.Version}
+		}
+		return &Task{Type: "pluggable_task", Attributes: pluggable}, nil
+	}
+	return nil, fmt.Errorf("<%s> tasks can not be converted", ct.XMLName.Local)
+}
+
+// codebeat:enable[ABC,CYCLO,LOC]
+
+func (ct *ConfigTask) runIf() (runIf []string, err error) {
+	for _, r := range ct.RunIf {
+		if err = configConvertible(r.UnknownAttrs, nil); err != nil {
+			return nil, fmt.Errorf("runif: %s", err)
+		}
+		runIf = append(runIf, r.Status)
+	}
+	return runIf, nil
+}
+
+// onCancel converts the task run when a task is cancelled. There is at most one.
+func (cts *ConfigTasks) onCancel() (*Task, error) {
+	if err := configConvertible(cts.UnknownAttrs, nil); err != nil {
+		return nil, fmt.Errorf("oncancel: %s", err)
+	}
+	if len(cts.Tasks) != 1 {
+		return nil, fmt.Errorf("oncancel holds %d tasks, not 1", len(cts.Tasks))
+	}
+	task, err := cts.Tasks[0].Task()
+	if err != nil {
+		return nil, fmt.Errorf("oncancel: %s", err)
+	}
+	return task, nil
+}
+
+// NewConfigTask converts a task of a pipeline config into the element of cruise-config.xml for its type.
+// codebeat:disable[ABC,CYCLO,LOC]
+func NewConfigTask(t *Task) (*ConfigTask, error) {
+	if err := t.validateType(); err != nil {
+		return nil, err
+	}
+
+	ct := &ConfigTask{}
+	var runIf []string
+	var onCancel *Task
+	switch a := t.Attributes.(type) {
+	case *TaskAttributesExec:
+		ct.XMLName.Local, runIf, onCancel = "exec", a.RunIf, a.OnCancel
+		ct.Command, ct.Args, ct.WorkingDir = a.Command, a.Arguments, a.WorkingDirectory
+	case *TaskAttributesAnt:
+		ct.XMLName.Local, runIf, onCancel = "ant", a.RunIf, a.OnCancel
+		ct.BuildFile, ct.Target, ct.WorkingDir = a.BuildFile, a.Target, a.WorkingDirectory
+	case *TaskAttributesNant:
+		ct.XMLName.Local, runIf, onCancel = "nant", a.RunIf, a.OnCancel
+		ct.BuildFile, ct.Target, ct.WorkingDir, ct.NantPath = a.BuildFile, a.Target, a.WorkingDirectory, a.NantPath
+	case *TaskAttributesRake:
+		ct.XMLName.Local, runIf, onCancel = "rake", a.RunIf, a.OnCancel
+		ct.BuildFile, ct.Target, ct.WorkingDir = a.BuildFile, a.Target, a.WorkingDirectory
+	case *TaskAttributesFetch:
+		runIf, onCancel = a.RunIf, a.OnCancel
+		ct.Pipeline, ct.Stage, ct.Job = a.Pipeline, a.Stage, a.Job
+		if a.ArtifactOrigin == "external" {
+			ct.XMLName.Local = "fetchPluggableArtifact"
+			ct.ArtifactID, ct.Configuration = a.ArtifactID, newConfigProperties(a.Configuration)
+			break
+		}
+		ct.XMLName.Local, ct.ArtifactOrigin, ct.Dest = "fetchartifact", a.ArtifactOrigin, a.Destination
+		if a.IsSourceAFile {
+			ct.SrcFile = a.Source
+		} else {
+			ct.SrcDir = a.Source
+		}
+	case *TaskAttributesPluggable:
+		ct.XMLName.Local, runIf, onCancel = "task", a.RunIf, a.OnCancel
+		if a.PluginConfiguration != nil {
+			ct.PluginConfiguration = &ConfigPluginConfiguration{ID: a.PluginConfiguration.ID, Version: a.PluginConfiguration.Version}
+		}
+		ct.Configuration = newConfigProperties(a.Configuration)
+	}
+
+	for _, status := range runIf {
+		ct.RunIf = append(ct.RunIf, ConfigTaskRunIf{Status: status})
+	}
+	if onCancel != nil {
+		cancel, err := NewConfigTask(onCancel)
+		if err != nil {
+			return nil, fmt.Errorf("on_cancel: %s", err)
+		}
+		ct.OnCancel = &ConfigTasks{Tasks: []ConfigTask{*cancel}}
+	}
+	return ct, nil
+}
+
+// codebeat:enable[ABC,CYCLO,LOC]
+
+// Material converts the material into the structure the pipeline config API uses. `pipeline` materials become
+// `dependency` ones, and `scm` materials `plugin` ones.
+// codebeat:disable[ABC,CYCLO,LOC]
+func (cm *ConfigMaterial) Material() (m Material, err error) {
+	if err = configConvertible(cm.UnknownAttrs, cm.Unknown); err != nil {
+		return m, err
+	}
+	filter, err := cm.filter()
+	if err != nil {
+		return m, err
+	}
+	autoUpdate := configBool(cm.AutoUpdate, true)
+	invert := configBool(cm.InvertFilter, false)
+
+	switch cm.XMLName.Local {
+	case "git":
+		m.Type = "git"
+		m.Attributes = &MaterialAttributesGit{
+			Name: cm.Name, URL: cm.URL, Branch: cm.Branch, ShallowClone: configBool(cm.ShallowClone, false),
+			Username: cm.Username, Password: cm.Password, EncryptedPassword: cm.EncryptedPassword,
+			Destination: cm.Destination, Filter: filter, InvertFilter: invert, AutoUpdate: autoUpdate,
+		}
+	case "svn":
+		m.Type = "svn"
+		m.Attributes = &MaterialAttributesSvn{
+			Name: cm.Name, URL: cm.URL, CheckExternals: configBool(cm.CheckExternals, false),
+			Username: cm.Username, Password: cm.Password, EncryptedPassword: cm.EncryptedPassword,
+			Destination: cm.Destination, Filter: filter, InvertFilter: invert, AutoUpdate: autoUpdate,
+		}
+	case "hg":
+		m.Type = "hg"
+		m.Attributes = &MaterialAttributesHg{
+			Name: cm.Name, URL: cm.URL,
+			Destination: cm.Destination, Filter: filter, InvertFilter: invert, AutoUpdate: autoUpdate,
+		}
+	case "p4":
+		m.Type = "p4"
+		m.Attributes = &MaterialAttributesP4{
+			Name: cm.Name, Port: cm.Port, UseTickets: configBool(cm.UseTickets, false), View: cm.View,
+			Username: cm.Username, Password: cm.Password, EncryptedPassword: cm.EncryptedPassword,
+			Destination: cm.Destination, Filter: filter, InvertFilter: invert, AutoUpdate: autoUpdate,
+		}
+	case "tfs":
+		m.Type = "tfs"
+		m.Attributes = &MaterialAttributesTfs{
+			Name: cm.Name, URL: cm.URL, Domain: cm.Domain, ProjectPath: cm.ProjectPath,
+			Username: cm.Username, Password: cm.Password, EncryptedPassword: cm.EncryptedPassword,
+			Destination: cm.Destination, Filter: filter, InvertFilter: invert, AutoUpdate: autoUpdate,
+		}
+	case "pipeline":
+		m.Type = "dependency"
+		m.Attributes = &MaterialAttributesDependency{
+			Name: cm.Name, Pipeline: cm.PipelineName, Stage: cm.StageName, AutoUpdate: true,
+			IgnoreForScheduling: configBool(cm.IgnoreForScheduling, false),
+		}
+	case "package":
+		m.Type = "package"
+		m.Attributes = &MaterialAttributesPackage{Ref: cm.Ref}
+	case "scm":
+		m.Type = "plugin"
+		m.Attributes = &MaterialAttributesPlugin{Ref: cm.Ref, Destination: cm.Destination, Filter: filter, InvertFilter: invert}
+	default:
+		return m, fmt.Errorf("<%s> materials can not be converted", cm.XMLName.Local)
+	}
+	return m, nil
+}
+
+// codebeat:enable[ABC,CYCLO,LOC]
+
+func (cm *ConfigMaterial) filter() (*MaterialFilter, error) {
+	if len(cm.Filters) == 0 {
+		return nil, nil
+	}
+	filter := &MaterialFilter{}
+	for _, f := range cm.Filters {
+		if err := configConvertible(f.UnknownAttrs, nil); err != nil {
+			return nil, fmt.Errorf("filter: %s", err)
+		}
+		filter.Ignore = append(filter.Ignore, f.Ignore)
+	}
+	return filter, nil
+}
+
+// NewConfigMaterial converts a material of a pipeline config into the element of cruise-config.xml for its type.
+// codebeat:disable[ABC,CYCLO,LOC]
+func NewConfigMaterial(m Material) (*ConfigMaterial, error) {
+	if m.Attributes == nil {
+		return nil, fmt.Errorf("'%s' material has no attributes", m.Type)
+	}
+
+	cm := &ConfigMaterial{}
+	switch a := materialAttributeValue(m.Attributes).(type) {
+	case MaterialAttributesGit:
+		cm.XMLName.Local, cm.Name, cm.URL, cm.Branch = "git", a.Name, a.URL, a.Branch
+		cm.ShallowClone = newConfigBool(a.ShallowClone, false)
+		cm.Username, cm.Password, cm.EncryptedPassword = a.Username, a.Password, a.EncryptedPassword
+		cm.setFilter(a.Destination, a.Filter, a.InvertFilter, a.AutoUpdate)
+	case MaterialAttributesSvn:
+		cm.XMLName.Local, cm.Name, cm.URL = "svn", a.Name, a.URL
+		cm.CheckExternals = newConfigBool(a.CheckExternals, false)
+		cm.Username, cm.Password, cm.EncryptedPassword = a.Username, a.Password, a.EncryptedPassword
+		cm.setFilter(a.Destination, a.Filter, a.InvertFilter, a.AutoUpdate)
+	case MaterialAttributesHg:
+		cm.XMLName.Local, cm.Name, cm.URL = "hg", a.Name, a.URL
+		cm.setFilter(a.Destination, a.Filter, a.InvertFilter, a.AutoUpdate)
+	case MaterialAttributesP4:
+		cm.XMLName.Local, cm.Name, cm.Port, cm.View = "p4", a.Name, a.Port, a.View
+		cm.UseTickets = newConfigBool(a.UseTickets, false)
+		cm.Username, cm.Password, cm.EncryptedPassword = a.Username, a.Password, a.EncryptedPassword
+		cm.setFilter(a.Destination, a.Filter, a.InvertFilter, a.AutoUpdate)
+	case MaterialAttributesTfs:
+		cm.XMLName.Local, cm.Name, cm.URL, cm.Domain, cm.ProjectPath = "tfs", a.Name, a.URL, a.Domain, a.ProjectPath
+		cm.Username, cm.Password, cm.EncryptedPassword = a.Username, a.Password, a.EncryptedPassword
+		cm.setFilter(a.Destination, a.Filter, a.InvertFilter, a.AutoUpdate)
+	case MaterialAttributesDependency:
+		cm.XMLName.Local, cm.Name, cm.PipelineName, cm.StageName = "pipeline", a.Name, a.Pipeline, a.Stage
+		cm.IgnoreForScheduling = newConfigBool(a.IgnoreForScheduling, false)
+	case MaterialAttributesPackage:
+		cm.XMLName.Local, cm.Ref = "package", a.Ref
+	case MaterialAttributesPlugin:
+		cm.XMLName.Local, cm.Ref = "scm", a.Ref
+		cm.setFilter(a.Destination, a.Filter, a.InvertFilter, true)
+	default:
+		return nil, fmt.Errorf("'%s' materials can not be converted", m.Type)
+	}
+	return cm, nil
+}
+
+// codebeat:enable[ABC,CYCLO,LOC]
+
+// setFilter sets the settings the source control materials share.
+func (cm *ConfigMaterial) setFilter(destination string, filter *MaterialFilter, invert bool, autoUpdate bool) {
+	cm.Destination = destination
+	cm.InvertFilter = newConfigBool(invert, false)
+	cm.AutoUpdate = newConfigBool(autoUpdate, true)
+	if filter != nil {
+		for _, ignore := range filter.Ignore {
+			cm.Filters = append(cm.Filters, ConfigFilter{Ignore: ignore})
+		}
+	}
+}
+
+// EnvironmentVariable converts the variable into the structure the APIs use.
+func (cv *ConfigEnvironmentVariable) EnvironmentVariable() (*EnvironmentVariable, error) {
+	if err := configConvertible(cv.UnknownAttrs, cv.Unknown); err != nil {
+		return nil, fmt.Errorf("environment variable '%s': %s", cv.Name, err)
+	}
+	return &EnvironmentVariable{
+		Name:           cv.Name,
+		Value:          cv.Value,
+		EncryptedValue: cv.EncryptedValue,
+		Secure:         configBool(cv.Secure, false),
+	}, nil
+}
+
+// NewConfigEnvironmentVariable converts a variable into the `<variable>` element of cruise-config.xml.
+func NewConfigEnvironmentVariable(v *EnvironmentVariable) *ConfigEnvironmentVariable {
+	return &ConfigEnvironmentVariable{
+		Name:           v.Name,
+		Secure:         newConfigBool(v.Secure, false),
+		Value:          v.Value,
+		EncryptedValue: v.EncryptedValue,
+	}
+}
+
+func configEnvironmentVariables(cvs []ConfigEnvironmentVariable) (envs []*EnvironmentVariable, err error) {
+	for i := range cvs {
+		env, err := cvs[i].EnvironmentVariable()
+		if err != nil {
+			return nil, err
+		}
+		envs = append(envs, env)
+	}
+	return envs, nil
+}
+
+func newConfigEnvironmentVariables(envs []*EnvironmentVariable) (cvs []ConfigEnvironmentVariable) {
+	for _, env := range envs {
+		cvs = append(cvs, *NewConfigEnvironmentVariable(env))
+	}
+	return cvs
+}
+
+// configPluginConfiguration converts the configuration of a plugin. The structure the APIs use has no encrypted values.
+func configPluginConfiguration(properties []ConfigProperty) (configuration []PluginConfigurationKVPair, err error) {
+	for _, property := range properties {
+		if err = configConvertible(property.UnknownAttrs, property.Unknown); err != nil {
+			return nil, fmt.Errorf("property '%s': %s", property.Key, err)
+		}
+		if property.EncryptedValue != "" {
+			return nil, fmt.Errorf("the encrypted value of property '%s' can not be converted", property.Key)
+		}
+		configuration = append(configuration, PluginConfigurationKVPair{Key: property.Key, Value: property.Value})
+	}
+	return configuration, nil
+}
+
+func newConfigProperties(configuration []PluginConfigurationKVPair) (properties []ConfigProperty) {
+	for _, kv := range configuration {
+		properties = append(properties, ConfigProperty{Key: kv.Key, Value: kv.Value})
+	}
+	return properties
+}
+
+// configConvertible reports the first attribute or element unknown to the model of cruise-config.xml, which the
+// structures of the APIs have no room for.
+func configConvertible(attrs []xml.Attr, elements []ConfigElement) error {
+	if len(attrs) > 0 {
+		return fmt.Errorf("attribute '%s' can not be converted", xmlRawName(attrs[0].Name))
+	}
+	if len(elements) > 0 {
+		return fmt.Errorf("element <%s> can not be converted", xmlRawName(elements[0].XMLName))
+	}
+	return nil
+}
+
+// configBool reads an optional boolean attribute.
+func configBool(b *bool, defaultValue bool) bool {
+	if b == nil {
+		return defaultValue
+	}
+	return *b
+}
+
+// newConfigBool sets an optional boolean attribute, when it does not have its default value.
+func newConfigBool(b bool, defaultValue bool) *bool {
+	if b == defaultValue {
+		return nil
+	}
+	return &b
+}
@@ -0,0 +1,203 @@
+package gocd
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func TestConfigXMLConvert(t *testing.T) {
+	t.Run("Pipeline", testConfigXMLConvertPipeline)
+	t.Run("Pipelines", testConfigXMLConvertPipelines)
+	t.Run("RoundTrip", testConfigXMLConvertRoundTrip)
+	t.Run("Errors", testConfigXMLConvertErrors)
+}
+
+// convertibleConfigXML reads config.1.xml without the elements and attributes unknown to the model, and with a job
+// timeout the pipeline config API can hold.
+func convertibleConfigXML(t *testing.T) *ConfigXML {
+	b, err := ioutil.ReadFile("test/resources/config.1.xml")
+	assert.NoError(t, err)
+	cx, err := ParseConfigXML(b)
+	assert.NoError(t, err)
+
+	app := &cx.PipelineGroups[0].Pipelines[0]
+	app.UnknownAttrs, app.Unknown = nil, nil
+	job := &app.Stages[0].Jobs[0]
+	job.Tasks.Tasks = job.Tasks.Tasks[:len(job.Tasks.Tasks)-1]
+	job.Timeout = "30"
+	return cx
+}
+
+func testConfigXMLConvertPipeline(t *testing.T) {
+	cx := convertibleConfigXML(t)
+	p, err := cx.PipelineGroups[0].Pipelines[0].Pipeline()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "app", p.Name)
+	assert.Equal(t, "unlockWhenFinished", p.LockBehavior)
+	assert.False(t, p.EnablePipelineLocking)
+	assert.Equal(t, []*Parameter{{Name: "env", Value: "dev"}}, p.Parameters)
+	assert.Equal(t, "generic", p.TrackingTool.Type)
+	assert.Equal(t, "https://jira.example.com/browse/${ID}", p.TrackingTool.Attributes.URLPattern)
+	assert.Equal(t, &Timer{Spec: "0 0 22 ? * MON-FRI", OnlyOnChanges: true}, p.Timer)
+	assert.Equal(t, &EnvironmentVariable{Name: "TOKEN", EncryptedValue: "AES:token", Secure: true}, p.EnvironmentVariables[1])
+
+	var types []string
+	for _, m := range p.Materials {
+		types = append(types, m.Type)
+	}
+	assert.Equal(t, []string{"git", "svn", "hg", "p4", "tfs", "dependency", "package", "plugin"}, types)
+	git := p.Materials[0].Attributes.(*MaterialAttributesGit)
+	assert.False(t, git.AutoUpdate)
+	assert.True(t, git.InvertFilter)
+	assert.True(t, git.ShallowClone)
+	assert.Equal(t, &MaterialFilter{Ignore: []string{"src/**"}}, git.Filter)
+	assert.True(t, p.Materials[1].Attributes.(*MaterialAttributesSvn).AutoUpdate)
+	assert.Equal(t, "//depot/... //ws/...", p.Materials[3].Attributes.(*MaterialAttributesP4).View)
+	assert.Equal(t, &MaterialAttributesDependency{Name: "up", Pipeline: "upstream", Stage: "build", AutoUpdate: true},
+		p.Materials[5].Attributes)
+	assert.Equal(t, &MaterialAttributesPlugin{Ref: "github-pr", Destination: "pr"}, p.Materials[7].Attributes)
+
+	build := p.Stages[0]
+	assert.True(t, build.FetchMaterials)
+	assert.True(t, build.NeverCleanupArtifacts)
+	assert.Equal(t, &Approval{Type: "success", AllowOnlyOnSuccess: true,
+		Authorization: &Authorization{Users: []string{}, Roles: []string{}}}, build.Approval)
+	deploy := p.Stages[1]
+	assert.False(t, deploy.FetchMaterials)
+	assert.Equal(t, &Approval{Type: "manual",
+		Authorization: &Authorization{Users: []string{"admin"}, Roles: []string{"developers"}}}, deploy.Approval)
+
+	job := build.Jobs[0]
+	assert.Equal(t, TimeoutField(30), job.Timeout)
+	assert.Equal(t, 2, job.RunInstanceCount)
+	assert.Equal(t, "docker-small", job.ElasticProfileID)
+	assert.Equal(t, []*Tab{{Name: "coverage", Path: "reports/coverage.html"}}, job.Tabs)
+	assert.Equal(t, []*JobProperty{{Name: "coverage", Source: "reports/coverage.xml", XPath: "//coverage/@line-rate"}}, job.Properties)
+	assert.Equal(t, []*Artifact{
+		{Type: "build", Source: "bin/", Destination: "dist"},
+		{Type: "test", Source: "reports/"},
+		{Type: "external", ID: "image", StoreID: "registry",
+			Configuration: []PluginConfigurationKVPair{{Key: "BuildFile", Value: "image.json"}}},
+	}, job.Artifacts)
+
+	types = nil
+	for _, task := range job.Tasks {
+		types = append(types, task.Type)
+	}
+	assert.Equal(t, []string{"exec", "ant", "nant", "rake", "fetch", "fetch", "pluggable_task"}, types)
+	assert.Equal(t, &TaskAttributesExec{
+		RunIf: []string{"passed", "failed"}, Command: "make", Arguments: []string{"-j", "4"}, WorkingDirectory: "app",
+		OnCancel: &Task{Type: "exec", Attributes: &TaskAttributesExec{Command: "pkill", Arguments: []string{"make"}}},
+	}, job.Tasks[0].Attributes)
+	assert.Equal(t, &TaskAttributesAnt{
+		BuildFile: "build.xml", Target: "all", WorkingDirectory: "java",
+		OnCancel: &Task{Type: "exec", Attributes: &TaskAttributesExec{Command: "pkill", Arguments: []string{"java"}}},
+	}, job.Tasks[1].Attributes)
+	assert.Equal(t, &TaskAttributesFetch{
+		ArtifactOrigin: "gocd", RunIf: []string{"any"}, Pipeline: "upstream", Stage: "build", Job: "compile",
+		Source: "bin/app", IsSourceAFile: true, Destination: "bin",
+		OnCancel: &Task{Type: "exec", Attributes: &TaskAttributesExec{Command: "rm", Arguments: []string{"bin/app"}}},
+	}, job.Tasks[4].Attributes)
+	assert.Equal(t, "external", job.Tasks[5].Attributes.(*TaskAttributesFetch).ArtifactOrigin)
+	assert.Equal(t, "image", job.Tasks[5].Attributes.(*TaskAttributesFetch).ArtifactID)
+	assert.Equal(t, &TaskAttributesPluggable{
+		RunIf:               []string{"passed"},
+		PluginConfiguration: &TaskPluginConfiguration{ID: "script-executor", Version: "1"},
+		Configuration:       []PluginConfigurationKVPair{{Key: "script", Value: "./notify.sh"}},
+	}, job.Tasks[6].Attributes)
+}
+
+func testConfigXMLConvertPipelines(t *testing.T) {
+	cx := convertibleConfigXML(t)
+	pipelines, err := cx.Pipelines()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Len(t, pipelines, 2)
+	assert.Equal(t, "build", pipelines[0].Group)
+	assert.Equal(t, "upstream", pipelines[1].Name)
+	assert.Equal(t, "build-template", pipelines[1].Template)
+	assert.Equal(t, "build", pipelines[1].Group)
+}
+
+func testConfigXMLConvertRoundTrip(t *testing.T) {
+	cx := convertibleConfigXML(t)
+	for i := range cx.PipelineGroups[0].Pipelines {
+		p, err := cx.PipelineGroups[0].Pipelines[i].Pipeline()
+		if !assert.NoError(t, err) {
+			return
+		}
+		cp, err := NewConfigPipeline(p)
+		if !assert.NoError(t, err) {
+			return
+		}
+		converted, err := cp.Pipeline()
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, p, converted)
+
+		changes, err := DiffPipelines(p, converted)
+		assert.NoError(t, err)
+		assert.Empty(t, changes)
+	}
+
+	p, err := cx.PipelineGroups[0].Pipelines[0].Pipeline()
+	assert.NoError(t, err)
+	cp, err := NewConfigPipeline(p)
+	assert.NoError(t, err)
+	assert.Nil(t, cp.IsLocked)
+	assert.Nil(t, cp.Stages[0].FetchMaterials)
+	assert.Equal(t, &ConfigApproval{Type: "success", AllowOnlyOnSuccess: cp.Stages[0].Approval.AllowOnlyOnSuccess},
+		cp.Stages[0].Approval)
+	assert.Equal(t, "2", cp.Stages[0].Jobs[0].RunInstanceCount)
+	assert.Equal(t, "30", cp.Stages[0].Jobs[0].Timeout)
+	assert.Equal(t, "pkill", cp.Stages[0].Jobs[0].Tasks.Tasks[1].OnCancel.Tasks[0].Command)
+	assert.Equal(t, "rm", cp.Stages[0].Jobs[0].Tasks.Tasks[4].OnCancel.Tasks[0].Command)
+	assert.Equal(t, "fetchPluggableArtifact", cp.Stages[0].Jobs[0].Tasks.Tasks[5].XMLName.Local)
+	assert.Len(t, cp.Stages[0].Jobs[0].PluggableArtifacts, 1)
+
+	cx.PipelineGroups[0].Pipelines[0] = *cp
+	_, err = cx.XML()
+	assert.NoError(t, err)
+}
+
+func testConfigXMLConvertErrors(t *testing.T) {
+	b, err := ioutil.ReadFile("test/resources/config.1.xml")
+	assert.NoError(t, err)
+	cx, err := ParseConfigXML(b)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = cx.Pipelines()
+	assert.EqualError(t, err, "pipeline 'app': attribute 'futureAttribute' can not be converted")
+
+	app := &cx.PipelineGroups[0].Pipelines[0]
+	app.UnknownAttrs = nil
+	_, err = app.Pipeline()
+	assert.EqualError(t, err, "pipeline 'app': element <newThing> can not be converted")
+
+	app.Unknown = nil
+	_, err = app.Pipeline()
+	assert.EqualError(t, err, "pipeline 'app': stage 'build': job 'compile': a timeout of 0, never timing out, can not be converted")
+
+	app.Stages[0].Jobs[0].Timeout = ""
+	_, err = app.Pipeline()
+	assert.EqualError(t, err, "pipeline 'app': stage 'build': job 'compile': task 8: <futureTask> tasks can not be converted")
+
+	job := &ConfigJob{Name: "all", RunInstanceCount: "all"}
+	_, err = job.Job()
+	assert.EqualError(t, err, "job 'all': running 'all' instances of a job can not be converted")
+
+	_, err = NewConfigPipeline(&Pipeline{Name: "mingle", TrackingTool: &TrackingTool{Type: "mingle"}})
+	assert.EqualError(t, err, "pipeline 'mingle': 'mingle' tracking tools can not be converted")
+
+	_, err = NewConfigJob(&Job{Name: "unknown", Artifacts: []*Artifact{{Type: "unknown"}}})
+	assert.EqualError(t, err, "job 'unknown': 'unknown' artifacts can not be converted")
+}
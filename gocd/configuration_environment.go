@@ -0,0 +1,43 @@
+package gocd
+
+import (
+	"encoding/xml"
+)
+
+// ConfigEnvironment is an `<environment>` element, grouping pipelines with the agents they run on.
+type ConfigEnvironment struct {
+	Name                 string                      `xml:"name,attr"`
+	EnvironmentVariables []ConfigEnvironmentVariable `xml:"environmentvariables>variable,omitempty" json:",omitempty"`
+	Agents               []ConfigEnvironmentAgent    `xml:"agents>physical,omitempty" json:",omitempty"`
+	Pipelines            []ConfigEnvironmentPipeline `xml:"pipelines>pipeline,omitempty" json:",omitempty"`
+	UnknownAttrs         []xml.Attr                  `xml:",any,attr" json:",omitempty"`
+	Unknown              []ConfigElement             `xml:",any" json:",omitempty"`
+}
+
+// ConfigEnvironmentAgent refers to an agent of an environment by its UUID.
+type ConfigEnvironmentAgent struct {
+	UUID         string     `xml:"uuid,attr"`
+	UnknownAttrs []xml.Attr `xml:",any,attr" json:",omitempty"`
+}
+
+// ConfigEnvironmentPipeline refers to a pipeline of an environment by its name.
+type ConfigEnvironmentPipeline struct {
+	Name         string     `xml:"name,attr"`
+	UnknownAttrs []xml.Attr `xml:",any,attr" json:",omitempty"`
+}
+
+// ConfigAgent is an agent registered with the server. Servers from 20.1 keep agents in their database instead.
+// codebeat:disable[TOO_MANY_IVARS]
+type ConfigAgent struct {
+	Hostname        string          `xml:"hostname,attr"`
+	IPAddress       string          `xml:"ipaddress,attr"`
+	UUID            string          `xml:"uuid,attr"`
+	IsDisabled      *bool           `xml:"isDisabled,attr,omitempty" json:",omitempty"`
+	ElasticAgentID  string          `xml:"elasticAgentId,attr,omitempty" json:",omitempty"`
+	ElasticPluginID string          `xml:"elasticPluginId,attr,omitempty" json:",omitempty"`
+	Resources       []string        `xml:"resources>resource,omitempty" json:",omitempty"`
+	UnknownAttrs    []xml.Attr      `xml:",any,attr" json:",omitempty"`
+	Unknown         []ConfigElement `xml:",any" json:",omitempty"`
+}
+
+// codebeat:enable[TOO_MANY_IVARS]
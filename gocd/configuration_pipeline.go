@@ -0,0 +1,263 @@
+package gocd
+
+import (
+	"encoding/xml"
+)
+
+// ConfigPipelineGroup is a `<pipelines>` element, holding the pipelines of a group and who may use them.
+type ConfigPipelineGroup struct {
+	Name          string                    `xml:"group,attr"`
+	Authorization *ConfigGroupAuthorization `xml:"authorization" json:",omitempty"`
+	Pipelines     []ConfigPipeline          `xml:"pipeline"`
+	UnknownAttrs  []xml.Attr                `xml:",any,attr" json:",omitempty"`
+	Unknown       []ConfigElement           `xml:",any" json:",omitempty"`
+}
+
+// ConfigGroupAuthorization lists the users and roles which may view, operate and administer the pipelines of a group,
+// or view and administer a template.
+type ConfigGroupAuthorization struct {
+	AllGroupAdminsAreViewers *bool                `xml:"allGroupAdminsAreViewers,attr,omitempty"`
+	View                     *ConfigAuthorization `xml:"view"`
+	Operate                  *ConfigAuthorization `xml:"operate"`
+	Admins                   *ConfigAuthorization `xml:"admins"`
+	UnknownAttrs             []xml.Attr           `xml:",any,attr" json:",omitempty"`
+	Unknown                  []ConfigElement      `xml:",any" json:",omitempty"`
+}
+
+// ConfigAuthorization is a list of users and roles.
+type ConfigAuthorization struct {
+	Users        []string        `xml:"user"`
+	Roles        []string        `xml:"role"`
+	UnknownAttrs []xml.Attr      `xml:",any,attr" json:",omitempty"`
+	Unknown      []ConfigElement `xml:",any" json:",omitempty"`
+}
+
+// ConfigTemplate is a pipeline template, holding the stages of the pipelines which use it.
+type ConfigTemplate struct {
+	Name          string                    `xml:"name,attr"`
+	Authorization *ConfigGroupAuthorization `xml:"authorization" json:",omitempty"`
+	Stages        []ConfigStage             `xml:"stage"`
+	UnknownAttrs  []xml.Attr                `xml:",any,attr" json:",omitempty"`
+	Unknown       []ConfigElement           `xml:",any" json:",omitempty"`
+}
+
+// ConfigPipeline is a `<pipeline>` element. A pipeline using a template names it in `Template`, and has no stages.
+// codebeat:disable[TOO_MANY_IVARS]
+type ConfigPipeline struct {
+	Name                 string                      `xml:"name,attr"`
+	LabelTemplate        string                      `xml:"labeltemplate,attr,omitempty"`
+	IsLocked             *bool                       `xml:"isLocked,attr,omitempty" json:",omitempty"`
+	LockBehavior         string                      `xml:"lockBehavior,attr,omitempty" json:",omitempty"`
+	Template             string                      `xml:"template,attr,omitempty" json:",omitempty"`
+	Params               []ConfigParam               `xml:"params>param,omitempty"`
+	TrackingTool         *ConfigTrackingTool         `xml:"trackingtool" json:",omitempty"`
+	Timer                *ConfigTimer                `xml:"timer"`
+	EnvironmentVariables []ConfigEnvironmentVariable `xml:"environmentvariables>variable,omitempty"`
+	Materials            ConfigMaterials             `xml:"materials"`
+	Stages               []ConfigStage               `xml:"stage"`
+	UnknownAttrs         []xml.Attr                  `xml:",any,attr" json:",omitempty"`
+	Unknown              []ConfigElement             `xml:",any" json:",omitempty"`
+}
+
+// codebeat:enable[TOO_MANY_IVARS]
+
+// ConfigParam is a parameter of a pipeline, referred to as `#{name}` in its configuration.
+type ConfigParam struct {
+	Name         string     `xml:"name,attr"`
+	Value        string     `xml:",chardata"`
+	UnknownAttrs []xml.Attr `xml:",any,attr" json:",omitempty"`
+}
+
+// ConfigTrackingTool turns the references to issues in commit messages into links.
+type ConfigTrackingTool struct {
+	Link         string          `xml:"link,attr"`
+	Regex        string          `xml:"regex,attr"`
+	UnknownAttrs []xml.Attr      `xml:",any,attr" json:",omitempty"`
+	Unknown      []ConfigElement `xml:",any" json:",omitempty"`
+}
+
+// ConfigTimer schedules a pipeline with a cron-like specification.
+type ConfigTimer struct {
+	OnlyOnChanges *bool      `xml:"onlyOnChanges,attr,omitempty"`
+	Spec          string     `xml:",chardata"`
+	UnknownAttrs  []xml.Attr `xml:",any,attr" json:",omitempty"`
+}
+
+// ConfigEnvironmentVariable is an environment variable of a pipeline, stage, job or environment. A secure variable
+// has an encrypted value.
+type ConfigEnvironmentVariable struct {
+	Name           string          `xml:"name,attr"`
+	Secure         *bool           `xml:"secure,attr,omitempty" json:",omitempty"`
+	Value          string          `xml:"value,omitempty"`
+	EncryptedValue string          `xml:"encryptedValue,omitempty" json:",omitempty"`
+	UnknownAttrs   []xml.Attr      `xml:",any,attr" json:",omitempty"`
+	Unknown        []ConfigElement `xml:",any" json:",omitempty"`
+}
+
+// ConfigMaterials holds the materials of a pipeline, in the order they are configured.
+type ConfigMaterials struct {
+	Materials    []ConfigMaterial `xml:",any"`
+	UnknownAttrs []xml.Attr       `xml:",any,attr" json:",omitempty"`
+}
+
+// ConfigMaterial is a material of any type. The type is the name of the element, one of `git`, `svn`, `hg`, `p4`,
+// `tfs`, `pipeline`, `package` or `scm`, kept in XMLName. Only the fields the type uses are set.
+// codebeat:disable[TOO_MANY_IVARS]
+type ConfigMaterial struct {
+	// Because we need to preserve the order of materials, and we have an array of elements with mixed types,
+	// we need to use this generic xml type for materials.
+	XMLName             xml.Name        `json:",omitempty"`
+	Name                string          `xml:"materialName,attr,omitempty" json:",omitempty"`
+	URL                 string          `xml:"url,attr,omitempty" json:",omitempty"`
+	Branch              string          `xml:"branch,attr,omitempty" json:",omitempty"`
+	ShallowClone        *bool           `xml:"shallowClone,attr,omitempty" json:",omitempty"`
+	CheckExternals      *bool           `xml:"checkexternals,attr,omitempty" json:",omitempty"`
+	Port                string          `xml:"port,attr,omitempty" json:",omitempty"`
+	UseTickets          *bool           `xml:"useTickets,attr,omitempty" json:",omitempty"`
+	Domain              string          `xml:"domain,attr,omitempty" json:",omitempty"`
+	ProjectPath         string          `xml:"projectPath,attr,omitempty" json:",omitempty"`
+	Username            string          `xml:"username,attr,omitempty" json:",omitempty"`
+	Password            string          `xml:"password,attr,omitempty" json:",omitempty"`
+	EncryptedPassword   string          `xml:"encryptedPassword,attr,omitempty" json:",omitempty"`
+	PipelineName        string          `xml:"pipelineName,attr,omitempty" json:",omitempty"`
+	StageName           string          `xml:"stageName,attr,omitempty" json:",omitempty"`
+	IgnoreForScheduling *bool           `xml:"ignoreForScheduling,attr,omitempty" json:",omitempty"`
+	Ref                 string          `xml:"ref,attr,omitempty" json:",omitempty"`
+	Destination         string          `xml:"dest,attr,omitempty" json:",omitempty"`
+	AutoUpdate          *bool           `xml:"autoUpdate,attr,omitempty" json:",omitempty"`
+	InvertFilter        *bool           `xml:"invertFilter,attr,omitempty" json:",omitempty"`
+	View                string          `xml:"view,omitempty" json:",omitempty"`
+	Filters             []ConfigFilter  `xml:"filter>ignore,omitempty" json:",omitempty"`
+	UnknownAttrs        []xml.Attr      `xml:",any,attr" json:",omitempty"`
+	Unknown             []ConfigElement `xml:",any" json:",omitempty"`
+}
+
+// codebeat:enable[TOO_MANY_IVARS]
+
+// ConfigFilter is a pattern of paths whose changes do not trigger a pipeline, or the only ones which do when the
+// filter of the material is inverted.
+type ConfigFilter struct {
+	Ignore       string     `xml:"pattern,attr,omitempty"`
+	UnknownAttrs []xml.Attr `xml:",any,attr" json:",omitempty"`
+}
+
+// GitRepositoryMaterial is a git material of a pipeline.
+//
+// Deprecated: Use ConfigMaterial, which holds the materials of every type, in ConfigPipeline.Materials.
+type GitRepositoryMaterial struct {
+	URL     string         `xml:"url,attr"`
+	Filters []ConfigFilter `xml:"filter>ignore,omitempty"`
+}
+
+// PipelineMaterial is a dependency material of a pipeline.
+//
+// Deprecated: Use ConfigMaterial, which holds the materials of every type, in ConfigPipeline.Materials.
+type PipelineMaterial struct {
+	Name         string `xml:"pipelineName,attr"`
+	StageName    string `xml:"stageName,attr"`
+	MaterialName string `xml:"materialName,attr"`
+}
+
+// GitMaterials returns the git materials of the pipeline.
+//
+// Deprecated: Use Materials, which keeps the materials of every type in the order they are configured.
+func (cp *ConfigPipeline) GitMaterials() (materials []GitRepositoryMaterial) {
+	for _, m := range cp.Materials.Materials {
+		if m.XMLName.Local == "git" {
+			materials = append(materials, GitRepositoryMaterial{URL: m.URL, Filters: m.Filters})
+		}
+	}
+	return
+}
+
+// PipelineMaterials returns the dependency materials of the pipeline.
+//
+// Deprecated: Use Materials, which keeps the materials of every type in the order they are configured.
+func (cp *ConfigPipeline) PipelineMaterials() (materials []PipelineMaterial) {
+	for _, m := range cp.Materials.Materials {
+		if m.XMLName.Local == "pipeline" {
+			materials = append(materials, PipelineMaterial{Name: m.PipelineName, StageName: m.StageName, MaterialName: m.Name})
+		}
+	}
+	return
+}
+
+// ConfigStage is a `<stage>` element of a pipeline or a template.
+// codebeat:disable[TOO_MANY_IVARS]
+type ConfigStage struct {
+	Name                      string                      `xml:"name,attr"`
+	FetchMaterials            *bool                       `xml:"fetchMaterials,attr,omitempty" json:",omitempty"`
+	ArtifactCleanupProhibited *bool                       `xml:"artifactCleanupProhibited,attr,omitempty" json:",omitempty"`
+	CleanWorkingDir           *bool                       `xml:"cleanWorkingDir,attr,omitempty" json:",omitempty"`
+	Approval                  *ConfigApproval             `xml:"approval" json:",omitempty"`
+	EnvironmentVariables      []ConfigEnvironmentVariable `xml:"environmentvariables>variable,omitempty" json:",omitempty"`
+	Jobs                      []ConfigJob                 `xml:"jobs>job,omitempty"`
+	UnknownAttrs              []xml.Attr                  `xml:",any,attr" json:",omitempty"`
+	Unknown                   []ConfigElement             `xml:",any" json:",omitempty"`
+}
+
+// codebeat:enable[TOO_MANY_IVARS]
+
+// ConfigApproval is how a stage is triggered, `success` for automatically and `manual`, and who may trigger it.
+type ConfigApproval struct {
+	Type               string               `xml:"type,attr,omitempty" json:",omitempty"`
+	AllowOnlyOnSuccess *bool                `xml:"allowOnlyOnSuccess,attr,omitempty" json:",omitempty"`
+	Authorization      *ConfigAuthorization `xml:"authorization" json:",omitempty"`
+	UnknownAttrs       []xml.Attr           `xml:",any,attr" json:",omitempty"`
+	Unknown            []ConfigElement      `xml:",any" json:",omitempty"`
+}
+
+// ConfigJob is a `<job>` element of a stage.
+// codebeat:disable[TOO_MANY_IVARS]
+type ConfigJob struct {
+	Name                 string                      `xml:"name,attr"`
+	Timeout              string                      `xml:"timeout,attr,omitempty" json:",omitempty"`
+	RunInstanceCount     string                      `xml:"runInstanceCount,attr,omitempty" json:",omitempty"`
+	ElasticProfileID     string                      `xml:"elasticProfileId,attr,omitempty" json:",omitempty"`
+	EnvironmentVariables []ConfigEnvironmentVariable `xml:"environmentvariables>variable,omitempty" json:",omitempty"`
+	Tasks                ConfigTasks                 `xml:"tasks"`
+	Tabs                 []ConfigTab                 `xml:"tabs>tab,omitempty" json:",omitempty"`
+	Resources            []string                    `xml:"resources>resource,omitempty" json:",omitempty"`
+	Artifacts            []ConfigArtifact            `xml:"artifacts>artifact,omitempty" json:",omitempty"`
+	Tests                []ConfigArtifact            `xml:"artifacts>test,omitempty" json:",omitempty"`
+	PluggableArtifacts   []ConfigPluggableArtifact   `xml:"artifacts>pluggableArtifact,omitempty" json:",omitempty"`
+	Properties           []ConfigJobProperty         `xml:"properties>property,omitempty" json:",omitempty"`
+	UnknownAttrs         []xml.Attr                  `xml:",any,attr" json:",omitempty"`
+	Unknown              []ConfigElement             `xml:",any" json:",omitempty"`
+}
+
+// codebeat:enable[TOO_MANY_IVARS]
+
+// ConfigArtifact is a file or directory a job publishes. Servers from 18.7 set its type, older ones list test
+// artifacts as `<test>` elements, in ConfigJob.Tests.
+type ConfigArtifact struct {
+	Type         string          `xml:"type,attr,omitempty" json:",omitempty"`
+	Src          string          `xml:"src,attr"`
+	Destination  string          `xml:"dest,attr,omitempty" json:",omitempty"`
+	UnknownAttrs []xml.Attr      `xml:",any,attr" json:",omitempty"`
+	Unknown      []ConfigElement `xml:",any" json:",omitempty"`
+}
+
+// ConfigPluggableArtifact is an artifact a job publishes to an artifact store.
+type ConfigPluggableArtifact struct {
+	ID            string           `xml:"id,attr"`
+	StoreID       string           `xml:"storeId,attr"`
+	Configuration []ConfigProperty `xml:"configuration>property,omitempty"`
+	UnknownAttrs  []xml.Attr       `xml:",any,attr" json:",omitempty"`
+	Unknown       []ConfigElement  `xml:",any" json:",omitempty"`
+}
+
+// ConfigTab shows an artifact of a job in a tab of the job details page.
+type ConfigTab struct {
+	Name         string     `xml:"name,attr"`
+	Path         string     `xml:"path,attr"`
+	UnknownAttrs []xml.Attr `xml:",any,attr" json:",omitempty"`
+}
+
+// ConfigJobProperty is a property a job reads from an XML artifact with an XPath expression.
+type ConfigJobProperty struct {
+	Name         string     `xml:"name,attr"`
+	Src          string     `xml:"src,attr"`
+	XPath        string     `xml:"xpath,attr"`
+	UnknownAttrs []xml.Attr `xml:",any,attr" json:",omitempty"`
+}
@@ -4,31 +4,47 @@ import (
 	"encoding/xml"
 )
 
-// ConfigTasks part of cruise-control.xml. @TODO better documentation
+// ConfigTasks holds the tasks of a job, in the order they are run.
 type ConfigTasks struct {
-	Tasks []ConfigTask `xml:",any"`
+	Tasks        []ConfigTask `xml:",any"`
+	UnknownAttrs []xml.Attr   `xml:",any,attr" json:",omitempty"`
 }
 
-// ConfigTask part of cruise-control.xml. @TODO better documentation
+// ConfigTask is a task of any type. The type is the name of the element, one of `exec`, `ant`, `nant`, `rake`,
+// `fetchartifact`, `fetchPluggableArtifact` or `task` for a pluggable task, kept in XMLName. Only the fields the type
+// uses are set.
 // codebeat:disable[TOO_MANY_IVARS]
 type ConfigTask struct {
 	// Because we need to preserve the order of tasks, and we have an array of elements with mixed types,
 	// we need to use this generic xml type for tasks.
-	XMLName  xml.Name        `json:",omitempty"`
-	Type     string          `xml:"type,omitempty"`
-	RunIf    ConfigTaskRunIf `xml:"runif"`
-	Command  string          `xml:"command,attr,omitempty"  json:",omitempty"`
-	Args     []string        `xml:"arg,omitempty"  json:",omitempty"`
-	Pipeline string          `xml:"pipeline,attr,omitempty"  json:",omitempty"`
-	Stage    string          `xml:"stage,attr,omitempty"  json:",omitempty"`
-	Job      string          `xml:"job,attr,omitempty"  json:",omitempty"`
-	SrcFile  string          `xml:"srcfile,attr,omitempty"  json:",omitempty"`
-	SrcDir   string          `xml:"srcdir,attr,omitempty"  json:",omitempty"`
+	XMLName             xml.Name                   `json:",omitempty"`
+	Type                string                     `xml:"type,omitempty"`
+	Command             string                     `xml:"command,attr,omitempty"  json:",omitempty"`
+	WorkingDir          string                     `xml:"workingdir,attr,omitempty"  json:",omitempty"`
+	BuildFile           string                     `xml:"buildfile,attr,omitempty"  json:",omitempty"`
+	Target              string                     `xml:"target,attr,omitempty"  json:",omitempty"`
+	NantPath            string                     `xml:"nantpath,attr,omitempty"  json:",omitempty"`
+	ArtifactOrigin      string                     `xml:"artifactOrigin,attr,omitempty"  json:",omitempty"`
+	Pipeline            string                     `xml:"pipeline,attr,omitempty"  json:",omitempty"`
+	Stage               string                     `xml:"stage,attr,omitempty"  json:",omitempty"`
+	Job                 string                     `xml:"job,attr,omitempty"  json:",omitempty"`
+	SrcFile             string                     `xml:"srcfile,attr,omitempty"  json:",omitempty"`
+	SrcDir              string                     `xml:"srcdir,attr,omitempty"  json:",omitempty"`
+	Dest                string                     `xml:"dest,attr,omitempty"  json:",omitempty"`
+	ArtifactID          string                     `xml:"artifactId,attr,omitempty"  json:",omitempty"`
+	PluginConfiguration *ConfigPluginConfiguration `xml:"pluginConfiguration"  json:",omitempty"`
+	Configuration       []ConfigProperty           `xml:"configuration>property,omitempty"  json:",omitempty"`
+	Args                []string                   `xml:"arg,omitempty"  json:",omitempty"`
+	RunIf               []ConfigTaskRunIf          `xml:"runif"`
+	OnCancel            *ConfigTasks               `xml:"oncancel"  json:",omitempty"`
+	UnknownAttrs        []xml.Attr                 `xml:",any,attr" json:",omitempty"`
+	Unknown             []ConfigElement            `xml:",any" json:",omitempty"`
 }
 
 // codebeat:enable[TOO_MANY_IVARS]
 
-// ConfigTaskRunIf part of cruise-control.xml. @TODO better documentation
+// ConfigTaskRunIf is a status of the job, `passed`, `failed` or `any`, in which the task is run.
 type ConfigTaskRunIf struct {
-	Status string `xml:"status,attr"`
+	Status       string     `xml:"status,attr"`
+	UnknownAttrs []xml.Attr `xml:",any,attr" json:",omitempty"`
 }
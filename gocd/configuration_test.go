@@ -1,11 +1,16 @@
 package gocd
 
 import (
+	"bytes"
 	"context"
+	"encoding/xml"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"sort"
+	"strings"
 	"testing"
 )
 
@@ -37,6 +42,240 @@ func testConfigurationGet(t *testing.T) {
 	assert.Equal(t, cfg.Server.Security.PasswordFile.Path, "/etc/go/password.properties")
 	assert.Equal(t, "defaultGroup", cfg.PipelineGroups[0].Name)
 	assert.Len(t, cfg.PipelineGroups, 1)
+	assert.NotNil(t, cfg.order, "the order of the elements is kept to write them back")
+}
+
+func TestConfigXML(t *testing.T) {
+	t.Run("Parse", testConfigXMLParse)
+	t.Run("RoundTrip", testConfigXMLRoundTrip)
+	t.Run("Build", testConfigXMLBuild)
+	t.Run("Errors", testConfigXMLErrors)
+}
+
+func testConfigXMLParse(t *testing.T) {
+	b, err := ioutil.ReadFile("test/resources/config.1.xml")
+	assert.NoError(t, err)
+	cx, err := ParseConfigXML(b)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 115, cx.SchemaVersion)
+	assert.Equal(t, 60, *cx.Server.JobTimeout)
+	assert.Equal(t, "token-key", cx.Server.TokenGenerationKey)
+	assert.True(t, *cx.Server.Security.AllowOnlyKnownUsersToLogin)
+	assert.Equal(t, []string{"alice", "bob"}, cx.Server.Security.Roles[0].Users)
+	assert.Equal(t, "file-auth", cx.Server.Security.PluginRoles[0].AuthConfigID)
+	assert.Equal(t, []string{"admin"}, cx.Server.Security.Admins)
+	assert.Equal(t, []string{"ldap-admins"}, cx.Server.Security.AdminRoles)
+	assert.Equal(t, 587, *cx.Server.MailHost.Port)
+	assert.False(t, *cx.Server.Backup.EmailOnSuccess)
+	assert.Equal(t, "20.0", cx.Server.Artifacts.PurgeSettings.PurgeUptoDiskSpace)
+	assert.Equal(t, "https://gocd.example.com", cx.Server.SiteURLs.SecureSiteURL)
+
+	assert.Equal(t, "local-docker", cx.Elastic.AgentProfiles[0].ClusterProfileID)
+	assert.Equal(t, "cd.go.contrib.elastic-agent.docker", cx.Elastic.ClusterProfiles[0].PluginID)
+	assert.Equal(t, "AES:registry", cx.ArtifactStores[0].Properties[1].EncryptedValue)
+	assert.Equal(t, "Secrets of the build pipelines", cx.SecretConfigs[0].Description)
+	assert.Equal(t, "deny", cx.SecretConfigs[0].Rules.Rules[1].XMLName.Local)
+	assert.Equal(t, "*", cx.SecretConfigs[0].Rules.Rules[1].Resource)
+	assert.False(t, *cx.Repositories[0].Packages[0].AutoUpdate)
+	assert.Equal(t, "github.pr", cx.SCMS[0].PluginConfiguration.ID)
+	assert.Equal(t, "main", cx.ConfigRepositories[0].Git.Branch)
+	assert.Nil(t, cx.ConfigRepositories[0].Hg)
+
+	group := cx.PipelineGroups[0]
+	assert.Equal(t, []string{"developers"}, group.Authorization.Operate.Roles)
+	p := group.Pipelines[0]
+	assert.Equal(t, "unlockWhenFinished", p.LockBehavior)
+	assert.Equal(t, []ConfigParam{{Name: "env", Value: "dev"}}, p.Params)
+	assert.Equal(t, `([A-Z]+-\d+)`, p.TrackingTool.Regex)
+	assert.True(t, *p.Timer.OnlyOnChanges)
+	assert.Equal(t, "0 0 22 ? * MON-FRI", p.Timer.Spec)
+	assert.True(t, *p.EnvironmentVariables[1].Secure)
+	assert.Equal(t, "AES:token", p.EnvironmentVariables[1].EncryptedValue)
+
+	var types []string
+	for _, m := range p.Materials.Materials {
+		types = append(types, m.XMLName.Local)
+	}
+	assert.Equal(t, []string{"git", "svn", "hg", "p4", "tfs", "pipeline", "package", "scm"}, types)
+	assert.False(t, *p.Materials.Materials[0].AutoUpdate)
+	assert.Equal(t, []ConfigFilter{{Ignore: "src/**"}}, p.Materials.Materials[0].Filters)
+	assert.Equal(t, "//depot/... //ws/...", p.Materials.Materials[3].View)
+	assert.Equal(t, "$/project", p.Materials.Materials[4].ProjectPath)
+	assert.Equal(t, "upstream", p.Materials.Materials[5].PipelineName)
+	assert.Equal(t, []GitRepositoryMaterial{{URL: "https://github.com/example/app.git", Filters: []ConfigFilter{{Ignore: "src/**"}}}}, p.GitMaterials())
+	assert.Equal(t, "upstream", p.PipelineMaterials()[0].Name)
+
+	stage := p.Stages[0]
+	assert.True(t, *stage.ArtifactCleanupProhibited)
+	assert.True(t, *stage.Approval.AllowOnlyOnSuccess)
+	job := stage.Jobs[0]
+	assert.Equal(t, "0", job.Timeout)
+	assert.Equal(t, "2", job.RunInstanceCount)
+	assert.Equal(t, []string{"linux", "docker"}, job.Resources)
+	assert.Equal(t, "test", job.Artifacts[1].Type)
+	assert.Equal(t, "registry", job.PluggableArtifacts[0].StoreID)
+	assert.Equal(t, "//coverage/@line-rate", job.Properties[0].XPath)
+	assert.Equal(t, "reports/coverage.html", job.Tabs[0].Path)
+
+	tasks := job.Tasks.Tasks
+	types = nil
+	for _, task := range tasks {
+		types = append(types, task.XMLName.Local)
+	}
+	assert.Equal(t, []string{"exec", "ant", "nant", "rake", "fetchartifact", "fetchPluggableArtifact", "task", "futureTask"}, types)
+	assert.Equal(t, []string{"-j", "4"}, tasks[0].Args)
+	assert.Equal(t, []ConfigTaskRunIf{{Status: "passed"}, {Status: "failed"}}, tasks[0].RunIf)
+	assert.Equal(t, "pkill", tasks[0].OnCancel.Tasks[0].Command)
+	assert.Equal(t, "/opt/nant", tasks[2].NantPath)
+	assert.Equal(t, "bin", tasks[4].Dest)
+	assert.Equal(t, "image", tasks[5].ArtifactID)
+	assert.Equal(t, "script-executor", tasks[6].PluginConfiguration.ID)
+	assert.Equal(t, "futureSetting", tasks[7].Unknown[0].XMLName.Local)
+
+	assert.Equal(t, []string{"admin"}, p.Stages[1].Approval.Authorization.Users)
+	assert.Equal(t, "newThing", p.Unknown[0].XMLName.Local)
+	assert.Equal(t, "futureElement", p.Unknown[1].XMLName.Local)
+	assert.Equal(t, []xml.Attr{{Name: xml.Name{Local: "futureAttribute"}, Value: "kept"}}, p.UnknownAttrs)
+
+	assert.Equal(t, "build-template", group.Pipelines[1].Template)
+	assert.False(t, *group.Pipelines[1].IsLocked)
+	assert.Equal(t, "build-template", cx.Templates[0].Name)
+	assert.False(t, *cx.Templates[0].Authorization.AllGroupAdminsAreViewers)
+	assert.Equal(t, "staging", cx.Environments[0].EnvironmentVariables[0].Value)
+	assert.Equal(t, "app", cx.Environments[0].Pipelines[0].Name)
+	assert.Equal(t, cx.Agents[0].UUID, cx.Environments[0].Agents[0].UUID)
+	assert.Equal(t, []string{"linux"}, cx.Agents[0].Resources)
+}
+
+func testConfigXMLRoundTrip(t *testing.T) {
+	for _, name := range []string{"config.0.xml", "config.1.xml", "../../../godata/default.gocd.config.xml"} {
+		t.Run(name, func(t *testing.T) {
+			b, err := ioutil.ReadFile("test/resources/" + name)
+			assert.NoError(t, err)
+			cx, err := ParseConfigXML(b)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			out, err := cx.XML()
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(out), `<?xml version="1.0" encoding="utf-8"?>`+"\n"+`<cruise schemaVersion="`))
+			assert.Contains(t, string(out), ` xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:noNamespaceSchemaLocation="cruise-config.xsd">`)
+			assert.Equal(t, canonicalXML(t, b), canonicalXML(t, out))
+
+			again, err := ParseConfigXML(out)
+			assert.NoError(t, err)
+			assert.Equal(t, cx, again)
+			rewritten, err := again.XML()
+			assert.NoError(t, err)
+			assert.Equal(t, string(out), string(rewritten))
+		})
+	}
+}
+
+func testConfigXMLBuild(t *testing.T) {
+	autoUpdate := false
+	cx := &ConfigXML{
+		SchemaVersion: 115,
+		PipelineGroups: []ConfigPipelineGroup{{
+			Name: "build",
+			Pipelines: []ConfigPipeline{{
+				Name:      "app",
+				Materials: ConfigMaterials{Materials: []ConfigMaterial{{XMLName: xml.Name{Local: "git"}, URL: "https://github.com/example/app.git", AutoUpdate: &autoUpdate}}},
+				Stages: []ConfigStage{{
+					Name: "build",
+					Jobs: []ConfigJob{{
+						Name:  "compile",
+						Tasks: ConfigTasks{Tasks: []ConfigTask{{XMLName: xml.Name{Local: "exec"}, Command: "make"}}},
+					}},
+				}},
+			}},
+		}},
+	}
+
+	b, err := cx.XML()
+	assert.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="utf-8"?>
+<cruise schemaVersion="115">
+  <pipelines group="build">
+    <pipeline name="app">
+      <materials>
+        <git url="https://github.com/example/app.git" autoUpdate="false" />
+      </materials>
+      <stage name="build">
+        <jobs>
+          <job name="compile">
+            <tasks>
+              <exec command="make" />
+            </tasks>
+          </job>
+        </jobs>
+      </stage>
+    </pipeline>
+  </pipelines>
+</cruise>
+`, string(b))
+}
+
+func testConfigXMLErrors(t *testing.T) {
+	_, err := ParseConfigXML([]byte(`<pipelines group="build"/>`))
+	assert.EqualError(t, err, "expected element type <cruise> but have <pipelines>")
+
+	_, err = ParseConfigXML([]byte(`<cruise schemaVersion="latest"/>`))
+	assert.Error(t, err)
+}
+
+// canonicalXML renders a document with the attributes of each element sorted, and the whitespace around text removed,
+// so that documents holding the same elements in the same order are equal.
+func canonicalXML(t *testing.T, b []byte) string {
+	type node struct {
+		name     string
+		attrs    []string
+		text     string
+		children []*node
+	}
+
+	root := &node{}
+	stack := []*node{root}
+	d := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			return ""
+		}
+
+		top := stack[len(stack)-1]
+		switch tok := token.(type) {
+		case xml.StartElement:
+			n := &node{name: tok.Name.Space + ":" + tok.Name.Local}
+			for _, attr := range tok.Attr {
+				n.attrs = append(n.attrs, attr.Name.Space+":"+attr.Name.Local+"="+attr.Value)
+			}
+			sort.Strings(n.attrs)
+			top.children = append(top.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			top.text += string(tok)
+		}
+	}
+
+	var render func(n *node) string
+	render = func(n *node) string {
+		children := make([]string, 0, len(n.children))
+		for _, child := range n.children {
+			children = append(children, render(child))
+		}
+		return fmt.Sprintf("<%s %s>%q[%s]", n.name, strings.Join(n.attrs, " "), strings.TrimSpace(n.text), strings.Join(children, ""))
+	}
+	return render(root)
 }
 
 func testConfigurationGetVersion(t *testing.T) {
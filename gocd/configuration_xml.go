@@ -0,0 +1,269 @@
+package gocd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ConfigElement is an element of cruise-config.xml which is not part of the model. It is written back as it was read.
+type ConfigElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr" json:",omitempty"`
+	InnerXML string     `xml:",innerxml"`
+}
+
+// configXMLWrappers are the elements which only wrap a list of other elements, such as `<params>`. encoding/xml
+// writes them for `a>b` fields even when the list is empty, so XML drops them when they are.
+var configXMLWrappers = map[string]bool{
+	"admins": true, "agentProfiles": true, "agents": true, "artifactStores": true, "artifacts": true,
+	"authConfigs": true, "clusterProfiles": true, "config-repos": true, "configuration": true,
+	"environments": true, "environmentvariables": true, "filter": true, "jobs": true, "packages": true,
+	"params": true, "pipelines": true, "profiles": true, "properties": true, "repositories": true,
+	"resources": true, "roles": true, "scms": true, "secretConfigs": true, "tabs": true, "templates": true,
+	"users": true,
+}
+
+var (
+	xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	xmlAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;",
+		"\n", "&#xA;", "\r", "&#xD;", "\t", "&#x9;")
+)
+
+// ParseConfigXML reads a cruise-config.xml document, such as the one returned by ConfigurationService.Get or the
+// `config/cruise-config.xml` file of a server. The order of the elements is remembered, so that XML writes the
+// elements the model does not know where they were read.
+func ParseConfigXML(content []byte) (*ConfigXML, error) {
+	cx := &ConfigXML{}
+	if err := xml.Unmarshal(content, cx); err != nil {
+		return nil, err
+	}
+
+	root, err := readXMLNode(xml.NewDecoder(bytes.NewReader(content)))
+	if err != nil {
+		return nil, err
+	}
+	cx.order = root.order()
+	return cx, nil
+}
+
+// XML renders the configuration as a cruise-config.xml document, indented the way the server writes it. The elements
+// of a configuration read by ParseConfigXML are written in the order they were read, the others in the order of the
+// schema.
+func (cx *ConfigXML) XML() ([]byte, error) {
+	b, err := xml.Marshal(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := readXMLNode(xml.NewDecoder(bytes.NewReader(b)))
+	if err != nil {
+		return nil, err
+	}
+	if cx.order != nil {
+		root.sort(cx.order)
+	}
+	buf := bytes.NewBufferString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	root.write(buf, 0)
+	return buf.Bytes(), nil
+}
+
+// MarshalXML writes the namespaced attributes of the root element, such as `xsi:noNamespaceSchemaLocation`, with the
+// prefixes they were declared with. encoding/xml would otherwise declare a namespace of its own for each of them.
+func (cx ConfigXML) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type configXML ConfigXML
+	prefixes := map[string]string{}
+	for _, attr := range cx.UnknownAttrs {
+		if attr.Name.Space == "xmlns" {
+			prefixes[attr.Value] = attr.Name.Local
+		}
+	}
+
+	attrs := make([]xml.Attr, 0, len(cx.UnknownAttrs))
+	for _, attr := range cx.UnknownAttrs {
+		if attr.Name.Space == "xmlns" {
+			attr.Name = xml.Name{Local: "xmlns:" + attr.Name.Local}
+		} else if prefix, ok := prefixes[attr.Name.Space]; ok {
+			attr.Name = xml.Name{Local: prefix + ":" + attr.Name.Local}
+		}
+		attrs = append(attrs, attr)
+	}
+	cx.UnknownAttrs = attrs
+	start.Name = xml.Name{Local: "cruise"}
+	return e.EncodeElement(configXML(cx), start)
+}
+
+// xmlOrder is the position of an element among its siblings, and of its own children.
+type xmlOrder struct {
+	key      string
+	children []*xmlOrder
+}
+
+// xmlNode is an element of a document being rewritten. Its content holds text, comments and child elements, in order.
+type xmlNode struct {
+	name    string
+	attrs   []xml.Attr
+	content []interface{}
+}
+
+// readXMLNode reads the next element of a document, keeping the names as they are written.
+func readXMLNode(d *xml.Decoder) (*xmlNode, error) {
+	root := &xmlNode{}
+	stack := []*xmlNode{root}
+	for {
+		token, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		top := stack[len(stack)-1]
+		switch tok := token.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: xmlRawName(tok.Name), attrs: tok.Attr}
+			top.content = append(top.content, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			if configXMLWrappers[top.name] && len(top.attrs) == 0 && len(top.content) == 0 {
+				parent := stack[len(stack)-1]
+				parent.content = parent.content[:len(parent.content)-1]
+			}
+		case xml.CharData:
+			top.content = append(top.content, string(tok))
+		case xml.Comment:
+			top.content = append(top.content, xml.Comment(tok.Copy()))
+		}
+	}
+
+	for _, c := range root.content {
+		if n, ok := c.(*xmlNode); ok {
+			return n, nil
+		}
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+func xmlRawName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+func (n *xmlNode) hasElements() bool {
+	for _, c := range n.content {
+		if _, ok := c.(string); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// write renders the element with two spaces of indentation per level. The text of an element holding only text is
+// kept as it is, whitespace only text between elements is replaced by the indentation.
+func (n *xmlNode) write(buf *bytes.Buffer, depth int) {
+	indent := strings.Repeat("  ", depth)
+	buf.WriteString(indent + "<" + n.name)
+	for _, attr := range n.attrs {
+		buf.WriteString(" " + xmlRawName(attr.Name) + `="` + xmlAttrEscaper.Replace(attr.Value) + `"`)
+	}
+
+	if len(n.content) == 0 {
+		buf.WriteString(" />\n")
+		return
+	}
+	if !n.hasElements() {
+		buf.WriteString(">")
+		for _, c := range n.content {
+			buf.WriteString(xmlTextEscaper.Replace(c.(string)))
+		}
+		buf.WriteString("</" + n.name + ">\n")
+		return
+	}
+
+	buf.WriteString(">\n")
+	for _, c := range n.content {
+		switch c := c.(type) {
+		case *xmlNode:
+			c.write(buf, depth+1)
+		case xml.Comment:
+			buf.WriteString(indent + "  <!--" + string(c) + "-->\n")
+		case string:
+			if strings.TrimSpace(c) != "" {
+				buf.WriteString(indent + "  " + xmlTextEscaper.Replace(c) + "\n")
+			}
+		}
+	}
+	buf.WriteString(indent + "</" + n.name + ">\n")
+}
+
+// key identifies an element among its siblings by its name, and by the attribute naming it, if any.
+func (n *xmlNode) key() string {
+	for _, attr := range n.attrs {
+		if attr.Name.Space == "" && (attr.Name.Local == "name" || attr.Name.Local == "id" || attr.Name.Local == "group") {
+			return n.name + " " + attr.Value
+		}
+	}
+	return n.name
+}
+
+func (n *xmlNode) elements() (elements []*xmlNode) {
+	for _, c := range n.content {
+		if child, ok := c.(*xmlNode); ok {
+			elements = append(elements, child)
+		}
+	}
+	return
+}
+
+// order records the order of the elements of the document.
+func (n *xmlNode) order() *xmlOrder {
+	o := &xmlOrder{key: n.key()}
+	for _, child := range n.elements() {
+		o.children = append(o.children, child.order())
+	}
+	return o
+}
+
+// sort moves the child elements of the node, and of its descendants, to the positions of the same elements in the
+// order they were read. Elements are matched by their key, and among elements with the same key, by their rank, so
+// that adding or removing an element does not move the others. Elements which were not read stay after the element
+// they follow.
+func (n *xmlNode) sort(o *xmlOrder) {
+	positions := map[string][]int{}
+	for i, child := range o.children {
+		positions[child.key] = append(positions[child.key], i)
+	}
+
+	elements := n.elements()
+	if len(elements) != len(n.content) {
+		// Elements mixed with text are left as they are.
+		return
+	}
+	ranks := make([]int, len(elements))
+	seen := map[string]int{}
+	rank := -1
+	for i, child := range elements {
+		key := child.key()
+		if matches := positions[key]; seen[key] < len(matches) {
+			rank = matches[seen[key]]
+			child.sort(o.children[rank])
+		}
+		seen[key]++
+		ranks[i] = rank
+	}
+
+	sorted := make([]int, len(elements))
+	for i := range sorted {
+		sorted[i] = i
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return ranks[sorted[i]] < ranks[sorted[j]] })
+	for i, index := range sorted {
+		n.content[i] = elements[index]
+	}
+}
@@ -16,6 +16,11 @@ type CipherText struct {
 // Encrypt takes a plaintext value and returns a cipher text.
 func (es *EncryptionService) Encrypt(ctx context.Context, plaintext string) (c *CipherText, resp *APIResponse, err error) {
 
+	apiVersion, err := es.client.getAPIVersion(ctx, "admin/encrypt")
+	if err != nil {
+		return nil, nil, err
+	}
+
 	c = &CipherText{}
 	_, resp, err = es.client.postAction(ctx, &APIClientRequest{
 		Path:         "admin/encrypt",
@@ -23,7 +28,7 @@ func (es *EncryptionService) Encrypt(ctx context.Context, plaintext string) (c *
 		RequestBody: &map[string]string{
 			"value": plaintext,
 		},
-		APIVersion: apiV1,
+		APIVersion: apiVersion,
 	})
 
 	return
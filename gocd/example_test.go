@@ -244,3 +244,26 @@ func ExamplePipelineConfigsService_Get() {
 	}
 	fmt.Printf("  - Version: %s\n", p.Version)
 }
+
+func ExampleNewPipeline() {
+	// This example builds a pipeline with a build stage, and a deploy stage triggered by hand
+	p, err := gocd.NewPipeline("my_pipeline_name").
+		Group("my_pipeline_group").
+		Git("https://github.com/gocd/gocd", "master").
+		Stage("build").
+		Job("compile").Exec("make", "all").Artifact("bin/", "").
+		Job("test").Exec("make", "test").
+		Stage("deploy").Manual().
+		Job("deploy").Fetch("", "build", "compile", "bin/").Exec("./deploy.sh").
+		Build()
+	if err != nil {
+		panic(err)
+	}
+
+	for _, stage := range p.Stages {
+		fmt.Printf("%s (%s): %d jobs\n", stage.Name, stage.Approval.Type, len(stage.Jobs))
+	}
+	// Output:
+	// build (success): 2 jobs
+	// deploy (manual): 1 jobs
+}
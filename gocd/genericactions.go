@@ -6,8 +6,6 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
-
-	"github.com/sirupsen/logrus"
 )
 
 // APIClientRequest helper struct to reduce amount of code.
@@ -73,22 +71,17 @@ func (c *Client) deleteAction(ctx context.Context, path string, apiversion strin
 func (c *Client) httpAction(ctx context.Context, r *APIClientRequest) (responseBody interface{}, resp *APIResponse, err error) {
 
 	var req *APIRequest
-	var requestBodyProvided, hasEmptyResponseType, hasJSONResponseType bool
+	var requestBodyProvided, hasJSONResponseType bool
 
 	requestBodyProvided = r.RequestBody != nil
-	hasEmptyResponseType = r.ResponseType == ""
-	if !hasEmptyResponseType {
-		hasJSONResponseType = r.ResponseType == responseTypeJSON
-	}
 
-	c.Log.WithFields(logrus.Fields{
-		"Method": r.Method,
-		"Path":   r.Path,
-	}).Debug("Requesting Endpoint")
+	log := c.log()
+	log.Debug("Requesting Endpoint", "Method", r.Method, "Path", redactURL(r.Path))
 
-	if hasEmptyResponseType {
+	if r.ResponseType == "" {
 		r.ResponseType = responseTypeJSON
 	}
+	hasJSONResponseType = r.ResponseType == responseTypeJSON
 
 	versionAction(r.RequestBody, func(ver Versioned) {
 		if r.Headers == nil {
@@ -102,7 +95,7 @@ func (c *Client) httpAction(ctx context.Context, r *APIClientRequest) (responseB
 		return false, nil, err
 	}
 	if requestBodyProvided {
-		c.Log.WithField("RequestBody", req.Body).Debug("Sending Request Body")
+		log.Debug("Sending Request Body", "RequestBody", redactRequestBody(r.Path, req.Body))
 	}
 
 	if len(r.Headers) > 0 {
@@ -111,7 +104,7 @@ func (c *Client) httpAction(ctx context.Context, r *APIClientRequest) (responseB
 		}
 	}
 
-	c.Log.WithFields(headerLogFields(req.HTTP.Header)).Debug("Request Header")
+	log.Debug("Request Header", redactHeaders(req.HTTP.Header)...)
 
 	if resp, err = c.Do(ctx, req, r.ResponseBody, r.ResponseType); err != nil {
 		return r.ResponseBody, resp, err
@@ -123,12 +116,12 @@ func (c *Client) httpAction(ctx context.Context, r *APIClientRequest) (responseB
 
 	if hasJSONResponseType {
 		b, _ := json.Marshal(r.ResponseBody)
-		c.Log.WithFields(headerLogFields(resp.HTTP.Header)).Debug("Response Headers")
-		c.Log.WithFields(logrus.Fields{
-			"Protocol": resp.HTTP.Proto,
-			"Status":   resp.HTTP.Status,
-			"Body":     string(b),
-		}).Debug("Response")
+		log.Debug("Response Headers", redactHeaders(resp.HTTP.Header)...)
+		log.Debug("Response",
+			"Protocol", resp.HTTP.Proto,
+			"Status", resp.HTTP.Status,
+			"Body", redactBody(string(b)),
+		)
 	}
 
 	return r.ResponseBody, resp, err
@@ -140,16 +133,6 @@ func versionAction(versioned interface{}, verFunc func(versionsed Versioned)) {
 	}
 }
 
-func headerLogFields(headers http.Header) logrus.Fields {
-	fields := logrus.Fields{}
-	for header, values := range headers {
-		for _, value := range values {
-			fields[header] = value
-		}
-	}
-	return fields
-}
-
 func parseVersions(response *http.Response, versioned Versioned) {
 	etag := response.Header.Get("Etag")
 	versioned.SetVersion(
@@ -3,7 +3,6 @@ package gocd
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
@@ -46,6 +45,8 @@ const (
 	apiV9 = "application/vnd.go.cd.v9+json"
 	// Version 10 of the GoCD API.
 	apiV10 = "application/vnd.go.cd.v10+json"
+	// Version 11 of the GoCD API.
+	apiV11 = "application/vnd.go.cd.v11+json"
 )
 
 //Body Response Types
@@ -68,9 +69,10 @@ type StringResponse struct {
 // APIResponse encapsulates the net/http.Response object, a string representing the Body, and a gocd.Request object
 // encapsulating the response from the API.
 type APIResponse struct {
-	HTTP    *http.Response
-	Body    string
-	Request *APIRequest
+	HTTP      *http.Response
+	Body      string
+	Request   *APIRequest
+	FromCache bool
 }
 
 // APIRequest encapsulates the net/http.Request object, and a string representing the Body.
@@ -81,12 +83,16 @@ type APIRequest struct {
 
 // Client struct which acts as an interface to the GoCD Server. Exposes resource service handlers.
 type Client struct {
-	clientMu sync.Mutex // clientMu protects the client during multi-threaded calls
-	client   *http.Client
+	clientMu   sync.Mutex // clientMu protects the client during multi-threaded calls
+	client     *http.Client
+	baseClient *http.Client
+	middleware []Middleware
 
 	params *ClientParameters
 
-	Log *logrus.Logger
+	// Log is the logrus logger used when no Logger has been provided with SetLogger.
+	Log    *logrus.Logger
+	logger Logger
 
 	Agents            *AgentsService
 	PipelineGroups    *PipelineGroupsService
@@ -106,6 +112,15 @@ type Client struct {
 
 	common service
 	cookie string
+	cache  *CacheOptions
+	err    error // err is raised by every request if the client could not be set up from its configuration.
+
+	validateBeforeWrite bool
+
+	instrumentation *Instrumentation
+
+	serverVersionMu sync.Mutex
+	serverVersion   *ServerVersion
 }
 
 // ClientParameters describe how the client interacts with the GoCD Server
@@ -136,7 +151,6 @@ type PaginationResponse struct {
 // service is a generic service encapsulating the client for talking to the GoCD server.
 type service struct {
 	client *Client
-	log    *logrus.Logger
 }
 
 // Auth structure wrapping the Username and Password variables, which are used to get an Auth cookie header used for
@@ -160,12 +174,13 @@ func (c *Configuration) Client() *Client {
 // allow overriding of http client structures.
 func NewClient(cfg *Configuration, httpClient *http.Client) *Client {
 
-	httpClient = generateHTTPClient(cfg, httpClient)
+	httpClient, err := generateHTTPClient(cfg, httpClient)
 
 	baseURL, _ := url.Parse(cfg.Server)
 
 	c := &Client{
-		client: httpClient,
+		client:     httpClient,
+		baseClient: httpClient,
 		params: &ClientParameters{
 			BaseURL:   baseURL,
 			UserAgent: userAgent,
@@ -173,10 +188,10 @@ func NewClient(cfg *Configuration, httpClient *http.Client) *Client {
 			Password:  cfg.Password,
 		},
 		Log: logrus.New(),
+		err: err,
 	}
 
 	c.common.client = c
-	c.common.log = c.Log
 
 	attachServices(c)
 
@@ -185,17 +200,18 @@ func NewClient(cfg *Configuration, httpClient *http.Client) *Client {
 	return c
 }
 
-// generateHTTPClient taking into account ssl, and existing httpClient
-func generateHTTPClient(cfg *Configuration, httpClient *http.Client) *http.Client {
-	if httpClient == nil {
-		httpClient = http.DefaultClient
-		if strings.HasPrefix(cfg.Server, "https") && cfg.SkipSslCheck {
-			httpClient.Transport = &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: cfg.SkipSslCheck},
-			}
-		}
+// generateHTTPClient taking into account ssl, and existing httpClient. If no httpClient is provided, a new one is
+// created from the configuration with its own transport, so that http.DefaultClient is never modified. If the
+// configuration is invalid, a client with the default transport settings is returned along with the error.
+func generateHTTPClient(cfg *Configuration, httpClient *http.Client) (*http.Client, error) {
+	if httpClient != nil {
+		return httpClient, nil
+	}
+	httpClient, err := cfg.HTTPClient()
+	if err != nil {
+		return &http.Client{Transport: newTransport()}, err
 	}
-	return httpClient
+	return httpClient, nil
 }
 
 // attachServices to the client to give access to the difference API resources.
@@ -241,6 +257,10 @@ func (c *Client) NewRequest(method, urlStr string, body interface{}, apiVersion
 	var buf io.ReadWriter
 	req = &APIRequest{}
 
+	if c.err != nil {
+		return req, c.err
+	}
+
 	// I'm not sure how to get this method to return an error intentionally for testing. For testing purposes, I've
 	// added a switch so that the error handling in dependent methods can be tested.
 	if os.Getenv("GOCD_RAISE_ERROR_NEW_REQUEST") == "yes" {
@@ -302,19 +322,28 @@ func (c *Client) NewRequest(method, urlStr string, body interface{}, apiVersion
 }
 
 // Do takes an HTTP request and resposne the response from the GoCD API endpoint.
-func (c *Client) Do(ctx context.Context, req *APIRequest, v interface{}, responseType string) (*APIResponse, error) {
+func (c *Client) Do(ctx context.Context, req *APIRequest, v interface{}, responseType string) (resp *APIResponse, err error) {
+	ctx, finish := c.startInstrumentation(ctx, req.HTTP)
+	resp, err = c.do(ctx, req, v, responseType)
+	finish(resp, err)
+	return
+}
+
+func (c *Client) do(ctx context.Context, req *APIRequest, v interface{}, responseType string) (*APIResponse, error) {
 	var err error
 	var resp *http.Response
+	var fromCache bool
 
 	req.HTTP = req.HTTP.WithContext(ctx)
 
-	if resp, err = c.client.Do(req.HTTP); err != nil {
+	if resp, fromCache, err = c.doCached(req.HTTP); err != nil {
 		return nil, err
 	}
 
 	r := &APIResponse{
-		Request: req,
-		HTTP:    resp,
+		Request:   req,
+		HTTP:      resp,
+		FromCache: fromCache,
 	}
 
 	if v != nil {
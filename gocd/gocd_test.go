@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -52,7 +53,7 @@ type EqualityTest struct {
 func setup() {
 	// test server
 	mux = http.NewServeMux()
-	server = httptest.NewServer(mux)
+	server = httptest.NewServer(http.HandlerFunc(serveTestMux))
 
 	// gocd client configured to use test server
 	client = NewClient(&Configuration{
@@ -62,6 +63,17 @@ func setup() {
 	}, nil)
 }
 
+// serveTestMux serves requests with the handlers registered on the mux. As every service negotiates its API version,
+// the server version is answered with a default for tests which do not register their own handler for it.
+func serveTestMux(w http.ResponseWriter, r *http.Request) {
+	if _, pattern := mux.Handler(r); pattern == "" && r.URL.Path == "/api/version" {
+		j, _ := ioutil.ReadFile("test/resources/version.2.json")
+		fmt.Fprint(w, string(j))
+		return
+	}
+	mux.ServeHTTP(w, r)
+}
+
 func intSetup() {
 	intClient = NewClient(&Configuration{
 		Server: "http://127.0.0.1:8153/go/",
@@ -71,7 +83,6 @@ func intSetup() {
 // teardown closes the test HTTP server.
 func teardown() {
 	server.Close()
-	cachedServerVersion = nil
 }
 
 func runIntegrationTest(t *testing.T) bool {
@@ -108,6 +119,8 @@ func testClientNewHTTPS(t *testing.T) {
 
 	transport := c.client.Transport.(*http.Transport)
 	assert.True(t, transport.TLSClientConfig.InsecureSkipVerify)
+	assert.NotEqual(t, http.DefaultClient, c.client)
+	assert.Nil(t, http.DefaultClient.Transport)
 
 	client.Lock()
 	client.Unlock()
@@ -162,7 +175,7 @@ func testNewRequestFailDecode(t *testing.T) {
 			BaseURL: &url.URL{},
 		},
 	}
-	i := map[interface{}]string{}
+	i := make(chan int)
 	_, err := c.NewRequest("GET", "mock", i, "")
 	assert.Error(t, err)
 }
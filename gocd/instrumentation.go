@@ -0,0 +1,179 @@
+package gocd
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// endpointUnknown is used as the endpoint label for requests which do not match any known endpoint, so that the number
+// of distinct labels stays bounded.
+const endpointUnknown = "unknown"
+
+// endpointServices maps endpoint prefixes to the name of the service on the Client handling them. The longest matching
+// prefix wins.
+var endpointServices = map[string]string{
+	"/api/agents":                 "Agents",
+	"/api/admin/config_repos":     "ConfigRepos",
+	"/api/admin/config.xml":       "Configuration",
+	"/api/admin/encrypt":          "Encryption",
+	"/api/admin/environments":     "Environments",
+	"/api/jobs":                   "Jobs",
+	"/api/admin/pipelines":        "PipelineConfigs",
+	"/api/config/pipeline_groups": "PipelineGroups",
+	"/api/admin/templates":        "PipelineTemplates",
+	"/api/pipelines":              "Pipelines",
+	"/api/admin/plugin_info":      "Plugins",
+	"/properties":                 "Properties",
+	"/api/admin/security/roles":   "Roles",
+	"/api/version":                "ServerVersion",
+}
+
+// Instrumentation describes the telemetry hooks called for every request made to the GoCD server. Both fields are
+// optional.
+type Instrumentation struct {
+	Metrics MetricsRecorder
+	Tracer  Tracer
+}
+
+// MetricsRecorder receives a RequestMetrics for every request made to the GoCD server. It can be implemented with
+// request counters and latency histograms, eg with Prometheus:
+//
+//	type promRecorder struct {
+//		requests *prometheus.CounterVec   // labels: service, method, endpoint, status
+//		latency  *prometheus.HistogramVec // labels: service, method, endpoint, status
+//	}
+//
+//	func (p *promRecorder) RecordRequest(m *gocd.RequestMetrics) {
+//		labels := prometheus.Labels{"service": m.Service, "method": m.Method, "endpoint": m.Endpoint, "status": m.Status()}
+//		p.requests.With(labels).Inc()
+//		p.latency.With(labels).Observe(m.Duration.Seconds())
+//	}
+type MetricsRecorder interface {
+	RecordRequest(metrics *RequestMetrics)
+}
+
+// RequestMetrics describes a single request made to the GoCD server.
+type RequestMetrics struct {
+	Service    string        // Service is the name of the service on the Client, eg: "PipelineConfigs".
+	Method     string        // Method is the HTTP method of the request.
+	Endpoint   string        // Endpoint is the templated endpoint, eg: "/api/admin/pipelines/:pipeline_name".
+	StatusCode int           // StatusCode of the response, or 0 if no response was received.
+	Duration   time.Duration // Duration of the request, including reading the response body.
+	FromCache  bool          // FromCache is true if the response was served from the response cache.
+	Err        error         // Err is the error returned by the request, if any.
+}
+
+// Status returns the status code as a string, or "error" if no response was received. Useful as a metric label.
+func (rm *RequestMetrics) Status() string {
+	if rm.StatusCode == 0 {
+		return "error"
+	}
+	return strconv.Itoa(rm.StatusCode)
+}
+
+// Tracer creates a span for every request made to the GoCD server, and propagates the trace context to the server. It
+// can be implemented with OpenTelemetry:
+//
+//	type otelTracer struct {
+//		tracer trace.Tracer
+//	}
+//
+//	func (o *otelTracer) Start(ctx context.Context, name string, attributes map[string]string) (context.Context, gocd.Span) {
+//		ctx, span := o.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//		for key, value := range attributes {
+//			span.SetAttributes(attribute.String(key, value))
+//		}
+//		return ctx, &otelSpan{span}
+//	}
+//
+//	func (o *otelTracer) Inject(ctx context.Context, header http.Header) {
+//		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+//	}
+type Tracer interface {
+	Start(ctx context.Context, name string, attributes map[string]string) (context.Context, Span)
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span describes a single traced request.
+type Span interface {
+	SetAttribute(key string, value string)
+	End(err error)
+}
+
+// Instrument the client with metrics and tracing. Passing nil disables instrumentation.
+func (c *Client) Instrument(instrumentation *Instrumentation) {
+	c.Lock()
+	defer c.Unlock()
+	c.instrumentation = instrumentation
+}
+
+// startInstrumentation records the start of a request, and returns the context the request should be made with, and a
+// function to call once the request has completed.
+func (c *Client) startInstrumentation(ctx context.Context, req *http.Request) (context.Context, func(resp *APIResponse, err error)) {
+	c.Lock()
+	instrumentation := c.instrumentation
+	c.Unlock()
+	if instrumentation == nil {
+		return ctx, func(*APIResponse, error) {}
+	}
+
+	service, endpoint := c.requestEndpoint(req)
+	start := time.Now()
+
+	var span Span
+	if instrumentation.Tracer != nil {
+		ctx, span = instrumentation.Tracer.Start(ctx, req.Method+" "+endpoint, map[string]string{
+			"http.method":   req.Method,
+			"http.url":      redactURL(req.URL.String()),
+			"gocd.service":  service,
+			"gocd.endpoint": endpoint,
+		})
+		instrumentation.Tracer.Inject(ctx, req.Header)
+	}
+
+	return ctx, func(resp *APIResponse, err error) {
+		metrics := &RequestMetrics{
+			Service:  service,
+			Method:   req.Method,
+			Endpoint: endpoint,
+			Duration: time.Since(start),
+			Err:      err,
+		}
+		if resp != nil && resp.HTTP != nil {
+			metrics.StatusCode = resp.HTTP.StatusCode
+			metrics.FromCache = resp.FromCache
+		}
+
+		if span != nil {
+			span.SetAttribute("http.status_code", metrics.Status())
+			span.End(err)
+		}
+		if instrumentation.Metrics != nil {
+			instrumentation.Metrics.RecordRequest(metrics)
+		}
+	}
+}
+
+// requestEndpoint finds the service and templated endpoint for a request.
+func (c *Client) requestEndpoint(req *http.Request) (service string, endpoint string) {
+	path, err := c.relativePath(req.URL)
+	if err != nil {
+		return endpointUnknown, endpointUnknown
+	}
+
+	var hasEndpoint bool
+	if endpoint, hasEndpoint = serverVersionLookup.MatchEndpoint(path); !hasEndpoint {
+		return endpointUnknown, endpointUnknown
+	}
+
+	service, prefixLength := endpointUnknown, 0
+	for prefix, name := range endpointServices {
+		if (endpoint == prefix || strings.HasPrefix(endpoint, prefix+"/")) && len(prefix) > prefixLength {
+			service, prefixLength = name, len(prefix)
+		}
+	}
+	return service, endpoint
+}
@@ -0,0 +1,174 @@
+package gocd
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+type mockMetricsRecorder struct {
+	requests []*RequestMetrics
+}
+
+func (m *mockMetricsRecorder) RecordRequest(metrics *RequestMetrics) {
+	m.requests = append(m.requests, metrics)
+}
+
+type mockTracer struct {
+	spans []*mockSpan
+}
+
+type mockSpan struct {
+	name       string
+	attributes map[string]string
+	ended      bool
+	err        error
+}
+
+type mockSpanKey struct{}
+
+func (m *mockTracer) Start(ctx context.Context, name string, attributes map[string]string) (context.Context, Span) {
+	span := &mockSpan{name: name, attributes: attributes}
+	m.spans = append(m.spans, span)
+	return context.WithValue(ctx, mockSpanKey{}, fmt.Sprintf("span-%d", len(m.spans))), span
+}
+
+func (m *mockTracer) Inject(ctx context.Context, header http.Header) {
+	header.Set("Traceparent", ctx.Value(mockSpanKey{}).(string))
+}
+
+func (s *mockSpan) SetAttribute(key string, value string) {
+	s.attributes[key] = value
+}
+
+func (s *mockSpan) End(err error) {
+	s.ended = true
+	s.err = err
+}
+
+func TestInstrumentation(t *testing.T) {
+	t.Run("MetricsAndTracing", testInstrumentationMetricsAndTracing)
+	t.Run("RequestEndpoint", testInstrumentationRequestEndpoint)
+	t.Run("Disabled", testInstrumentationDisabled)
+	t.Run("Reconfigure", testInstrumentationReconfigure)
+}
+
+// testInstrumentationReconfigure turns instrumentation on and off while requests are made, for `go test -race` to
+// check.
+func testInstrumentationReconfigure(t *testing.T) {
+	setup()
+	defer teardown()
+
+	etag := `"etag-1"`
+	requests, notModified := 0, 0
+	mux.HandleFunc("/api/admin/environments", cachedEnvironmentHandler(t, &etag, &requests, &notModified))
+	mux.HandleFunc("/api/version", cacheServerVersionHandler)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			client.Instrument(&Instrumentation{})
+			client.Instrument(nil)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		_, _, err := client.Environments.List(context.Background())
+		assert.NoError(t, err)
+	}
+	<-done
+}
+
+func testInstrumentationMetricsAndTracing(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "span-1", r.Header.Get("Traceparent"))
+		j, _ := ioutil.ReadFile("test/resources/version.2.json")
+		fmt.Fprint(w, string(j))
+	})
+	mux.HandleFunc("/api/admin/pipelines/test-pipeline", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "span-2", r.Header.Get("Traceparent"))
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "not found"}`)
+	})
+
+	recorder := &mockMetricsRecorder{}
+	tracer := &mockTracer{}
+	client.Instrument(&Instrumentation{Metrics: recorder, Tracer: tracer})
+
+	_, _, err := client.PipelineConfigs.Get(context.Background(), "test-pipeline")
+	assert.Error(t, err)
+
+	assert.Len(t, recorder.requests, 2)
+	version, pipeline := recorder.requests[0], recorder.requests[1]
+	assert.Equal(t, "ServerVersion", version.Service)
+	assert.Equal(t, "/api/version", version.Endpoint)
+	assert.Equal(t, "200", version.Status())
+	assert.NoError(t, version.Err)
+
+	assert.Equal(t, "PipelineConfigs", pipeline.Service)
+	assert.Equal(t, "GET", pipeline.Method)
+	assert.Equal(t, "/api/admin/pipelines/:pipeline_name", pipeline.Endpoint)
+	assert.Equal(t, 404, pipeline.StatusCode)
+	assert.Error(t, pipeline.Err)
+	assert.True(t, pipeline.Duration > 0)
+
+	assert.Len(t, tracer.spans, 2)
+	span := tracer.spans[1]
+	assert.Equal(t, "GET /api/admin/pipelines/:pipeline_name", span.name)
+	assert.True(t, span.ended)
+	assert.Error(t, span.err)
+	assert.Equal(t, map[string]string{
+		"http.method":      "GET",
+		"http.url":         server.URL + "/api/admin/pipelines/test-pipeline",
+		"http.status_code": "404",
+		"gocd.service":     "PipelineConfigs",
+		"gocd.endpoint":    "/api/admin/pipelines/:pipeline_name",
+	}, span.attributes)
+}
+
+func testInstrumentationRequestEndpoint(t *testing.T) {
+	setup()
+	defer teardown()
+
+	for _, test := range []struct {
+		path     string
+		service  string
+		endpoint string
+	}{
+		{"/api/agents/abc", "Agents", "/api/agents/:agent_uuid"},
+		{"/api/admin/templates", "PipelineTemplates", "/api/admin/templates"},
+		{"/api/pipelines/p/history/10", "Pipelines", "/api/pipelines/:pipeline_name/history/:offset"},
+		{"/api/admin/config_repos/repo", "ConfigRepos", "/api/admin/config_repos/:id"},
+		{"/properties/p/1/s/1/j", "Properties", "/properties/:pipeline_name/:pipeline_counter/:stage_name/:stage_counter/:job_name"},
+		{"/api/not/an/endpoint", endpointUnknown, endpointUnknown},
+	} {
+		req, _ := http.NewRequest("GET", server.URL+test.path, nil)
+		service, endpoint := client.requestEndpoint(req)
+		assert.Equal(t, test.service, service, test.path)
+		assert.Equal(t, test.endpoint, endpoint, test.path)
+	}
+}
+
+func testInstrumentationDisabled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/admin/encrypt", func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Traceparent"))
+		fmt.Fprint(w, `{"encrypted_value": "mock-value"}`)
+	})
+
+	recorder := &mockMetricsRecorder{}
+	client.Instrument(&Instrumentation{Metrics: recorder, Tracer: &mockTracer{}})
+	client.Instrument(nil)
+
+	_, _, err := client.Encryption.Encrypt(context.Background(), "value")
+	assert.NoError(t, err)
+	assert.Empty(t, recorder.requests)
+}
@@ -44,11 +44,15 @@ type Job struct {
 
 // codebeat:enable[TOO_MANY_IVARS]
 
-// Artifact describes the result of a job
+// Artifact describes the result of a job. An `external` artifact is published to the artifact store `StoreID` by an
+// artifact plugin, and is identified by `ID` instead of its source.
 type Artifact struct {
-	Type        string `json:"type"`
-	Source      string `json:"source"`
-	Destination string `json:"destination"`
+	Type          string                      `json:"type"`
+	Source        string                      `json:"source"`
+	Destination   string                      `json:"destination"`
+	ID            string                      `json:"id,omitempty"`
+	StoreID       string                      `json:"store_id,omitempty"`
+	Configuration []PluginConfigurationKVPair `json:"configuration,omitempty"`
 }
 
 // Tab description in a gocd job
@@ -109,33 +113,10 @@ type PluginConfigurationKVPair struct {
 
 // Task Describes a Task object in the GoCD api.
 type Task struct {
-	Type       string         `json:"type"`
-	Attributes TaskAttributes `json:"attributes"`
+	Type       string        `json:"type"`
+	Attributes TaskAttribute `json:"attributes"`
 }
 
-// TaskAttributes describes all the properties for a Task.
-// codebeat:disable[TOO_MANY_IVARS]
-type TaskAttributes struct {
-	RunIf               []string                    `json:"run_if,omitempty"`
-	Command             string                      `json:"command,omitempty"`
-	WorkingDirectory    string                      `json:"working_directory,omitempty"`
-	Arguments           []string                    `json:"arguments,omitempty"`
-	BuildFile           string                      `json:"build_file,omitempty"`
-	Target              string                      `json:"target,omitempty"`
-	NantPath            string                      `json:"nant_path,omitempty"`
-	Pipeline            string                      `json:"pipeline,omitempty"`
-	Stage               string                      `json:"stage,omitempty"`
-	Job                 string                      `json:"job,omitempty"`
-	Source              string                      `json:"source,omitempty"`
-	IsSourceAFile       bool                        `json:"is_source_a_file,omitempty"`
-	Destination         string                      `json:"destination,omitempty"`
-	PluginConfiguration *TaskPluginConfiguration    `json:"plugin_configuration,omitempty"`
-	Configuration       []PluginConfigurationKVPair `json:"configuration,omitempty"`
-	ArtifactOrigin      string                      `json:"artifact_origin,omitempty"`
-}
-
-// codebeat:enable[TOO_MANY_IVARS]
-
 // TaskPluginConfiguration is for specifying options for pluggable task
 type TaskPluginConfiguration struct {
 	ID      string `json:"id"`
@@ -187,9 +168,15 @@ type TimeoutField int
 
 // ListScheduled lists Pipeline groups
 func (js *JobsService) ListScheduled(ctx context.Context) (jobs []*JobSchedule, resp *APIResponse, err error) {
+	apiVersion, err := js.client.getAPIVersion(ctx, "jobs/scheduled.xml")
+	if err != nil {
+		return nil, nil, err
+	}
+
 	j := &JobScheduleResponse{}
 	_, resp, err = js.client.getAction(ctx, &APIClientRequest{
 		Path:         "jobs/scheduled.xml",
+		APIVersion:   apiVersion,
 		ResponseBody: j,
 		ResponseType: responseTypeXML,
 	})
@@ -102,40 +102,44 @@ func taskTaskValidateListScheduled(t *testing.T) {
 }
 
 func taskValidateSuccessAnt(t *testing.T) {
+	attributes := &TaskAttributesAnt{}
 	antTask := Task{
-		Type: "ant",
+		Type:       "ant",
+		Attributes: attributes,
 	}
 	assert.NotNil(t, antTask.Validate())
 
-	antTask.Attributes.RunIf = []string{"one", "two"}
+	attributes.RunIf = []string{"passed", "failed"}
 	assert.NotNil(t, antTask.Validate())
 
-	antTask.Attributes.BuildFile = "build-file"
+	attributes.BuildFile = "build-file"
 	assert.NotNil(t, antTask.Validate())
 
-	antTask.Attributes.Target = "target"
+	attributes.Target = "target"
 	assert.NotNil(t, antTask.Validate())
 
-	antTask.Attributes.WorkingDirectory = "working-directory"
+	attributes.WorkingDirectory = "working-directory"
 	assert.Nil(t, antTask.Validate())
 }
 
 func taskValidateSuccessExec(t *testing.T) {
+	attributes := &TaskAttributesExec{}
 	execTask := Task{
-		Type: "exec",
+		Type:       "exec",
+		Attributes: attributes,
 	}
 	assert.NotNil(t, execTask.Validate())
 
-	execTask.Attributes.RunIf = []string{"one", "two"}
+	attributes.RunIf = []string{"passed", "failed"}
 	assert.NotNil(t, execTask.Validate())
 
-	execTask.Attributes.Command = "command-one"
+	attributes.Command = "command-one"
 	assert.NotNil(t, execTask.Validate())
 
-	execTask.Attributes.Arguments = []string{"one", "two"}
+	attributes.Arguments = []string{"one", "two"}
 	assert.NotNil(t, execTask.Validate())
 
-	execTask.Attributes.WorkingDirectory = "one-two-three"
+	attributes.WorkingDirectory = "one-two-three"
 	assert.Nil(t, execTask.Validate())
 
 }
@@ -154,16 +158,25 @@ func taskValidateFail(t *testing.T) {
 
 	task.Type = "ant"
 	assert.NotNil(t, task.Validate())
+
+	task.Attributes = &TaskAttributesAnt{}
+	assert.NotNil(t, task.Validate())
+
+	task.Attributes = &TaskAttributesExec{}
+	assert.EqualError(t,
+		task.Validate(), "attributes of a 'ant' task must be *gocd.TaskAttributesAnt, not *gocd.TaskAttributesExec")
 }
 
 func TestJobValidate(t *testing.T) {
 	t.Run("ValidateJob", jobValidateSuccess)
 	t.Run("Exec", jobValidateExecSuccess)
 	t.Run("Ant", jobValidateAntSuccess)
-	//t.Run("Nant", job_ValidateNantSuccess)
-	//t.Run("Rake", job_ValidateRakeSuccess)
-	//t.Run("Fetch", job_ValidateFetchSuccess)
-	//t.Run("PluggableTask", job_ValidatePluggableTaskSuccess)
+	t.Run("Nant", jobValidateNantSuccess)
+	t.Run("Rake", jobValidateRakeSuccess)
+	t.Run("Fetch", jobValidateFetchSuccess)
+	t.Run("FetchExternal", jobValidateFetchExternalSuccess)
+	t.Run("PluggableTask", jobValidatePluggableTaskSuccess)
+	t.Run("Fail", jobValidateTaskFail)
 }
 
 func jobValidateSuccess(t *testing.T) {
@@ -177,45 +190,136 @@ func jobValidateSuccess(t *testing.T) {
 }
 
 func jobValidateExecSuccess(t *testing.T) {
-	err := (&TaskAttributes{
-		RunIf:            []string{"runif-exec"},
+	err := (&TaskAttributesExec{
+		RunIf:            []string{"passed"},
 		Command:          "my-test-command",
 		Arguments:        []string{"arg1", "arg2"},
 		WorkingDirectory: "test-working-diretory",
-	}).ValidateExec()
+	}).Validate()
 	assert.Nil(t, err)
 }
 
 func jobValidateAntSuccess(t *testing.T) {
-	err := (&TaskAttributes{
-		RunIf:            []string{"runif-ant"},
+	err := (&TaskAttributesAnt{
+		RunIf:            []string{"any"},
 		BuildFile:        "test-build-file",
 		Target:           "test-target",
 		WorkingDirectory: "test-working-directory",
-	}).ValidateAnt()
+	}).Validate()
+	assert.Nil(t, err)
+}
+
+func jobValidateNantSuccess(t *testing.T) {
+	err := (&TaskAttributesNant{
+		RunIf:            []string{"passed"},
+		BuildFile:        "test.build",
+		Target:           "test-target",
+		WorkingDirectory: "test-working-directory",
+		NantPath:         "/usr/local/bin",
+	}).Validate()
+	assert.Nil(t, err)
+
+	task := &Task{Type: "nant", Attributes: &TaskAttributesNant{RunIf: []string{"passed"}, Target: "build"}}
+	assert.Nil(t, task.Validate())
+	_, err = NewPipeline("p").Stage("build").Job("a").Task(task).Build()
+	assert.EqualError(t, err, "pipeline 'p' is invalid: pipeline must have at least one material",
+		"the task is accepted by the builder")
+
+	err = (&TaskAttributesNant{RunIf: []string{"always"}}).Validate()
+	assert.NotNil(t, err)
+}
+
+func jobValidateRakeSuccess(t *testing.T) {
+	err := (&TaskAttributesRake{
+		RunIf: []string{"failed"},
+	}).Validate()
 	assert.Nil(t, err)
 }
 
-//func job_ValidateNantSuccess(t *testing.T) {
-//	err := (&TaskAttributes{}).ValidateNant()
-//	assert.Nil(t, err)
-//
-//}
-//
-//func job_ValidateRakeSuccess(t *testing.T) {
-//	err := (&TaskAttributes{}).ValidateRake()
-//	assert.Nil(t, err)
-//
-//}
-//
-//func job_ValidateFetchSuccess(t *testing.T) {
-//	err := (&TaskAttributes{}).ValidateFetch()
-//	assert.Nil(t, err)
-//
-//}
-//
-//func job_ValidatePluggableTaskSuccess(t *testing.T) {
-//	err := (&TaskAttributes{}).ValidatePluggableTask()
-//	assert.Nil(t, err)
-//
-//}
+func jobValidateFetchSuccess(t *testing.T) {
+	for _, origin := range []string{"", "gocd"} {
+		err := (&TaskAttributesFetch{
+			ArtifactOrigin: origin,
+			RunIf:          []string{"passed"},
+			Pipeline:       "upstream",
+			Stage:          "upstream_stage",
+			Job:            "upstream_job",
+			Source:         "result",
+			Destination:    "test",
+		}).Validate()
+		assert.Nil(t, err, origin)
+	}
+}
+
+func jobValidateFetchExternalSuccess(t *testing.T) {
+	err := (&TaskAttributesFetch{
+		ArtifactOrigin: "external",
+		RunIf:          []string{"passed"},
+		Stage:          "upstream_stage",
+		Job:            "upstream_job",
+		ArtifactID:     "docker-image",
+		Configuration:  []PluginConfigurationKVPair{{Key: "EnvironmentVariablePrefix", Value: "IMAGE"}},
+	}).Validate()
+	assert.Nil(t, err)
+}
+
+func jobValidatePluggableTaskSuccess(t *testing.T) {
+	err := (&TaskAttributesPluggable{
+		RunIf:               []string{"passed"},
+		PluginConfiguration: &TaskPluginConfiguration{ID: "script-executor", Version: "1"},
+		Configuration:       []PluginConfigurationKVPair{{Key: "script", Value: "make"}},
+	}).Validate()
+	assert.Nil(t, err)
+}
+
+func jobValidateTaskFail(t *testing.T) {
+	fetch := func() *TaskAttributesFetch {
+		return &TaskAttributesFetch{RunIf: []string{"passed"}, Stage: "stage", Job: "job", Source: "result"}
+	}
+	pluggable := func() *TaskAttributesPluggable {
+		return &TaskAttributesPluggable{
+			RunIf:               []string{"passed"},
+			PluginConfiguration: &TaskPluginConfiguration{ID: "script-executor", Version: "1"},
+		}
+	}
+
+	for want, attributes := range map[string]TaskAttribute{
+		"'run_if' must be one of 'passed', 'failed' or 'any', not 'sometimes'": &TaskAttributesRake{RunIf: []string{"passed", "sometimes"}},
+		"'stage' must not be empty":  func() TaskAttribute { f := fetch(); f.Stage = ""; return f }(),
+		"'job' must not be empty":    func() TaskAttribute { f := fetch(); f.Job = ""; return f }(),
+		"'source' must not be empty": func() TaskAttribute { f := fetch(); f.Source = ""; return f }(),
+		"'artifact_origin' must be 'gocd' or 'external', not 's3'": func() TaskAttribute {
+			f := fetch()
+			f.ArtifactOrigin = "s3"
+			return f
+		}(),
+		"'artifact_id' and 'configuration' can only be set when 'artifact_origin' is 'external'": func() TaskAttribute {
+			f := fetch()
+			f.ArtifactID = "docker-image"
+			return f
+		}(),
+		"'artifact_id' must not be empty": func() TaskAttribute {
+			f := fetch()
+			f.ArtifactOrigin, f.Source = "external", ""
+			return f
+		}(),
+		"'source', 'is_source_a_file' and 'destination' can not be set when 'artifact_origin' is 'external'": func() TaskAttribute {
+			f := fetch()
+			f.ArtifactOrigin, f.ArtifactID = "external", "docker-image"
+			return f
+		}(),
+		"'plugin_configuration' must not be empty": &TaskAttributesPluggable{RunIf: []string{"any"}},
+		"'plugin_configuration.version' must not be empty": func() TaskAttribute {
+			p := pluggable()
+			p.PluginConfiguration.Version = ""
+			return p
+		}(),
+		"'configuration' key 'script' is duplicated": func() TaskAttribute {
+			p := pluggable()
+			p.Configuration = []PluginConfigurationKVPair{{Key: "script"}, {Key: "script"}}
+			return p
+		}(),
+	} {
+		assert.EqualError(t, attributes.Validate(), want)
+	}
+}
@@ -1,11 +1,14 @@
 package gocd
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
-// ValidateExec checks that the specified values for the Task struct are correct for a cli exec task
-func (t *TaskAttributes) ValidateExec() error {
-	if len(t.RunIf) == 0 {
-		return errors.New("'run_if' must not be empty")
+// Validate checks that the specified values for the Task struct are correct for a cli exec task
+func (t *TaskAttributesExec) Validate() error {
+	if err := validateRunIf(t.RunIf); err != nil {
+		return err
 	}
 	if t.Command == "" {
 		return errors.New("'command' must not be empty")
@@ -20,11 +23,10 @@ func (t *TaskAttributes) ValidateExec() error {
 	return nil
 }
 
-// ValidateAnt checks that the specified values for the Task struct are correct for a an Ant task
-func (t *TaskAttributes) ValidateAnt() error {
-	// Ensure valid attributes are set.
-	if len(t.RunIf) == 0 {
-		return errors.New("'run_if' must not be empty")
+// Validate checks that the specified values for the Task struct are correct for a an Ant task
+func (t *TaskAttributesAnt) Validate() error {
+	if err := validateRunIf(t.RunIf); err != nil {
+		return err
 	}
 	if t.BuildFile == "" {
 		return errors.New("'build_file' must not be empty")
@@ -36,67 +38,101 @@ func (t *TaskAttributes) ValidateAnt() error {
 		return errors.New("'working_directory' must not empty")
 	}
 
-	// Ensure extra attributes are not set
-	// @TODO Rewrite this as a generator.
-	//if t.Command != "" {
-	//	return errors.New("'Command' can not be set for 'Ant' tasks")
-	//}
-	//if t.Arguments != nil || len(t.Arguments) > 0 {
-	//	return errors.New("'Command' can not be set for 'Ant' tasks")
-	//}
-	//if t.BuildFile != "" {
-	//	return errors.New("'BuildFile' can not be set for 'Ant' tasks")
-	//}
-	//if t.Target != "" {
-	//	return errors.New("'Target' can not be set for 'Ant' tasks")
-	//}
-	//if t.NantPath != "" {
-	//	return errors.New("'NantPath' can not be set for 'Ant' tasks")
-	//}
-	//if t.Pipeline != "" {
-	//	return errors.New("'Pipeline' can not be set for 'Ant' tasks")
-	//}
-	//if t.Stage != "" {
-	//	return errors.New("'Stage' can not be set for 'Ant' tasks")
-	//}
-	//if t.Job != "" {
-	//	return errors.New("'Job' can not be set for 'Ant' tasks")
-	//}
-	//if t.Source != "" {
-	//	return errors.New("'Source' can not be set for 'Ant' tasks")
-	//}
-	//if t.IsSourceAFile != "" {
-	//	return errors.New("'IsSourceAFile' can not be set for 'Ant' tasks")
-	//}
-	//if t.Destination != "" {
-	//	return errors.New("'Destination' can not be set for 'Ant' tasks")
-	//}
-	//if t.PluginConfiguration != nil {
-	//	return errors.New("'PluginConfiguration' can not be set for 'Ant' tasks")
-	//}
-	//if t.Configuration != nil {
-	//	return errors.New("'Configuration' can not be set for 'Ant' tasks")
-	//}
+	return nil
+}
+
+// Validate checks that the specified values for the Task struct are correct for a a Nant task. Nant uses the build
+// file in the working directory, its default target, and the nant on the path of the agent, if they are not set.
+func (t *TaskAttributesNant) Validate() error {
+	return validateRunIf(t.RunIf)
+}
+
+// Validate checks that the specified values for the Task struct are correct for a a Rake task. Rake uses the Rakefile
+// in the working directory, and its default target, if they are not set.
+func (t *TaskAttributesRake) Validate() error {
+	return validateRunIf(t.RunIf)
+}
+
+// Validate checks that the specified values for the Task struct are correct for a a Fetch task. The pipeline defaults
+// to the pipeline the task is in, so only the stage and job are required.
+func (t *TaskAttributesFetch) Validate() error {
+	if err := validateRunIf(t.RunIf); err != nil {
+		return err
+	}
+	if t.Stage == "" {
+		return errors.New("'stage' must not be empty")
+	}
+	if t.Job == "" {
+		return errors.New("'job' must not be empty")
+	}
+
+	switch t.ArtifactOrigin {
+	case "", "gocd":
+		if t.Source == "" {
+			return errors.New("'source' must not be empty")
+		}
+		if t.ArtifactID != "" || len(t.Configuration) > 0 {
+			return errors.New("'artifact_id' and 'configuration' can only be set when 'artifact_origin' is 'external'")
+		}
+	case "external":
+		if t.ArtifactID == "" {
+			return errors.New("'artifact_id' must not be empty")
+		}
+		if t.Source != "" || t.IsSourceAFile || t.Destination != "" {
+			return errors.New("'source', 'is_source_a_file' and 'destination' can not be set when 'artifact_origin' is 'external'")
+		}
+		return validateConfiguration(t.Configuration)
+	default:
+		return fmt.Errorf("'artifact_origin' must be 'gocd' or 'external', not '%s'", t.ArtifactOrigin)
+	}
 
 	return nil
 }
 
-// ValidateNant checks that the specified values for the Task struct are correct for a a Nant task
-//func (t *TaskAttributes) ValidateNant() error {
-//	return errors.New("Not Implemented")
-//}
+// Validate checks that the specified values for the Task struct are correct for a a Plugin task
+func (t *TaskAttributesPluggable) Validate() error {
+	if err := validateRunIf(t.RunIf); err != nil {
+		return err
+	}
+	if t.PluginConfiguration == nil {
+		return errors.New("'plugin_configuration' must not be empty")
+	}
+	if t.PluginConfiguration.ID == "" {
+		return errors.New("'plugin_configuration.id' must not be empty")
+	}
+	if t.PluginConfiguration.Version == "" {
+		return errors.New("'plugin_configuration.version' must not be empty")
+	}
 
-// ValidateRake checks that the specified values for the Task struct are correct for a a Rake task
-//func (t *TaskAttributes) ValidateRake() error {
-//	return errors.New("Not Implemented")
-//}
+	return validateConfiguration(t.Configuration)
+}
 
-// ValidateRake checks that the specified values for the Task struct are correct for a a Rake task
-//func (t *TaskAttributes) ValidateFetch() error {
-//	return errors.New("Not Implemented")
-//}
+// validateRunIf checks a task is run on at least one, known, stage result.
+func validateRunIf(runIf []string) error {
+	if len(runIf) == 0 {
+		return errors.New("'run_if' must not be empty")
+	}
+	for _, condition := range runIf {
+		switch condition {
+		case "passed", "failed", "any":
+		default:
+			return fmt.Errorf("'run_if' must be one of 'passed', 'failed' or 'any', not '%s'", condition)
+		}
+	}
+	return nil
+}
 
-// ValidatePluggableTask checks that the specified values for the Task struct are correct for a a Plugin task
-//func (t *TaskAttributes) ValidatePluggableTask() error {
-//	return errors.New("Not Implemented")
-//}
+// validateConfiguration checks plugin configuration keys are set and unique.
+func validateConfiguration(configuration []PluginConfigurationKVPair) error {
+	keys := map[string]bool{}
+	for _, kv := range configuration {
+		if kv.Key == "" {
+			return errors.New("'configuration' keys must not be empty")
+		}
+		if keys[kv.Key] {
+			return fmt.Errorf("'configuration' key '%s' is duplicated", kv.Key)
+		}
+		keys[kv.Key] = true
+	}
+	return nil
+}
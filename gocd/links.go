@@ -1,8 +1,12 @@
 package gocd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/url"
+	"sort"
 	"strings"
 )
 
@@ -48,15 +52,26 @@ func (al HALLinks) Keys() (keys []string) {
 	return
 }
 
-// MarshallJSON allows the encoding of links into JSON
-func (al HALLinks) MarshallJSON() ([]byte, error) {
+// MarshalJSON allows the encoding of links into JSON
+func (al HALLinks) MarshalJSON() ([]byte, error) {
 	ls := map[string]*linkHref{}
 	for _, link := range al.links {
-		ls[link.Name] = &linkHref{H: link.URL.String()}
+		href := ""
+		if link.URL != nil {
+			href = link.URL.String()
+		}
+		ls[link.Name] = &linkHref{H: href}
 	}
 	return json.Marshal(ls)
 }
 
+// MarshallJSON allows the encoding of links into JSON.
+//
+// Deprecated: Use MarshalJSON, which satisfies json.Marshaler.
+func (al HALLinks) MarshallJSON() ([]byte, error) {
+	return al.MarshalJSON()
+}
+
 // UnmarshalJSON allows the decoding of links from JSON
 func (al *HALLinks) UnmarshalJSON(j []byte) (err error) {
 	var d linkField
@@ -64,9 +79,16 @@ func (al *HALLinks) UnmarshalJSON(j []byte) (err error) {
 		return
 	}
 
+	// Sort the link names, so that the order of the links is stable between decodings.
+	names := make([]string, 0, len(d))
+	for linkName := range d {
+		names = append(names, linkName)
+	}
+	sort.Strings(names)
+
 	var u *url.URL
-	for linkName, value := range d {
-		if u, err = url.Parse(value["href"]); err != nil {
+	for _, linkName := range names {
+		if u, err = url.Parse(d[linkName]["href"]); err != nil {
 			break
 		}
 		al.Add(&HALLink{
@@ -76,3 +98,101 @@ func (al *HALLinks) UnmarshalJSON(j []byte) (err error) {
 	}
 	return
 }
+
+// Self returns the `self` link, which points to the resource itself.
+func (al HALLinks) Self() *HALLink {
+	return al.Get("self")
+}
+
+// Doc returns the `doc` link, which points to the documentation for the resource.
+func (al HALLinks) Doc() *HALLink {
+	return al.Get("doc")
+}
+
+// Find returns the `find` link, which is a template for finding resources of the same type. See HALLink.Expand.
+func (al HALLinks) Find() *HALLink {
+	return al.Get("find")
+}
+
+// Next returns the `next` link, which points to the next page of a paginated resource.
+func (al HALLinks) Next() *HALLink {
+	return al.Get("next")
+}
+
+// Previous returns the `previous` link, which points to the previous page of a paginated resource.
+func (al HALLinks) Previous() *HALLink {
+	return al.Get("previous")
+}
+
+// Expand the `:param` placeholders in a templated link, eg: `/go/api/agents/:uuid`. A link without a URL is returned
+// unchanged.
+func (l *HALLink) Expand(params map[string]string) (link *HALLink) {
+	if l == nil || l.URL == nil {
+		return l
+	}
+	u := *l.URL
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		if value, isParam := params[strings.TrimPrefix(segment, ":")]; isParam && strings.HasPrefix(segment, ":") {
+			segments[i] = value
+		}
+	}
+	u.Path = strings.Join(segments, "/")
+	u.RawPath = ""
+
+	return &HALLink{
+		Name: l.Name,
+		URL:  &u,
+	}
+}
+
+// Follow a HAL link, and decode the JSON response into `out`. The link is resolved against the server the client is
+// configured for, and the request is made with the Accept version the library uses for the endpoint the link points
+// to.
+func (c *Client) Follow(ctx context.Context, link *HALLink, out interface{}) (resp *APIResponse, err error) {
+	if link == nil || link.URL == nil {
+		return nil, errors.New("can not follow an empty link")
+	}
+
+	var path string
+	if path, err = c.linkPath(link); err != nil {
+		return
+	}
+
+	var apiVersion string
+	if apiVersion, err = c.getAPIVersionForPath(ctx, path); err != nil {
+		return
+	}
+
+	_, resp, err = c.getAction(ctx, &APIClientRequest{
+		Path:         path,
+		APIVersion:   apiVersion,
+		ResponseBody: out,
+	})
+	return
+}
+
+// linkPath resolves the link against the BaseURL of the client, and returns the path relative to the BaseURL, starting
+// with a `/`, so that it can be used to build a request.
+func (c *Client) linkPath(link *HALLink) (path string, err error) {
+	u := c.BaseURL().ResolveReference(link.URL)
+	if path, err = c.relativePath(u); err != nil {
+		return "", fmt.Errorf("link '%s' is not served by the GoCD server at '%s'", link.URL, c.BaseURL())
+	}
+	if u.RawQuery != "" {
+		path = path + "?" + u.RawQuery
+	}
+	return
+}
+
+// relativePath returns the path of `u` relative to the BaseURL of the client, starting with a `/`.
+func (c *Client) relativePath(u *url.URL) (string, error) {
+	basePath := c.BaseURL().Path
+	if !strings.HasSuffix(basePath, "/") {
+		basePath += "/"
+	}
+	if !strings.HasPrefix(u.Path, basePath) {
+		return "", fmt.Errorf("'%s' is not under '%s'", u.Path, basePath)
+	}
+	return "/" + strings.TrimPrefix(u.Path, basePath), nil
+}
@@ -1,7 +1,12 @@
 package gocd
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
 	"net/url"
 	"testing"
 )
@@ -11,6 +16,11 @@ func TestLinks(t *testing.T) {
 	t.Run("UnmarshallJSON", testUnmarshallJSON)
 	t.Run("Keys", testLinkKeys)
 	t.Run("GetOk", testLinkGetOk)
+	t.Run("RoundTrip", testLinkRoundTrip)
+	t.Run("Named", testLinkNamed)
+	t.Run("Expand", testLinkExpand)
+	t.Run("Follow", testLinkFollow)
+	t.Run("FollowFail", testLinkFollowFail)
 }
 
 func testLinkGetOk(t *testing.T) {
@@ -55,7 +65,8 @@ func testUnmarshallJSON(t *testing.T) {
 		}`)
 	l2 := HALLinks{}
 	err2 := l2.UnmarshalJSON(badURL)
-	assert.EqualError(t, err2, "parse -test://bad-url: first path segment in URL cannot contain colon")
+	assert.Error(t, err2)
+	assert.Contains(t, err2.Error(), "first path segment in URL cannot contain colon")
 }
 
 func testMarshallJSON(t *testing.T) {
@@ -70,4 +81,117 @@ func testMarshallJSON(t *testing.T) {
 	}
 
 	assert.Equal(t, "{\"test-link\":{\"href\":\"http://example.com\"}}", string(b))
+
+	b, err = json.Marshal(struct {
+		Links *HALLinks `json:"_links"`
+	}{&l})
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"_links\":{\"test-link\":{\"href\":\"http://example.com\"}}}", string(b))
+}
+
+func testLinkRoundTrip(t *testing.T) {
+	j, _ := ioutil.ReadFile("test/resources/agent.0.json")
+	a := Agent{}
+	assert.NoError(t, json.Unmarshal(j, &a))
+
+	b, err := json.Marshal(a)
+	assert.NoError(t, err)
+
+	a2 := Agent{}
+	assert.NoError(t, json.Unmarshal(b, &a2))
+	assert.Equal(t, a.Links, a2.Links)
+	assert.Equal(t, a.BuildDetails.Links, a2.BuildDetails.Links)
+	assert.Equal(t, []string{"doc", "find", "self"}, a2.Links.Keys())
+}
+
+func testLinkNamed(t *testing.T) {
+	l := HALLinks{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+  "self": {"href": "https://ci.example.com/go/api/agents/1"},
+  "doc": {"href": "https://api.gocd.org/#agents"},
+  "find": {"href": "https://ci.example.com/go/api/agents/:uuid"},
+  "next": {"href": "https://ci.example.com/go/api/agents?after=1"},
+  "previous": {"href": "https://ci.example.com/go/api/agents?before=1"}
+}`), &l))
+
+	assert.Equal(t, "https://ci.example.com/go/api/agents/1", l.Self().URL.String())
+	assert.Equal(t, "https://api.gocd.org/#agents", l.Doc().URL.String())
+	assert.Equal(t, "https://ci.example.com/go/api/agents/:uuid", l.Find().URL.String())
+	assert.Equal(t, "https://ci.example.com/go/api/agents?after=1", l.Next().URL.String())
+	assert.Equal(t, "https://ci.example.com/go/api/agents?before=1", l.Previous().URL.String())
+}
+
+func testLinkExpand(t *testing.T) {
+	u, _ := url.Parse("https://ci.example.com/go/api/agents/:uuid")
+	l := &HALLink{Name: "find", URL: u}
+
+	expanded := l.Expand(map[string]string{"uuid": "adb9540a"})
+	assert.Equal(t, "https://ci.example.com/go/api/agents/adb9540a", expanded.URL.String())
+	assert.Equal(t, "https://ci.example.com/go/api/agents/:uuid", l.URL.String())
+
+	empty := &HALLink{Name: "find"}
+	assert.Equal(t, empty, empty.Expand(map[string]string{"uuid": "adb9540a"}))
+	assert.Nil(t, (*HALLink)(nil).Expand(nil))
+}
+
+func testLinkFollow(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/agents/adb9540a-b954-4571-9d9b-2f330739d4da", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method, "Unexpected HTTP method")
+		assert.Equal(t, apiV4, r.Header.Get("Accept"))
+		j, _ := ioutil.ReadFile("test/resources/agent.0.json")
+		fmt.Fprint(w, string(j))
+	})
+	mux.HandleFunc("/api/admin/templates/template0", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, apiV3, r.Header.Get("Accept"))
+		j, _ := ioutil.ReadFile("test/resources/pipelinetemplate.0.json")
+		fmt.Fprint(w, string(j))
+	})
+	mux.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		j, _ := ioutil.ReadFile("test/resources/version.2.json")
+		fmt.Fprint(w, string(j))
+	})
+
+	agent := &Agent{}
+	u, _ := url.Parse(server.URL + "/api/agents/adb9540a-b954-4571-9d9b-2f330739d4da")
+	_, err := client.Follow(context.Background(), &HALLink{Name: "self", URL: u}, agent)
+	assert.NoError(t, err)
+	assert.Equal(t, "agent01.example.com", agent.Hostname)
+
+	// Links are resolved against the server the client is configured for, whatever host the link refers to.
+	u, _ = url.Parse("https://ci.example.com/api/agents/adb9540a-b954-4571-9d9b-2f330739d4da")
+	agent.client = client
+	agent.Links = &HALLinks{}
+	agent.Links.Add(&HALLink{Name: "self", URL: u})
+	refreshed, _, err := agent.Refresh(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "adb9540a-b954-4571-9d9b-2f330739d4da", refreshed.UUID)
+
+	template := &PipelineTemplate{}
+	u, _ = url.Parse("/api/admin/templates/template0")
+	_, err = client.Follow(context.Background(), &HALLink{Name: "self", URL: u}, template)
+	assert.NoError(t, err)
+	assert.Equal(t, "template1", template.Name)
+}
+
+func testLinkFollowFail(t *testing.T) {
+	setup()
+	defer teardown()
+
+	_, err := client.Follow(context.Background(), nil, nil)
+	assert.EqualError(t, err, "can not follow an empty link")
+
+	u, _ := url.Parse("/api/unknown/endpoint")
+	_, err = client.Follow(context.Background(), &HALLink{Name: "self", URL: u}, nil)
+	assert.EqualError(t, err, "could not find API version tag for '/api/unknown/endpoint'")
+
+	c := NewClient(&Configuration{Server: server.URL + "/go/"}, nil)
+	u, _ = url.Parse("/other/api/agents")
+	_, err = c.Follow(context.Background(), &HALLink{Name: "self", URL: u}, nil)
+	assert.EqualError(t, err, fmt.Sprintf("link '/other/api/agents' is not served by the GoCD server at '%s/go/'", server.URL))
+
+	_, _, err = (&Agent{}).Refresh(context.Background())
+	assert.EqualError(t, err, "agent was not retrieved from a GoCD server")
 }
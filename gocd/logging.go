@@ -1,11 +1,95 @@
 package gocd
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/sirupsen/logrus"
 )
 
+// Logger receives the log messages emitted by a Client. Fields are passed as alternating keys and values, so a
+// *slog.Logger from the standard library satisfies this interface without an adapter:
+//
+//	client.SetLogger(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+//
+// Secrets are redacted from the request and response bodies and headers before they are logged.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// NewLogrusLogger wraps a logrus logger so it can be used as a Logger.
+func NewLogrusLogger(log *logrus.Logger) Logger {
+	return &logrusLogger{log: log}
+}
+
+// NopLogger discards all log messages.
+type NopLogger struct{}
+
+// Debug discards the message.
+func (NopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+
+// Info discards the message.
+func (NopLogger) Info(msg string, keysAndValues ...interface{}) {}
+
+// Warn discards the message.
+func (NopLogger) Warn(msg string, keysAndValues ...interface{}) {}
+
+// Error discards the message.
+func (NopLogger) Error(msg string, keysAndValues ...interface{}) {}
+
+type logrusLogger struct {
+	log *logrus.Logger
+}
+
+func (ll *logrusLogger) Debug(msg string, keysAndValues ...interface{}) {
+	ll.entry(keysAndValues).Debug(msg)
+}
+
+func (ll *logrusLogger) Info(msg string, keysAndValues ...interface{}) {
+	ll.entry(keysAndValues).Info(msg)
+}
+
+func (ll *logrusLogger) Warn(msg string, keysAndValues ...interface{}) {
+	ll.entry(keysAndValues).Warn(msg)
+}
+
+func (ll *logrusLogger) Error(msg string, keysAndValues ...interface{}) {
+	ll.entry(keysAndValues).Error(msg)
+}
+
+// entry converts alternating keys and values to logrus fields. A key without a value is logged with the key "!BADKEY",
+// in the same way as slog.
+func (ll *logrusLogger) entry(keysAndValues []interface{}) *logrus.Entry {
+	fields := logrus.Fields{}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 == len(keysAndValues) {
+			fields["!BADKEY"] = keysAndValues[i]
+			break
+		}
+		fields[fmt.Sprint(keysAndValues[i])] = keysAndValues[i+1]
+	}
+	return ll.log.WithFields(fields)
+}
+
+// SetLogger replaces the logger used by the client. Passing nil restores the default logger, which writes to the logrus
+// logger in `Client.Log` configured with SetupLogging.
+func (c *Client) SetLogger(logger Logger) {
+	c.Lock()
+	defer c.Unlock()
+	c.logger = logger
+}
+
+// log returns the logger for the client.
+func (c *Client) log() Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return NewLogrusLogger(c.Log)
+}
+
 // Set logging level and type constants
 const (
 	LogLevelEnvVarName = "GOCD_LOG_LEVEL"
@@ -0,0 +1,28 @@
+//go:build go1.21
+// +build go1.21
+
+package gocd
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+)
+
+func TestLoggerSlog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var logger Logger = slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	c := NewClient(&Configuration{Server: "https://my-goserver:8154/go/"}, nil)
+	c.SetLogger(logger)
+	c.log().Debug("Requesting Endpoint", "Method", "GET", "Path", "agents")
+
+	entry := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "Requesting Endpoint", entry["msg"])
+	assert.Equal(t, "DEBUG", entry["level"])
+	assert.Equal(t, "GET", entry["Method"])
+	assert.Equal(t, "agents", entry["Path"])
+}
@@ -1,7 +1,13 @@
 package gocd
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"net/http"
 	"os"
 	"testing"
 )
@@ -26,3 +32,98 @@ func TestLogging(t *testing.T) {
 	os.Setenv(LogTypeEnvVarName, existingType)
 	os.Setenv(LogLevelEnvVarName, existingLevel)
 }
+
+// recordingLogger stores the messages it receives.
+type recordingLogger struct {
+	messages []string
+	fields   []map[string]interface{}
+}
+
+func (rl *recordingLogger) record(msg string, keysAndValues []interface{}) {
+	fields := map[string]interface{}{}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fields[keysAndValues[i].(string)] = keysAndValues[i+1]
+	}
+	rl.messages = append(rl.messages, msg)
+	rl.fields = append(rl.fields, fields)
+}
+
+func (rl *recordingLogger) Debug(msg string, keysAndValues ...interface{}) { rl.record(msg, keysAndValues) }
+func (rl *recordingLogger) Info(msg string, keysAndValues ...interface{})  { rl.record(msg, keysAndValues) }
+func (rl *recordingLogger) Warn(msg string, keysAndValues ...interface{})  { rl.record(msg, keysAndValues) }
+func (rl *recordingLogger) Error(msg string, keysAndValues ...interface{}) { rl.record(msg, keysAndValues) }
+
+func TestLogger(t *testing.T) {
+	t.Run("SetLogger", testLoggerSetLogger)
+	t.Run("Logrus", testLoggerLogrus)
+	t.Run("Nop", testLoggerNop)
+}
+
+func testLoggerSetLogger(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/admin/encrypt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "JSESSIONID=secret-session")
+		fmt.Fprint(w, `{"encrypted_value": "aSdiFgRRZ6A="}`)
+	})
+
+	// Negotiate the server version first, so that only the request under test is observed.
+	_, _, err := client.ServerVersion.Get(context.Background())
+	assert.NoError(t, err)
+
+	logger := &recordingLogger{}
+	client.SetLogger(logger)
+
+	_, _, err = client.Encryption.Encrypt(context.Background(), "my-secret")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"Requesting Endpoint",
+		"Sending Request Body",
+		"Request Header",
+		"Response Headers",
+		"Response",
+	}, logger.messages)
+	assert.Equal(t, "POST", logger.fields[0]["Method"])
+	assert.Equal(t, redacted, logger.fields[2]["Authorization"])
+	assert.Equal(t, redacted, logger.fields[3]["Set-Cookie"])
+	assert.Contains(t, logger.fields[4]["Body"], redacted)
+
+	for _, fields := range logger.fields {
+		for _, value := range fields {
+			assert.NotContains(t, fmt.Sprint(value), "mockPassword")
+			assert.NotContains(t, fmt.Sprint(value), "secret-session")
+			assert.NotContains(t, fmt.Sprint(value), "aSdiFgRRZ6A=")
+			assert.NotContains(t, fmt.Sprint(value), "my-secret")
+		}
+	}
+
+	client.SetLogger(nil)
+	assert.IsType(t, &logrusLogger{}, client.log())
+}
+
+func testLoggerLogrus(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := logrus.New()
+	log.Out = buf
+	log.Formatter = &logrus.JSONFormatter{}
+	log.SetLevel(logrus.DebugLevel)
+
+	NewLogrusLogger(log).Info("message", "key", "value", "dangling")
+
+	entry := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "message", entry["msg"])
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "value", entry["key"])
+	assert.Equal(t, "dangling", entry["!BADKEY"])
+}
+
+func testLoggerNop(t *testing.T) {
+	var logger Logger = NopLogger{}
+	logger.Debug("message", "key", "value")
+	logger.Info("message")
+	logger.Warn("message")
+	logger.Error("message")
+}
@@ -0,0 +1,95 @@
+package gocd
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// Middleware wraps the http.RoundTripper used by the client, to inspect or modify requests before they are sent and
+// responses after they are received. Middleware can be used for authentication, audit logging, metrics, or header
+// propagation.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc allows a function to be used as an http.RoundTripper.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls the function.
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// BeforeRequest creates a Middleware calling `hook` before each request is sent. If the hook returns an error, the
+// request is not sent and the error is returned to the caller.
+func BeforeRequest(hook func(req *http.Request) error) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if err := hook(req); err != nil {
+				return nil, err
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// AfterResponse creates a Middleware calling `hook` after each response is received, or after the request failed. If
+// the hook returns an error, it is returned to the caller instead of the response.
+func AfterResponse(hook func(req *http.Request, resp *http.Response, err error) error) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if hookErr := hook(req, resp, err); hookErr != nil {
+				if resp != nil && resp.Body != nil {
+					resp.Body.Close()
+				}
+				return nil, hookErr
+			}
+			return resp, err
+		})
+	}
+}
+
+// Use adds middleware to the client. Middleware is called in the order it was added, so the first middleware sees
+// requests first and responses last. Responses served from the response cache do not go through the middleware.
+func (c *Client) Use(middleware ...Middleware) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.middleware = append(c.middleware, middleware...)
+
+	var transport http.RoundTripper = RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return c.baseTransport().RoundTrip(req)
+	})
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		transport = c.middleware[i](transport)
+	}
+
+	// Copy the http client rather than modifying it, as it may have been provided by the caller and be in use elsewhere.
+	httpClient := *c.baseClient
+	httpClient.Transport = transport
+	c.client = &httpClient
+}
+
+// baseTransport returns the transport of the http client, before any middleware was applied.
+func (c *Client) baseTransport() http.RoundTripper {
+	if c.baseClient.Transport != nil {
+		return c.baseClient.Transport
+	}
+	return http.DefaultTransport
+}
+
+// newTransport creates a transport private to a client, with the same settings as http.DefaultTransport, so that
+// changes to the transport do not leak into the rest of the program.
+func newTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
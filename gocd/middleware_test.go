@@ -0,0 +1,120 @@
+package gocd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Run("Order", testMiddlewareOrder)
+	t.Run("BeforeRequestFail", testMiddlewareBeforeRequestFail)
+	t.Run("AfterResponseFail", testMiddlewareAfterResponseFail)
+	t.Run("ProvidedClient", testMiddlewareProvidedClient)
+}
+
+func testMiddlewareOrder(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/admin/encrypt", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer mock-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "mock-trace", r.Header.Get("X-Trace-Id"))
+		fmt.Fprint(w, `{"encrypted_value": "mock-value"}`)
+	})
+
+	// Negotiate the server version first, so that only the request under test is observed.
+	_, _, err := client.ServerVersion.Get(context.Background())
+	assert.NoError(t, err)
+
+	calls := []string{}
+	client.Use(
+		BeforeRequest(func(req *http.Request) error {
+			calls = append(calls, "before-1")
+			req.Header.Set("Authorization", "Bearer mock-token")
+			return nil
+		}),
+		AfterResponse(func(req *http.Request, resp *http.Response, err error) error {
+			calls = append(calls, "after-1")
+			return nil
+		}),
+	)
+	client.Use(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			calls = append(calls, "before-2")
+			req.Header.Set("X-Trace-Id", "mock-trace")
+			resp, err := next.RoundTrip(req)
+			calls = append(calls, fmt.Sprintf("after-2 %d", resp.StatusCode))
+			return resp, err
+		})
+	})
+
+	value, _, err := client.Encryption.Encrypt(context.Background(), "value")
+	assert.NoError(t, err)
+	assert.Equal(t, "mock-value", value.EncryptedValue)
+	assert.Equal(t, []string{"before-1", "before-2", "after-2 200", "after-1"}, calls)
+}
+
+func testMiddlewareBeforeRequestFail(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.Use(BeforeRequest(func(req *http.Request) error {
+		return errors.New("mock-error")
+	}))
+
+	_, _, err := client.Encryption.Encrypt(context.Background(), "value")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "mock-error")
+}
+
+func testMiddlewareAfterResponseFail(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/admin/encrypt", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	// Negotiate the server version first, so that only the request under test is observed.
+	_, _, err := client.ServerVersion.Get(context.Background())
+	assert.NoError(t, err)
+
+	statuses := []int{}
+	client.Use(AfterResponse(func(req *http.Request, resp *http.Response, err error) error {
+		statuses = append(statuses, resp.StatusCode)
+		if resp.StatusCode == http.StatusForbidden {
+			return errors.New("mock-forbidden")
+		}
+		return nil
+	}))
+
+	_, _, err = client.Encryption.Encrypt(context.Background(), "value")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "mock-forbidden")
+	assert.Equal(t, []int{http.StatusForbidden}, statuses)
+}
+
+func testMiddlewareProvidedClient(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/admin/encrypt", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "yes", r.Header.Get("X-Middleware"))
+		fmt.Fprint(w, `{"encrypted_value": "mock-value"}`)
+	})
+
+	httpClient := &http.Client{}
+	c := NewClient(&Configuration{Server: server.URL}, httpClient)
+	c.Use(BeforeRequest(func(req *http.Request) error {
+		req.Header.Set("X-Middleware", "yes")
+		return nil
+	}))
+
+	_, _, err := c.Encryption.Encrypt(context.Background(), "value")
+	assert.NoError(t, err)
+	assert.Nil(t, httpClient.Transport, "the provided http client must not be modified")
+}
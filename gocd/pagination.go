@@ -0,0 +1,210 @@
+package gocd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// PageCursor describes the position of a page in a paginated collection. Offset based endpoints use `Offset`, while
+// cursor based endpoints (GoCD >= 19.x) use `After` to walk towards older items and `Before` to walk towards newer
+// items.
+type PageCursor struct {
+	Offset int
+	After  string
+	Before string
+}
+
+// Page describes a single page of items returned by a paginated endpoint. `Next` is nil when there are no more pages.
+type Page struct {
+	Items    []interface{}
+	Next     *PageCursor
+	Response *APIResponse
+}
+
+// PageFetcher retrieves the page at the provided cursor.
+type PageFetcher func(ctx context.Context, cursor *PageCursor) (*Page, error)
+
+// PageIterator lazily walks through the items of a paginated endpoint. Pages are only requested from the server as
+// the iterator advances.
+//
+//	it := gocd.NewPageIterator(fetcher, 0)
+//	for it.Next(ctx) {
+//		fmt.Println(it.Item())
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type PageIterator struct {
+	fetch    PageFetcher
+	cursor   *PageCursor
+	maxItems int
+
+	items []interface{}
+	index int
+	count int
+	done  bool
+	err   error
+	resp  *APIResponse
+}
+
+// NewPageIterator creates an iterator starting at the first page. If `maxItems` is greater than zero, the iterator
+// will stop after that many items.
+func NewPageIterator(fetch PageFetcher, maxItems int) *PageIterator {
+	return NewPageIteratorAt(fetch, &PageCursor{}, maxItems)
+}
+
+// NewPageIteratorAt creates an iterator starting at the provided cursor.
+func NewPageIteratorAt(fetch PageFetcher, cursor *PageCursor, maxItems int) *PageIterator {
+	if cursor == nil {
+		cursor = &PageCursor{}
+	}
+	return &PageIterator{
+		fetch:    fetch,
+		cursor:   cursor,
+		maxItems: maxItems,
+	}
+}
+
+// Next advances the iterator to the next item, requesting the next page from the server if required. It returns false
+// when there are no more items, the item limit has been reached, the context has been cancelled, or an error occurred.
+func (it *PageIterator) Next(ctx context.Context) bool {
+	if it.done {
+		return false
+	}
+	if it.err = ctx.Err(); it.err != nil {
+		return it.stop()
+	}
+	if it.maxItems > 0 && it.count >= it.maxItems {
+		return it.stop()
+	}
+
+	// Skip over any empty pages until we find an item or run out of pages.
+	for it.index >= len(it.items) {
+		if it.cursor == nil {
+			return it.stop()
+		}
+		if !it.fetchPage(ctx) {
+			return it.stop()
+		}
+	}
+
+	it.index++
+	it.count++
+	return true
+}
+
+func (it *PageIterator) fetchPage(ctx context.Context) bool {
+	var page *Page
+	if page, it.err = it.fetch(ctx, it.cursor); it.err != nil {
+		return false
+	}
+	if page == nil {
+		return false
+	}
+
+	it.resp = page.Response
+	it.items = page.Items
+	it.index = 0
+	if len(page.Items) == 0 {
+		// An empty page means there is nothing more to retrieve, whatever the server advertised.
+		it.cursor = nil
+		return false
+	}
+	it.cursor = page.Next
+	return true
+}
+
+func (it *PageIterator) stop() bool {
+	it.done = true
+	it.items = nil
+	it.index = 0
+	return false
+}
+
+// Item returns the item the iterator is currently positioned on.
+func (it *PageIterator) Item() interface{} {
+	if it.index == 0 || it.index > len(it.items) {
+		return nil
+	}
+	return it.items[it.index-1]
+}
+
+// Err returns the first error encountered while iterating, including context cancellation.
+func (it *PageIterator) Err() error {
+	return it.err
+}
+
+// Response returns the last response received from the server.
+func (it *PageIterator) Response() *APIResponse {
+	return it.resp
+}
+
+// nextCursor returns the cursor for the page following the current one. Cursor based links are preferred when the
+// server provides them, otherwise the offset based pagination details are used.
+func nextCursor(cursor *PageCursor, links *HALLinks, pagination *PaginationResponse, count int) *PageCursor {
+	if next := nextLinkCursor(cursor, links); next != nil {
+		return next
+	}
+	if cursor.isCursor() {
+		return nil
+	}
+	return nextOffsetCursor(cursor, pagination, count)
+}
+
+// nextOffsetCursor returns the cursor for the page following the current one for offset based endpoints, or nil if
+// the current page was the last one.
+func nextOffsetCursor(cursor *PageCursor, pagination *PaginationResponse, count int) *PageCursor {
+	if pagination == nil || count == 0 {
+		return nil
+	}
+	offset := cursor.Offset + count
+	if offset >= pagination.Total {
+		return nil
+	}
+	return &PageCursor{Offset: offset}
+}
+
+// nextLinkCursor returns the cursor for the page following the current one for cursor based endpoints, by reading the
+// `after` (or `before` when walking backwards) query parameter from the HAL `next` (or `previous`) link. It returns nil
+// if there is no such link.
+func nextLinkCursor(cursor *PageCursor, links *HALLinks) *PageCursor {
+	if links == nil {
+		return nil
+	}
+
+	name, param := "next", "after"
+	if cursor.Before != "" {
+		name, param = "previous", "before"
+	}
+
+	link := links.Get(name)
+	if link == nil || link.URL == nil {
+		return nil
+	}
+
+	value := link.URL.Query().Get(param)
+	if value == "" {
+		return nil
+	}
+	if param == "before" {
+		return &PageCursor{Before: value}
+	}
+	return &PageCursor{After: value}
+}
+
+// cursorQuery builds the query string used by cursor based endpoints.
+func (pc *PageCursor) cursorQuery() string {
+	if pc.After != "" {
+		return fmt.Sprintf("?after=%s", url.QueryEscape(pc.After))
+	}
+	if pc.Before != "" {
+		return fmt.Sprintf("?before=%s", url.QueryEscape(pc.Before))
+	}
+	return ""
+}
+
+// isCursor returns true if this cursor points to a page of a cursor based endpoint.
+func (pc *PageCursor) isCursor() bool {
+	return pc.After != "" || pc.Before != ""
+}
@@ -0,0 +1,139 @@
+package gocd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestPageIterator(t *testing.T) {
+	t.Run("Offset", testPageIteratorOffset)
+	t.Run("MaxItems", testPageIteratorMaxItems)
+	t.Run("Error", testPageIteratorError)
+	t.Run("Cancelled", testPageIteratorCancelled)
+	t.Run("NextLinkCursor", testPageIteratorNextLinkCursor)
+	t.Run("PipelineHistory", testPageIteratorPipelineHistory)
+}
+
+// offsetFetcher serves `total` integers in pages of `size`, counting the number of requests made.
+func offsetFetcher(total, size int, requests *int) PageFetcher {
+	return func(ctx context.Context, cursor *PageCursor) (*Page, error) {
+		*requests++
+		items := []interface{}{}
+		for i := cursor.Offset; i < cursor.Offset+size && i < total; i++ {
+			items = append(items, i)
+		}
+		return &Page{
+			Items: items,
+			Next:  nextOffsetCursor(cursor, &PaginationResponse{Offset: cursor.Offset, Total: total, PageSize: size}, len(items)),
+		}, nil
+	}
+}
+
+func testPageIteratorOffset(t *testing.T) {
+	requests := 0
+	it := NewPageIterator(offsetFetcher(7, 3, &requests), 0)
+
+	items := []int{}
+	for it.Next(context.Background()) {
+		items = append(items, it.Item().(int))
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6}, items)
+	assert.Equal(t, 3, requests)
+	assert.Nil(t, it.Item())
+	assert.False(t, it.Next(context.Background()))
+}
+
+func testPageIteratorMaxItems(t *testing.T) {
+	requests := 0
+	it := NewPageIterator(offsetFetcher(7, 3, &requests), 3)
+
+	count := 0
+	for it.Next(context.Background()) {
+		count++
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, 3, count)
+	assert.Equal(t, 1, requests)
+}
+
+func testPageIteratorError(t *testing.T) {
+	it := NewPageIterator(func(ctx context.Context, cursor *PageCursor) (*Page, error) {
+		if cursor.Offset > 0 {
+			return nil, errors.New("mock error")
+		}
+		return &Page{Items: []interface{}{"a"}, Next: &PageCursor{Offset: 1}}, nil
+	}, 0)
+
+	count := 0
+	for it.Next(context.Background()) {
+		count++
+	}
+	assert.Equal(t, 1, count)
+	assert.EqualError(t, it.Err(), "mock error")
+}
+
+func testPageIteratorCancelled(t *testing.T) {
+	requests := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	it := NewPageIterator(offsetFetcher(7, 3, &requests), 0)
+	assert.False(t, it.Next(ctx))
+	assert.Equal(t, context.Canceled, it.Err())
+	assert.Equal(t, 0, requests)
+}
+
+func testPageIteratorNextLinkCursor(t *testing.T) {
+	links := &HALLinks{}
+	next, _ := url.Parse("https://ci.example.com/go/api/pipelines/p/history?after=12")
+	previous, _ := url.Parse("https://ci.example.com/go/api/pipelines/p/history?before=20")
+	links.Add(&HALLink{Name: "next", URL: next})
+	links.Add(&HALLink{Name: "previous", URL: previous})
+
+	assert.Equal(t, &PageCursor{After: "12"}, nextLinkCursor(&PageCursor{}, links))
+	assert.Equal(t, &PageCursor{Before: "20"}, nextLinkCursor(&PageCursor{Before: "30"}, links))
+	assert.Nil(t, nextLinkCursor(&PageCursor{}, &HALLinks{}))
+	assert.Nil(t, nextLinkCursor(&PageCursor{}, nil))
+
+	assert.Nil(t, nextCursor(&PageCursor{After: "12"}, &HALLinks{}, &PaginationResponse{Total: 100}, 10))
+	assert.Equal(t, &PageCursor{Offset: 10}, nextCursor(&PageCursor{}, nil, &PaginationResponse{Total: 100}, 10))
+}
+
+func testPageIteratorPipelineHistory(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/pipelines/test-pipeline/history", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method, "Unexpected HTTP method")
+
+		next := ""
+		counter := 3
+		switch r.URL.Query().Get("after") {
+		case "":
+			next = fmt.Sprintf(`, "next": {"href": "%s/api/pipelines/test-pipeline/history?after=2"}`, server.URL)
+		case "2":
+			counter = 1
+		default:
+			t.Errorf("Unexpected cursor '%s'", r.URL.RawQuery)
+		}
+
+		fmt.Fprintf(w, `{
+  "_links": {"self": {"href": "%s/api/pipelines/test-pipeline/history"}%s},
+  "pipelines": [{"name": "test-pipeline", "counter": %d}, {"name": "test-pipeline", "counter": %d}]
+}`, server.URL, next, counter, counter-1)
+	})
+
+	it := client.Pipelines.IterateHistory("test-pipeline", 0)
+	counters := []int{}
+	for it.Next(context.Background()) {
+		counters = append(counters, it.Instance().Counter)
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []int{3, 2, 1, 0}, counters)
+}
@@ -3,10 +3,19 @@ package gocd
 import (
 	"context"
 	"fmt"
-	"github.com/hashicorp/go-version"
 	"net/url"
 )
 
+// Values for Pipeline.LockBehavior
+const (
+	// LockBehaviorLockOnFailure locks the pipeline when a stage fails, until it is unlocked or completes successfully.
+	LockBehaviorLockOnFailure = "lockOnFailure"
+	// LockBehaviorUnlockWhenFinished prevents the pipeline from running more than one instance at a time.
+	LockBehaviorUnlockWhenFinished = "unlockWhenFinished"
+	// LockBehaviorNone never locks the pipeline.
+	LockBehaviorNone = "none"
+)
+
 // PipelinesService describes the HAL _link resource for the api response object for a pipelineconfig
 type PipelinesService service
 
@@ -22,9 +31,9 @@ type Pipeline struct {
 	Links                 *HALLinks              `json:"_links,omitempty"`
 	Group                 string                 `json:"group,omitempty"`                   // Group is only used/set when creating or editing a pipeline config
 	LabelTemplate         string                 `json:"label_template,omitempty"`          // LabelTemplate is available for the pipeline config API since v1 (GoCD >= 15.3.0).
-	EnablePipelineLocking bool                   `json:"enable_pipeline_locking,omitempty"` // EnablePipelineLocking is available for the pipeline config API v1 to v4 (GoCD >= 15.3.0 to GoCD < 17.12.0). Use LockBehavior after that.
+	EnablePipelineLocking bool                   `json:"enable_pipeline_locking,omitempty"` // EnablePipelineLocking is available for the pipeline config API v1 to v4 (GoCD >= 15.3.0 to GoCD < 17.12.0). Use LockBehavior when the server supports CapabilityLockBehavior.
 	Name                  string                 `json:"name"`                              // Name is available for the pipeline config API since v1 (GoCD >= 15.3.0).
-	LockBehavior          string                 `json:"lock_behavior,omitempty"`           // LockBehavior is available for the pipeline config API v5 and v6 only (GoCD >= 17.12.0).
+	LockBehavior          string                 `json:"lock_behavior,omitempty"`           // LockBehavior is available for the pipeline config API since v5 (GoCD >= 17.12.0), see CapabilityLockBehavior.
 	Template              string                 `json:"template,omitempty"`                // Template is available for the pipeline config API since v1 (GoCD >= 15.3.0).
 	Origin                *PipelineConfigOrigin  `json:"origin,omitempty"`                  // Origin is available for the pipeline config API since v3 (GoCD >= 17.4.0).
 	Parameters            []*Parameter           `json:"parameters,omitempty"`              // Parameters is available for the pipeline config API since v1 (GoCD >= 15.3.0).
@@ -87,7 +96,9 @@ type MaterialFilter struct {
 
 // PipelineHistory describes the history of runs for a pipeline
 type PipelineHistory struct {
-	Pipelines []*PipelineInstance `json:"pipelines"`
+	Links      *HALLinks           `json:"_links,omitempty"`
+	Pipelines  []*PipelineInstance `json:"pipelines"`
+	Pagination *PaginationResponse `json:"pagination,omitempty"`
 }
 
 // PipelineInstance describes a single pipeline run
@@ -165,11 +176,25 @@ type pipelineActionRequest struct {
 	RawQuery string
 }
 
-// GetStatus returns a list of pipeline instanves describing the pipeline history.
-func (pgs *PipelinesService) GetStatus(ctx context.Context, name string, offset int) (ps *PipelineStatus, resp *APIResponse, err error) {
+// GetStatus returns whether the pipeline is paused, locked, and schedulable. The offset is ignored.
+//
+// Deprecated: Use Status. The status endpoint describes the pipeline as it is now, rather than its history, so it can
+// not be paginated.
+func (pgs *PipelinesService) GetStatus(ctx context.Context, name string, offset int) (*PipelineStatus, *APIResponse, error) {
+	return pgs.Status(ctx, name)
+}
+
+// Status returns whether the pipeline is paused, locked, and schedulable.
+func (pgs *PipelinesService) Status(ctx context.Context, name string) (ps *PipelineStatus, resp *APIResponse, err error) {
+	apiVersion, err := pgs.client.getAPIVersion(ctx, "pipelines/:pipeline_name/status")
+	if err != nil {
+		return nil, nil, err
+	}
+
 	ps = &PipelineStatus{}
 	_, resp, err = pgs.client.getAction(ctx, &APIClientRequest{
 		Path:         fmt.Sprintf("pipelines/%s/status", name),
+		APIVersion:   apiVersion,
 		ResponseBody: ps,
 	})
 
@@ -199,14 +224,12 @@ func (pgs *PipelinesService) ReleaseLock(ctx context.Context, name string) (bool
 		Pipeline: name,
 	}
 
-	// if version is >= 18.2.0 use "unlock"
-	releaseLockBeforeVersion, _ := version.NewVersion("18.2.0")
-	v, _, err := pgs.client.ServerVersion.Get(context.Background())
+	supportsUnlock, err := pgs.client.Supports(ctx, CapabilityPipelineUnlock)
 	if err != nil {
 		return false, nil, err
 	}
 
-	if v.VersionParts.LessThan(releaseLockBeforeVersion) {
+	if !supportsUnlock {
 		request.Action = "releaseLock"
 	}
 
@@ -232,9 +255,15 @@ func (pgs *PipelinesService) Schedule(ctx context.Context, name string, body *Sc
 // GetInstance of a pipeline run.
 func (pgs *PipelinesService) GetInstance(ctx context.Context, name string, counter int) (pt *PipelineInstance, resp *APIResponse, err error) {
 
+	apiVersion, err := pgs.client.getAPIVersion(ctx, "pipelines/:pipeline_name/instance/:pipeline_counter")
+	if err != nil {
+		return nil, nil, err
+	}
+
 	pt = &PipelineInstance{}
 	_, resp, err = pgs.client.getAction(ctx, &APIClientRequest{
 		Path:         fmt.Sprintf("pipelines/%s/instance/%d", name, counter),
+		APIVersion:   apiVersion,
 		ResponseBody: &pt,
 	})
 
@@ -243,16 +272,76 @@ func (pgs *PipelinesService) GetInstance(ctx context.Context, name string, count
 
 // GetHistory returns a list of pipeline instances describing the pipeline history.
 func (pgs *PipelinesService) GetHistory(ctx context.Context, name string, offset int) (pt *PipelineHistory, resp *APIResponse, err error) {
+	return pgs.GetHistoryPage(ctx, name, &PageCursor{Offset: offset})
+}
+
+// GetHistoryPage returns the page of pipeline instances at the provided cursor. Offset cursors are used for the
+// paginated history of GoCD < 19.x, while `After`/`Before` cursors are used with the cursor based history of newer
+// servers.
+func (pgs *PipelinesService) GetHistoryPage(ctx context.Context, name string, cursor *PageCursor) (pt *PipelineHistory, resp *APIResponse, err error) {
+	if cursor == nil {
+		cursor = &PageCursor{}
+	}
+
+	path := pgs.buildPaginatedStub("pipelines/%s/history", name, cursor.Offset)
+	if cursor.isCursor() {
+		path = fmt.Sprintf("pipelines/%s/history%s", name, cursor.cursorQuery())
+	}
+
+	apiVersion, err := pgs.client.getAPIVersionForPath(ctx, "/api/"+path)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	pt = &PipelineHistory{}
 	_, resp, err = pgs.client.getAction(ctx, &APIClientRequest{
-		Path:         pgs.buildPaginatedStub("pipelines/%s/history", name, offset),
+		Path:         path,
+		APIVersion:   apiVersion,
 		ResponseBody: &pt,
 	})
 
 	return
 }
 
+// PipelineHistoryIterator walks through every page of the history of a pipeline, most recent instance first.
+type PipelineHistoryIterator struct {
+	*PageIterator
+}
+
+// IterateHistory creates an iterator over the instances of the pipeline. If `maxItems` is greater than zero, the
+// iterator will stop after that many instances.
+func (pgs *PipelinesService) IterateHistory(name string, maxItems int) *PipelineHistoryIterator {
+	return pgs.IterateHistoryAt(name, &PageCursor{}, maxItems)
+}
+
+// IterateHistoryAt creates an iterator over the instances of the pipeline, starting at the provided cursor.
+func (pgs *PipelinesService) IterateHistoryAt(name string, cursor *PageCursor, maxItems int) *PipelineHistoryIterator {
+	return &PipelineHistoryIterator{
+		PageIterator: NewPageIteratorAt(func(ctx context.Context, cursor *PageCursor) (*Page, error) {
+			history, resp, err := pgs.GetHistoryPage(ctx, name, cursor)
+			if err != nil {
+				return nil, err
+			}
+
+			items := make([]interface{}, len(history.Pipelines))
+			for i, instance := range history.Pipelines {
+				items[i] = instance
+			}
+			return &Page{
+				Items:    items,
+				Next:     nextCursor(cursor, history.Links, history.Pagination, len(items)),
+				Response: resp,
+			}, nil
+		}, cursor, maxItems),
+	}
+}
+
+// Instance returns the pipeline instance the iterator is currently positioned on.
+func (it *PipelineHistoryIterator) Instance() *PipelineInstance {
+	instance, _ := it.Item().(*PipelineInstance)
+	return instance
+}
+
 func (pgs *PipelinesService) pipelineAction(ctx context.Context, request *pipelineActionRequest) (bool, *APIResponse, error) {
 
 	apiVersion, err := pgs.client.getAPIVersion(ctx, fmt.Sprintf("pipelines/:pipeline_name/%s", request.Action))
@@ -0,0 +1,475 @@
+package gocd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PipelineBuilder assembles a Pipeline, or a PipelineTemplate, with chained calls. Stages are added to the pipeline,
+// jobs to the last stage, and tasks to the last job:
+//
+//	p, err := gocd.NewPipeline("my-pipeline").
+//		Group("my-group").
+//		Git("https://github.com/gocd/gocd", "master").
+//		Stage("build").
+//		Job("compile").Timeout(10).Exec("make", "all").
+//		Job("test").Exec("make", "test").
+//		Stage("deploy").Manual().
+//		Job("deploy").Exec("./deploy.sh").
+//		Build()
+//
+// Each call is checked as it is made. The first mistake is returned by Build or BuildTemplate, and the calls made after
+// it are ignored.
+type PipelineBuilder struct {
+	pipeline *Pipeline
+	template bool
+	err      error
+
+	stage *Stage
+	job   *Job
+	task  *Task
+}
+
+// NewPipeline starts building a pipeline config, emitted with Build.
+func NewPipeline(name string) *PipelineBuilder {
+	b := &PipelineBuilder{pipeline: &Pipeline{Name: name}}
+	b.checkName("NewPipeline", "pipeline", name)
+	return b
+}
+
+// NewTemplate starts building a pipeline template, emitted with BuildTemplate. Templates only hold stages, so the
+// settings of pipelines, such as materials, can not be used.
+func NewTemplate(name string) *PipelineBuilder {
+	b := &PipelineBuilder{pipeline: &Pipeline{Name: name}, template: true}
+	b.checkName("NewTemplate", "template", name)
+	return b
+}
+
+// Build the pipeline config. It is validated with Pipeline.Validate, so that it is ready to be created.
+func (b *PipelineBuilder) Build() (*Pipeline, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if b.template {
+		return nil, fmt.Errorf("template '%s' must be built with BuildTemplate", b.pipeline.Name)
+	}
+	if err := b.pipeline.Validate(); err != nil {
+		return nil, err
+	}
+	return b.pipeline, nil
+}
+
+// BuildTemplate builds the pipeline template. It is validated with PipelineTemplate.Validate, so that it is ready to be
+// created.
+func (b *PipelineBuilder) BuildTemplate() (*PipelineTemplate, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if !b.template {
+		return nil, fmt.Errorf("pipeline '%s' must be built with Build", b.pipeline.Name)
+	}
+	template := &PipelineTemplate{Name: b.pipeline.Name, Stages: b.pipeline.Stages}
+	if err := template.Validate(); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// Group sets the pipeline group the pipeline is created in.
+func (b *PipelineBuilder) Group(group string) *PipelineBuilder {
+	if b.pipelineSetting("Group") {
+		b.pipeline.Group = group
+	}
+	return b
+}
+
+// LabelTemplate sets the label given to each run of the pipeline, eg: `1.${COUNT}`.
+func (b *PipelineBuilder) LabelTemplate(labelTemplate string) *PipelineBuilder {
+	if b.pipelineSetting("LabelTemplate") {
+		b.pipeline.LabelTemplate = labelTemplate
+	}
+	return b
+}
+
+// LockBehavior sets one of LockBehaviorLockOnFailure, LockBehaviorUnlockWhenFinished, or LockBehaviorNone.
+func (b *PipelineBuilder) LockBehavior(lockBehavior string) *PipelineBuilder {
+	if !b.pipelineSetting("LockBehavior") {
+		return b
+	}
+	switch lockBehavior {
+	case LockBehaviorLockOnFailure, LockBehaviorUnlockWhenFinished, LockBehaviorNone:
+		b.pipeline.LockBehavior = lockBehavior
+	default:
+		b.fail("LockBehavior", "unknown lock behavior '%s'", lockBehavior)
+	}
+	return b
+}
+
+// Timer schedules the pipeline with a Quartz cron expression, eg: `0 0 22 ? * MON-FRI`.
+func (b *PipelineBuilder) Timer(spec string, onlyOnChanges bool) *PipelineBuilder {
+	if !b.pipelineSetting("Timer") {
+		return b
+	}
+	timer := &Timer{Spec: spec, OnlyOnChanges: onlyOnChanges}
+	if err := timer.Validate(); err != nil {
+		b.fail("Timer", "%s", err)
+		return b
+	}
+	b.pipeline.Timer = timer
+	return b
+}
+
+// Parameter adds a parameter to the pipeline, which is referred to as `#{name}` in its config.
+func (b *PipelineBuilder) Parameter(name string, value string) *PipelineBuilder {
+	if !b.pipelineSetting("Parameter") {
+		return b
+	}
+	for _, param := range b.pipeline.Parameters {
+		if param.Name == name {
+			b.fail("Parameter", "parameter '%s' is already set", name)
+			return b
+		}
+	}
+	b.pipeline.Parameters = append(b.pipeline.Parameters, &Parameter{Name: name, Value: value})
+	return b
+}
+
+// UseTemplate makes the pipeline use the stages of a template, rather than its own.
+func (b *PipelineBuilder) UseTemplate(template string) *PipelineBuilder {
+	if !b.pipelineSetting("UseTemplate") {
+		return b
+	}
+	if len(b.pipeline.Stages) > 0 {
+		b.fail("UseTemplate", "pipeline '%s' already has stages", b.pipeline.Name)
+		return b
+	}
+	b.pipeline.Template = template
+	return b
+}
+
+// Git adds a git material. The branch defaults to master when empty.
+func (b *PipelineBuilder) Git(url string, branch string) *PipelineBuilder {
+	return b.material("Git", Material{
+		Type:       "git",
+		Attributes: &MaterialAttributesGit{URL: url, Branch: branch, AutoUpdate: true},
+	})
+}
+
+// DependsOn adds a dependency material, triggering the pipeline when the stage of the upstream pipeline passes.
+func (b *PipelineBuilder) DependsOn(pipeline string, stage string) *PipelineBuilder {
+	return b.material("DependsOn", Material{
+		Type:       "dependency",
+		Attributes: &MaterialAttributesDependency{Pipeline: pipeline, Stage: stage, AutoUpdate: true},
+	})
+}
+
+// Material adds a material of any type. Its attributes must be set.
+func (b *PipelineBuilder) Material(m Material) *PipelineBuilder {
+	return b.material("Material", m)
+}
+
+func (b *PipelineBuilder) material(call string, m Material) *PipelineBuilder {
+	if !b.pipelineSetting(call) {
+		return b
+	}
+	if m.Attributes == nil {
+		b.fail(call, "'%s' material has no attributes", m.Type)
+		return b
+	}
+	for i := range b.pipeline.Materials {
+		if isEqual, err := b.pipeline.Materials[i].Equal(&m); err == nil && isEqual {
+			b.fail(call, "material '%s' is already used", materialLabel(m))
+			return b
+		}
+	}
+	b.pipeline.Materials = append(b.pipeline.Materials, m)
+	return b
+}
+
+// Env sets an environment variable on the last job, or on the last stage if it has no job yet, or on the pipeline
+// if it has no stage yet.
+func (b *PipelineBuilder) Env(name string, value string) *PipelineBuilder {
+	return b.env("Env", &EnvironmentVariable{Name: name, Value: value})
+}
+
+// SecureEnv sets a secure environment variable, in the same place Env would. The value is encrypted by the server.
+func (b *PipelineBuilder) SecureEnv(name string, value string) *PipelineBuilder {
+	return b.env("SecureEnv", &EnvironmentVariable{Name: name, Value: value, Secure: true})
+}
+
+func (b *PipelineBuilder) env(call string, env *EnvironmentVariable) *PipelineBuilder {
+	if b.err != nil {
+		return b
+	}
+	if env.Name == "" {
+		b.fail(call, "environment variable name must not be empty")
+		return b
+	}
+
+	var envs *[]*EnvironmentVariable
+	switch {
+	case b.job != nil:
+		envs = &b.job.EnvironmentVariables
+	case b.stage != nil:
+		envs = &b.stage.EnvironmentVariables
+	case b.template:
+		b.fail(call, "environment variables must be set on a stage or job of a template")
+		return b
+	default:
+		envs = &b.pipeline.EnvironmentVariables
+	}
+
+	for _, existing := range *envs {
+		if existing.Name == env.Name {
+			b.fail(call, "environment variable '%s' is already set", env.Name)
+			return b
+		}
+	}
+	*envs = append(*envs, env)
+	return b
+}
+
+// Stage adds a stage, which runs when the previous stage passes. The jobs added next are part of it.
+func (b *PipelineBuilder) Stage(name string) *PipelineBuilder {
+	if b.err != nil || !b.checkName("Stage", "stage", name) {
+		return b
+	}
+	if b.pipeline.Template != "" {
+		b.fail("Stage", "pipeline '%s' uses template '%s'", b.pipeline.Name, b.pipeline.Template)
+		return b
+	}
+	for _, stage := range b.pipeline.Stages {
+		if strings.EqualFold(stage.Name, name) {
+			b.fail("Stage", "stage '%s' already exists", name)
+			return b
+		}
+	}
+
+	b.stage = &Stage{
+		Name:           name,
+		FetchMaterials: true,
+		Approval: &Approval{
+			Type:          "success",
+			Authorization: &Authorization{Users: []string{}, Roles: []string{}},
+		},
+	}
+	b.job, b.task = nil, nil
+	b.pipeline.Stages = append(b.pipeline.Stages, b.stage)
+	return b
+}
+
+// Manual makes the last stage wait to be triggered by a user, rather than running when the previous stage passes.
+func (b *PipelineBuilder) Manual() *PipelineBuilder {
+	if b.inStage("Manual") {
+		b.stage.Approval.Type = "manual"
+	}
+	return b
+}
+
+// CleanWorkingDirectory makes the last stage delete the files left in the working directory by its previous runs.
+func (b *PipelineBuilder) CleanWorkingDirectory() *PipelineBuilder {
+	if b.inStage("CleanWorkingDirectory") {
+		b.stage.CleanWorkingDirectory = true
+	}
+	return b
+}
+
+// Job adds a job to the last stage. The tasks added next are part of it.
+func (b *PipelineBuilder) Job(name string) *PipelineBuilder {
+	if !b.inStage("Job") || !b.checkName("Job", "job", name) {
+		return b
+	}
+	for _, job := range b.stage.Jobs {
+		if strings.EqualFold(job.Name, name) {
+			b.fail("Job", "job '%s' already exists in stage '%s'", name, b.stage.Name)
+			return b
+		}
+	}
+
+	b.job = &Job{Name: name}
+	b.task = nil
+	b.stage.Jobs = append(b.stage.Jobs, b.job)
+	return b
+}
+
+// Timeout cancels the last job if it has not completed after a number of minutes.
+func (b *PipelineBuilder) Timeout(minutes int) *PipelineBuilder {
+	if !b.inJob("Timeout") {
+		return b
+	}
+	if minutes < 0 {
+		b.fail("Timeout", "timeout must not be negative")
+		return b
+	}
+	b.job.Timeout = TimeoutField(minutes)
+	return b
+}
+
+// Resources restricts the last job to the agents having all of these resources.
+func (b *PipelineBuilder) Resources(resources ...string) *PipelineBuilder {
+	if b.inJob("Resources") {
+		b.job.Resources = append(b.job.Resources, resources...)
+	}
+	return b
+}
+
+// ElasticProfile runs the last job on an elastic agent created from the profile.
+func (b *PipelineBuilder) ElasticProfile(id string) *PipelineBuilder {
+	if b.inJob("ElasticProfile") {
+		b.job.ElasticProfileID = id
+	}
+	return b
+}
+
+// Artifact publishes files of the last job, from a source path in its working directory.
+func (b *PipelineBuilder) Artifact(source string, destination string) *PipelineBuilder {
+	if !b.inJob("Artifact") {
+		return b
+	}
+	if source == "" {
+		b.fail("Artifact", "artifact source must not be empty")
+		return b
+	}
+	b.job.Artifacts = append(b.job.Artifacts, &Artifact{Type: "build", Source: source, Destination: destination})
+	return b
+}
+
+// Exec adds a task running a command to the last job.
+func (b *PipelineBuilder) Exec(command string, args ...string) *PipelineBuilder {
+	if command == "" {
+		return b.fail("Exec", "'command' must not be empty")
+	}
+	return b.addTask("Exec", &Task{
+		Type:       "exec",
+		Attributes: &TaskAttributesExec{RunIf: []string{"passed"}, Command: command, Arguments: args},
+	})
+}
+
+// Fetch adds a task retrieving an artifact stored by GoCD from a job of an upstream pipeline, or of this pipeline if
+// the pipeline is empty, to the last job.
+func (b *PipelineBuilder) Fetch(pipeline string, stage string, job string, source string) *PipelineBuilder {
+	return b.addTask("Fetch", &Task{
+		Type: "fetch",
+		Attributes: &TaskAttributesFetch{
+			RunIf:    []string{"passed"},
+			Pipeline: pipeline,
+			Stage:    stage,
+			Job:      job,
+			Source:   source,
+		},
+	})
+}
+
+// Task adds a task of any type to the last job. It is checked with Task.Validate.
+func (b *PipelineBuilder) Task(task *Task) *PipelineBuilder {
+	if b.err != nil {
+		return b
+	}
+	if task == nil {
+		return b.fail("Task", "task must not be nil")
+	}
+	if err := task.Validate(); err != nil {
+		return b.fail("Task", "%s", err)
+	}
+	return b.addTask("Task", task)
+}
+
+// RunIf sets the results of the previous tasks, 'passed', 'failed' or 'any', the last task runs on.
+func (b *PipelineBuilder) RunIf(conditions ...string) *PipelineBuilder {
+	if b.err != nil {
+		return b
+	}
+	if b.task == nil {
+		return b.fail("RunIf", "no task has been added to job '%s'", b.jobName())
+	}
+	if err := validateRunIf(conditions); err != nil {
+		return b.fail("RunIf", "%s", err)
+	}
+
+	switch a := b.task.Attributes.(type) {
+	case *TaskAttributesExec:
+		a.RunIf = conditions
+	case *TaskAttributesAnt:
+		a.RunIf = conditions
+	case *TaskAttributesNant:
+		a.RunIf = conditions
+	case *TaskAttributesRake:
+		a.RunIf = conditions
+	case *TaskAttributesFetch:
+		a.RunIf = conditions
+	case *TaskAttributesPluggable:
+		a.RunIf = conditions
+	}
+	return b
+}
+
+func (b *PipelineBuilder) addTask(call string, task *Task) *PipelineBuilder {
+	if !b.inJob(call) {
+		return b
+	}
+	if err := task.validateRunIf(); err != nil {
+		return b.fail(call, "%s", err)
+	}
+	b.task = task
+	b.job.Tasks = append(b.job.Tasks, task)
+	return b
+}
+
+// fail records the first mistake made with the builder.
+func (b *PipelineBuilder) fail(call string, format string, a ...interface{}) *PipelineBuilder {
+	if b.err == nil {
+		b.err = fmt.Errorf("%s: %s", call, fmt.Sprintf(format, a...))
+	}
+	return b
+}
+
+func (b *PipelineBuilder) checkName(call string, kind string, name string) bool {
+	probs := problems{}
+	probs.name(kind, name)
+	if len(probs) > 0 {
+		b.fail(call, "%s", probs[0])
+		return false
+	}
+	return true
+}
+
+// pipelineSetting checks a setting of pipelines is not used with a template.
+func (b *PipelineBuilder) pipelineSetting(call string) bool {
+	if b.err != nil {
+		return false
+	}
+	if b.template {
+		b.fail(call, "templates only hold stages, and can not be given pipeline settings")
+		return false
+	}
+	return true
+}
+
+func (b *PipelineBuilder) inStage(call string) bool {
+	if b.err != nil {
+		return false
+	}
+	if b.stage == nil {
+		b.fail(call, "no stage has been added to '%s'", b.pipeline.Name)
+		return false
+	}
+	return true
+}
+
+func (b *PipelineBuilder) inJob(call string) bool {
+	if !b.inStage(call) {
+		return false
+	}
+	if b.job == nil {
+		b.fail(call, "no job has been added to stage '%s'", b.stage.Name)
+		return false
+	}
+	return true
+}
+
+func (b *PipelineBuilder) jobName() string {
+	if b.job == nil {
+		return ""
+	}
+	return b.job.Name
+}
@@ -0,0 +1,235 @@
+package gocd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipelineBuilder(t *testing.T) {
+	t.Run("Pipeline", testPipelineBuilderPipeline)
+	t.Run("Template", testPipelineBuilderTemplate)
+	t.Run("UseTemplate", testPipelineBuilderUseTemplate)
+	t.Run("EnvironmentVariables", testPipelineBuilderEnvironmentVariables)
+	t.Run("RunIf", testPipelineBuilderRunIf)
+	t.Run("FirstError", testPipelineBuilderFirstError)
+	t.Run("Mistakes", testPipelineBuilderMistakes)
+	t.Run("Validate", testPipelineBuilderValidate)
+	t.Run("FanIn", testPipelineBuilderFanIn)
+}
+
+func testPipelineBuilderPipeline(t *testing.T) {
+	p, err := NewPipeline("my-pipeline").
+		Group("my-group").
+		LabelTemplate("1.${COUNT}").
+		LockBehavior(LockBehaviorUnlockWhenFinished).
+		Timer("0 0 22 ? * MON-FRI", true).
+		Parameter("env", "dev").
+		Git("https://github.com/gocd/gocd", "master").
+		DependsOn("upstream", "build").
+		Stage("build").CleanWorkingDirectory().
+		Job("compile").Timeout(10).Resources("linux", "go").Artifact("bin/", "dist").
+		Exec("make", "all").
+		Job("lint").ElasticProfile("docker").
+		Exec("make", "lint").
+		Stage("deploy").Manual().
+		Job("deploy").
+		Fetch("", "build", "compile", "dist/").
+		Exec("./deploy.sh").
+		Build()
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Pipeline{
+		Name:          "my-pipeline",
+		Group:         "my-group",
+		LabelTemplate: "1.${COUNT}",
+		LockBehavior:  LockBehaviorUnlockWhenFinished,
+		Timer:         &Timer{Spec: "0 0 22 ? * MON-FRI", OnlyOnChanges: true},
+		Parameters:    []*Parameter{{Name: "env", Value: "dev"}},
+		Materials: []Material{{
+			Type:       "git",
+			Attributes: &MaterialAttributesGit{URL: "https://github.com/gocd/gocd", Branch: "master", AutoUpdate: true},
+		}, {
+			Type:       "dependency",
+			Attributes: &MaterialAttributesDependency{Pipeline: "upstream", Stage: "build", AutoUpdate: true},
+		}},
+		Stages: []*Stage{{
+			Name:                  "build",
+			FetchMaterials:        true,
+			CleanWorkingDirectory: true,
+			Approval: &Approval{
+				Type:          "success",
+				Authorization: &Authorization{Users: []string{}, Roles: []string{}},
+			},
+			Jobs: []*Job{{
+				Name:      "compile",
+				Timeout:   10,
+				Resources: []string{"linux", "go"},
+				Artifacts: []*Artifact{{Type: "build", Source: "bin/", Destination: "dist"}},
+				Tasks: []*Task{{
+					Type:       "exec",
+					Attributes: &TaskAttributesExec{RunIf: []string{"passed"}, Command: "make", Arguments: []string{"all"}},
+				}},
+			}, {
+				Name:             "lint",
+				ElasticProfileID: "docker",
+				Tasks: []*Task{{
+					Type:       "exec",
+					Attributes: &TaskAttributesExec{RunIf: []string{"passed"}, Command: "make", Arguments: []string{"lint"}},
+				}},
+			}},
+		}, {
+			Name:           "deploy",
+			FetchMaterials: true,
+			Approval: &Approval{
+				Type:          "manual",
+				Authorization: &Authorization{Users: []string{}, Roles: []string{}},
+			},
+			Jobs: []*Job{{
+				Name: "deploy",
+				Tasks: []*Task{{
+					Type: "fetch",
+					Attributes: &TaskAttributesFetch{
+						RunIf:  []string{"passed"},
+						Stage:  "build",
+						Job:    "compile",
+						Source: "dist/",
+					},
+				}, {
+					Type:       "exec",
+					Attributes: &TaskAttributesExec{RunIf: []string{"passed"}, Command: "./deploy.sh"},
+				}},
+			}},
+		}},
+	}, p)
+
+	_, err = NewPipeline("my-pipeline").Git("https://github.com/gocd/gocd", "").Stage("build").Job("a").BuildTemplate()
+	assert.EqualError(t, err, "pipeline 'my-pipeline' must be built with Build")
+}
+
+func testPipelineBuilderTemplate(t *testing.T) {
+	pt, err := NewTemplate("my-template").
+		Stage("build").
+		Job("compile").Env("GOOS", "linux").Exec("make").
+		BuildTemplate()
+	assert.NoError(t, err)
+	assert.Equal(t, "my-template", pt.Name)
+	assert.Len(t, pt.Stages, 1)
+	assert.Equal(t, []*EnvironmentVariable{{Name: "GOOS", Value: "linux"}}, pt.Stages[0].Jobs[0].EnvironmentVariables)
+
+	_, err = NewTemplate("my-template").Stage("build").Job("compile").Build()
+	assert.EqualError(t, err, "template 'my-template' must be built with BuildTemplate")
+
+	_, err = NewTemplate("my-template").Git("https://github.com/gocd/gocd", "").BuildTemplate()
+	assert.EqualError(t, err, "Git: templates only hold stages, and can not be given pipeline settings")
+
+	_, err = NewTemplate("my-template").Env("GOOS", "linux").BuildTemplate()
+	assert.EqualError(t, err, "Env: environment variables must be set on a stage or job of a template")
+
+	_, err = NewTemplate("my-template").BuildTemplate()
+	assert.EqualError(t, err, "template 'my-template' is invalid: template must have at least one stage")
+}
+
+func testPipelineBuilderUseTemplate(t *testing.T) {
+	p, err := NewPipeline("my-pipeline").Git("https://github.com/gocd/gocd", "").UseTemplate("my-template").Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "my-template", p.Template)
+	assert.Empty(t, p.Stages)
+
+	_, err = NewPipeline("my-pipeline").UseTemplate("my-template").Stage("build").Build()
+	assert.EqualError(t, err, "Stage: pipeline 'my-pipeline' uses template 'my-template'")
+
+	_, err = NewPipeline("my-pipeline").Stage("build").UseTemplate("my-template").Build()
+	assert.EqualError(t, err, "UseTemplate: pipeline 'my-pipeline' already has stages")
+}
+
+func testPipelineBuilderEnvironmentVariables(t *testing.T) {
+	b := NewPipeline("my-pipeline").
+		Git("https://github.com/gocd/gocd", "").
+		Env("SCOPE", "pipeline").
+		SecureEnv("TOKEN", "secret").
+		Stage("build").Env("SCOPE", "stage").
+		Job("compile").Env("SCOPE", "job").Exec("make")
+	p, err := b.Build()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []*EnvironmentVariable{
+		{Name: "SCOPE", Value: "pipeline"},
+		{Name: "TOKEN", Value: "secret", Secure: true},
+	}, p.EnvironmentVariables)
+	assert.Equal(t, []*EnvironmentVariable{{Name: "SCOPE", Value: "stage"}}, p.Stages[0].EnvironmentVariables)
+	assert.Equal(t, []*EnvironmentVariable{{Name: "SCOPE", Value: "job"}}, p.Stages[0].Jobs[0].EnvironmentVariables)
+
+	_, err = b.Env("SCOPE", "again").Build()
+	assert.EqualError(t, err, "Env: environment variable 'SCOPE' is already set")
+}
+
+func testPipelineBuilderRunIf(t *testing.T) {
+	p, err := NewPipeline("my-pipeline").
+		Git("https://github.com/gocd/gocd", "").
+		Stage("build").
+		Job("compile").Exec("make").Exec("cleanup").RunIf("failed", "passed").
+		Build()
+	assert.NoError(t, err)
+	tasks := p.Stages[0].Jobs[0].Tasks
+	assert.Equal(t, []string{"passed"}, tasks[0].Attributes.(*TaskAttributesExec).RunIf)
+	assert.Equal(t, []string{"failed", "passed"}, tasks[1].Attributes.(*TaskAttributesExec).RunIf)
+
+	_, err = NewPipeline("my-pipeline").Stage("build").Job("compile").RunIf("any").Build()
+	assert.EqualError(t, err, "RunIf: no task has been added to job 'compile'")
+
+	_, err = NewPipeline("my-pipeline").Stage("build").Job("compile").Exec("make").RunIf("always").Build()
+	assert.EqualError(t, err, "RunIf: 'run_if' must be one of 'passed', 'failed' or 'any', not 'always'")
+}
+
+func testPipelineBuilderFirstError(t *testing.T) {
+	b := NewPipeline("my pipeline").Stage("").Job("compile")
+	_, err := b.Build()
+	assert.EqualError(t, err, "NewPipeline: pipeline name 'my pipeline' must only contain letters, numbers, underscores, hyphens and periods, must not start with a period, and must be at most 255 characters long")
+	assert.Empty(t, b.pipeline.Stages, "calls made after a mistake are ignored")
+}
+
+func testPipelineBuilderMistakes(t *testing.T) {
+	for want, b := range map[string]*PipelineBuilder{
+		"Job: no stage has been added to 'p'":              NewPipeline("p").Job("compile"),
+		"Exec: no job has been added to stage 'build'":     NewPipeline("p").Stage("build").Exec("make"),
+		"Exec: 'command' must not be empty":                NewPipeline("p").Stage("build").Job("a").Exec(""),
+		"Manual: no stage has been added to 'p'":           NewPipeline("p").Manual(),
+		"Stage: stage 'Build' already exists":              NewPipeline("p").Stage("build").Stage("Build"),
+		"Stage: stage name must not be empty":              NewPipeline("p").Stage(""),
+		"Job: job 'a' already exists in stage 'build'":     NewPipeline("p").Stage("build").Job("a").Job("a"),
+		"Timeout: timeout must not be negative":            NewPipeline("p").Stage("build").Job("a").Timeout(-1),
+		"Artifact: artifact source must not be empty":      NewPipeline("p").Stage("build").Job("a").Artifact("", "dist"),
+		"LockBehavior: unknown lock behavior 'sometimes'":  NewPipeline("p").LockBehavior("sometimes"),
+		"Parameter: parameter 'env' is already set":        NewPipeline("p").Parameter("env", "a").Parameter("env", "b"),
+		"Material: 'svn' material has no attributes":       NewPipeline("p").Material(Material{Type: "svn"}),
+		"Task: task must not be nil":                       NewPipeline("p").Stage("build").Job("a").Task(nil),
+		"Task: Missing `gocd.TaskAttribute` type":          NewPipeline("p").Stage("build").Job("a").Task(&Task{}),
+		"Env: environment variable name must not be empty": NewPipeline("p").Env("", "value"),
+		"Git: material 'git https://github.com/gocd/gocd' is already used": NewPipeline("p").
+			Git("https://github.com/gocd/gocd", "").Git("https://github.com/gocd/gocd", "master"),
+		"Timer: timer spec '0 0 22 * *' must have 6 or 7 fields, not 5": NewPipeline("p").Timer("0 0 22 * *", false),
+	} {
+		_, err := b.Build()
+		assert.EqualError(t, err, want)
+	}
+}
+
+func testPipelineBuilderValidate(t *testing.T) {
+	_, err := NewPipeline("my-pipeline").Stage("build").Job("compile").Exec("make").Build()
+	assert.EqualError(t, err, "pipeline 'my-pipeline' is invalid: pipeline must have at least one material")
+
+	_, err = NewPipeline("my-pipeline").Git("https://github.com/gocd/gocd", "").Stage("build").Build()
+	assert.EqualError(t, err, "pipeline 'my-pipeline' is invalid: stage 'build' must have at least one job")
+}
+
+func testPipelineBuilderFanIn(t *testing.T) {
+	p, err := NewPipeline("deploy").
+		DependsOn("build-a", "build").
+		DependsOn("build-b", "build").
+		Stage("deploy").Job("deploy").Exec("./deploy.sh").
+		Build()
+	assert.NoError(t, err)
+	assert.Len(t, p.Materials, 2)
+	assert.NoError(t, p.Validate())
+}
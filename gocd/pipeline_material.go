@@ -15,6 +15,10 @@ type MaterialAttributesGit struct {
 	URL    string `json:"url,omitempty"`
 	Branch string `json:"branch,omitempty"`
 
+	Username          string `json:"username,omitempty"`
+	Password          string `json:"password,omitempty"`
+	EncryptedPassword string `json:"encrypted_password,omitempty"`
+
 	SubmoduleFolder string `json:"submodule_folder,omitempty"`
 	ShallowClone    bool   `json:"shallow_clone,omitempty"`
 
@@ -99,10 +103,11 @@ type MaterialAttributesTfs struct {
 
 // MaterialAttributesDependency describes a Pipeline dependency material
 type MaterialAttributesDependency struct {
-	Name       string `json:"name,omitempty"`
-	Pipeline   string `json:"pipeline"`
-	Stage      string `json:"stage"`
-	AutoUpdate bool   `json:"auto_update,omitempty"`
+	Name                string `json:"name,omitempty"`
+	Pipeline            string `json:"pipeline"`
+	Stage               string `json:"stage"`
+	AutoUpdate          bool   `json:"auto_update,omitempty"`
+	IgnoreForScheduling bool   `json:"ignore_for_scheduling,omitempty"`
 }
 
 // MaterialAttributesPackage describes a package reference
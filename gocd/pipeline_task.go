@@ -0,0 +1,76 @@
+package gocd
+
+// TaskAttribute describes the behaviour of the GoCD task structures for a job. The structure used for a task is chosen
+// by the task's type when it is unmarshalled. Tasks of every type can have an `OnCancel` task, run when the job is
+// cancelled while the task runs.
+type TaskAttribute interface {
+	Validate() error
+}
+
+// TaskAttributesExec describes an `exec` task, running a command.
+type TaskAttributesExec struct {
+	RunIf            []string `json:"run_if,omitempty"`
+	Command          string   `json:"command,omitempty"`
+	Arguments        []string `json:"arguments,omitempty"`
+	WorkingDirectory string   `json:"working_directory,omitempty"`
+	OnCancel         *Task    `json:"on_cancel,omitempty"`
+}
+
+// TaskAttributesAnt describes an `ant` task.
+type TaskAttributesAnt struct {
+	RunIf            []string `json:"run_if,omitempty"`
+	BuildFile        string   `json:"build_file,omitempty"`
+	Target           string   `json:"target,omitempty"`
+	WorkingDirectory string   `json:"working_directory,omitempty"`
+	OnCancel         *Task    `json:"on_cancel,omitempty"`
+}
+
+// TaskAttributesNant describes a `nant` task.
+type TaskAttributesNant struct {
+	RunIf            []string `json:"run_if,omitempty"`
+	BuildFile        string   `json:"build_file,omitempty"`
+	Target           string   `json:"target,omitempty"`
+	WorkingDirectory string   `json:"working_directory,omitempty"`
+	NantPath         string   `json:"nant_path,omitempty"`
+	OnCancel         *Task    `json:"on_cancel,omitempty"`
+}
+
+// TaskAttributesRake describes a `rake` task.
+type TaskAttributesRake struct {
+	RunIf            []string `json:"run_if,omitempty"`
+	BuildFile        string   `json:"build_file,omitempty"`
+	Target           string   `json:"target,omitempty"`
+	WorkingDirectory string   `json:"working_directory,omitempty"`
+	OnCancel         *Task    `json:"on_cancel,omitempty"`
+}
+
+// TaskAttributesFetch describes a `fetch` task, retrieving an artifact from an upstream job. Artifacts stored by GoCD
+// are identified by `Source`, while artifacts stored by an artifact plugin (`ArtifactOrigin` "external") are identified
+// by `ArtifactID` and `Configuration`.
+// codebeat:disable[TOO_MANY_IVARS]
+type TaskAttributesFetch struct {
+	ArtifactOrigin string   `json:"artifact_origin,omitempty"`
+	RunIf          []string `json:"run_if,omitempty"`
+	Pipeline       string   `json:"pipeline,omitempty"`
+	Stage          string   `json:"stage,omitempty"`
+	Job            string   `json:"job,omitempty"`
+
+	Source        string `json:"source,omitempty"`
+	IsSourceAFile bool   `json:"is_source_a_file,omitempty"`
+	Destination   string `json:"destination,omitempty"`
+
+	ArtifactID    string                      `json:"artifact_id,omitempty"`
+	Configuration []PluginConfigurationKVPair `json:"configuration,omitempty"`
+
+	OnCancel *Task `json:"on_cancel,omitempty"`
+}
+
+// codebeat:enable[TOO_MANY_IVARS]
+
+// TaskAttributesPluggable describes a `pluggable_task`, provided by a task plugin.
+type TaskAttributesPluggable struct {
+	RunIf               []string                    `json:"run_if,omitempty"`
+	PluginConfiguration *TaskPluginConfiguration    `json:"plugin_configuration,omitempty"`
+	Configuration       []PluginConfigurationKVPair `json:"configuration,omitempty"`
+	OnCancel            *Task                       `json:"on_cancel,omitempty"`
+}
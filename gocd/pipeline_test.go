@@ -170,12 +170,34 @@ func TestPipelineServiceScheduleWithBodyForUnversionnedAPI(t *testing.T) {
 		},
 	}
 
+	// Negotiate the server version first, so that only the request under test is observed.
+	_, _, err := client.ServerVersion.Get(context.Background())
+	assert.NoError(t, err)
+	logger := &recordingLogger{}
+	client.SetLogger(logger)
+	tracer := &mockTracer{}
+	client.Instrument(&Instrumentation{Tracer: tracer})
+
 	result, _, err := client.Pipelines.Schedule(context.Background(), "test-pipeline", body)
 	if err != nil {
 		t.Error(err)
 	}
 
 	assert.True(t, result)
+
+	assert.Contains(t, logger.fields[0]["Path"], "variables%5BUSERNAME%5D=gocd")
+	assert.Contains(t, logger.fields[0]["Path"], "secure_variables%5BSSH_PASSPHRASE%5D="+redacted)
+	if assert.Len(t, tracer.spans, 1) {
+		assert.Contains(t, tracer.spans[0].attributes["http.url"], "secure_variables%5BSSH_PASSPHRASE%5D="+redacted)
+	}
+	for _, fields := range logger.fields {
+		for _, value := range fields {
+			assert.NotContains(t, fmt.Sprint(value), "some+passphrase")
+		}
+	}
+	for _, value := range tracer.spans[0].attributes {
+		assert.NotContains(t, value, "some+passphrase")
+	}
 }
 
 func testPipelineStageContainer(t *testing.T) {
@@ -221,7 +243,7 @@ func testPipelineServiceGetStatus(t *testing.T) {
 		fmt.Fprint(w, string(b))
 	})
 
-	ps, _, err := client.Pipelines.GetStatus(context.Background(), "test-pipeline", 0)
+	ps, _, err := client.Pipelines.Status(context.Background(), "test-pipeline")
 	if err != nil {
 		t.Error(err)
 	}
@@ -230,6 +252,10 @@ func testPipelineServiceGetStatus(t *testing.T) {
 	assert.False(t, ps.Locked)
 	assert.True(t, ps.Paused)
 	assert.False(t, ps.Schedulable)
+
+	deprecated, _, err := client.Pipelines.GetStatus(context.Background(), "test-pipeline", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, ps, deprecated)
 }
 
 func testPipelineServiceCreateDelete(t *testing.T) {
@@ -271,7 +297,7 @@ func testPipelineServiceCreateDelete(t *testing.T) {
 						Tasks: []*Task{
 							{
 								Type: "exec",
-								Attributes: TaskAttributes{
+								Attributes: &TaskAttributesExec{
 									RunIf:   []string{"passed"},
 									Command: "ls",
 								},
@@ -429,3 +455,42 @@ func testChoosePipelineConfirmHeader(t *testing.T) {
 		})
 	}
 }
+
+func TestPipelineServiceReleaseLock(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		versionFile string
+		action      string
+	}{
+		{
+			name:        "16.10.0",
+			versionFile: "test/resources/version.1.json",
+			action:      "releaseLock",
+		},
+		{
+			name:        "18.2.0",
+			versionFile: "test/resources/version.2.json",
+			action:      "unlock",
+		},
+	} {
+
+		t.Run(tt.name, func(t *testing.T) {
+			setup()
+			defer teardown()
+
+			mux.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+				j, _ := ioutil.ReadFile(tt.versionFile)
+				fmt.Fprint(w, string(j))
+			})
+
+			mux.HandleFunc("/api/pipelines/test-pipeline/"+tt.action, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, r.Method, "POST", "Unexpected HTTP method")
+				fmt.Fprint(w, string([]byte(`{"message" : "Pipeline lock released for test-pipeline"}`)))
+			})
+
+			result, _, err := client.Pipelines.ReleaseLock(context.Background(), "test-pipeline")
+			assert.NoError(t, err)
+			assert.True(t, result)
+		})
+	}
+}
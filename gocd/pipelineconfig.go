@@ -32,19 +32,30 @@ func (pcs *PipelineConfigsService) Get(ctx context.Context, name string) (p *Pip
 	return
 }
 
-// Update a pipeline configuration
+// Update a pipeline configuration. The pipeline is adapted to the negotiated API version, eg: `LockBehavior` is sent as
+// `enable_pipeline_locking` to GoCD < 17.12.0, and an error is returned if it uses settings the server can not express.
+// The pipeline is validated first if the client was set to ValidateBeforeWrite.
 func (pcs *PipelineConfigsService) Update(ctx context.Context, name string, p *Pipeline) (pr *Pipeline, resp *APIResponse, err error) {
 
+	if err = pcs.validate(p); err != nil {
+		return nil, nil, err
+	}
+
 	apiVersion, err := pcs.client.getAPIVersion(ctx, "admin/pipelines/:pipeline_name")
 	if err != nil {
 		return nil, nil, err
 	}
 
+	body, err := pcs.adaptPipeline(p, apiVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	pr = &Pipeline{}
 	_, resp, err = pcs.client.putAction(ctx, &APIClientRequest{
 		Path:         "admin/pipelines/" + name,
 		APIVersion:   apiVersion,
-		RequestBody:  p,
+		RequestBody:  body,
 		ResponseBody: pr,
 	})
 
@@ -53,21 +64,30 @@ func (pcs *PipelineConfigsService) Update(ctx context.Context, name string, p *P
 	return
 }
 
-// Create a pipeline configuration
+// Create a pipeline configuration. The pipeline is adapted to the negotiated API version, as it is by Update.
 func (pcs *PipelineConfigsService) Create(ctx context.Context, group string, p *Pipeline) (pr *Pipeline, resp *APIResponse, err error) {
 
+	if err = pcs.validate(p); err != nil {
+		return nil, nil, err
+	}
+
 	apiVersion, err := pcs.client.getAPIVersion(ctx, "admin/pipelines/:pipeline_name")
 	if err != nil {
 		return nil, nil, err
 	}
 
+	body, err := pcs.adaptPipeline(p, apiVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	pr = &Pipeline{}
 	_, resp, err = pcs.client.postAction(ctx, &APIClientRequest{
 		Path:       "admin/pipelines",
 		APIVersion: apiVersion,
 		RequestBody: &PipelineConfigRequest{
 			Group:    group,
-			Pipeline: p,
+			Pipeline: body,
 		},
 		ResponseBody: pr,
 	})
@@ -86,3 +106,23 @@ func (pcs *PipelineConfigsService) Delete(ctx context.Context, name string) (str
 
 	return pcs.client.deleteAction(ctx, "admin/pipelines/"+name, apiVersion)
 }
+
+// validate the pipeline if the client was set to ValidateBeforeWrite.
+func (pcs *PipelineConfigsService) validate(p *Pipeline) error {
+	if !pcs.client.validatesBeforeWrite() {
+		return nil
+	}
+	return p.Validate()
+}
+
+// adaptPipeline converts the pipeline to the representation expected by the API version, and logs any warnings.
+func (pcs *PipelineConfigsService) adaptPipeline(p *Pipeline, apiVersion string) (*Pipeline, error) {
+	adapted, warnings, err := p.forAPIVersion(apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range warnings {
+		pcs.client.log().Warn(warning, "pipeline", p.Name, "api_version", apiVersion)
+	}
+	return adapted, nil
+}
@@ -65,12 +65,12 @@ func TestPipelineConfig(t *testing.T) {
 	}
 	v, _, err := client.ServerVersion.Get(ctx)
 
-	var ta TaskAttributes
+	var ta *TaskAttributesFetch
 
 	artifactOriginAdded, _ := version.NewVersion("18.7.0")
 
 	if v.VersionParts.LessThan(artifactOriginAdded) {
-		ta = TaskAttributes{
+		ta = &TaskAttributesFetch{
 			RunIf:         []string{"passed"},
 			Pipeline:      "upstream",
 			Stage:         "upstream_stage",
@@ -80,7 +80,7 @@ func TestPipelineConfig(t *testing.T) {
 			Destination:   "test",
 		}
 	} else {
-		ta = TaskAttributes{
+		ta = &TaskAttributesFetch{
 			ArtifactOrigin: "gocd",
 			RunIf:          []string{"passed"},
 			Pipeline:       "upstream",
@@ -134,7 +134,7 @@ func TestPipelineConfig(t *testing.T) {
 					Attributes: ta,
 				}, {
 					Type: "exec",
-					Attributes: TaskAttributes{
+					Attributes: &TaskAttributesExec{
 						RunIf:   []string{"passed"},
 						Command: "ls",
 					},
@@ -194,7 +194,7 @@ func buildUpstreamPipelineStages() []*Stage {
 			Name: "upstream_job",
 			Tasks: []*Task{{
 				Type: "exec",
-				Attributes: TaskAttributes{
+				Attributes: &TaskAttributesExec{
 					RunIf:   []string{"passed"},
 					Command: "ls",
 				},
@@ -221,7 +221,7 @@ func buildMockPipelineStages() []*Stage {
 			Name: "defaultJob",
 			Tasks: []*Task{{
 				Type: "exec",
-				Attributes: TaskAttributes{
+				Attributes: &TaskAttributesExec{
 					RunIf:   []string{"passed"},
 					Command: "ls",
 				},
@@ -248,7 +248,7 @@ func buildMockPipelineStagesWithFetch() []*Stage {
 			Name: "defaultJob",
 			Tasks: []*Task{{
 				Type: "fetch",
-				Attributes: TaskAttributes{
+				Attributes: &TaskAttributesFetch{
 					ArtifactOrigin: "gocd",
 					RunIf:          []string{"passed"},
 					Pipeline:       "upstream",
@@ -260,7 +260,7 @@ func buildMockPipelineStagesWithFetch() []*Stage {
 				},
 			}, {
 				Type: "exec",
-				Attributes: TaskAttributes{
+				Attributes: &TaskAttributesExec{
 					RunIf:   []string{"passed"},
 					Command: "ls",
 				},
@@ -17,9 +17,15 @@ type PipelineGroup struct {
 // List Pipeline groups
 func (pgs *PipelineGroupsService) List(ctx context.Context, name string) (*PipelineGroups, *APIResponse, error) {
 
+	apiVersion, err := pgs.client.getAPIVersion(ctx, "config/pipeline_groups")
+	if err != nil {
+		return nil, nil, err
+	}
+
 	pg := []*PipelineGroup{}
 	_, resp, err := pgs.client.getAction(ctx, &APIClientRequest{
 		Path:         "config/pipeline_groups",
+		APIVersion:   apiVersion,
 		ResponseType: responseTypeJSON,
 		ResponseBody: &pg,
 	})
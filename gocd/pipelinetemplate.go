@@ -81,8 +81,13 @@ func (pts *PipelineTemplatesService) List(ctx context.Context) (pt []*PipelineTe
 	return
 }
 
-// Create a new PipelineTemplate object in the GoCD API.
+// Create a new PipelineTemplate object in the GoCD API. The template is validated first if the client was set to
+// ValidateBeforeWrite.
 func (pts *PipelineTemplatesService) Create(ctx context.Context, name string, st []*Stage) (ptr *PipelineTemplate, resp *APIResponse, err error) {
+	if err = pts.validate(&PipelineTemplate{Name: name, Stages: st}); err != nil {
+		return nil, nil, err
+	}
+
 	apiVersion, err := pts.client.getAPIVersion(ctx, "admin/templates")
 	if err != nil {
 		return nil, nil, err
@@ -105,8 +110,13 @@ func (pts *PipelineTemplatesService) Create(ctx context.Context, name string, st
 
 }
 
-// Update an PipelineTemplate object in the GoCD API.
+// Update an PipelineTemplate object in the GoCD API. The template is validated first if the client was set to
+// ValidateBeforeWrite.
 func (pts *PipelineTemplatesService) Update(ctx context.Context, name string, template *PipelineTemplate) (ptr *PipelineTemplate, resp *APIResponse, err error) {
+	if err = pts.validate(&PipelineTemplate{Name: name, Stages: template.Stages}); err != nil {
+		return nil, nil, err
+	}
+
 	apiVersion, err := pts.client.getAPIVersion(ctx, "admin/templates/:template_name")
 	if err != nil {
 		return nil, nil, err
@@ -137,3 +147,11 @@ func (pts *PipelineTemplatesService) Delete(ctx context.Context, name string) (s
 
 	return pts.client.deleteAction(ctx, fmt.Sprintf("admin/templates/%s", name), apiVersion)
 }
+
+// validate the template if the client was set to ValidateBeforeWrite.
+func (pts *PipelineTemplatesService) validate(pt *PipelineTemplate) error {
+	if !pts.client.validatesBeforeWrite() {
+		return nil
+	}
+	return pt.Validate()
+}
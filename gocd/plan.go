@@ -0,0 +1,387 @@
+package gocd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Actions of a PlanChange
+const (
+	PlanChangeCreate = "create"
+	PlanChangeUpdate = "update"
+	PlanChangeDelete = "delete"
+	PlanChangeSkip   = "skip"
+)
+
+// Plan lists the changes reconciling the pipelines and templates of a server with their desired state, as planned by
+// Client.PlanPipelines, to be made with Client.ApplyPlan.
+type Plan struct {
+	Changes []*PlanChange `json:"changes"`
+	// Unchanged is the number of pipelines and templates which already match their desired state.
+	Unchanged int `json:"unchanged"`
+}
+
+// PlanChange describes a change of a Plan.
+type PlanChange struct {
+	// Kind of the object, `template` or `pipeline`.
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Group is the group pipelines are created in.
+	Group string `json:"group,omitempty"`
+	// Action is one of PlanChangeCreate, PlanChangeUpdate, PlanChangeDelete, or PlanChangeSkip.
+	Action string `json:"action"`
+	// Reason explains why an object is skipped.
+	Reason string `json:"reason,omitempty"`
+	// Diff lists the changes made by an update. Templates are compared as pipelines made of their stages.
+	Diff []*PipelineChange `json:"diff,omitempty"`
+
+	// The desired pipeline or template. Updates carry the version they were planned against.
+	pipeline *Pipeline
+	template *PipelineTemplate
+}
+
+// String formats the change on a single line, followed by the lines of its diff, eg:
+//
+//	~ update pipeline 'my-pipeline'
+//	    ~ label_template: "${COUNT}" -> "1.${COUNT}"
+func (pc *PlanChange) String() string {
+	var line string
+	switch pc.Action {
+	case PlanChangeCreate:
+		line = fmt.Sprintf("+ create %s '%s'", pc.Kind, pc.Name)
+		if pc.Group != "" {
+			line += fmt.Sprintf(" in group '%s'", pc.Group)
+		}
+	case PlanChangeUpdate:
+		line = fmt.Sprintf("~ update %s '%s'", pc.Kind, pc.Name)
+	case PlanChangeDelete:
+		line = fmt.Sprintf("- delete %s '%s'", pc.Kind, pc.Name)
+	default:
+		line = fmt.Sprintf("  %s %s '%s': %s", pc.Action, pc.Kind, pc.Name, pc.Reason)
+	}
+
+	lines := []string{line}
+	for _, change := range pc.Diff {
+		lines = append(lines, "    "+change.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// String formats the changes of the plan, followed by the number of each action.
+func (p *Plan) String() string {
+	lines := []string{}
+	counts := map[string]int{}
+	for _, change := range p.Changes {
+		lines = append(lines, change.String())
+		counts[change.Action]++
+	}
+	lines = append(lines, fmt.Sprintf("Plan: %d to create, %d to update, %d to delete, %d skipped, %d unchanged.",
+		counts[PlanChangeCreate], counts[PlanChangeUpdate], counts[PlanChangeDelete], counts[PlanChangeSkip], p.Unchanged))
+	return strings.Join(lines, "\n")
+}
+
+// HasChanges reports whether applying the plan would change the server.
+func (p *Plan) HasChanges() bool {
+	for _, change := range p.Changes {
+		if change.Action != PlanChangeSkip {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanPipelines compares the desired pipelines and templates with those of the server, and plans the changes turning
+// the server into the desired state. Desired pipelines must have their Group set to be created.
+//
+// Pipelines defined in config repos are managed by their config repo, so they are skipped rather than updated, and
+// never deleted. Pipelines can not be moved between groups through the API, so pipelines in another group than the
+// desired one are skipped too. With prune, the pipelines and templates of the server missing from the desired state
+// are deleted, except for pipelines upstream of the pipelines which are kept, and templates which remain in use.
+func (c *Client) PlanPipelines(ctx context.Context, templates []*PipelineTemplate, pipelines []*Pipeline, prune bool) (*Plan, error) {
+	pp := &pipelinePlanner{client: c, prune: prune, plan: &Plan{}}
+	if err := pp.templates(ctx, templates); err != nil {
+		return nil, err
+	}
+	if err := pp.pipelines(ctx, pipelines); err != nil {
+		return nil, err
+	}
+	if prune {
+		pp.pruneTemplates(templates, pipelines)
+	}
+	return pp.plan, nil
+}
+
+type pipelinePlanner struct {
+	client *Client
+	prune  bool
+	plan   *Plan
+	// serverTemplates are the templates of the server, with the pipelines using them.
+	serverTemplates []*PipelineTemplate
+	// deleted are the names of the pipelines deleted by the plan.
+	deleted map[string]bool
+}
+
+func (pp *pipelinePlanner) add(change *PlanChange) {
+	pp.plan.Changes = append(pp.plan.Changes, change)
+}
+
+// codebeat:disable[ABC,CYCLO]
+func (pp *pipelinePlanner) templates(ctx context.Context, templates []*PipelineTemplate) error {
+	if len(templates) == 0 {
+		// Templates only need to be listed to prune them, and servers without templates have none to prune.
+		if !pp.prune {
+			return nil
+		}
+		if supported, err := pp.client.Supports(ctx, CapabilityTemplates); !supported || err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if pp.serverTemplates, _, err = pp.client.PipelineTemplates.List(ctx); err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, template := range pp.serverTemplates {
+		existing[template.Name] = true
+	}
+
+	for _, template := range templates {
+		if !existing[template.Name] {
+			pp.add(&PlanChange{Kind: "template", Name: template.Name, Action: PlanChangeCreate, template: template})
+			continue
+		}
+
+		current, _, err := pp.client.PipelineTemplates.Get(ctx, template.Name)
+		if err != nil {
+			return err
+		}
+		diff, err := DiffPipelines(&Pipeline{Stages: current.Stages}, &Pipeline{Stages: template.Stages})
+		if err != nil {
+			return fmt.Errorf("template '%s': %s", template.Name, err)
+		}
+		if len(diff) == 0 {
+			pp.plan.Unchanged++
+			continue
+		}
+		pp.add(&PlanChange{
+			Kind:     "template",
+			Name:     template.Name,
+			Action:   PlanChangeUpdate,
+			Diff:     diff,
+			template: &PipelineTemplate{Name: template.Name, Stages: template.Stages, Version: current.Version},
+		})
+	}
+	return nil
+}
+
+func (pp *pipelinePlanner) pipelines(ctx context.Context, pipelines []*Pipeline) error {
+	sorted, err := SortPipelinesByDependency(pipelines)
+	if err != nil {
+		return err
+	}
+	groups, _, err := pp.client.PipelineGroups.List(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	for _, p := range sorted {
+		group := groups.GetGroupByPipelineName(p.Name)
+		if group == nil {
+			if p.Group == "" {
+				return fmt.Errorf("pipeline '%s' must have a group to be created", p.Name)
+			}
+			pp.add(&PlanChange{Kind: "pipeline", Name: p.Name, Group: p.Group, Action: PlanChangeCreate, pipeline: p})
+			continue
+		}
+		if err = pp.pipeline(ctx, p, group.Name); err != nil {
+			return err
+		}
+	}
+
+	if pp.prune {
+		return pp.prunePipelines(ctx, groups, sorted)
+	}
+	return nil
+}
+
+// codebeat:enable[ABC,CYCLO]
+
+func (pp *pipelinePlanner) pipeline(ctx context.Context, p *Pipeline, group string) error {
+	current, _, err := pp.client.PipelineConfigs.Get(ctx, p.Name)
+	if err != nil {
+		return err
+	}
+	skip := &PlanChange{Kind: "pipeline", Name: p.Name, Action: PlanChangeSkip}
+	if current.DefinedInConfigRepo() {
+		skip.Reason = "it is defined in a config repo"
+		pp.add(skip)
+		return nil
+	}
+	if p.Group != "" && p.Group != group {
+		skip.Reason = fmt.Sprintf("it is in group '%s' rather than '%s', and can not be moved", group, p.Group)
+		pp.add(skip)
+		return nil
+	}
+
+	diff, err := DiffPipelines(current, p)
+	if err != nil {
+		return fmt.Errorf("pipeline '%s': %s", p.Name, err)
+	}
+	if len(diff) == 0 {
+		pp.plan.Unchanged++
+		return nil
+	}
+	desired := *p
+	desired.Version = current.Version
+	pp.add(&PlanChange{Kind: "pipeline", Name: p.Name, Action: PlanChangeUpdate, Diff: diff, pipeline: &desired})
+	return nil
+}
+
+// prunePipelines deletes the pipelines missing from the desired state, the pipelines depending on others first.
+// Pipelines which remain upstream of a pipeline which is kept, such as a desired pipeline or one defined in a config
+// repo, are skipped, as GoCD refuses to delete them.
+// codebeat:disable[ABC,CYCLO]
+func (pp *pipelinePlanner) prunePipelines(ctx context.Context, groups *PipelineGroups, pipelines []*Pipeline) error {
+	desired := map[string]bool{}
+	downstream := map[string]map[string]bool{}
+	keep := func(p *Pipeline) {
+		for _, upstream := range upstreamPipelines(p) {
+			if downstream[upstream] == nil {
+				downstream[upstream] = map[string]bool{}
+			}
+			downstream[upstream][p.Name] = true
+		}
+	}
+	for _, p := range pipelines {
+		desired[p.Name] = true
+		keep(p)
+	}
+
+	var missing []*Pipeline
+	for _, group := range *groups {
+		for _, summary := range group.Pipelines {
+			if desired[summary.Name] {
+				continue
+			}
+			p, _, err := pp.client.PipelineConfigs.Get(ctx, summary.Name)
+			if err != nil {
+				return err
+			}
+			if p.DefinedInConfigRepo() {
+				keep(p)
+			} else {
+				missing = append(missing, p)
+			}
+		}
+	}
+
+	sorted, err := SortPipelinesByDependency(missing)
+	if err != nil {
+		return err
+	}
+	pp.deleted = map[string]bool{}
+	for i := len(sorted) - 1; i >= 0; i-- {
+		p := sorted[i]
+		if users := downstream[p.Name]; len(users) > 0 {
+			names := make([]string, 0, len(users))
+			for name := range users {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			pp.add(&PlanChange{Kind: "pipeline", Name: p.Name, Action: PlanChangeSkip,
+				Reason: fmt.Sprintf("it is upstream of pipeline(s) '%s'", strings.Join(names, "', '"))})
+			keep(p)
+			continue
+		}
+		pp.deleted[p.Name] = true
+		pp.add(&PlanChange{Kind: "pipeline", Name: p.Name, Action: PlanChangeDelete})
+	}
+	return nil
+}
+
+// codebeat:enable[ABC,CYCLO]
+
+// pruneTemplates deletes the templates missing from the desired state, unless pipelines still use them.
+func (pp *pipelinePlanner) pruneTemplates(templates []*PipelineTemplate, pipelines []*Pipeline) {
+	desired := map[string]bool{}
+	for _, template := range templates {
+		desired[template.Name] = true
+	}
+
+	for _, template := range pp.serverTemplates {
+		if desired[template.Name] {
+			continue
+		}
+		users := map[string]bool{}
+		if template.Embedded != nil {
+			for _, p := range template.Embedded.Pipelines {
+				users[p.Name] = !pp.deleted[p.Name]
+			}
+		}
+		for _, p := range pipelines {
+			users[p.Name] = users[p.Name] || p.Template == template.Name
+		}
+
+		var used []string
+		for name, uses := range users {
+			if uses {
+				used = append(used, name)
+			}
+		}
+		if len(used) > 0 {
+			sort.Strings(used)
+			pp.add(&PlanChange{Kind: "template", Name: template.Name, Action: PlanChangeSkip,
+				Reason: fmt.Sprintf("it is used by pipeline(s) '%s'", strings.Join(used, "', '"))})
+			continue
+		}
+		pp.add(&PlanChange{Kind: "template", Name: template.Name, Action: PlanChangeDelete})
+	}
+}
+
+// ApplyPlan makes the changes of a plan, in the order they were planned. Updates are made against the version of the
+// pipeline or template the plan was made from, so they fail if it changed on the server since. Applying stops at the
+// first change the server refuses, after the changes before it were made.
+func (c *Client) ApplyPlan(ctx context.Context, plan *Plan) error {
+	for _, change := range plan.Changes {
+		var err error
+		switch {
+		case change.Action == PlanChangeSkip:
+			continue
+		case change.Kind == "template":
+			err = c.applyTemplateChange(ctx, change)
+		default:
+			err = c.applyPipelineChange(ctx, change)
+		}
+		if err != nil {
+			return fmt.Errorf("could not %s %s '%s': %s", change.Action, change.Kind, change.Name, err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) applyTemplateChange(ctx context.Context, change *PlanChange) (err error) {
+	switch change.Action {
+	case PlanChangeCreate:
+		_, _, err = c.PipelineTemplates.Create(ctx, change.Name, change.template.Stages)
+	case PlanChangeUpdate:
+		_, _, err = c.PipelineTemplates.Update(ctx, change.Name, change.template)
+	case PlanChangeDelete:
+		_, _, err = c.PipelineTemplates.Delete(ctx, change.Name)
+	}
+	return err
+}
+
+func (c *Client) applyPipelineChange(ctx context.Context, change *PlanChange) (err error) {
+	switch change.Action {
+	case PlanChangeCreate:
+		_, _, err = c.PipelineConfigs.Create(ctx, change.Group, change.pipeline)
+	case PlanChangeUpdate:
+		_, _, err = c.PipelineConfigs.Update(ctx, change.Name, change.pipeline)
+	case PlanChangeDelete:
+		_, _, err = c.PipelineConfigs.Delete(ctx, change.Name)
+	}
+	return err
+}
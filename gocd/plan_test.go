@@ -0,0 +1,198 @@
+package gocd_test
+
+import (
+	"context"
+	"github.com/beamly/go-gocd/gocd"
+	"github.com/beamly/go-gocd/gocdtest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	t.Run("PlanApply", testPlanApply)
+	t.Run("Prune", testPlanPrune)
+	t.Run("PruneUpstream", testPlanPruneUpstream)
+	t.Run("StaleVersion", testPlanStaleVersion)
+}
+
+// desiredPipeline is a pipeline of the desired state, in a group.
+func desiredPipeline(group string, name string, upstream ...string) *gocd.Pipeline {
+	p := snapshotPipeline(name, upstream...)
+	p.Group = group
+	return p
+}
+
+func testPlanApply(t *testing.T) {
+	ctx := context.Background()
+	server := gocdtest.NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	_, _, err := client.PipelineTemplates.Create(ctx, "build-template", snapshotPipeline("template").Stages)
+	assert.NoError(t, err)
+	_, _, err = client.PipelineConfigs.Create(ctx, "build", snapshotPipeline("app"))
+	assert.NoError(t, err)
+	_, _, err = client.PipelineConfigs.Create(ctx, "build", snapshotPipeline("moved"))
+	assert.NoError(t, err)
+	_, _, err = client.PipelineConfigs.Create(ctx, "build", snapshotPipeline("same"))
+	assert.NoError(t, err)
+	remote := snapshotPipeline("remote")
+	remote.Origin = &gocd.PipelineConfigOrigin{Type: "config_repo", File: "my-repo"}
+	server.AddPipeline("build", remote)
+
+	template := &gocd.PipelineTemplate{Name: "build-template", Stages: snapshotPipeline("template").Stages}
+	template.Stages[0].Jobs[0].Timeout = 10
+	app := desiredPipeline("build", "app")
+	app.LabelTemplate = "1.${COUNT}"
+	remoteChanged := desiredPipeline("build", "remote")
+	remoteChanged.LabelTemplate = "1.${COUNT}"
+	templates := []*gocd.PipelineTemplate{template}
+	pipelines := []*gocd.Pipeline{
+		desiredPipeline("deploy", "release", "app"),
+		app,
+		desiredPipeline("deploy", "moved"),
+		desiredPipeline("build", "same"),
+		remoteChanged,
+	}
+
+	plan, err := client.PlanPipelines(ctx, templates, pipelines, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "~ update template 'build-template'\n"+
+		"    ~ stages[build].jobs[compile].timeout: null -> 10\n"+
+		"~ update pipeline 'app'\n"+
+		"    ~ label_template: null -> \"1.${COUNT}\"\n"+
+		"+ create pipeline 'release' in group 'deploy'\n"+
+		"  skip pipeline 'moved': it is in group 'build' rather than 'deploy', and can not be moved\n"+
+		"  skip pipeline 'remote': it is defined in a config repo\n"+
+		"Plan: 1 to create, 2 to update, 0 to delete, 2 skipped, 1 unchanged.", plan.String())
+	assert.True(t, plan.HasChanges())
+
+	if !assert.NoError(t, client.ApplyPlan(ctx, plan)) {
+		return
+	}
+	updated, _, err := client.PipelineConfigs.Get(ctx, "app")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.${COUNT}", updated.LabelTemplate)
+	created, _, err := client.PipelineConfigs.Get(ctx, "release")
+	assert.NoError(t, err)
+	assert.Equal(t, "deploy", created.Group)
+	unchanged, _, err := client.PipelineConfigs.Get(ctx, "remote")
+	assert.NoError(t, err)
+	assert.Empty(t, unchanged.LabelTemplate)
+
+	plan, err = client.PlanPipelines(ctx, templates, pipelines, false)
+	assert.NoError(t, err)
+	assert.False(t, plan.HasChanges())
+	assert.Equal(t, 4, plan.Unchanged)
+}
+
+func testPlanPrune(t *testing.T) {
+	ctx := context.Background()
+	server := gocdtest.NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	for _, name := range []string{"unused-template", "remote-template", "old-template"} {
+		_, _, err := client.PipelineTemplates.Create(ctx, name, snapshotPipeline("template").Stages)
+		assert.NoError(t, err)
+	}
+	_, _, err := client.PipelineConfigs.Create(ctx, "build", snapshotPipeline("app"))
+	assert.NoError(t, err)
+	old := snapshotPipeline("old")
+	old.Stages, old.Template = nil, "old-template"
+	_, _, err = client.PipelineConfigs.Create(ctx, "build", old)
+	assert.NoError(t, err)
+	_, _, err = client.PipelineConfigs.Create(ctx, "build", snapshotPipeline("old-downstream", "old"))
+	assert.NoError(t, err)
+	remote := snapshotPipeline("remote")
+	remote.Stages, remote.Template = nil, "remote-template"
+	remote.Origin = &gocd.PipelineConfigOrigin{Type: "config_repo", File: "my-repo"}
+	server.AddPipeline("build", remote)
+
+	plan, err := client.PlanPipelines(ctx, nil, []*gocd.Pipeline{desiredPipeline("build", "app")}, true)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "- delete pipeline 'old-downstream'\n"+
+		"- delete pipeline 'old'\n"+
+		"- delete template 'old-template'\n"+
+		"  skip template 'remote-template': it is used by pipeline(s) 'remote'\n"+
+		"- delete template 'unused-template'\n"+
+		"Plan: 0 to create, 0 to update, 4 to delete, 1 skipped, 1 unchanged.", plan.String())
+
+	if !assert.NoError(t, client.ApplyPlan(ctx, plan)) {
+		return
+	}
+	groups, _, err := client.PipelineGroups.List(ctx, "")
+	assert.NoError(t, err)
+	var names []string
+	for _, p := range (*groups)[0].Pipelines {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"app", "remote"}, names)
+	templates, _, err := client.PipelineTemplates.List(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, templates, 1) {
+		assert.Equal(t, "remote-template", templates[0].Name)
+	}
+}
+
+func testPlanPruneUpstream(t *testing.T) {
+	ctx := context.Background()
+	server := gocdtest.NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	for _, p := range []*gocd.Pipeline{
+		snapshotPipeline("base"),
+		snapshotPipeline("lib", "base"),
+		snapshotPipeline("app", "lib"),
+		snapshotPipeline("remote-upstream"),
+		snapshotPipeline("unused"),
+	} {
+		_, _, err := client.PipelineConfigs.Create(ctx, "build", p)
+		assert.NoError(t, err)
+	}
+	remote := snapshotPipeline("remote", "remote-upstream")
+	remote.Origin = &gocd.PipelineConfigOrigin{Type: "config_repo", File: "my-repo"}
+	server.AddPipeline("build", remote)
+
+	plan, err := client.PlanPipelines(ctx, nil, []*gocd.Pipeline{desiredPipeline("build", "app", "lib")}, true)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "- delete pipeline 'unused'\n"+
+		"  skip pipeline 'remote-upstream': it is upstream of pipeline(s) 'remote'\n"+
+		"  skip pipeline 'lib': it is upstream of pipeline(s) 'app'\n"+
+		"  skip pipeline 'base': it is upstream of pipeline(s) 'lib'\n"+
+		"Plan: 0 to create, 0 to update, 1 to delete, 3 skipped, 1 unchanged.", plan.String())
+	assert.NoError(t, client.ApplyPlan(ctx, plan))
+}
+
+func testPlanStaleVersion(t *testing.T) {
+	ctx := context.Background()
+	server := gocdtest.NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	_, _, err := client.PipelineConfigs.Create(ctx, "build", snapshotPipeline("app"))
+	assert.NoError(t, err)
+	app := desiredPipeline("build", "app")
+	app.LabelTemplate = "1.${COUNT}"
+	plan, err := client.PlanPipelines(ctx, nil, []*gocd.Pipeline{app}, false)
+	assert.NoError(t, err)
+
+	current, _, err := client.PipelineConfigs.Get(ctx, "app")
+	assert.NoError(t, err)
+	current.LabelTemplate = "2.${COUNT}"
+	_, _, err = client.PipelineConfigs.Update(ctx, "app", current)
+	assert.NoError(t, err)
+
+	err = client.ApplyPlan(ctx, plan)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "could not update pipeline 'app'")
+		assert.Contains(t, err.Error(), "Someone has modified the configuration for pipeline 'app'.")
+	}
+}
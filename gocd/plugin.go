@@ -21,16 +21,16 @@ type PluginsResponse struct {
 type Plugin struct {
 	Links                     *HALLinks                 `json:"_links"`
 	ID                        string                    `json:"id"`
-	Name                      string                    `json:"name,omitempty"`                        // Name is available for the plugin API v1 and v2 only (GoCD >= 16.7.0 to < 17.9.0).
+	Name                      string                    `json:"name,omitempty"`                        // Name is returned by the plugin API v1 and v2 only (GoCD >= 16.7.0 to < 17.9.0), and filled from About.Name for later versions.
 	DisplayName               string                    `json:"display_name,omitempty"`                // DisplayName is available for the plugin API v1 and v2 only (GoCD >= 16.7.0 to < 17.9.0).
-	Version                   string                    `json:"version,omitempty"`                     // Version is available for the plugin API v1 and v2 only (GoCD >= 16.7.0 to < 17.9.0).
-	Type                      string                    `json:"type,omitempty"`                        // Type is available for the plugin API v1, v2 and v3 only (GoCD >= 16.7.0 to < 18.3.0). Can be one of `authentication`, `notification`, `package-repository`, `task`, `scm`.
+	Version                   string                    `json:"version,omitempty"`                     // Version is returned by the plugin API v1 and v2 only (GoCD >= 16.7.0 to < 17.9.0), and filled from About.Version for later versions.
+	Type                      string                    `json:"type,omitempty"`                        // Type is available for the plugin API v1, v2 and v3 only (GoCD >= 16.7.0 to < 18.3.0). Can be one of `authentication`, `notification`, `package-repository`, `task`, `scm`. Filled from Extensions for later versions, for plugins with a single extension.
 	PluggableInstanceSettings PluggableInstanceSettings `json:"pluggable_instance_settings,omitempty"` // PluggableInstanceSettings is available for the plugin API v1 and v2 only (GoCD >= 16.7.0 to < 17.9.0).
 	Image                     PluginIcon                `json:"image,omitempty"`                       // Image is available for the plugin API v2 only (GoCD >= 16.12.0 to < 17.9.0).
 	Status                    PluginStatus              `json:"status,omitempty"`                      // Status is available for the plugin API v3 and v4 (GoCD >= 17.9.0).
 	PluginFileLocation        string                    `json:"plugin_file_location,omitempty"`        // PluginFileLocation is available for the plugin API v3 and v4 (GoCD >= 17.9.0).
 	BundledPlugin             bool                      `json:"bundled_plugin,omitempty"`              // BundledPlugin is available for the plugin API v3 and v4 (GoCD >= 17.9.0).
-	About                     PluginAbout               `json:"about,omitempty"`                       // About is available for the plugin API v3 and v4 (GoCD >= 17.9.0).
+	About                     PluginAbout               `json:"about,omitempty"`                       // About is returned by the plugin API v3 and v4 (GoCD >= 17.9.0). Its Name and Version are filled for earlier versions.
 	ExtensionInfo             *PluginExtensionInfo      `json:"extension_info,omitempty"`              // ExtensionInfo is available for the plugin API v3 only (GoCD >= 17.9.0 to < 18.3.0).
 	Extensions                []*PluginExtension        `json:"extensions,omitempty"`                  //Extensions is available for the plugin API v4 (GoCD >= 18.3.0).
 }
@@ -151,6 +151,15 @@ type PluginView struct {
 	Template string `json:"template"`
 }
 
+// PluginSettings describes the settings of a plugin, such as the credentials used by a notification plugin. Secure
+// settings are returned with an EncryptedValue.
+type PluginSettings struct {
+	Links         *HALLinks             `json:"_links,omitempty"`
+	PluginID      string                `json:"plugin_id"`
+	Configuration []*ConfigRepoProperty `json:"configuration"`
+	Version       string                `json:"version,omitempty"`
+}
+
 // List retrieves all plugins
 func (ps *PluginsService) List(ctx context.Context) (*PluginsResponse, *APIResponse, error) {
 	apiVersion, err := ps.client.getAPIVersion(ctx, "admin/plugin_info")
@@ -163,6 +172,9 @@ func (ps *PluginsService) List(ctx context.Context) (*PluginsResponse, *APIRespo
 		ResponseBody: &pr,
 		APIVersion:   apiVersion,
 	})
+	for _, plugin := range pr.Embedded.PluginInfo {
+		plugin.fromAPIVersion(apiVersion)
+	}
 
 	return &pr, resp, err
 }
@@ -179,6 +191,59 @@ func (ps *PluginsService) Get(ctx context.Context, name string) (p *Plugin, resp
 		ResponseBody: p,
 		APIVersion:   apiVersion,
 	})
+	p.fromAPIVersion(apiVersion)
+
+	return
+}
+
+// GetSettings retrieves the settings of a plugin. GoCD responds with a 404 status for plugins which have not been
+// configured yet.
+func (ps *PluginsService) GetSettings(ctx context.Context, pluginID string) (settings *PluginSettings, resp *APIResponse, err error) {
+	apiVersion, err := ps.client.getAPIVersion(ctx, "admin/plugin_settings/:plugin_id")
+	if err != nil {
+		return nil, nil, err
+	}
+	settings = &PluginSettings{}
+	_, resp, err = ps.client.getAction(ctx, &APIClientRequest{
+		Path:         fmt.Sprintf("admin/plugin_settings/%s", pluginID),
+		ResponseBody: settings,
+		APIVersion:   apiVersion,
+	})
+
+	return
+}
+
+// CreateSettings configures a plugin which has no settings yet.
+func (ps *PluginsService) CreateSettings(ctx context.Context, settings *PluginSettings) (out *PluginSettings, resp *APIResponse, err error) {
+	apiVersion, err := ps.client.getAPIVersion(ctx, "admin/plugin_settings")
+	if err != nil {
+		return nil, nil, err
+	}
+	out = &PluginSettings{}
+	_, resp, err = ps.client.postAction(ctx, &APIClientRequest{
+		Path:         "admin/plugin_settings",
+		RequestBody:  settings,
+		ResponseBody: out,
+		APIVersion:   apiVersion,
+	})
+
+	return
+}
+
+// UpdateSettings replaces the settings of a plugin. The Version of the settings must be the one they were retrieved
+// with.
+func (ps *PluginsService) UpdateSettings(ctx context.Context, settings *PluginSettings) (out *PluginSettings, resp *APIResponse, err error) {
+	apiVersion, err := ps.client.getAPIVersion(ctx, "admin/plugin_settings/:plugin_id")
+	if err != nil {
+		return nil, nil, err
+	}
+	out = &PluginSettings{}
+	_, resp, err = ps.client.putAction(ctx, &APIClientRequest{
+		Path:         fmt.Sprintf("admin/plugin_settings/%s", settings.PluginID),
+		RequestBody:  settings,
+		ResponseBody: out,
+		APIVersion:   apiVersion,
+	})
 
 	return
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
 	"testing"
 )
 
@@ -13,6 +15,92 @@ func TestPluginApi(t *testing.T) {
 
 	t.Run("List", testPluginAPIList)
 	t.Run("Get", testPluginAPIGet)
+	t.Run("GetSettings", testPluginAPIGetSettings)
+	t.Run("CreateSettings", testPluginAPICreateSettings)
+	t.Run("UpdateSettings", testPluginAPIUpdateSettings)
+	t.Run("FromAPIVersion", testPluginFromAPIVersion)
+}
+
+func testPluginFromAPIVersion(t *testing.T) {
+	v2 := &Plugin{ID: "yum", Name: "Yum Plugin", Version: "2.0.3", Type: "package-repository"}
+	v2.fromAPIVersion(apiV2)
+	assert.Equal(t, PluginAbout{Name: "Yum Plugin", Version: "2.0.3"}, v2.About)
+
+	v4 := &Plugin{
+		ID:         "yum",
+		About:      PluginAbout{Name: "Yum Plugin", Version: "2.0.3"},
+		Extensions: []*PluginExtension{{Type: "package-repository"}},
+	}
+	v4.fromAPIVersion(apiV4)
+	assert.Equal(t, "Yum Plugin", v4.Name)
+	assert.Equal(t, "2.0.3", v4.Version)
+	assert.Equal(t, "package-repository", v4.Type)
+
+	multiple := &Plugin{Extensions: []*PluginExtension{{Type: "notification"}, {Type: "analytics"}}}
+	multiple.fromAPIVersion(apiV6)
+	assert.Empty(t, multiple.Type)
+}
+
+func testPluginAPIGetSettings(t *testing.T) {
+	mux.HandleFunc("/api/admin/plugin_settings/slack", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method, "Unexpected HTTP method")
+		assert.Equal(t, apiV1, r.Header.Get("Accept"))
+		w.Header().Set("ETag", `"settings-version"`)
+		fmt.Fprint(w, `{
+  "plugin_id": "slack",
+  "configuration": [
+    {"key": "channel", "value": "#builds"},
+    {"key": "token", "encrypted_value": "AES:token"}
+  ]
+}`)
+	})
+
+	settings, _, err := client.Plugins.GetSettings(context.Background(), "slack")
+	assert.NoError(t, err)
+	assert.Equal(t, &PluginSettings{
+		PluginID: "slack",
+		Configuration: []*ConfigRepoProperty{
+			{Key: "channel", Value: "#builds"},
+			{Key: "token", EncryptedValue: "AES:token"},
+		},
+		Version: "settings-version",
+	}, settings)
+}
+
+func testPluginAPICreateSettings(t *testing.T) {
+	mux.HandleFunc("/api/admin/plugin_settings", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method, "Unexpected HTTP method")
+		b, _ := ioutil.ReadAll(r.Body)
+		assert.JSONEq(t, `{"plugin_id": "github", "configuration": [{"key": "server", "value": "https://github.com"}]}`, string(b))
+		w.Header().Set("ETag", `"created-version"`)
+		fmt.Fprint(w, string(b))
+	})
+
+	settings, _, err := client.Plugins.CreateSettings(context.Background(), &PluginSettings{
+		PluginID:      "github",
+		Configuration: []*ConfigRepoProperty{{Key: "server", Value: "https://github.com"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "github", settings.PluginID)
+	assert.Equal(t, "created-version", settings.Version)
+}
+
+func testPluginAPIUpdateSettings(t *testing.T) {
+	mux.HandleFunc("/api/admin/plugin_settings/email", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PUT", r.Method, "Unexpected HTTP method")
+		assert.Equal(t, `"old-version"`, r.Header.Get("If-Match"))
+		w.Header().Set("ETag", `"new-version"`)
+		fmt.Fprint(w, `{"plugin_id": "email", "configuration": [{"key": "host", "value": "smtp.example.com"}]}`)
+	})
+
+	settings, _, err := client.Plugins.UpdateSettings(context.Background(), &PluginSettings{
+		PluginID:      "email",
+		Configuration: []*ConfigRepoProperty{{Key: "host", Value: "smtp.example.com"}},
+		Version:       "old-version",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "smtp.example.com", settings.Configuration[0].Value)
+	assert.Equal(t, "new-version", settings.Version)
 }
 
 func testPluginAPIList(t *testing.T) {
@@ -33,7 +33,7 @@ type PropertyCreateResponse struct {
 // List the properties for the given job/pipeline/stage run.
 func (ps *PropertiesService) List(ctx context.Context, pr *PropertyRequest) (*Properties, *APIResponse, error) {
 
-	ps.log.WithField("endpoint", "PropertiesServices.List").Info("Calling endpoint")
+	ps.client.log().Info("Calling endpoint", "endpoint", "PropertiesServices.List")
 	return ps.commonPropertiesAction(ctx, fmt.Sprintf("/properties/%s/%d/%s/%d/%s",
 		pr.Pipeline, pr.PipelineCounter,
 		pr.Stage, pr.StageCounter,
@@ -43,7 +43,7 @@ func (ps *PropertiesService) List(ctx context.Context, pr *PropertyRequest) (*Pr
 
 // Get a specific property for the given job/pipeline/stage run.
 func (ps *PropertiesService) Get(ctx context.Context, name string, pr *PropertyRequest) (*Properties, *APIResponse, error) {
-	ps.log.WithField("endpoint", "PropertiesServices.Get").Info("Calling endpoint")
+	ps.client.log().Info("Calling endpoint", "endpoint", "PropertiesServices.Get")
 	return ps.commonPropertiesAction(ctx, fmt.Sprintf("/properties/%s/%d/%s/%d/%s/%s",
 		pr.Pipeline, pr.PipelineCounter,
 		pr.Stage, pr.StageCounter,
@@ -54,14 +54,21 @@ func (ps *PropertiesService) Get(ctx context.Context, name string, pr *PropertyR
 // Create a specific property for the given job/pipeline/stage run.
 func (ps *PropertiesService) Create(ctx context.Context, name string, value string, pr *PropertyRequest) (responseIsValid bool, resp *APIResponse, err error) {
 	responseBuffer := bytes.NewBuffer([]byte(""))
+	path := fmt.Sprintf("/properties/%s/%d/%s/%d/%s/%s",
+		pr.Pipeline, pr.PipelineCounter,
+		pr.Stage, pr.StageCounter,
+		pr.Job, name,
+	)
+
+	ps.client.log().Info("Calling endpoint", "endpoint", "PropertiesServices.Create")
+	apiVersion, err := ps.client.getAPIVersionForPath(ctx, path)
+	if err != nil {
+		return false, nil, err
+	}
 
-	ps.log.WithField("endpoint", "PropertiesServices.Create").Info("Calling endpoint")
 	_, resp, err = ps.client.postAction(ctx, &APIClientRequest{
-		Path: fmt.Sprintf("/properties/%s/%d/%s/%d/%s/%s",
-			pr.Pipeline, pr.PipelineCounter,
-			pr.Stage, pr.StageCounter,
-			pr.Job, name,
-		),
+		Path:         path,
+		APIVersion:   apiVersion,
 		ResponseType: responseTypeText,
 		ResponseBody: responseBuffer,
 		RequestBody:  fmt.Sprintf("%s=%s", name, value),
@@ -91,12 +98,18 @@ func (ps *PropertiesService) ListHistorical(ctx context.Context, pr *PropertyReq
 }
 
 func (ps *PropertiesService) commonPropertiesAction(ctx context.Context, path string, isDatum bool) (p *Properties, resp *APIResponse, err error) {
+	apiVersion, err := ps.client.getAPIVersionForPath(ctx, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	p = &Properties{
 		UnmarshallWithHeader: true,
 		IsDatum:              isDatum,
 	}
 	_, resp, err = ps.client.getAction(ctx, &APIClientRequest{
 		Path:         path,
+		APIVersion:   apiVersion,
 		ResponseBody: p,
 	})
 
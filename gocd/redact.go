@@ -0,0 +1,80 @@
+package gocd
+
+import (
+	"encoding/json"
+	"github.com/beamly/go-gocd/internal/redact"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// redacted replaces secrets in log messages.
+const redacted = "[REDACTED]"
+
+// redactedHeaders lists the headers which carry credentials.
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// redactedKeys lists the JSON keys whose values are always secret.
+var redactedKeys = map[string]bool{
+	"password":           true,
+	"encrypted_password": true,
+	"encrypted_value":    true,
+}
+
+// redactedRequestPaths lists the endpoints whose request bodies are entirely secret.
+var redactedRequestPaths = map[string]bool{
+	"admin/encrypt": true,
+}
+
+// redactURL removes the values of secure variables from a URL or path.
+func redactURL(u string) string {
+	return redact.URL(u, redacted)
+}
+
+// redactHeaders converts headers to alternating keys and values for logging, with credentials redacted.
+func redactHeaders(headers http.Header) []interface{} {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	keysAndValues := make([]interface{}, 0, len(names)*2)
+	for _, name := range names {
+		value := strings.Join(headers[name], ", ")
+		if redactedHeaders[http.CanonicalHeaderKey(name)] {
+			value = redacted
+		}
+		keysAndValues = append(keysAndValues, name, value)
+	}
+	return keysAndValues
+}
+
+// redactRequestBody removes secrets from the body of a request to the path.
+func redactRequestBody(path string, body string) string {
+	if redactedRequestPaths[strings.TrimPrefix(path, "/api/")] {
+		return redacted
+	}
+	return redactBody(body)
+}
+
+// redactBody removes passwords, encrypted values, and the values of secure variables from a request or response body.
+// Bodies which are not JSON are treated as XML.
+func redactBody(body string) string {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(body), &decoded); err != nil {
+		return redact.XML(body, redacted)
+	}
+
+	redact.JSON(decoded, redactedKeys, redacted)
+	b, err := json.Marshal(decoded)
+	if err != nil {
+		return redacted
+	}
+	return string(b)
+}
@@ -0,0 +1,70 @@
+package gocd
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	t.Run("Headers", testRedactHeaders)
+	t.Run("JSON", testRedactJSON)
+	t.Run("XML", testRedactXML)
+	t.Run("RequestPath", testRedactRequestPath)
+	t.Run("URL", testRedactURL)
+}
+
+func testRedactHeaders(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Accept", apiV1)
+	headers.Set("Authorization", "Basic bW9jazptb2Nr")
+	headers.Set("Cookie", "JSESSIONID=secret")
+	headers.Add("Set-Cookie", "a=1")
+	headers.Add("Set-Cookie", "b=2")
+
+	assert.Equal(t, []interface{}{
+		"Accept", apiV1,
+		"Authorization", redacted,
+		"Cookie", redacted,
+		"Set-Cookie", redacted,
+	}, redactHeaders(headers))
+}
+
+func testRedactJSON(t *testing.T) {
+	body := `{
+  "environment_variables": [
+    {"name": "PLAIN", "secure": false, "value": "visible"},
+    {"name": "SECRET", "secure": true, "value": "hidden"},
+    {"name": "ENCRYPTED", "secure": true, "encrypted_value": "aSdiFgRRZ6A="}
+  ],
+  "materials": [{"type": "git", "attributes": {"url": "https://example.com/repo.git", "password": "hunter2", "encrypted_password": ""}}]
+}`
+	redactedBody := redactBody(body)
+
+	assert.Contains(t, redactedBody, "visible")
+	assert.Contains(t, redactedBody, "https://example.com/repo.git")
+	assert.NotContains(t, redactedBody, "hidden")
+	assert.NotContains(t, redactedBody, "aSdiFgRRZ6A=")
+	assert.NotContains(t, redactedBody, "hunter2")
+	assert.Contains(t, redactedBody, `"encrypted_password":""`)
+}
+
+func testRedactXML(t *testing.T) {
+	body := `<git url="https://example.com/repo.git" encryptedPassword="AES:abc" /><variable name="SECRET" secure="true"><encryptedValue>AES:def</encryptedValue></variable>`
+
+	assert.Equal(t,
+		`<git url="https://example.com/repo.git" encryptedPassword="[REDACTED]" /><variable name="SECRET" secure="true"><encryptedValue>[REDACTED]</encryptedValue></variable>`,
+		redactBody(body))
+}
+
+func testRedactRequestPath(t *testing.T) {
+	assert.Equal(t, redacted, redactRequestBody("admin/encrypt", `{"value": "secret"}`))
+	assert.Equal(t, `{"value":"visible"}`, redactRequestBody("admin/environments", `{"value": "visible"}`))
+}
+
+func testRedactURL(t *testing.T) {
+	assert.Equal(t,
+		"pipelines/p/schedule?secure_variables%5BTOKEN%5D=[REDACTED]&variables%5BENV%5D=dev&secure_variables[KEY]=[REDACTED]",
+		redactURL("pipelines/p/schedule?secure_variables%5BTOKEN%5D=abc+def&variables%5BENV%5D=dev&secure_variables[KEY]=xyz"))
+	assert.Equal(t, "pipelines/p/schedule", redactURL("pipelines/p/schedule"))
+}
@@ -1,5 +1,12 @@
 package gocd
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
 // GetLinks returns HAL links for agent
 func (a *Agent) GetLinks() *HALLinks {
 	return a.Links
@@ -9,3 +16,49 @@ func (a *Agent) GetLinks() *HALLinks {
 func (a *Agent) RemoveLinks() {
 	a.Links = nil
 }
+
+// Refresh retrieves the latest state of the agent by following its `self` link.
+func (a *Agent) Refresh(ctx context.Context) (agent *Agent, resp *APIResponse, err error) {
+	if a.client == nil {
+		return nil, nil, errors.New("agent was not retrieved from a GoCD server")
+	}
+	if a.Links == nil {
+		return nil, nil, errors.New("agent does not have any links")
+	}
+
+	agent = &Agent{}
+	if resp, err = a.client.Follow(ctx, a.Links.Self(), agent); err != nil {
+		return
+	}
+	agent.client = a.client
+	return
+}
+
+// UnmarshalJSON an agent. Since agents API v6 (GoCD 19.9.0) environments are objects rather than names, so both forms
+// are accepted and reduced to the environment names.
+func (a *Agent) UnmarshalJSON(b []byte) (err error) {
+	type agent Agent
+	raw := struct {
+		*agent
+		Environments []json.RawMessage `json:"environments,omitempty"`
+	}{agent: (*agent)(a)}
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return
+	}
+
+	a.Environments = nil
+	for _, env := range raw.Environments {
+		var name string
+		if err = json.Unmarshal(env, &name); err != nil {
+			named := struct {
+				Name string `json:"name"`
+			}{}
+			if json.Unmarshal(env, &named) != nil {
+				return fmt.Errorf("unexpected agent environment: %s", env)
+			}
+			name, err = named.Name, nil
+		}
+		a.Environments = append(a.Environments, name)
+	}
+	return
+}
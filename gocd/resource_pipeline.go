@@ -71,3 +71,9 @@ func (pr *PipelineConfigRequest) GetVersion() (version string) {
 func (pr *PipelineConfigRequest) SetVersion(version string) {
 	pr.Pipeline.SetVersion(version)
 }
+
+// DefinedInConfigRepo reports whether the pipeline config is defined in a config repo. Such pipelines can only be
+// changed in their repository, so the server refuses to update or delete them through the API.
+func (p *Pipeline) DefinedInConfigRepo() bool {
+	return p.Origin != nil && p.Origin.Type == "config_repo"
+}
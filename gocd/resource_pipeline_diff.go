@@ -0,0 +1,337 @@
+package gocd
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Kinds of PipelineChange
+const (
+	PipelineChangeAdded   = "added"
+	PipelineChangeRemoved = "removed"
+	PipelineChangeChanged = "changed"
+)
+
+// PipelineChange describes a difference between two pipeline configs.
+type PipelineChange struct {
+	// Kind is one of PipelineChangeAdded, PipelineChangeRemoved, or PipelineChangeChanged.
+	Kind string `json:"kind"`
+	// Path locates the change in the pipeline config, eg: `stages[build].jobs[compile].tasks[1]` or `label_template`.
+	Path string `json:"path"`
+	// Before is the removed or changed value, and After the added or changed value.
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// String formats the change on a single line, eg: `~ label_template: "${COUNT}" -> "1.${COUNT}"`.
+func (pc *PipelineChange) String() string {
+	switch pc.Kind {
+	case PipelineChangeAdded:
+		return "+ " + pc.Path
+	case PipelineChangeRemoved:
+		return "- " + pc.Path
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", pc.Path, diffValueString(pc.Before), diffValueString(pc.After))
+	}
+}
+
+func diffValueString(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// DiffPipelines lists the changes turning pipeline config `a` into `b`, eg: the config of a pipeline on the server and
+// the one in a repository. Stages, jobs, parameters and environment variables are matched by name, tasks by position,
+// and materials with Material.Equal. The fields owned by the server, `Links`, `Version`, `Origin`, and the `Group` which
+// is not returned with the pipeline config, are ignored.
+func DiffPipelines(a, b *Pipeline) (changes []*PipelineChange, err error) {
+	if a == nil {
+		a = &Pipeline{}
+	}
+	if b == nil {
+		b = &Pipeline{}
+	}
+
+	d := &pipelineDiff{}
+	d.fields("", pipelineFields(a), pipelineFields(b))
+	d.parameters(a.Parameters, b.Parameters)
+	d.environmentVariables("", a.EnvironmentVariables, b.EnvironmentVariables)
+	if err = d.materials(a.Materials, b.Materials); err != nil {
+		return nil, err
+	}
+	d.stages(a.Stages, b.Stages)
+
+	return d.changes, nil
+}
+
+// pipelineFields returns the pipeline without its server owned fields and the collections which are compared entry by
+// entry.
+func pipelineFields(p *Pipeline) *Pipeline {
+	fields := *p
+	fields.Links, fields.Version, fields.Origin, fields.Group, fields.Label = nil, "", nil, "", ""
+	fields.Parameters, fields.EnvironmentVariables, fields.Materials, fields.Stages = nil, nil, nil, nil
+	return &fields
+}
+
+type pipelineDiff struct {
+	changes []*PipelineChange
+}
+
+func (d *pipelineDiff) add(kind string, path string, before, after interface{}) {
+	d.changes = append(d.changes, &PipelineChange{Kind: kind, Path: path, Before: before, After: after})
+}
+
+// fields compares the JSON representation of two structures field by field.
+func (d *pipelineDiff) fields(path string, a, b interface{}) {
+	am, bm := jsonFields(a), jsonFields(b)
+
+	keys := []string{}
+	for key := range am {
+		keys = append(keys, key)
+	}
+	for key := range bm {
+		if _, inA := am[key]; !inA {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !reflect.DeepEqual(am[key], bm[key]) {
+			d.add(PipelineChangeChanged, joinPath(path, key), am[key], bm[key])
+		}
+	}
+}
+
+func jsonFields(v interface{}) (fields map[string]interface{}) {
+	fields = map[string]interface{}{}
+	if b, err := json.Marshal(v); err == nil {
+		json.Unmarshal(b, &fields)
+	}
+	return
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func (d *pipelineDiff) parameters(a, b []*Parameter) {
+	byName := map[string]*Parameter{}
+	for _, param := range b {
+		if param != nil {
+			byName[param.Name] = param
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, param := range a {
+		if param == nil {
+			continue
+		}
+		seen[param.Name] = true
+		path := fmt.Sprintf("parameters[%s]", param.Name)
+		if other, inB := byName[param.Name]; !inB {
+			d.add(PipelineChangeRemoved, path, param, nil)
+		} else if other.Value != param.Value {
+			d.add(PipelineChangeChanged, path+".value", param.Value, other.Value)
+		}
+	}
+	for _, param := range b {
+		if param != nil && !seen[param.Name] {
+			d.add(PipelineChangeAdded, fmt.Sprintf("parameters[%s]", param.Name), nil, param)
+		}
+	}
+}
+
+func (d *pipelineDiff) environmentVariables(path string, a, b []*EnvironmentVariable) {
+	byName := map[string]*EnvironmentVariable{}
+	for _, env := range b {
+		if env != nil {
+			byName[env.Name] = env
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, env := range a {
+		if env == nil {
+			continue
+		}
+		seen[env.Name] = true
+		envPath := joinPath(path, fmt.Sprintf("environment_variables[%s]", env.Name))
+		if other, inB := byName[env.Name]; !inB {
+			d.add(PipelineChangeRemoved, envPath, env, nil)
+		} else {
+			d.fields(envPath, env, other)
+		}
+	}
+	for _, env := range b {
+		if env != nil && !seen[env.Name] {
+			d.add(PipelineChangeAdded, joinPath(path, fmt.Sprintf("environment_variables[%s]", env.Name)), nil, env)
+		}
+	}
+}
+
+// materials pairs the materials which are equal, and compares their attributes. The materials which could not be
+// paired were added or removed.
+func (d *pipelineDiff) materials(a, b []Material) error {
+	paired := make([]bool, len(b))
+	for _, m := range a {
+		match := -1
+		for j := range b {
+			if paired[j] {
+				continue
+			}
+			isEqual, err := m.Equal(&b[j])
+			if err != nil {
+				return err
+			}
+			if isEqual {
+				match = j
+				break
+			}
+		}
+
+		path := fmt.Sprintf("materials[%s]", materialLabel(m))
+		if match < 0 {
+			d.add(PipelineChangeRemoved, path, m, nil)
+			continue
+		}
+		paired[match] = true
+		d.fields(path, m.Attributes, b[match].Attributes)
+	}
+
+	for j, m := range b {
+		if !paired[j] {
+			d.add(PipelineChangeAdded, fmt.Sprintf("materials[%s]", materialLabel(m)), nil, m)
+		}
+	}
+	return nil
+}
+
+// materialLabel identifies a material by its name, or by its type and location.
+func materialLabel(m Material) string {
+	if name, _ := materialName(m); name != "" {
+		return name
+	}
+	attributes := jsonFields(m.Attributes)
+	for _, key := range []string{"url", "port", "pipeline", "ref"} {
+		if location, hasLocation := attributes[key].(string); hasLocation && location != "" {
+			return m.Type + " " + location
+		}
+	}
+	return m.Type
+}
+
+func (d *pipelineDiff) stages(a, b []*Stage) {
+	byName := map[string]*Stage{}
+	for _, stage := range b {
+		if stage != nil {
+			byName[stage.Name] = stage
+		}
+	}
+
+	seen := map[string]bool{}
+	orderA := []string{}
+	for _, stage := range a {
+		if stage == nil {
+			continue
+		}
+		seen[stage.Name] = true
+		path := fmt.Sprintf("stages[%s]", stage.Name)
+		other, inB := byName[stage.Name]
+		if !inB {
+			d.add(PipelineChangeRemoved, path, stage, nil)
+			continue
+		}
+		orderA = append(orderA, stage.Name)
+		d.stage(path, stage, other)
+	}
+
+	orderB := []string{}
+	for _, stage := range b {
+		if stage == nil {
+			continue
+		}
+		if !seen[stage.Name] {
+			d.add(PipelineChangeAdded, fmt.Sprintf("stages[%s]", stage.Name), nil, stage)
+			continue
+		}
+		orderB = append(orderB, stage.Name)
+	}
+
+	if !reflect.DeepEqual(orderA, orderB) {
+		d.add(PipelineChangeChanged, "stages", orderA, orderB)
+	}
+}
+
+func (d *pipelineDiff) stage(path string, a, b *Stage) {
+	fieldsA, fieldsB := *a, *b
+	fieldsA.Jobs, fieldsA.EnvironmentVariables = nil, nil
+	fieldsB.Jobs, fieldsB.EnvironmentVariables = nil, nil
+	d.fields(path, &fieldsA, &fieldsB)
+	d.environmentVariables(path, a.EnvironmentVariables, b.EnvironmentVariables)
+
+	byName := map[string]*Job{}
+	for _, job := range b.Jobs {
+		if job != nil {
+			byName[job.Name] = job
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, job := range a.Jobs {
+		if job == nil {
+			continue
+		}
+		seen[job.Name] = true
+		jobPath := fmt.Sprintf("%s.jobs[%s]", path, job.Name)
+		if other, inB := byName[job.Name]; !inB {
+			d.add(PipelineChangeRemoved, jobPath, job, nil)
+		} else {
+			d.job(jobPath, job, other)
+		}
+	}
+	for _, job := range b.Jobs {
+		if job != nil && !seen[job.Name] {
+			d.add(PipelineChangeAdded, fmt.Sprintf("%s.jobs[%s]", path, job.Name), nil, job)
+		}
+	}
+}
+
+func (d *pipelineDiff) job(path string, a, b *Job) {
+	fieldsA, fieldsB := *a, *b
+	fieldsA.Tasks, fieldsA.EnvironmentVariables = nil, nil
+	fieldsB.Tasks, fieldsB.EnvironmentVariables = nil, nil
+	d.fields(path, &fieldsA, &fieldsB)
+	d.environmentVariables(path, a.EnvironmentVariables, b.EnvironmentVariables)
+
+	for i := 0; i < len(a.Tasks) || i < len(b.Tasks); i++ {
+		taskPath := fmt.Sprintf("%s.tasks[%d]", path, i)
+		switch {
+		case i >= len(b.Tasks):
+			d.add(PipelineChangeRemoved, taskPath, a.Tasks[i], nil)
+		case i >= len(a.Tasks):
+			d.add(PipelineChangeAdded, taskPath, nil, b.Tasks[i])
+		case !reflect.DeepEqual(jsonFields(a.Tasks[i]), jsonFields(b.Tasks[i])):
+			d.add(PipelineChangeChanged, taskPath, a.Tasks[i], b.Tasks[i])
+		}
+	}
+}
+
+// FormatPipelineChanges formats changes one per line, as listed by PipelineChange.String.
+func FormatPipelineChanges(changes []*PipelineChange) string {
+	lines := make([]string, 0, len(changes))
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,196 @@
+package gocd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffPipelines(t *testing.T) {
+	t.Run("Identical", testDiffPipelinesIdentical)
+	t.Run("ServerFields", testDiffPipelinesServerFields)
+	t.Run("Fields", testDiffPipelinesFields)
+	t.Run("Materials", testDiffPipelinesMaterials)
+	t.Run("Stages", testDiffPipelinesStages)
+	t.Run("Jobs", testDiffPipelinesJobs)
+	t.Run("EnvironmentVariables", testDiffPipelinesEnvironmentVariables)
+	t.Run("String", testDiffPipelinesString)
+	t.Run("JSON", testDiffPipelinesJSON)
+}
+
+func diffPaths(t *testing.T, a, b *Pipeline) map[string]string {
+	changes, err := DiffPipelines(a, b)
+	assert.NoError(t, err)
+	paths := map[string]string{}
+	for _, change := range changes {
+		paths[change.Path] = change.Kind
+	}
+	return paths
+}
+
+func testDiffPipelinesIdentical(t *testing.T) {
+	changes, err := DiffPipelines(validPipeline(), validPipeline())
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func testDiffPipelinesServerFields(t *testing.T) {
+	server := validPipeline()
+	server.Links = &HALLinks{}
+	server.Version = "mock-version"
+	server.Origin = &PipelineConfigOrigin{Type: "gocd"}
+
+	local := validPipeline()
+	local.Group = "my-group"
+
+	changes, err := DiffPipelines(server, local)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func testDiffPipelinesFields(t *testing.T) {
+	a := validPipeline()
+	b := validPipeline()
+	b.LabelTemplate = "1.${COUNT}"
+	b.LockBehavior = LockBehaviorNone
+	b.Timer.OnlyOnChanges = true
+
+	changes, err := DiffPipelines(a, b)
+	assert.NoError(t, err)
+	assert.Equal(t, []*PipelineChange{
+		{Kind: PipelineChangeChanged, Path: "label_template", Before: "${COUNT}-${my-repo[:7]}", After: "1.${COUNT}"},
+		{Kind: PipelineChangeChanged, Path: "lock_behavior", After: "none"},
+		{Kind: PipelineChangeChanged, Path: "timer", Before: map[string]interface{}{"spec": "0 0 22 ? * MON-FRI"},
+			After: map[string]interface{}{"spec": "0 0 22 ? * MON-FRI", "only_on_changes": true}},
+	}, changes)
+
+	b = validPipeline()
+	b.Parameters = []*Parameter{{Name: "env", Value: "prod"}}
+	assert.Equal(t, map[string]string{"parameters[env]": PipelineChangeAdded}, diffPaths(t, a, b))
+	a.Parameters = []*Parameter{{Name: "env", Value: "dev"}, {Name: "region", Value: "eu"}}
+	assert.Equal(t, map[string]string{
+		"parameters[env].value": PipelineChangeChanged,
+		"parameters[region]":    PipelineChangeRemoved,
+	}, diffPaths(t, a, b))
+}
+
+func testDiffPipelinesMaterials(t *testing.T) {
+	a := validPipeline()
+	b := validPipeline()
+
+	// Materials are paired with Material.Equal, so an unset branch matches master, and the name is compared as an
+	// attribute of the material.
+	b.Materials[0] = Material{
+		Type:       "git",
+		Attributes: &MaterialAttributesGit{Name: "gocd", URL: "https://github.com/gocd/gocd", Branch: "master"},
+	}
+	b.LabelTemplate = "${COUNT}"
+	a.LabelTemplate = "${COUNT}"
+	assert.Equal(t, map[string]string{
+		"materials[my-repo].name":   PipelineChangeChanged,
+		"materials[my-repo].branch": PipelineChangeChanged,
+	}, diffPaths(t, a, b))
+
+	b.Materials[0] = Material{Type: "git", Attributes: &MaterialAttributesGit{URL: "https://github.com/gocd/docs"}}
+	b.Materials = append(b.Materials, Material{Type: "package", Attributes: &MaterialAttributesPackage{Ref: "pkg-1"}})
+	assert.Equal(t, map[string]string{
+		"materials[my-repo]":                          PipelineChangeRemoved,
+		"materials[git https://github.com/gocd/docs]": PipelineChangeAdded,
+		"materials[package pkg-1]":                    PipelineChangeAdded,
+	}, diffPaths(t, a, b))
+}
+
+func testDiffPipelinesStages(t *testing.T) {
+	a := validPipeline()
+	b := validPipeline()
+	b.Stages = append(b.Stages, &Stage{Name: "deploy", Jobs: []*Job{{Name: "deploy"}}})
+	b.Stages[0].CleanWorkingDirectory = true
+	assert.Equal(t, map[string]string{
+		"stages[build].clean_working_directory": PipelineChangeChanged,
+		"stages[deploy]":                        PipelineChangeAdded,
+	}, diffPaths(t, a, b))
+
+	b = validPipeline()
+	b.Stages[0], b.Stages[1] = b.Stages[1], b.Stages[0]
+	changes, err := DiffPipelines(a, b)
+	assert.NoError(t, err)
+	assert.Equal(t, []*PipelineChange{
+		{Kind: PipelineChangeChanged, Path: "stages", Before: []string{"build", "test"}, After: []string{"test", "build"}},
+	}, changes)
+
+	b.Stages = b.Stages[:1]
+	assert.Equal(t, map[string]string{"stages[build]": PipelineChangeRemoved}, diffPaths(t, a, b))
+}
+
+func testDiffPipelinesJobs(t *testing.T) {
+	a := validPipeline()
+	b := validPipeline()
+	b.Stages[0].Jobs[0].Tasks[0] = &Task{Type: "exec", Attributes: &TaskAttributesExec{Command: "make", Arguments: []string{"all"}}}
+	b.Stages[0].Jobs[0].Tasks = append(b.Stages[0].Jobs[0].Tasks, &Task{Type: "rake", Attributes: &TaskAttributesRake{}})
+	b.Stages[0].Jobs[0].Resources = []string{"linux"}
+	b.Stages[0].Jobs = b.Stages[0].Jobs[:1]
+	b.Stages[1].Jobs = append(b.Stages[1].Jobs, &Job{Name: "integration"})
+
+	assert.Equal(t, map[string]string{
+		"stages[build].jobs[compile].tasks[0]":  PipelineChangeChanged,
+		"stages[build].jobs[compile].tasks[2]":  PipelineChangeAdded,
+		"stages[build].jobs[compile].resources": PipelineChangeChanged,
+		"stages[build].jobs[lint]":              PipelineChangeRemoved,
+		"stages[test].jobs[integration]":        PipelineChangeAdded,
+	}, diffPaths(t, a, b))
+
+	a.Stages[0].Jobs[0].Tasks = append(a.Stages[0].Jobs[0].Tasks, &Task{Type: "rake", Attributes: &TaskAttributesRake{}})
+	b.Stages[0].Jobs[0].Tasks = b.Stages[0].Jobs[0].Tasks[:1]
+	assert.Contains(t, diffPaths(t, a, b), "stages[build].jobs[compile].tasks[2]")
+	assert.Equal(t, PipelineChangeRemoved, diffPaths(t, a, b)["stages[build].jobs[compile].tasks[1]"])
+}
+
+func testDiffPipelinesEnvironmentVariables(t *testing.T) {
+	a := validPipeline()
+	a.EnvironmentVariables = []*EnvironmentVariable{{Name: "GO_ENV", Value: "dev"}, {Name: "TOKEN", EncryptedValue: "abc", Secure: true}}
+	a.Stages[0].EnvironmentVariables = []*EnvironmentVariable{{Name: "DEBUG", Value: "1"}}
+	b := validPipeline()
+	b.EnvironmentVariables = []*EnvironmentVariable{{Name: "GO_ENV", Value: "prod"}, {Name: "REGION", Value: "eu"}}
+	b.Stages[0].Jobs[0].EnvironmentVariables = []*EnvironmentVariable{{Name: "DEBUG", Value: "1"}}
+
+	assert.Equal(t, map[string]string{
+		"environment_variables[GO_ENV].value":                      PipelineChangeChanged,
+		"environment_variables[TOKEN]":                             PipelineChangeRemoved,
+		"environment_variables[REGION]":                            PipelineChangeAdded,
+		"stages[build].environment_variables[DEBUG]":               PipelineChangeRemoved,
+		"stages[build].jobs[compile].environment_variables[DEBUG]": PipelineChangeAdded,
+	}, diffPaths(t, a, b))
+}
+
+func testDiffPipelinesString(t *testing.T) {
+	a := validPipeline()
+	b := validPipeline()
+	b.LabelTemplate = "1.${COUNT}"
+	b.Stages = append(b.Stages, &Stage{Name: "deploy"})
+	b.Stages = b.Stages[1:]
+
+	changes, err := DiffPipelines(a, b)
+	assert.NoError(t, err)
+	assert.Equal(t, `~ label_template: "${COUNT}-${my-repo[:7]}" -> "1.${COUNT}"
+- stages[build]
++ stages[deploy]`, FormatPipelineChanges(changes))
+}
+
+func testDiffPipelinesJSON(t *testing.T) {
+	a := validPipeline()
+	b := validPipeline()
+	b.Stages = b.Stages[:1]
+
+	changes, err := DiffPipelines(a, b)
+	assert.NoError(t, err)
+	out, err := json.Marshal(changes)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"kind": "removed", "path": "stages[test]", "before": {
+		"name": "test",
+		"fetch_materials": false,
+		"clean_working_directory": false,
+		"never_cleanup_artifacts": false,
+		"jobs": [{"name": "compile"}]
+	}}]`, string(out))
+}
@@ -3,6 +3,7 @@ package gocd
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 )
 
@@ -11,8 +12,23 @@ func (m Material) Equal(a *Material) (isEqual bool, err error) {
 	if m.Type != a.Type {
 		return
 	}
+	if m.Attributes == nil || a.Attributes == nil {
+		return m.Attributes == nil && a.Attributes == nil, nil
+	}
+
+	return m.Attributes.equal(materialAttributeValue(a.Attributes))
+}
 
-	return m.Attributes.equal(a.Attributes)
+// materialAttributeValue dereferences pointers to material attributes, such as the ones created when unmarshalling a
+// material, as attributes are compared by value.
+func materialAttributeValue(ma MaterialAttribute) MaterialAttribute {
+	v := reflect.ValueOf(ma)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		if value, isAttribute := v.Elem().Interface().(MaterialAttribute); isAttribute {
+			return value
+		}
+	}
+	return ma
 }
 
 // UnmarshalJSON string into a Material struct
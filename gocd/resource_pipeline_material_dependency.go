@@ -33,6 +33,10 @@ func (mad MaterialAttributesDependency) GenerateGeneric() (ma map[string]interfa
 	if mad.AutoUpdate {
 		ma["auto_update"] = mad.AutoUpdate
 	}
+
+	if mad.IgnoreForScheduling {
+		ma["ignore_for_scheduling"] = mad.IgnoreForScheduling
+	}
 	return
 }
 
@@ -61,6 +65,8 @@ func unmarshallMaterialAttributesDependency(mad *MaterialAttributesDependency, i
 			mad.Stage = value.(string)
 		case "auto_update":
 			mad.AutoUpdate = value.(bool)
+		case "ignore_for_scheduling":
+			mad.IgnoreForScheduling = value.(bool)
 		}
 	}
 }
@@ -33,6 +33,15 @@ func (mag MaterialAttributesGit) GenerateGeneric() (ma map[string]interface{}) {
 		"shallow_clone":    mag.ShallowClone,
 		"invert_filter":    mag.InvertFilter,
 	}
+	if mag.Username != "" {
+		ma["username"] = mag.Username
+	}
+	if mag.Password != "" {
+		ma["password"] = mag.Password
+	}
+	if mag.EncryptedPassword != "" {
+		ma["encrypted_password"] = mag.EncryptedPassword
+	}
 	if f := mag.Filter.GenerateGeneric(); f != nil {
 		ma["filter"] = f
 	}
@@ -64,6 +73,12 @@ func unmarshallMaterialAttributesGit(mag *MaterialAttributesGit, i map[string]in
 			mag.AutoUpdate = value.(bool)
 		case "branch":
 			mag.Branch = value.(string)
+		case "username":
+			mag.Username = value.(string)
+		case "password":
+			mag.Password = value.(string)
+		case "encrypted_password":
+			mag.EncryptedPassword = value.(string)
 		case "submodule_folder":
 			mag.SubmoduleFolder = value.(string)
 		case "destination":
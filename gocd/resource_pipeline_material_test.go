@@ -1,6 +1,7 @@
 package gocd
 
 import (
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"strconv"
 	"testing"
@@ -145,6 +146,16 @@ func testMaterialEquality(t *testing.T) {
 	ok, err := s1.Equal(&s2)
 	assert.Nil(t, err)
 	assert.True(t, ok)
+
+	// Unmarshalled materials hold pointers to their attributes.
+	s3 := Material{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"type": "git", "attributes": {"url": "https://github.com/gocd/gocd", "branch": "master"}}`), &s3))
+	ok, err = s3.Equal(&s2)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = s2.Equal(&s3)
+	assert.Nil(t, err)
+	assert.True(t, ok)
 }
 
 func testMaterialAttributeEquality(t *testing.T) {
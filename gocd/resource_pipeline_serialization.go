@@ -0,0 +1,135 @@
+package gocd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// forAPIVersion adapts a pipeline to the version of the pipeline config API it is sent to, converting between fields
+// which express the same setting in different versions. An error is returned if the pipeline uses a setting which the
+// API version can not express. The pipeline is not modified, and warnings describe conversions the caller should know
+// about.
+func (p *Pipeline) forAPIVersion(apiVersion string) (adapted *Pipeline, warnings []string, err error) {
+	version := apiVersionNumber(apiVersion)
+	copied := *p
+	adapted = &copied
+
+	if warnings, err = adapted.adaptLocking(version); err != nil {
+		return nil, nil, err
+	}
+
+	stages := make([]*Stage, len(p.Stages))
+	for i, stage := range p.Stages {
+		if stages[i], err = stage.forAPIVersion(p.Name, version); err != nil {
+			return nil, nil, err
+		}
+	}
+	if p.Stages != nil {
+		adapted.Stages = stages
+	}
+
+	return adapted, warnings, nil
+}
+
+// adaptLocking converts between `enable_pipeline_locking`, used up to v4 of the pipeline config API, and
+// `lock_behavior`, used from v5.
+func (p *Pipeline) adaptLocking(version int) (warnings []string, err error) {
+	if version < 5 {
+		switch p.LockBehavior {
+		case "":
+		case LockBehaviorLockOnFailure:
+			p.EnablePipelineLocking = true
+		case LockBehaviorNone:
+			if p.EnablePipelineLocking {
+				return nil, fmt.Errorf("pipeline '%s' can not both enable pipeline locking and set lock_behavior '%s'", p.Name, p.LockBehavior)
+			}
+		default:
+			return nil, fmt.Errorf("lock_behavior '%s' of pipeline '%s' can not be expressed with the pipeline config API v%d (GoCD < 17.12.0)", p.LockBehavior, p.Name, version)
+		}
+		p.LockBehavior = ""
+		return nil, nil
+	}
+
+	if p.EnablePipelineLocking {
+		switch p.LockBehavior {
+		case "":
+			p.LockBehavior = LockBehaviorLockOnFailure
+			warnings = append(warnings, fmt.Sprintf("enable_pipeline_locking of pipeline '%s' is not supported by the pipeline config API v%d, and was converted to lock_behavior '%s'", p.Name, version, LockBehaviorLockOnFailure))
+		case LockBehaviorLockOnFailure:
+		default:
+			return nil, fmt.Errorf("pipeline '%s' can not both enable pipeline locking and set lock_behavior '%s'", p.Name, p.LockBehavior)
+		}
+		p.EnablePipelineLocking = false
+	}
+	return warnings, nil
+}
+
+// forAPIVersion copies the stage, adapting fetch tasks to the version of the pipeline config API. The origin of fetched
+// artifacts can only be set from v6 of the API, where it is required, and artifacts published by artifact plugins can
+// only be defined from v6.
+func (s *Stage) forAPIVersion(pipeline string, version int) (adapted *Stage, err error) {
+	if s == nil {
+		return nil, nil
+	}
+	copied := *s
+	adapted = &copied
+	if s.Jobs == nil {
+		return adapted, nil
+	}
+
+	adapted.Jobs = make([]*Job, len(s.Jobs))
+	for i, job := range s.Jobs {
+		if job == nil {
+			continue
+		}
+		copiedJob := *job
+		adapted.Jobs[i] = &copiedJob
+		for _, artifact := range job.Artifacts {
+			if version < 6 && artifact != nil && artifact.Type == "external" {
+				return nil, fmt.Errorf("external artifact '%s' in job '%s' of stage '%s' in pipeline '%s' is published by an artifact plugin, which can not be expressed with the pipeline config API v%d (GoCD < 18.7.0)",
+					artifact.ID, job.Name, s.Name, pipeline, version)
+			}
+		}
+		if job.Tasks == nil {
+			continue
+		}
+
+		copiedJob.Tasks = make([]*Task, len(job.Tasks))
+		for j, task := range job.Tasks {
+			if task == nil {
+				continue
+			}
+			copiedTask := *task
+			copiedJob.Tasks[j] = &copiedTask
+			fetch, isFetch := task.Attributes.(*TaskAttributesFetch)
+			if !isFetch || fetch == nil {
+				continue
+			}
+			copiedFetch := *fetch
+			copiedTask.Attributes = &copiedFetch
+
+			switch {
+			case version >= 6 && fetch.ArtifactOrigin == "":
+				copiedFetch.ArtifactOrigin = "gocd"
+			case version < 6 && fetch.ArtifactOrigin == "gocd":
+				copiedFetch.ArtifactOrigin = ""
+			case version < 6 && fetch.ArtifactOrigin != "":
+				return nil, fmt.Errorf("fetch task in job '%s' of stage '%s' in pipeline '%s' uses artifact_origin '%s', which can not be expressed with the pipeline config API v%d (GoCD < 18.7.0)",
+					job.Name, s.Name, pipeline, fetch.ArtifactOrigin, version)
+			}
+		}
+	}
+	return adapted, nil
+}
+
+// apiVersionNumber extracts the version number from an Accept header, eg: 5 for `application/vnd.go.cd.v5+json`. The
+// unversioned API is version 0.
+func apiVersionNumber(apiVersion string) int {
+	number := strings.TrimSuffix(strings.TrimPrefix(apiVersion, "application/vnd.go.cd.v"), "+json")
+	version, err := strconv.Atoi(number)
+	if err != nil {
+		return 0
+	}
+	return version
+}
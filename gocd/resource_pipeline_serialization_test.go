@@ -0,0 +1,212 @@
+package gocd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestPipelineForAPIVersion(t *testing.T) {
+	t.Run("LockBehaviorToLocking", testPipelineForAPIVersionLockBehaviorToLocking)
+	t.Run("LockingToLockBehavior", testPipelineForAPIVersionLockingToLockBehavior)
+	t.Run("LockingConflict", testPipelineForAPIVersionLockingConflict)
+	t.Run("FetchArtifactOrigin", testPipelineForAPIVersionFetchArtifactOrigin)
+	t.Run("ExternalArtifacts", testPipelineForAPIVersionExternalArtifacts)
+	t.Run("APIVersionNumber", testPipelineForAPIVersionNumber)
+	t.Run("Update", testPipelineForAPIVersionUpdate)
+	t.Run("Create", testPipelineForAPIVersionCreate)
+}
+
+func testPipelineForAPIVersionLockBehaviorToLocking(t *testing.T) {
+	for lockBehavior, locking := range map[string]bool{
+		"":                        false,
+		LockBehaviorLockOnFailure: true,
+		LockBehaviorNone:          false,
+	} {
+		p := &Pipeline{Name: "my-pipeline", LockBehavior: lockBehavior}
+		adapted, warnings, err := p.forAPIVersion(apiV4)
+		assert.NoError(t, err)
+		assert.Empty(t, warnings)
+		assert.Equal(t, locking, adapted.EnablePipelineLocking, lockBehavior)
+		assert.Empty(t, adapted.LockBehavior)
+		assert.Equal(t, lockBehavior, p.LockBehavior, "the pipeline must not be modified")
+	}
+
+	_, _, err := (&Pipeline{Name: "my-pipeline", LockBehavior: LockBehaviorUnlockWhenFinished}).forAPIVersion(apiV4)
+	assert.EqualError(t, err, "lock_behavior 'unlockWhenFinished' of pipeline 'my-pipeline' can not be expressed with the pipeline config API v4 (GoCD < 17.12.0)")
+}
+
+func testPipelineForAPIVersionLockingToLockBehavior(t *testing.T) {
+	p := &Pipeline{Name: "my-pipeline", EnablePipelineLocking: true}
+	adapted, warnings, err := p.forAPIVersion(apiV5)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"enable_pipeline_locking of pipeline 'my-pipeline' is not supported by the pipeline config API v5, and was converted to lock_behavior 'lockOnFailure'",
+	}, warnings)
+	assert.False(t, adapted.EnablePipelineLocking)
+	assert.Equal(t, LockBehaviorLockOnFailure, adapted.LockBehavior)
+	assert.True(t, p.EnablePipelineLocking)
+
+	p = &Pipeline{Name: "my-pipeline", EnablePipelineLocking: true, LockBehavior: LockBehaviorLockOnFailure}
+	adapted, warnings, err = p.forAPIVersion(apiV10)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.False(t, adapted.EnablePipelineLocking)
+	assert.Equal(t, LockBehaviorLockOnFailure, adapted.LockBehavior)
+}
+
+func testPipelineForAPIVersionLockingConflict(t *testing.T) {
+	for _, apiVersion := range []string{apiV3, apiV6} {
+		p := &Pipeline{Name: "my-pipeline", EnablePipelineLocking: true, LockBehavior: LockBehaviorNone}
+		_, _, err := p.forAPIVersion(apiVersion)
+		assert.EqualError(t, err, "pipeline 'my-pipeline' can not both enable pipeline locking and set lock_behavior 'none'", apiVersion)
+	}
+}
+
+func testPipelineForAPIVersionFetchArtifactOrigin(t *testing.T) {
+	fetch := func(origin string) *Pipeline {
+		return &Pipeline{
+			Name: "my-pipeline",
+			Stages: []*Stage{{
+				Name: "my-stage",
+				Jobs: []*Job{{
+					Name: "my-job",
+					Tasks: []*Task{
+						{Type: "exec", Attributes: &TaskAttributesExec{Command: "make"}},
+						{Type: "fetch", Attributes: &TaskAttributesFetch{ArtifactOrigin: origin, Pipeline: "upstream"}},
+					},
+				}},
+			}},
+		}
+	}
+	origin := func(p *Pipeline) string {
+		return p.Stages[0].Jobs[0].Tasks[1].Attributes.(*TaskAttributesFetch).ArtifactOrigin
+	}
+
+	p := fetch("")
+	adapted, _, err := p.forAPIVersion(apiV6)
+	assert.NoError(t, err)
+	assert.Equal(t, "gocd", origin(adapted))
+	assert.Equal(t, "", origin(p), "the pipeline must not be modified")
+	assert.Equal(t, "make", adapted.Stages[0].Jobs[0].Tasks[0].Attributes.(*TaskAttributesExec).Command)
+
+	adapted, _, err = fetch("gocd").forAPIVersion(apiV5)
+	assert.NoError(t, err)
+	assert.Equal(t, "", origin(adapted))
+
+	adapted, _, err = fetch("external").forAPIVersion(apiV10)
+	assert.NoError(t, err)
+	assert.Equal(t, "external", origin(adapted))
+
+	_, _, err = fetch("external").forAPIVersion(apiV5)
+	assert.EqualError(t, err, "fetch task in job 'my-job' of stage 'my-stage' in pipeline 'my-pipeline' uses artifact_origin 'external', which can not be expressed with the pipeline config API v5 (GoCD < 18.7.0)")
+}
+
+func testPipelineForAPIVersionExternalArtifacts(t *testing.T) {
+	p := &Pipeline{
+		Name: "my-pipeline",
+		Stages: []*Stage{{
+			Name: "my-stage",
+			Jobs: []*Job{{
+				Name: "my-job",
+				Artifacts: []*Artifact{
+					{Type: "build", Source: "bin/"},
+					{Type: "external", ID: "image", StoreID: "registry"},
+				},
+			}},
+		}},
+	}
+
+	adapted, _, err := p.forAPIVersion(apiV6)
+	assert.NoError(t, err)
+	assert.Equal(t, p.Stages[0].Jobs[0].Artifacts, adapted.Stages[0].Jobs[0].Artifacts)
+
+	_, _, err = p.forAPIVersion(apiV5)
+	assert.EqualError(t, err, "external artifact 'image' in job 'my-job' of stage 'my-stage' in pipeline 'my-pipeline' is published by an artifact plugin, which can not be expressed with the pipeline config API v5 (GoCD < 18.7.0)")
+
+	p.Stages[0].Jobs[0].Artifacts = p.Stages[0].Jobs[0].Artifacts[:1]
+	_, _, err = p.forAPIVersion(apiV1)
+	assert.NoError(t, err)
+}
+
+func testPipelineForAPIVersionNumber(t *testing.T) {
+	for apiVersion, number := range map[string]int{
+		apiV0:           0,
+		apiV1:           1,
+		apiV10:          10,
+		"text/html":     0,
+		"something+xml": 0,
+	} {
+		assert.Equal(t, number, apiVersionNumber(apiVersion), apiVersion)
+	}
+}
+
+func testPipelineForAPIVersionUpdate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		j, _ := ioutil.ReadFile("test/resources/version.1.json")
+		fmt.Fprint(w, string(j))
+	})
+	mux.HandleFunc("/api/admin/pipelines/my-pipeline", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, apiV3, r.Header.Get("Accept"))
+
+		body := map[string]interface{}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, true, body["enable_pipeline_locking"])
+		assert.NotContains(t, body, "lock_behavior")
+		fmt.Fprint(w, `{"name": "my-pipeline", "enable_pipeline_locking": true}`)
+	})
+
+	p, _, err := client.PipelineConfigs.Update(context.Background(), "my-pipeline", &Pipeline{
+		Name:         "my-pipeline",
+		LockBehavior: LockBehaviorLockOnFailure,
+	})
+	assert.NoError(t, err)
+	assert.True(t, p.EnablePipelineLocking)
+
+	_, _, err = client.PipelineConfigs.Update(context.Background(), "my-pipeline", &Pipeline{
+		Name:         "my-pipeline",
+		LockBehavior: LockBehaviorUnlockWhenFinished,
+	})
+	assert.EqualError(t, err, "lock_behavior 'unlockWhenFinished' of pipeline 'my-pipeline' can not be expressed with the pipeline config API v3 (GoCD < 17.12.0)")
+}
+
+func testPipelineForAPIVersionCreate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		j, _ := ioutil.ReadFile("test/resources/version.2.json")
+		fmt.Fprint(w, string(j))
+	})
+	mux.HandleFunc("/api/admin/pipelines", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, apiV5, r.Header.Get("Accept"))
+
+		body := struct {
+			Group    string                 `json:"group"`
+			Pipeline map[string]interface{} `json:"pipeline"`
+		}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, LockBehaviorLockOnFailure, body.Pipeline["lock_behavior"])
+		assert.NotContains(t, body.Pipeline, "enable_pipeline_locking")
+		fmt.Fprint(w, `{"name": "my-pipeline", "lock_behavior": "lockOnFailure"}`)
+	})
+
+	logger := &recordingLogger{}
+	client.SetLogger(logger)
+	defer client.SetLogger(nil)
+
+	p, _, err := client.PipelineConfigs.Create(context.Background(), "my-group", &Pipeline{
+		Name:                  "my-pipeline",
+		EnablePipelineLocking: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, LockBehaviorLockOnFailure, p.LockBehavior)
+	assert.Contains(t, logger.messages, "enable_pipeline_locking of pipeline 'my-pipeline' is not supported by the pipeline config API v5, and was converted to lock_behavior 'lockOnFailure'")
+}
@@ -0,0 +1,117 @@
+package gocd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// cronField describes a field of a Quartz cron expression, which GoCD uses for pipeline timers.
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // names are aliases for the values from min, eg: "JAN" for 1.
+	// special matches the values only allowed in this field, eg: "L" or "15W" in the day of month.
+	special *regexp.Regexp
+}
+
+var cronFields = []cronField{
+	{name: "seconds", min: 0, max: 59},
+	{name: "minutes", min: 0, max: 59},
+	{name: "hours", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31, special: regexp.MustCompile(`^(L(-\d+)?|LW|\d+W)$`)},
+	{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+	{name: "day of week", min: 1, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}, special: regexp.MustCompile(`^(L|\w+L|\w+#[1-5])$`)},
+	{name: "year", min: 1970, max: 2099},
+}
+
+// Validate the timer's spec, a Quartz cron expression with seconds, minutes, hours, day of month, month, day of week,
+// and optionally year fields, eg: `0 0 22 ? * MON-FRI`.
+func (t *Timer) Validate() error {
+	fields := strings.Fields(t.Spec)
+	if len(fields) < 6 || len(fields) > 7 {
+		return fmt.Errorf("timer spec '%s' must have 6 or 7 fields, not %d", t.Spec, len(fields))
+	}
+
+	for i, value := range fields {
+		if err := cronFields[i].validate(value); err != nil {
+			return fmt.Errorf("timer spec '%s' is invalid: %s", t.Spec, err)
+		}
+	}
+
+	dayOfMonth, dayOfWeek := fields[3], fields[5]
+	if (dayOfMonth == "?") == (dayOfWeek == "?") {
+		return fmt.Errorf("timer spec '%s' is invalid: exactly one of day of month and day of week must be '?'", t.Spec)
+	}
+	return nil
+}
+
+func (cf cronField) validate(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if err := cf.validateItem(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateItem checks an item of a list, eg: `*`, `?`, `5`, `MON-FRI`, `0/15`, or `10-40/5`.
+func (cf cronField) validateItem(item string) error {
+	if item == "*" {
+		return nil
+	}
+	if item == "?" {
+		if cf.special == nil {
+			return fmt.Errorf("'?' is only allowed in the day of month and day of week")
+		}
+		return nil
+	}
+	if cf.special != nil && cf.special.MatchString(item) {
+		return cf.validateSpecial(item)
+	}
+
+	rangeSpec, step := item, ""
+	if i := strings.Index(item, "/"); i >= 0 {
+		rangeSpec, step = item[:i], item[i+1:]
+		if n, err := strconv.Atoi(step); err != nil || n < 1 {
+			return fmt.Errorf("invalid step '%s' in %s", step, cf.name)
+		}
+		if rangeSpec == "*" {
+			return nil
+		}
+	}
+
+	bounds := strings.SplitN(rangeSpec, "-", 2)
+	for _, bound := range bounds {
+		if _, err := cf.value(bound); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSpecial checks the values used with `L`, `W` and `#`.
+func (cf cronField) validateSpecial(item string) error {
+	value := strings.TrimRight(strings.SplitN(item, "#", 2)[0], "LW")
+	if value == "" || strings.HasPrefix(item, "L-") {
+		return nil
+	}
+	_, err := cf.value(value)
+	return err
+}
+
+// value parses a single value of the field, which may be a name.
+func (cf cronField) value(value string) (int, error) {
+	for i, name := range cf.names {
+		if strings.EqualFold(name, value) {
+			return cf.min + i, nil
+		}
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < cf.min || n > cf.max {
+		return 0, fmt.Errorf("invalid %s '%s', must be between %d and %d", cf.name, value, cf.min, cf.max)
+	}
+	return n, nil
+}
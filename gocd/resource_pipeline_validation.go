@@ -0,0 +1,195 @@
+package gocd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// labelTemplateToken matches the tokens of a label template, eg: `${COUNT}`, `${git[:7]}`, or `${env:BUILD}`.
+var labelTemplateToken = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// materialTokenTruncation matches the truncation of a material revision in a label template, eg: `[:7]`.
+var materialTokenTruncation = regexp.MustCompile(`\[:\d+\]$`)
+
+// Validate the pipeline locally, returning a ValidationError listing every problem found. This catches mistakes which
+// would be rejected by the GoCD server, without making a request to it. Tasks are only checked for their type and
+// `run_if` values, see Task.Validate for the stricter checks of each type of task.
+func (p *Pipeline) Validate() error {
+	probs := problems{}
+	probs.name("pipeline", p.Name)
+
+	p.validateMaterials(&probs)
+	p.validateLabelTemplate(&probs)
+	if p.Timer != nil && p.Timer.Spec != "" {
+		if err := p.Timer.Validate(); err != nil {
+			probs.add("%s", err)
+		}
+	}
+
+	switch {
+	case p.Template != "" && len(p.Stages) > 0:
+		probs.add("pipeline must not have stages when it uses template '%s'", p.Template)
+	case p.Template != "":
+		probs.name("template", p.Template)
+	case len(p.Stages) == 0:
+		probs.add("pipeline must have a template or at least one stage")
+	}
+	validateStages(&probs, p.Stages)
+
+	return probs.err(fmt.Sprintf("pipeline '%s'", p.Name))
+}
+
+// Validate the template locally, returning a ValidationError listing every problem found, as Pipeline.Validate does.
+func (pt *PipelineTemplate) Validate() error {
+	probs := problems{}
+	probs.name("template", pt.Name)
+	if len(pt.Stages) == 0 {
+		probs.add("template must have at least one stage")
+	}
+	validateStages(&probs, pt.Stages)
+
+	return probs.err(fmt.Sprintf("template '%s'", pt.Name))
+}
+
+// validateMaterials checks a pipeline has materials, and that they can be told apart: several materials of the same
+// type must be named, and names must be unique. Unnamed dependency materials are known by their upstream pipeline.
+func (p *Pipeline) validateMaterials(probs *problems) {
+	if len(p.Materials) == 0 {
+		probs.add("pipeline must have at least one material")
+	}
+
+	byType := map[string]int{}
+	for _, m := range p.Materials {
+		byType[strings.ToLower(m.Type)]++
+	}
+
+	names := map[string]bool{}
+	for i, m := range p.Materials {
+		if m.Attributes == nil {
+			probs.add("material %d of type '%s' has no attributes", i+1, m.Type)
+			continue
+		}
+		name, named := materialReference(m)
+		if !named {
+			continue
+		}
+		if name == "" {
+			if byType[strings.ToLower(m.Type)] > 1 {
+				probs.add("'%s' material %d must be named, as the pipeline has several '%s' materials", m.Type, i+1, m.Type)
+			}
+			continue
+		}
+
+		probs.name("material", name)
+		if names[strings.ToLower(name)] {
+			probs.add("material name '%s' is duplicated", name)
+		}
+		names[strings.ToLower(name)] = true
+	}
+}
+
+// validateLabelTemplate checks each token of the label template refers to the pipeline counter, an environment
+// variable, or a material, and that the label changes with each run of the pipeline.
+func (p *Pipeline) validateLabelTemplate(probs *problems) {
+	if p.LabelTemplate == "" {
+		return
+	}
+
+	materials := map[string]bool{}
+	for _, m := range p.Materials {
+		if name, _ := materialReference(m); name != "" {
+			materials[strings.ToLower(name)] = true
+		}
+	}
+
+	unique := false
+	for _, match := range labelTemplateToken.FindAllStringSubmatch(p.LabelTemplate, -1) {
+		token := match[1]
+		switch {
+		case strings.EqualFold(token, "COUNT"):
+			unique = true
+		case strings.HasPrefix(strings.ToLower(token), "env:") && len(token) > len("env:"):
+		case materials[strings.ToLower(materialTokenTruncation.ReplaceAllString(token, ""))]:
+			unique = true
+		default:
+			probs.add("label template token '${%s}' does not refer to COUNT, an environment variable, or a material", token)
+		}
+	}
+
+	if strings.Count(p.LabelTemplate, "${") != len(labelTemplateToken.FindAllString(p.LabelTemplate, -1)) {
+		probs.add("label template '%s' has an unterminated token", p.LabelTemplate)
+	}
+	if !unique {
+		probs.add("label template '%s' must contain '${COUNT}' or a material token", p.LabelTemplate)
+	}
+}
+
+// validateStages checks stage and job names are valid and unique, and that tasks have valid types and `run_if` values.
+func validateStages(probs *problems, stages []*Stage) {
+	stageNames := map[string]bool{}
+	for _, stage := range stages {
+		if stage == nil {
+			continue
+		}
+		probs.name("stage", stage.Name)
+		if stageNames[strings.ToLower(stage.Name)] {
+			probs.add("stage name '%s' is duplicated", stage.Name)
+		}
+		stageNames[strings.ToLower(stage.Name)] = true
+
+		if len(stage.Jobs) == 0 {
+			probs.add("stage '%s' must have at least one job", stage.Name)
+		}
+		jobNames := map[string]bool{}
+		for _, job := range stage.Jobs {
+			if job == nil {
+				continue
+			}
+			probs.name("job", job.Name)
+			if jobNames[strings.ToLower(job.Name)] {
+				probs.add("job name '%s' is duplicated in stage '%s'", job.Name, stage.Name)
+			}
+			jobNames[strings.ToLower(job.Name)] = true
+
+			for i, task := range job.Tasks {
+				if task == nil {
+					continue
+				}
+				if err := task.validateRunIf(); err != nil {
+					probs.add("task %d of job '%s' in stage '%s': %s", i+1, job.Name, stage.Name, err)
+				}
+			}
+		}
+	}
+}
+
+// materialReference returns the name the material is known by in the pipeline, which is the upstream pipeline of
+// unnamed dependency materials.
+func materialReference(m Material) (name string, named bool) {
+	name, named = materialName(m)
+	if dependency, isDependency := m.Attributes.(*MaterialAttributesDependency); isDependency && name == "" {
+		name = dependency.Pipeline
+	}
+	return
+}
+
+// materialName returns the name of a material, and false for the materials which are referenced rather than named.
+func materialName(m Material) (name string, named bool) {
+	switch a := m.Attributes.(type) {
+	case *MaterialAttributesGit:
+		return a.Name, true
+	case *MaterialAttributesSvn:
+		return a.Name, true
+	case *MaterialAttributesHg:
+		return a.Name, true
+	case *MaterialAttributesP4:
+		return a.Name, true
+	case *MaterialAttributesTfs:
+		return a.Name, true
+	case *MaterialAttributesDependency:
+		return a.Name, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,254 @@
+package gocd
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipelineValidate(t *testing.T) {
+	t.Run("Valid", testPipelineValidateValid)
+	t.Run("AllProblems", testPipelineValidateAllProblems)
+	t.Run("Names", testPipelineValidateNames)
+	t.Run("Materials", testPipelineValidateMaterials)
+	t.Run("LabelTemplate", testPipelineValidateLabelTemplate)
+	t.Run("Timer", testPipelineValidateTimer)
+	t.Run("Template", testPipelineValidateTemplate)
+	t.Run("RunIf", testPipelineValidateRunIf)
+	t.Run("PipelineTemplate", testPipelineValidatePipelineTemplate)
+	t.Run("BeforeWrite", testPipelineValidateBeforeWrite)
+}
+
+func validPipeline() *Pipeline {
+	return &Pipeline{
+		Name:          "my-pipeline",
+		LabelTemplate: "${COUNT}-${my-repo[:7]}",
+		Materials: []Material{{
+			Type:       "git",
+			Attributes: &MaterialAttributesGit{Name: "my-repo", URL: "https://github.com/gocd/gocd"},
+		}, {
+			Type:       "dependency",
+			Attributes: &MaterialAttributesDependency{Pipeline: "upstream", Stage: "build"},
+		}},
+		Timer: &Timer{Spec: "0 0 22 ? * MON-FRI"},
+		Stages: []*Stage{{
+			Name: "build",
+			Jobs: []*Job{{
+				Name: "compile",
+				Tasks: []*Task{
+					{Type: "exec", Attributes: &TaskAttributesExec{Command: "make"}},
+					{Type: "exec", Attributes: &TaskAttributesExec{RunIf: []string{"failed"}, Command: "cleanup"}},
+				},
+			}, {
+				Name: "lint",
+			}},
+		}, {
+			Name: "test",
+			Jobs: []*Job{{Name: "compile"}},
+		}},
+	}
+}
+
+func problemsOf(t *testing.T, err error) []string {
+	if !assert.Error(t, err) {
+		return nil
+	}
+	verr, isValidationError := err.(*ValidationError)
+	if !assert.True(t, isValidationError, err.Error()) {
+		return nil
+	}
+	return verr.Problems
+}
+
+func testPipelineValidateValid(t *testing.T) {
+	assert.NoError(t, validPipeline().Validate())
+
+	p := validPipeline()
+	p.LabelTemplate = "${upstream}"
+	assert.NoError(t, p.Validate())
+
+	p.LabelTemplate = "1.0.${COUNT}-${env:BUILD_TAG}-#{param}"
+	assert.NoError(t, p.Validate())
+}
+
+func testPipelineValidateAllProblems(t *testing.T) {
+	p := validPipeline()
+	p.Name = ".hidden"
+	p.Stages[1].Name = "BUILD"
+	p.Timer.Spec = "0 0 22 * *"
+
+	err := p.Validate()
+	assert.Equal(t, []string{
+		"pipeline name '.hidden' must only contain letters, numbers, underscores, hyphens and periods, must not start with a period, and must be at most 255 characters long",
+		"timer spec '0 0 22 * *' must have 6 or 7 fields, not 5",
+		"stage name 'BUILD' is duplicated",
+	}, problemsOf(t, err))
+	assert.True(t, strings.HasPrefix(err.Error(), "pipeline '.hidden' is invalid: pipeline name '.hidden' must only"))
+}
+
+func testPipelineValidateNames(t *testing.T) {
+	p := validPipeline()
+	p.Name = strings.Repeat("a", 256)
+	p.Stages[0].Name = ""
+	p.Stages[0].Jobs[1].Name = "Compile"
+	p.Stages[1].Jobs = append(p.Stages[1].Jobs, &Job{Name: "with space"})
+
+	assert.Equal(t, []string{
+		"pipeline name '" + p.Name + "' must only contain letters, numbers, underscores, hyphens and periods, must not start with a period, and must be at most 255 characters long",
+		"stage name must not be empty",
+		"job name 'Compile' is duplicated in stage ''",
+		"job name 'with space' must only contain letters, numbers, underscores, hyphens and periods, must not start with a period, and must be at most 255 characters long",
+	}, problemsOf(t, p.Validate()))
+}
+
+func testPipelineValidateMaterials(t *testing.T) {
+	p := validPipeline()
+	p.Materials = nil
+	p.LabelTemplate = ""
+	assert.Equal(t, []string{"pipeline must have at least one material"}, problemsOf(t, p.Validate()))
+
+	p = validPipeline()
+	p.LabelTemplate = ""
+	p.Materials = append(p.Materials,
+		Material{Type: "git", Attributes: &MaterialAttributesGit{URL: "https://github.com/gocd/docs"}},
+		Material{Type: "hg", Attributes: &MaterialAttributesHg{Name: "My-Repo", URL: "https://hg.example.com"}},
+		Material{Type: "package", Attributes: &MaterialAttributesPackage{Ref: "pkg-1"}},
+		Material{Type: "package", Attributes: &MaterialAttributesPackage{Ref: "pkg-2"}},
+		Material{Type: "svn"},
+	)
+	assert.Equal(t, []string{
+		"'git' material 3 must be named, as the pipeline has several 'git' materials",
+		"material name 'My-Repo' is duplicated",
+		"material 7 of type 'svn' has no attributes",
+	}, problemsOf(t, p.Validate()))
+
+	p = validPipeline()
+	p.Materials = append(p.Materials,
+		Material{Type: "dependency", Attributes: &MaterialAttributesDependency{Pipeline: "other-upstream", Stage: "build"}},
+		Material{Type: "dependency", Attributes: &MaterialAttributesDependency{Pipeline: "upstream", Stage: "test"}},
+		Material{Type: "dependency", Attributes: &MaterialAttributesDependency{Name: "my-repo", Pipeline: "third-upstream", Stage: "build"}},
+	)
+	assert.Equal(t, []string{
+		"material name 'upstream' is duplicated",
+		"material name 'my-repo' is duplicated",
+	}, problemsOf(t, p.Validate()))
+}
+
+func testPipelineValidateLabelTemplate(t *testing.T) {
+	for labelTemplate, want := range map[string][]string{
+		"${COUNT":          {"label template '${COUNT' has an unterminated token", "label template '${COUNT' must contain '${COUNT}' or a material token"},
+		"${env:BUILD}":     {"label template '${env:BUILD}' must contain '${COUNT}' or a material token"},
+		"${COUNT}-${docs}": {"label template token '${docs}' does not refer to COUNT, an environment variable, or a material"},
+		"${count}-${env:}": {"label template token '${env:}' does not refer to COUNT, an environment variable, or a material"},
+	} {
+		p := validPipeline()
+		p.LabelTemplate = labelTemplate
+		assert.Equal(t, want, problemsOf(t, p.Validate()), labelTemplate)
+	}
+}
+
+func testPipelineValidateTimer(t *testing.T) {
+	for _, spec := range []string{
+		"0 0 22 ? * MON-FRI",
+		"0 0/15 * * * ?",
+		"0 15 10 ? * 6L 2030",
+		"0 15 10 L-2 * ?",
+		"0 15 10 15W JAN,MAR-DEC/2 ?",
+		"0 15 10 ? * 6#3",
+		"*/5 * * * * ?",
+	} {
+		assert.NoError(t, (&Timer{Spec: spec}).Validate(), spec)
+	}
+
+	for spec, want := range map[string]string{
+		"0 0 22 * * MON":      "timer spec '0 0 22 * * MON' is invalid: exactly one of day of month and day of week must be '?'",
+		"0 0 22 ? * ?":        "timer spec '0 0 22 ? * ?' is invalid: exactly one of day of month and day of week must be '?'",
+		"60 0 22 ? * MON":     "timer spec '60 0 22 ? * MON' is invalid: invalid seconds '60', must be between 0 and 59",
+		"0 0 22 ? FOO MON":    "timer spec '0 0 22 ? FOO MON' is invalid: invalid month 'FOO', must be between 1 and 12",
+		"0 0/0 22 ? * MON":    "timer spec '0 0/0 22 ? * MON' is invalid: invalid step '0' in minutes",
+		"0 ? 22 ? * MON":      "timer spec '0 ? 22 ? * MON' is invalid: '?' is only allowed in the day of month and day of week",
+		"0 0 22 ? * MON 1900": "timer spec '0 0 22 ? * MON 1900' is invalid: invalid year '1900', must be between 1970 and 2099",
+	} {
+		assert.EqualError(t, (&Timer{Spec: spec}).Validate(), want, spec)
+	}
+
+	p := validPipeline()
+	p.Timer = &Timer{}
+	assert.NoError(t, p.Validate(), "an empty timer is not validated")
+}
+
+func testPipelineValidateTemplate(t *testing.T) {
+	p := validPipeline()
+	p.Template = "my-template"
+	assert.Equal(t, []string{"pipeline must not have stages when it uses template 'my-template'"}, problemsOf(t, p.Validate()))
+
+	p.Stages = nil
+	assert.NoError(t, p.Validate())
+
+	p.Template = ""
+	assert.Equal(t, []string{"pipeline must have a template or at least one stage"}, problemsOf(t, p.Validate()))
+}
+
+func testPipelineValidateRunIf(t *testing.T) {
+	p := validPipeline()
+	p.Stages[0].Jobs[0].Tasks = append(p.Stages[0].Jobs[0].Tasks,
+		&Task{Type: "rake", Attributes: &TaskAttributesRake{RunIf: []string{"passed", "always"}}},
+		&Task{Type: "fetch", Attributes: &TaskAttributesExec{}},
+	)
+
+	assert.Equal(t, []string{
+		"task 3 of job 'compile' in stage 'build': 'run_if' must be one of 'passed', 'failed' or 'any', not 'always'",
+		"task 4 of job 'compile' in stage 'build': attributes of a 'fetch' task must be *gocd.TaskAttributesFetch, not *gocd.TaskAttributesExec",
+	}, problemsOf(t, p.Validate()))
+}
+
+func testPipelineValidatePipelineTemplate(t *testing.T) {
+	assert.NoError(t, (&PipelineTemplate{Name: "my-template", Stages: validPipeline().Stages}).Validate())
+
+	err := (&PipelineTemplate{Name: "my template"}).Validate()
+	assert.Equal(t, []string{
+		"template name 'my template' must only contain letters, numbers, underscores, hyphens and periods, must not start with a period, and must be at most 255 characters long",
+		"template must have at least one stage",
+	}, problemsOf(t, err))
+	assert.True(t, strings.HasPrefix(err.Error(), "template 'my template' is invalid: "))
+}
+
+func testPipelineValidateBeforeWrite(t *testing.T) {
+	setup()
+	defer teardown()
+
+	requests := 0
+	mux.HandleFunc("/api/admin/pipelines/my-pipeline", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"name": "my-pipeline"}`))
+	})
+	mux.HandleFunc("/api/admin/templates", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"name": "my-template"}`))
+	})
+
+	invalid := validPipeline()
+	invalid.Stages = nil
+
+	// Validation is opt-in, so the server is left to reject the pipeline by default.
+	_, _, err := client.PipelineConfigs.Update(context.Background(), "my-pipeline", invalid)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	client.ValidateBeforeWrite(true)
+
+	_, _, err = client.PipelineConfigs.Update(context.Background(), "my-pipeline", invalid)
+	assert.EqualError(t, err, "pipeline 'my-pipeline' is invalid: pipeline must have a template or at least one stage")
+	_, _, err = client.PipelineConfigs.Create(context.Background(), "my-group", invalid)
+	assert.Error(t, err)
+	_, _, err = client.PipelineTemplates.Create(context.Background(), "my-template", nil)
+	assert.EqualError(t, err, "template 'my-template' is invalid: template must have at least one stage")
+	assert.Equal(t, 1, requests)
+
+	_, _, err = client.PipelineConfigs.Update(context.Background(), "my-pipeline", validPipeline())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, requests)
+}
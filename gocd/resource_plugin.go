@@ -0,0 +1,50 @@
+package gocd
+
+// SetVersion sets a version string for these plugin settings
+func (ps *PluginSettings) SetVersion(version string) {
+	ps.Version = version
+}
+
+// GetVersion retrieves a version string for these plugin settings
+func (ps *PluginSettings) GetVersion() (version string) {
+	return ps.Version
+}
+
+// RemoveLinks from the plugin settings for json marshalling.
+func (ps *PluginSettings) RemoveLinks() {
+	ps.Links = nil
+}
+
+// GetLinks from the plugin settings
+func (ps *PluginSettings) GetLinks() *HALLinks {
+	return ps.Links
+}
+
+// fromAPIVersion fills the fields of the plugin which the version of the plugin info API it was read with does not
+// return, from their equivalent in that version. The name and version of a plugin are returned at the top level up to
+// v2 of the API, and in About from v3. The type of a plugin is returned at the top level up to v3, and with each
+// extension from v4, so it is only filled for plugins with a single extension.
+func (p *Plugin) fromAPIVersion(apiVersion string) {
+	if p == nil {
+		return
+	}
+	if apiVersionNumber(apiVersion) < 3 {
+		if p.About.Name == "" {
+			p.About.Name = p.Name
+		}
+		if p.About.Version == "" {
+			p.About.Version = p.Version
+		}
+		return
+	}
+
+	if p.Name == "" {
+		p.Name = p.About.Name
+	}
+	if p.Version == "" {
+		p.Version = p.About.Version
+	}
+	if p.Type == "" && len(p.Extensions) == 1 && p.Extensions[0] != nil {
+		p.Type = p.Extensions[0].Type
+	}
+}
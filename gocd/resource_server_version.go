@@ -1,20 +1,64 @@
 package gocd
 
 import (
+	"context"
 	"fmt"
 	"github.com/hashicorp/go-version"
 	"sort"
+	"strings"
 )
 
 var serverVersionLookup *serverVersionCollection
 
 func init() {
-	// This structure lists the minimum version of GoCD in which the corresponding API version is available for a given endpoint
+	pluginInfo := newVersionCollection(
+		newServerAPI("16.7.0", apiV1),
+		newServerAPI("16.12.0", apiV2),
+		newServerAPI("17.9.0", apiV3),
+		newServerAPI("18.3.0", apiV4),
+		newServerAPI("19.3.0", apiV5),
+		newServerAPI("19.6.0", apiV6))
+
+	agents := newVersionCollection(
+		newServerAPI("16.1.0", apiV2),
+		newServerAPI("16.10.0", apiV3),
+		newServerAPI("17.4.0", apiV4),
+		newServerAPI("19.3.0", apiV5),
+		newServerAPI("19.9.0", apiV6),
+		newServerAPI("20.2.0", apiV7))
+
+	agentJobRunHistory := newVersionCollection(
+		newServerAPI("16.1.0", apiV2),
+		newServerAPI("16.10.0", apiV3),
+		newServerAPI("17.4.0", apiV4),
+		newServerAPI("20.1.0", apiV1))
+
+	configRepos := newVersionCollection(
+		newServerAPI("17.12.0", apiV1),
+		newServerAPI("19.10.0", apiV2),
+		newServerAPI("20.2.0", apiV3),
+		newServerAPI("20.8.0", apiV4))
+
+	pipelineHistory := newVersionCollection(
+		newServerAPI("14.3.0", apiV0),
+		newServerAPI("20.1.0", apiV1))
+
+	pluginSettings := newVersionCollection(
+		newServerAPI("17.12.0", apiV1))
+
+	unversioned := newVersionCollection(
+		newServerAPI("14.3.0", apiV0))
+
+	// This structure lists the minimum version of GoCD in which the corresponding API version is available for a given
+	// endpoint. Requests to servers older than the first version listed for an endpoint are refused, and newer servers
+	// are sent the newest API version listed. The APIs used by the library kept the versions they had in GoCD 20.8.0
+	// up to GoCD 23.x, apart from plugin info, whose versions are only listed up to GoCD 20.8.0.
 	serverVersionLookup = &serverVersionCollection{
 		mapping: map[endpointS]*serverAPIVersionMappingCollection{
 			"/api/version": newVersionCollection(
 				newServerAPI("16.6.0", apiV1)),
 			"/api/admin/pipelines/:pipeline_name": newVersionCollection(
+				newServerAPI("20.8.0", apiV11),
 				newServerAPI("19.10.0", apiV10),
 				newServerAPI("19.8.0", apiV9),
 				newServerAPI("19.6.0", apiV8),
@@ -38,13 +82,16 @@ func init() {
 			"/api/pipelines/:pipeline_name/schedule": newVersionCollection(
 				newServerAPI("14.3.0", apiV0),
 				newServerAPI("18.2.0", apiV1)),
-			"/api/admin/plugin_info": newVersionCollection(
-				newServerAPI("16.7.0", apiV1),
-				newServerAPI("16.12.0", apiV2),
-				newServerAPI("17.9.0", apiV3),
-				newServerAPI("18.3.0", apiV4),
-				newServerAPI("19.3.0", apiV5),
-				newServerAPI("19.6.0", apiV6)),
+			"/api/pipelines/:pipeline_name/status": newVersionCollection(
+				newServerAPI("14.3.0", apiV0),
+				newServerAPI("20.1.0", apiV1)),
+			"/api/pipelines/:pipeline_name/history":                    pipelineHistory,
+			"/api/pipelines/:pipeline_name/history/:offset":            unversioned,
+			"/api/pipelines/:pipeline_name/instance/:pipeline_counter": pipelineHistory,
+			"/api/admin/plugin_info":                                   pluginInfo,
+			"/api/admin/plugin_info/:plugin_id":                        pluginInfo,
+			"/api/admin/plugin_settings":                               pluginSettings,
+			"/api/admin/plugin_settings/:plugin_id":                    pluginSettings,
 			"/api/admin/templates": newVersionCollection(
 				newServerAPI("16.10.0", apiV1),
 				newServerAPI("16.11.0", apiV2),
@@ -73,20 +120,140 @@ func init() {
 			"/api/admin/environments/:environment_name": newVersionCollection(
 				newServerAPI("16.7.0", apiV2),
 				newServerAPI("19.9.0", apiV3)),
+			"/api/agents":                                     agents,
+			"/api/agents/:agent_uuid":                         agents,
+			"/api/agents/:agent_uuid/job_run_history":         agentJobRunHistory,
+			"/api/agents/:agent_uuid/job_run_history/:offset": agentJobRunHistory,
+			"/api/admin/config_repos":                         configRepos,
+			"/api/admin/config_repos/:id":                     configRepos,
+			"/api/admin/encrypt": newVersionCollection(
+				newServerAPI("17.1.0", apiV1)),
+			"/api/config/pipeline_groups": unversioned,
+			"/api/admin/config.xml":       unversioned,
+			"/api/jobs/scheduled.xml":     unversioned,
+			"/properties/search":          unversioned,
+			"/properties/:pipeline_name/:pipeline_counter/:stage_name/:stage_counter/:job_name":                unversioned,
+			"/properties/:pipeline_name/:pipeline_counter/:stage_name/:stage_counter/:job_name/:property_name": unversioned,
 		},
 	}
 }
 
+// UnsupportedEndpointError is returned when the GoCD server is older than the first version providing an endpoint.
+type UnsupportedEndpointError struct {
+	Endpoint       string
+	ServerVersion  string
+	MinimumVersion string
+}
+
+func (e *UnsupportedEndpointError) Error() string {
+	return fmt.Sprintf("could not find api version for server version '%s': '%s' requires GoCD >= %s",
+		e.ServerVersion, e.Endpoint, e.MinimumVersion)
+}
+
+// Capability is a feature of GoCD which is only available from a given server version.
+type Capability string
+
+// Capabilities which can be checked with Client.Supports
+const (
+	// CapabilityLockBehavior is set when pipeline configs use `lock_behavior` instead of `enable_pipeline_locking`.
+	CapabilityLockBehavior Capability = "lock_behavior"
+	// CapabilityPipelineUnlock is set when pipeline locks are released with the `unlock` endpoint, instead of
+	// `releaseLock`.
+	CapabilityPipelineUnlock Capability = "pipeline_unlock"
+	// CapabilityPipelineOrigin is set when pipeline configs describe whether they are defined in a config repo.
+	CapabilityPipelineOrigin Capability = "pipeline_origin"
+	// CapabilityEnvironments is set when environments can be managed through the API.
+	CapabilityEnvironments Capability = "environments"
+	// CapabilityPluginInfo is set when plugin information can be retrieved through the API.
+	CapabilityPluginInfo Capability = "plugin_info"
+	// CapabilityTemplates is set when pipeline templates can be managed through the API.
+	CapabilityTemplates Capability = "templates"
+	// CapabilityRoles is set when security roles can be managed through the API.
+	CapabilityRoles Capability = "roles"
+	// CapabilityConfigRepos is set when config repos can be managed through the API.
+	CapabilityConfigRepos Capability = "config_repos"
+	// CapabilityPluginSettings is set when the settings of plugins can be managed through the API.
+	CapabilityPluginSettings Capability = "plugin_settings"
+)
+
+// capabilityLookup lists the minimum version of GoCD providing each capability.
+var capabilityLookup = map[Capability]*version.Version{
+	CapabilityLockBehavior:   mustVersion("17.12.0"),
+	CapabilityPipelineUnlock: mustVersion("18.2.0"),
+	CapabilityPipelineOrigin: mustVersion("17.4.0"),
+	CapabilityEnvironments:   mustVersion("16.7.0"),
+	CapabilityPluginInfo:     mustVersion("16.7.0"),
+	CapabilityTemplates:      mustVersion("16.10.0"),
+	CapabilityRoles:          mustVersion("17.5.0"),
+	CapabilityConfigRepos:    mustVersion("17.12.0"),
+	CapabilityPluginSettings: mustVersion("17.12.0"),
+}
+
+// Supports checks whether this version of GoCD provides a capability.
+func (sv *ServerVersion) Supports(capability Capability) (bool, error) {
+	minimum, isKnown := capabilityLookup[capability]
+	if !isKnown {
+		return false, fmt.Errorf("unknown capability '%s'", capability)
+	}
+	return !sv.VersionParts.LessThan(minimum), nil
+}
+
+// Capabilities lists the capabilities this version of GoCD provides, sorted by name.
+func (sv *ServerVersion) Capabilities() []Capability {
+	capabilities := []Capability{}
+	for capability, minimum := range capabilityLookup {
+		if !sv.VersionParts.LessThan(minimum) {
+			capabilities = append(capabilities, capability)
+		}
+	}
+	sort.Slice(capabilities, func(i, j int) bool {
+		return capabilities[i] < capabilities[j]
+	})
+	return capabilities
+}
+
+// Supports checks whether the GoCD server provides a capability, so that callers can branch on features rather than
+// comparing server versions.
+//
+//	if supported, err := client.Supports(ctx, gocd.CapabilityLockBehavior); err == nil && supported {
+//		pipeline.LockBehavior = gocd.LockBehaviorLockOnFailure
+//	}
+func (c *Client) Supports(ctx context.Context, capability Capability) (bool, error) {
+	v, _, err := c.ServerVersion.Get(ctx)
+	if err != nil {
+		return false, err
+	}
+	return v.Supports(capability)
+}
+
 // GetAPIVersion for a given endpoint and method
 func (sv *ServerVersion) GetAPIVersion(endpoint string) (apiVersion string, err error) {
 
 	if versions, hasEndpoint := serverVersionLookup.GetEndpointOk(endpoint); hasEndpoint {
-		return versions.GetAPIVersion(sv.VersionParts)
+		if apiVersion, err = versions.GetAPIVersion(sv.VersionParts); err != nil {
+			return "", &UnsupportedEndpointError{
+				Endpoint:       endpoint,
+				ServerVersion:  sv.VersionParts.String(),
+				MinimumVersion: versions.minimum().String(),
+			}
+		}
+		return apiVersion, nil
 	}
 
 	return "", fmt.Errorf("could not find API version tag for '%s'", endpoint)
 }
 
+// GetAPIVersionForPath finds the API version for the endpoint matching a concrete path, eg:
+// `/api/admin/templates/my-template`.
+func (sv *ServerVersion) GetAPIVersionForPath(path string) (apiVersion string, err error) {
+	endpoint, hasEndpoint := serverVersionLookup.MatchEndpoint(path)
+	if !hasEndpoint {
+		return "", fmt.Errorf("could not find API version tag for '%s'", path)
+	}
+
+	return sv.GetAPIVersion(endpoint)
+}
+
 func (sv *ServerVersion) parseVersion() (err error) {
 	sv.VersionParts, err = version.NewVersion(sv.Version)
 	return
@@ -123,6 +290,15 @@ type serverAPIVersionMappingCollection struct {
 	mappings []*serverVersionToAcceptMapping
 }
 
+// mustVersion parses a hardcoded version, and panics on any errors.
+func mustVersion(v string) *version.Version {
+	parsed, err := version.NewVersion(v)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
 // newServerAPISlice provides some syntactic sugar to make the chaining resources a bit easier
 // to read.
 func newVersionCollection(mappings ...*serverVersionToAcceptMapping) *serverAPIVersionMappingCollection {
@@ -145,6 +321,64 @@ func newServerAPI(serverVersion, apiVersion string) (mapping *serverVersionToAcc
 	return
 }
 
+// MatchEndpoint finds the endpoint template matching a concrete path, eg: `/api/admin/templates/my-template` matches
+// `/api/admin/templates/:template_name`. When several templates match, the one with the fewest placeholders wins.
+func (svc *serverVersionCollection) MatchEndpoint(path string) (endpoint string, hasEndpoint bool) {
+	templates := make([]endpointS, 0, len(svc.mapping))
+	for template := range svc.mapping {
+		templates = append(templates, template)
+	}
+
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+
+	bestParams := -1
+	for _, template := range templates {
+		if params, matches := matchEndpointTemplate(string(template), segments); matches {
+			if bestParams < 0 || params < bestParams || (params == bestParams && string(template) < endpoint) {
+				endpoint, bestParams = string(template), params
+			}
+		}
+	}
+	return endpoint, bestParams >= 0
+}
+
+// matchEndpointTemplate checks whether the path segments match the template, and returns how many placeholders were
+// used.
+func matchEndpointTemplate(template string, segments []string) (params int, matches bool) {
+	templateSegments := strings.Split(template, "/")
+	if len(templateSegments) != len(segments) {
+		return 0, false
+	}
+	for i, segment := range templateSegments {
+		if strings.HasPrefix(segment, ":") && segments[i] != "" {
+			params++
+			continue
+		}
+		if segment != segments[i] {
+			return 0, false
+		}
+	}
+	return params, true
+}
+
+// getAPIVersionForPath finds the Accept header to use for a concrete path, relative to the server base url, eg:
+// `/api/admin/templates/my-template`.
+func (c *Client) getAPIVersionForPath(ctx context.Context, path string) (apiVersion string, err error) {
+	endpoint, hasEndpoint := serverVersionLookup.MatchEndpoint(path)
+	if !hasEndpoint {
+		return "", fmt.Errorf("could not find API version tag for '%s'", path)
+	}
+
+	v, _, err := c.ServerVersion.Get(ctx)
+	if err != nil {
+		return "", err
+	}
+	return v.GetAPIVersion(endpoint)
+}
+
 func (svc *serverVersionCollection) GetEndpointOk(endpoint string) (endpointMapping *serverAPIVersionMappingCollection, hasEndpoint bool) {
 	endpointMapping, hasEndpoint = svc.mapping[endpointS(endpoint)]
 	return
@@ -168,6 +402,12 @@ func (c *serverAPIVersionMappingCollection) GetAPIVersion(versionParts *version.
 	return lastMapping.API, nil
 }
 
+// minimum returns the first server version providing the endpoint.
+func (c *serverAPIVersionMappingCollection) minimum() *version.Version {
+	c.Sort()
+	return c.mappings[0].Server
+}
+
 // Sort the version collections
 func (c *serverAPIVersionMappingCollection) Sort() {
 	sort.Sort(c)
@@ -13,6 +13,121 @@ func testServerVersionResource(t *testing.T) {
 	t.Run("Equal", testServerVersionEqual)
 	t.Run("GetAPIVersion", testServerVersionGetAPIVersion)
 	t.Run("GetAPIVersionFail", testServerVersionGetAPIVersionFail)
+	t.Run("UnsupportedEndpoint", testServerVersionUnsupportedEndpoint)
+	t.Run("Mappings", testServerVersionMappings)
+	t.Run("NewerServers", testServerVersionNewerServers)
+	t.Run("MatchEndpoint", testServerVersionMatchEndpoint)
+	t.Run("GetAPIVersionForPath", testServerVersionGetAPIVersionForPath)
+	t.Run("Supports", testServerVersionSupports)
+	t.Run("Capabilities", testServerVersionCapabilities)
+	t.Run("CapabilityEndpoints", testServerVersionCapabilityEndpoints)
+}
+
+func testServerVersionSupports(t *testing.T) {
+	for _, test := range []struct {
+		version    string
+		capability Capability
+		supported  bool
+	}{
+		{"17.11.0", CapabilityLockBehavior, false},
+		{"17.12.0", CapabilityLockBehavior, true},
+		{"18.1.0", CapabilityPipelineUnlock, false},
+		{"18.2.0", CapabilityPipelineUnlock, true},
+		{"20.1.0", CapabilityRoles, true},
+	} {
+		ver, err := version.NewVersion(test.version)
+		assert.NoError(t, err)
+		sv := &ServerVersion{Version: test.version, VersionParts: ver}
+
+		supported, err := sv.Supports(test.capability)
+		assert.NoError(t, err)
+		assert.Equal(t, test.supported, supported, "%s on %s", test.capability, test.version)
+	}
+
+	ver, _ := version.NewVersion("18.2.0")
+	_, err := (&ServerVersion{VersionParts: ver}).Supports("time_travel")
+	assert.EqualError(t, err, "unknown capability 'time_travel'")
+}
+
+func testServerVersionCapabilities(t *testing.T) {
+	ver, err := version.NewVersion("17.4.0")
+	assert.NoError(t, err)
+	sv := &ServerVersion{Version: "17.4.0", VersionParts: ver}
+
+	assert.Equal(t, []Capability{
+		CapabilityEnvironments,
+		CapabilityPipelineOrigin,
+		CapabilityPluginInfo,
+		CapabilityTemplates,
+	}, sv.Capabilities())
+}
+
+// testServerVersionCapabilityEndpoints ensures capabilities providing an API are consistent with the versions of the
+// API negotiated for it.
+func testServerVersionCapabilityEndpoints(t *testing.T) {
+	for capability, endpoint := range map[Capability]string{
+		CapabilityEnvironments:   "/api/admin/environments",
+		CapabilityPluginInfo:     "/api/admin/plugin_info",
+		CapabilityTemplates:      "/api/admin/templates",
+		CapabilityRoles:          "/api/admin/security/roles",
+		CapabilityPipelineUnlock: "/api/pipelines/:pipeline_name/unlock",
+		CapabilityLockBehavior:   "/api/admin/pipelines/:pipeline_name",
+		CapabilityConfigRepos:    "/api/admin/config_repos",
+		CapabilityPluginSettings: "/api/admin/plugin_settings",
+	} {
+		versions, hasEndpoint := serverVersionLookup.GetEndpointOk(endpoint)
+		assert.True(t, hasEndpoint, endpoint)
+
+		minimum := capabilityLookup[capability]
+		apiVersion, err := versions.GetAPIVersion(minimum)
+		assert.NoError(t, err, "%s at %s", endpoint, minimum)
+
+		before := mustVersion(fmt.Sprintf("%d.%d.0", minimum.Segments()[0], minimum.Segments()[1]-1))
+		previous, err := versions.GetAPIVersion(before)
+		if err == nil {
+			assert.NotEqual(t, apiVersion, previous, "%s should change API version at %s", endpoint, minimum)
+		}
+	}
+}
+
+func testServerVersionGetAPIVersionForPath(t *testing.T) {
+	ver, err := version.NewVersion("18.2.0")
+	assert.NoError(t, err)
+	sv := &ServerVersion{Version: "18.2.0", VersionParts: ver}
+
+	for path, expected := range map[string]string{
+		"/api/admin/templates/my-template": apiV3,
+		"/api/admin/environments":          apiV2,
+		"/api/agents/my-agent":             apiV4,
+	} {
+		apiVersion, err := sv.GetAPIVersionForPath(path)
+		assert.NoError(t, err, path)
+		assert.Equal(t, expected, apiVersion, path)
+	}
+
+	_, err = sv.GetAPIVersionForPath("/api/unknown")
+	assert.EqualError(t, err, "could not find API version tag for '/api/unknown'")
+}
+
+func testServerVersionMatchEndpoint(t *testing.T) {
+	for _, test := range []struct {
+		path     string
+		endpoint string
+		matches  bool
+	}{
+		{"/api/version", "/api/version", true},
+		{"/api/admin/templates/my-template", "/api/admin/templates/:template_name", true},
+		{"/api/admin/templates/", "/api/admin/templates", true},
+		{"/api/pipelines/my-pipeline/unlock", "/api/pipelines/:pipeline_name/unlock", true},
+		{"/api/pipelines/my-pipeline/history/10?x=y", "/api/pipelines/:pipeline_name/history/:offset", true},
+		{"/api/agents/my-agent", "/api/agents/:agent_uuid", true},
+		{"/api/admin/templates//", "", false},
+		{"/api/unknown", "", false},
+	} {
+		endpoint, matches := serverVersionLookup.MatchEndpoint(test.path)
+		assert.Equal(t, test.matches, matches, test.path)
+		assert.Equal(t, test.endpoint, endpoint, test.path)
+	}
 }
 
 func testServerVersionEqual(t *testing.T) {
@@ -95,7 +210,7 @@ func testServerVersionGetAPIVersionFail(t *testing.T) {
 	}{
 		{
 			endpoint: "/api/version",
-			want:     "could not find api version for server version '1.0.0'",
+			want:     "could not find api version for server version '1.0.0': '/api/version' requires GoCD >= 16.6.0",
 			v:        &ServerVersion{Version: "1.0.0"},
 		},
 		{
@@ -105,7 +220,7 @@ func testServerVersionGetAPIVersionFail(t *testing.T) {
 		},
 		{
 			endpoint: "/api/admin/pipelines/:pipeline_name",
-			want:     "could not find api version for server version '0.1.0'",
+			want:     "could not find api version for server version '0.1.0': '/api/admin/pipelines/:pipeline_name' requires GoCD >= 15.3.0",
 			v:        &ServerVersion{Version: "0.1.0"},
 		},
 	} {
@@ -117,6 +232,82 @@ func testServerVersionGetAPIVersionFail(t *testing.T) {
 	}
 }
 
+func testServerVersionUnsupportedEndpoint(t *testing.T) {
+	v := &ServerVersion{Version: "17.4.0"}
+	assert.NoError(t, v.parseVersion())
+
+	_, err := v.GetAPIVersion("/api/admin/config_repos")
+	assert.Equal(t, &UnsupportedEndpointError{
+		Endpoint:       "/api/admin/config_repos",
+		ServerVersion:  "17.4.0",
+		MinimumVersion: "17.12.0",
+	}, err)
+	assert.EqualError(t, err, "could not find api version for server version '17.4.0': '/api/admin/config_repos' requires GoCD >= 17.12.0")
+}
+
+func testServerVersionMappings(t *testing.T) {
+	for _, test := range []struct {
+		version  string
+		endpoint string
+		want     string
+	}{
+		{version: "16.1.0", endpoint: "/api/agents", want: apiV2},
+		{version: "19.9.0", endpoint: "/api/agents/:agent_uuid", want: apiV6},
+		{version: "20.2.0", endpoint: "/api/agents", want: apiV7},
+		{version: "23.1.0", endpoint: "/api/agents", want: apiV7},
+		{version: "20.1.0", endpoint: "/api/agents/:agent_uuid/job_run_history", want: apiV1},
+		{version: "17.12.0", endpoint: "/api/admin/config_repos", want: apiV1},
+		{version: "20.8.0", endpoint: "/api/admin/config_repos/:id", want: apiV4},
+		{version: "22.3.0", endpoint: "/api/admin/environments", want: apiV3},
+		{version: "20.8.0", endpoint: "/api/admin/pipelines/:pipeline_name", want: apiV11},
+		{version: "20.1.0", endpoint: "/api/pipelines/:pipeline_name/history", want: apiV1},
+		{version: "19.12.0", endpoint: "/api/pipelines/:pipeline_name/history", want: apiV0},
+		{version: "20.1.0", endpoint: "/api/pipelines/:pipeline_name/status", want: apiV1},
+		{version: "16.1.0", endpoint: "/api/admin/config.xml", want: apiV0},
+		{version: "17.1.0", endpoint: "/api/admin/encrypt", want: apiV1},
+	} {
+		v := &ServerVersion{Version: test.version}
+		assert.NoError(t, v.parseVersion())
+
+		apiVersion, err := v.GetAPIVersion(test.endpoint)
+		assert.NoError(t, err, test.endpoint)
+		assert.Equal(t, test.want, apiVersion, fmt.Sprintf("%s on %s", test.endpoint, test.version))
+	}
+}
+
+// testServerVersionNewerServers checks the API version of each endpoint on the GoCD releases after the last version
+// listed for it.
+func testServerVersionNewerServers(t *testing.T) {
+	for endpoint, want := range map[string]string{
+		"/api/version":                                             apiV1,
+		"/api/admin/pipelines/:pipeline_name":                      apiV11,
+		"/api/pipelines/:pipeline_name/pause":                      apiV1,
+		"/api/pipelines/:pipeline_name/unpause":                    apiV1,
+		"/api/pipelines/:pipeline_name/unlock":                     apiV1,
+		"/api/pipelines/:pipeline_name/schedule":                   apiV1,
+		"/api/pipelines/:pipeline_name/status":                     apiV1,
+		"/api/pipelines/:pipeline_name/history":                    apiV1,
+		"/api/pipelines/:pipeline_name/instance/:pipeline_counter": apiV1,
+		"/api/admin/plugin_settings/:plugin_id":                    apiV1,
+		"/api/admin/templates/:template_name":                      apiV7,
+		"/api/admin/security/roles/:role_name":                     apiV3,
+		"/api/admin/environments/:environment_name":                apiV3,
+		"/api/agents/:agent_uuid":                                  apiV7,
+		"/api/agents/:agent_uuid/job_run_history":                  apiV1,
+		"/api/admin/config_repos/:id":                              apiV4,
+		"/api/admin/encrypt":                                       apiV1,
+	} {
+		for _, serverVersion := range []string{"21.1.0", "22.3.0", "23.1.0"} {
+			v := &ServerVersion{Version: serverVersion}
+			assert.NoError(t, v.parseVersion())
+
+			apiVersion, err := v.GetAPIVersion(endpoint)
+			assert.NoError(t, err, endpoint)
+			assert.Equal(t, want, apiVersion, fmt.Sprintf("%s on %s", endpoint, serverVersion))
+		}
+	}
+}
+
 func TestNewserverAPIVersionMapping(t *testing.T) {
 
 	mockVersion, err := version.NewVersion("1.0.0")
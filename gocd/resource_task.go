@@ -1,17 +1,115 @@
 package gocd
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
 
 // Validate each of the possible task types.
 func (t *Task) Validate() error {
-	switch t.Type {
-	case "":
+	if err := t.validateType(); err != nil {
+		return err
+	}
+
+	return t.Attributes.Validate()
+}
+
+// validateType checks the task's attributes are set, and match its type.
+func (t *Task) validateType() error {
+	if t.Type == "" {
 		return errors.New("Missing `gocd.TaskAttribute` type")
+	}
+
+	expected, err := newTaskAttribute(t.Type)
+	if err != nil {
+		return errors.New("Unexpected `gocd.Task.Attribute` types")
+	}
+	if t.Attributes == nil {
+		return fmt.Errorf("'%s' task is missing its attributes", t.Type)
+	}
+	if reflect.TypeOf(t.Attributes) != reflect.TypeOf(expected) {
+		return fmt.Errorf("attributes of a '%s' task must be %T, not %T", t.Type, expected, t.Attributes)
+	}
+	return nil
+}
+
+// validateRunIf checks the task's type, and its `run_if` values if any are set. GoCD runs tasks without `run_if` values
+// when the previous tasks passed.
+func (t *Task) validateRunIf() error {
+	if err := t.validateType(); err != nil {
+		return err
+	}
+
+	var runIf []string
+	switch a := t.Attributes.(type) {
+	case *TaskAttributesExec:
+		runIf = a.RunIf
+	case *TaskAttributesAnt:
+		runIf = a.RunIf
+	case *TaskAttributesNant:
+		runIf = a.RunIf
+	case *TaskAttributesRake:
+		runIf = a.RunIf
+	case *TaskAttributesFetch:
+		runIf = a.RunIf
+	case *TaskAttributesPluggable:
+		runIf = a.RunIf
+	}
+	if len(runIf) == 0 {
+		return nil
+	}
+	return validateRunIf(runIf)
+}
+
+// UnmarshalJSON string into a Task struct, choosing the structure of the attributes from the task's type.
+func (t *Task) UnmarshalJSON(b []byte) (err error) {
+	raw := struct {
+		Type       string          `json:"type"`
+		Attributes json.RawMessage `json:"attributes"`
+	}{}
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return
+	}
+
+	t.Type = raw.Type
+	return t.IngestAttributes(raw.Attributes)
+}
+
+// IngestAttributes to Task from their JSON representation
+func (t *Task) IngestAttributes(rawAttributes json.RawMessage) (err error) {
+	attributes, err := newTaskAttribute(t.Type)
+	if err != nil {
+		return
+	}
+
+	if len(rawAttributes) > 0 && string(rawAttributes) != "null" {
+		if err = json.Unmarshal(rawAttributes, attributes); err != nil {
+			return
+		}
+	}
+	t.Attributes = attributes
+
+	return
+}
+
+// newTaskAttribute creates an empty attribute structure for a type of task.
+func newTaskAttribute(taskType string) (TaskAttribute, error) {
+	switch taskType {
 	case "exec":
-		return t.Attributes.ValidateExec()
+		return &TaskAttributesExec{}, nil
 	case "ant":
-		return t.Attributes.ValidateAnt()
+		return &TaskAttributesAnt{}, nil
+	case "nant":
+		return &TaskAttributesNant{}, nil
+	case "rake":
+		return &TaskAttributesRake{}, nil
+	case "fetch":
+		return &TaskAttributesFetch{}, nil
+	case "pluggable_task":
+		return &TaskAttributesPluggable{}, nil
 	default:
-		return errors.New("Unexpected `gocd.Task.Attribute` types")
+		return nil, fmt.Errorf("Unexpected Task type: '%s'", taskType)
 	}
 }
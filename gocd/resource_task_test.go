@@ -0,0 +1,117 @@
+package gocd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskUnmarshal(t *testing.T) {
+	t.Run("Types", testTaskUnmarshalTypes)
+	t.Run("Fetch", testTaskUnmarshalFetch)
+	t.Run("Pluggable", testTaskUnmarshalPluggable)
+	t.Run("RoundTrip", testTaskUnmarshalRoundTrip)
+	t.Run("Fail", testTaskUnmarshalFail)
+}
+
+func testTaskUnmarshalTypes(t *testing.T) {
+	for taskType, expected := range map[string]TaskAttribute{
+		"exec":           &TaskAttributesExec{},
+		"ant":            &TaskAttributesAnt{},
+		"nant":           &TaskAttributesNant{},
+		"rake":           &TaskAttributesRake{},
+		"fetch":          &TaskAttributesFetch{},
+		"pluggable_task": &TaskAttributesPluggable{},
+	} {
+		task := &Task{}
+		assert.NoError(t, json.Unmarshal([]byte(`{"type": "`+taskType+`"}`), task), taskType)
+		assert.Equal(t, expected, task.Attributes, taskType)
+	}
+
+	task := &Task{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"type": "exec",
+		"attributes": {"run_if": ["passed"], "command": "make", "arguments": ["test"], "working_directory": "src"}
+	}`), task))
+	assert.Equal(t, &TaskAttributesExec{
+		RunIf:            []string{"passed"},
+		Command:          "make",
+		Arguments:        []string{"test"},
+		WorkingDirectory: "src",
+	}, task.Attributes)
+	assert.NoError(t, task.Validate())
+}
+
+func testTaskUnmarshalFetch(t *testing.T) {
+	task := &Task{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"type": "fetch",
+		"attributes": {
+			"artifact_origin": "external",
+			"run_if": ["passed"],
+			"pipeline": "upstream",
+			"stage": "build",
+			"job": "image",
+			"artifact_id": "docker-image",
+			"configuration": [{"key": "EnvironmentVariablePrefix", "value": "IMAGE"}]
+		}
+	}`), task))
+
+	fetch, isFetch := task.Attributes.(*TaskAttributesFetch)
+	if assert.True(t, isFetch) {
+		assert.Equal(t, "external", fetch.ArtifactOrigin)
+		assert.Equal(t, "docker-image", fetch.ArtifactID)
+		assert.Equal(t, []PluginConfigurationKVPair{{Key: "EnvironmentVariablePrefix", Value: "IMAGE"}}, fetch.Configuration)
+	}
+	assert.NoError(t, task.Validate())
+}
+
+func testTaskUnmarshalPluggable(t *testing.T) {
+	task := &Task{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"type": "pluggable_task",
+		"attributes": {
+			"run_if": ["any"],
+			"plugin_configuration": {"id": "script-executor", "version": "1"},
+			"configuration": [{"key": "script", "value": "make test"}]
+		}
+	}`), task))
+
+	pluggable, isPluggable := task.Attributes.(*TaskAttributesPluggable)
+	if assert.True(t, isPluggable) {
+		assert.Equal(t, &TaskPluginConfiguration{ID: "script-executor", Version: "1"}, pluggable.PluginConfiguration)
+		assert.Equal(t, "make test", pluggable.Configuration[0].Value)
+	}
+	assert.NoError(t, task.Validate())
+}
+
+func testTaskUnmarshalRoundTrip(t *testing.T) {
+	task := &Task{
+		Type: "nant",
+		Attributes: &TaskAttributesNant{
+			RunIf:     []string{"passed"},
+			BuildFile: "default.build",
+			Target:    "test",
+			NantPath:  "/opt/nant",
+		},
+	}
+	b, err := json.Marshal(task)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "nant",
+		"attributes": {"run_if": ["passed"], "build_file": "default.build", "target": "test", "nant_path": "/opt/nant"}
+	}`, string(b))
+
+	decoded := &Task{}
+	assert.NoError(t, json.Unmarshal(b, decoded))
+	assert.Equal(t, task, decoded)
+}
+
+func testTaskUnmarshalFail(t *testing.T) {
+	err := json.Unmarshal([]byte(`{"type": "powershell", "attributes": {}}`), &Task{})
+	assert.EqualError(t, err, "Unexpected Task type: 'powershell'")
+
+	err = json.Unmarshal([]byte(`{"type": "exec", "attributes": {"command": 1}}`), &Task{})
+	assert.Error(t, err)
+}
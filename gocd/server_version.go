@@ -8,8 +8,6 @@ import (
 // ServerVersionService exposes calls for interacting with ServerVersion objects in the GoCD API.
 type ServerVersionService service
 
-var cachedServerVersion *ServerVersion
-
 // ServerVersion of the GoCD installation
 type ServerVersion struct {
 	Version      string `json:"version"`
@@ -22,8 +20,8 @@ type ServerVersion struct {
 
 // Get retrieves information about a specific plugin.
 func (svs *ServerVersionService) Get(ctx context.Context) (v *ServerVersion, resp *APIResponse, err error) {
-	if cachedServerVersion != nil {
-		return cachedServerVersion, nil, nil
+	if cached := svs.client.cachedServerVersion(); cached != nil {
+		return cached, nil, nil
 	}
 
 	v = &ServerVersion{}
@@ -37,7 +35,21 @@ func (svs *ServerVersionService) Get(ctx context.Context) (v *ServerVersion, res
 
 	err = v.parseVersion()
 
-	cachedServerVersion = v
+	svs.client.setCachedServerVersion(v)
 
 	return
 }
+
+// cachedServerVersion returns the server version retrieved by this client, if any. The version is cached per client, so
+// that clients for different servers do not share it.
+func (c *Client) cachedServerVersion() *ServerVersion {
+	c.serverVersionMu.Lock()
+	defer c.serverVersionMu.Unlock()
+	return c.serverVersion
+}
+
+func (c *Client) setCachedServerVersion(v *ServerVersion) {
+	c.serverVersionMu.Lock()
+	defer c.serverVersionMu.Unlock()
+	c.serverVersion = v
+}
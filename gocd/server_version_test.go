@@ -14,6 +14,28 @@ func TestServerVersion(t *testing.T) {
 	t.Run("ServerVersionCaching", testServerVersionCaching)
 	t.Run("ServerVersion", testServerVersion)
 	t.Run("Resource", testServerVersionResource)
+	t.Run("ClientSupports", testServerVersionClientSupports)
+}
+
+func testServerVersionClientSupports(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		j, _ := ioutil.ReadFile("test/resources/version.2.json")
+		fmt.Fprint(w, string(j))
+	})
+
+	supported, err := client.Supports(context.Background(), CapabilityPipelineUnlock)
+	assert.NoError(t, err)
+	assert.True(t, supported)
+
+	supported, err = client.Supports(context.Background(), CapabilityRoles)
+	assert.NoError(t, err)
+	assert.True(t, supported)
+
+	_, err = client.Supports(context.Background(), Capability("unknown"))
+	assert.EqualError(t, err, "unknown capability 'unknown'")
 }
 
 func testServerVersion(t *testing.T) {
@@ -31,7 +53,6 @@ func testServerVersion(t *testing.T) {
 		fmt.Fprint(w, string(j))
 	})
 
-	cachedServerVersion = nil
 	v, _, err := client.ServerVersion.Get(context.Background())
 
 	assert.NoError(t, err)
@@ -46,7 +67,7 @@ func testServerVersion(t *testing.T) {
 	}, v)
 
 	// Verify that the server version is cached
-	assert.Equal(t, cachedServerVersion, v)
+	assert.Equal(t, client.cachedServerVersion(), v)
 
 }
 
@@ -55,7 +76,7 @@ func testServerVersionCaching(t *testing.T) {
 		ver, err := version.NewVersion("18.7.0")
 		assert.NoError(t, err)
 
-		cachedServerVersion = &ServerVersion{
+		intClient.serverVersion = &ServerVersion{
 			Version:      "18.7.0",
 			BuildNumber:  "7121",
 			GitSha:       "75d1247f58ab8bcde3c5b43392a87347979f82c5",
@@ -0,0 +1,392 @@
+package gocd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Snapshot holds the configuration of a GoCD server which can be kept as code. It is written to, and read from, a
+// directory of JSON files, and restored to a server with Client.ImportSnapshot. The fields owned by the server, such
+// as links and versions, are left out.
+//
+// Secure values are kept encrypted, so they can only be restored to a server using the same cipher. Pipelines defined in
+// config repos are restored by their config repo, so they are not part of a snapshot. Agents register themselves with
+// a server, so only the resources and environments of agents are.
+type Snapshot struct {
+	Roles          []*Role
+	ConfigRepos    []*ConfigRepo
+	PluginSettings []*PluginSettings
+	Templates      []*PipelineTemplate
+	PipelineGroups []*SnapshotPipelineGroup
+	Pipelines      []*Pipeline // Pipelines have their Group set.
+	Environments   []*SnapshotEnvironment
+	Agents         []*SnapshotAgent
+}
+
+// SnapshotPipelineGroup is a pipeline group in a snapshot. The pipelines of a group are recorded with the pipelines,
+// but a group is recorded on its own too, so that groups without pipelines are kept.
+type SnapshotPipelineGroup struct {
+	Name string `json:"name"`
+}
+
+// SnapshotEnvironment is an environment in a snapshot. The agents of an environment are recorded with the agents.
+type SnapshotEnvironment struct {
+	Name                 string                 `json:"name"`
+	Pipelines            []string               `json:"pipelines,omitempty"`
+	EnvironmentVariables []*EnvironmentVariable `json:"environment_variables,omitempty"`
+}
+
+// SnapshotAgent is the configuration of an agent in a snapshot.
+type SnapshotAgent struct {
+	UUID         string   `json:"uuid"`
+	Hostname     string   `json:"hostname,omitempty"`
+	Resources    []string `json:"resources,omitempty"`
+	Environments []string `json:"environments,omitempty"`
+}
+
+// The directories of a snapshot, holding a file per object. Pipelines are in a directory per group, eg:
+// `pipelines/my-group/my-pipeline.json`.
+const (
+	snapshotRoles          = "roles"
+	snapshotConfigRepos    = "config-repos"
+	snapshotPluginSettings = "plugin-settings"
+	snapshotTemplates      = "templates"
+	snapshotPipelineGroups = "pipeline-groups"
+	snapshotPipelines      = "pipelines"
+	snapshotEnvironments   = "environments"
+	snapshotAgents         = "agents"
+)
+
+// ExportSnapshot reads the configuration of the server. Objects the server can not manage through its API, such as
+// roles before GoCD 17.5.0, are left out. Elastic agents, which only live as long as the job they run, are left out too.
+func (c *Client) ExportSnapshot(ctx context.Context) (*Snapshot, error) {
+	s := &Snapshot{}
+	for _, export := range []func(context.Context, *Snapshot) error{
+		c.exportRoles,
+		c.exportConfigRepos,
+		c.exportPluginSettings,
+		c.exportTemplates,
+		c.exportPipelines,
+		c.exportEnvironments,
+		c.exportAgents,
+	} {
+		if err := export(ctx, s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (c *Client) exportRoles(ctx context.Context, s *Snapshot) error {
+	if supported, err := c.Supports(ctx, CapabilityRoles); !supported || err != nil {
+		return err
+	}
+
+	roles, _, err := c.Roles.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		role.RemoveLinks()
+		role.Version = ""
+	}
+	s.Roles = append(s.Roles, roles...)
+	return nil
+}
+
+func (c *Client) exportConfigRepos(ctx context.Context, s *Snapshot) error {
+	if supported, err := c.Supports(ctx, CapabilityConfigRepos); !supported || err != nil {
+		return err
+	}
+
+	repos, _, err := c.ConfigRepos.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, repo := range repos {
+		repo.Links, repo.Version, repo.client = nil, "", nil
+	}
+	s.ConfigRepos = append(s.ConfigRepos, repos...)
+	return nil
+}
+
+func (c *Client) exportPluginSettings(ctx context.Context, s *Snapshot) error {
+	if supported, err := c.Supports(ctx, CapabilityPluginSettings); !supported || err != nil {
+		return err
+	}
+
+	plugins, _, err := c.Plugins.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, plugin := range plugins.Embedded.PluginInfo {
+		settings, resp, err := c.Plugins.GetSettings(ctx, plugin.ID)
+		if isNotFound(resp) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		settings.RemoveLinks()
+		settings.Version = ""
+		s.PluginSettings = append(s.PluginSettings, settings)
+	}
+	return nil
+}
+
+func (c *Client) exportTemplates(ctx context.Context, s *Snapshot) error {
+	if supported, err := c.Supports(ctx, CapabilityTemplates); !supported || err != nil {
+		return err
+	}
+
+	templates, _, err := c.PipelineTemplates.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, summary := range templates {
+		template, _, err := c.PipelineTemplates.Get(ctx, summary.Name)
+		if err != nil {
+			return err
+		}
+		template.Links, template.Embedded, template.Version = nil, nil, ""
+		s.Templates = append(s.Templates, template)
+	}
+	return nil
+}
+
+// exportPipelines records the pipelines which are not defined in config repos, and their groups. Groups only holding
+// pipelines defined in config repos are left to the config repos too.
+func (c *Client) exportPipelines(ctx context.Context, s *Snapshot) error {
+	groups, _, err := c.PipelineGroups.List(ctx, "")
+	if err != nil {
+		return err
+	}
+	for _, group := range *groups {
+		exported := 0
+		for _, summary := range group.Pipelines {
+			p, _, err := c.PipelineConfigs.Get(ctx, summary.Name)
+			if err != nil {
+				return err
+			}
+			if p.DefinedInConfigRepo() {
+				continue
+			}
+			p.Links, p.Version, p.Origin = nil, "", nil
+			p.Group = group.Name
+			s.Pipelines = append(s.Pipelines, p)
+			exported++
+		}
+		if exported > 0 || len(group.Pipelines) == 0 {
+			s.PipelineGroups = append(s.PipelineGroups, &SnapshotPipelineGroup{Name: group.Name})
+		}
+	}
+	return nil
+}
+
+func (c *Client) exportEnvironments(ctx context.Context, s *Snapshot) error {
+	if supported, err := c.Supports(ctx, CapabilityEnvironments); !supported || err != nil {
+		return err
+	}
+
+	environments, _, err := c.Environments.List(ctx)
+	if err != nil || environments.Embedded == nil {
+		return err
+	}
+	for _, env := range environments.Embedded.Environments {
+		se := &SnapshotEnvironment{Name: env.Name, EnvironmentVariables: env.EnvironmentVariables}
+		for _, p := range env.Pipelines {
+			se.Pipelines = append(se.Pipelines, p.Name)
+		}
+		s.Environments = append(s.Environments, se)
+	}
+	return nil
+}
+
+func (c *Client) exportAgents(ctx context.Context, s *Snapshot) error {
+	agents, _, err := c.Agents.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, agent := range agents {
+		if agent.ElasticAgentID != "" {
+			continue
+		}
+		s.Agents = append(s.Agents, &SnapshotAgent{
+			UUID:         agent.UUID,
+			Hostname:     agent.Hostname,
+			Resources:    agent.Resources,
+			Environments: agent.Environments,
+		})
+	}
+	return nil
+}
+
+// isNotFound reports whether the server responded that an object does not exist.
+func isNotFound(resp *APIResponse) bool {
+	return resp != nil && resp.HTTP != nil && resp.HTTP.StatusCode == http.StatusNotFound
+}
+
+// Write the snapshot to a directory, with a file per object. The directories of a previous snapshot are replaced, so
+// that objects which were removed from the server are removed from the snapshot too.
+func (s *Snapshot) Write(dir string) error {
+	for _, kind := range []string{snapshotRoles, snapshotConfigRepos, snapshotPluginSettings, snapshotTemplates,
+		snapshotPipelineGroups, snapshotPipelines, snapshotEnvironments, snapshotAgents} {
+		if err := os.RemoveAll(filepath.Join(dir, kind)); err != nil {
+			return err
+		}
+	}
+
+	files := map[string]interface{}{}
+	for _, role := range s.Roles {
+		files[filepath.Join(snapshotRoles, role.Name)] = role
+	}
+	for _, repo := range s.ConfigRepos {
+		files[filepath.Join(snapshotConfigRepos, repo.ID)] = repo
+	}
+	for _, settings := range s.PluginSettings {
+		files[filepath.Join(snapshotPluginSettings, settings.PluginID)] = settings
+	}
+	for _, template := range s.Templates {
+		files[filepath.Join(snapshotTemplates, template.Name)] = template
+	}
+	for _, group := range s.PipelineGroups {
+		files[filepath.Join(snapshotPipelineGroups, group.Name)] = group
+	}
+	for _, p := range s.Pipelines {
+		// The group is recorded by the directory of the pipeline.
+		pipeline := *p
+		pipeline.Group = ""
+		files[filepath.Join(snapshotPipelines, p.Group, p.Name)] = &pipeline
+	}
+	for _, env := range s.Environments {
+		files[filepath.Join(snapshotEnvironments, env.Name)] = env
+	}
+	for _, agent := range s.Agents {
+		files[filepath.Join(snapshotAgents, agent.UUID)] = agent
+	}
+
+	for name, v := range files {
+		if err := writeSnapshotFile(filepath.Join(dir, name+".json"), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSnapshotFile(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// ReadSnapshot reads a snapshot written by Snapshot.Write. The snapshot directory must exist, but the directories of
+// each kind of object missing from it are read as empty.
+// codebeat:disable[ABC,CYCLO]
+func ReadSnapshot(dir string) (*Snapshot, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("'%s' is not a directory", dir)
+	}
+
+	s := &Snapshot{}
+	err = readSnapshotFiles(dir, snapshotRoles, func(path string) error {
+		role := &Role{}
+		s.Roles = append(s.Roles, role)
+		return readSnapshotFile(path, role)
+	})
+	if err == nil {
+		err = readSnapshotFiles(dir, snapshotConfigRepos, func(path string) error {
+			repo := &ConfigRepo{}
+			s.ConfigRepos = append(s.ConfigRepos, repo)
+			return readSnapshotFile(path, repo)
+		})
+	}
+	if err == nil {
+		err = readSnapshotFiles(dir, snapshotPluginSettings, func(path string) error {
+			settings := &PluginSettings{}
+			s.PluginSettings = append(s.PluginSettings, settings)
+			return readSnapshotFile(path, settings)
+		})
+	}
+	if err == nil {
+		err = readSnapshotFiles(dir, snapshotTemplates, func(path string) error {
+			template := &PipelineTemplate{}
+			s.Templates = append(s.Templates, template)
+			return readSnapshotFile(path, template)
+		})
+	}
+	if err == nil {
+		err = readSnapshotFiles(dir, snapshotPipelineGroups, func(path string) error {
+			group := &SnapshotPipelineGroup{}
+			s.PipelineGroups = append(s.PipelineGroups, group)
+			return readSnapshotFile(path, group)
+		})
+	}
+	if err == nil {
+		err = readSnapshotFiles(dir, filepath.Join(snapshotPipelines, "*"), func(path string) error {
+			p := &Pipeline{}
+			s.Pipelines = append(s.Pipelines, p)
+			err := readSnapshotFile(path, p)
+			p.Group = filepath.Base(filepath.Dir(path))
+			return err
+		})
+	}
+	if err == nil {
+		err = readSnapshotFiles(dir, snapshotEnvironments, func(path string) error {
+			env := &SnapshotEnvironment{}
+			s.Environments = append(s.Environments, env)
+			return readSnapshotFile(path, env)
+		})
+	}
+	if err == nil {
+		err = readSnapshotFiles(dir, snapshotAgents, func(path string) error {
+			agent := &SnapshotAgent{}
+			s.Agents = append(s.Agents, agent)
+			return readSnapshotFile(path, agent)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// codebeat:enable[ABC,CYCLO]
+
+// readSnapshotFiles calls read for each JSON file of a directory of the snapshot, in the order of their names.
+func readSnapshotFiles(dir string, kind string, read func(path string) error) error {
+	paths, err := filepath.Glob(filepath.Join(dir, kind, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err = read(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSnapshotFile(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,593 @@
+package gocd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Actions of a SnapshotChange
+const (
+	SnapshotChangeCreate    = "create"
+	SnapshotChangeUpdate    = "update"
+	SnapshotChangeUnchanged = "unchanged"
+	SnapshotChangeSkip      = "skip"
+)
+
+// SnapshotChange describes what importing a snapshot does to an object of the server.
+type SnapshotChange struct {
+	// Kind of the object, eg: `pipeline` or `plugin settings`.
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Action is one of SnapshotChangeCreate, SnapshotChangeUpdate, SnapshotChangeUnchanged, or SnapshotChangeSkip.
+	Action string `json:"action"`
+	// Reason explains why an object is skipped.
+	Reason string `json:"reason,omitempty"`
+}
+
+// String formats the change on a single line, eg: `update pipeline 'my-pipeline'`.
+func (sc *SnapshotChange) String() string {
+	line := fmt.Sprintf("%s %s '%s'", sc.Action, sc.Kind, sc.Name)
+	if sc.Reason != "" {
+		line += ": " + sc.Reason
+	}
+	return line
+}
+
+// FormatSnapshotChanges formats the changes with one change per line, followed by the number of each action.
+func FormatSnapshotChanges(changes []*SnapshotChange) string {
+	lines := []string{}
+	counts := map[string]int{}
+	for _, change := range changes {
+		lines = append(lines, change.String())
+		counts[change.Action]++
+	}
+	lines = append(lines, fmt.Sprintf("%d to create, %d to update, %d unchanged, %d skipped.",
+		counts[SnapshotChangeCreate], counts[SnapshotChangeUpdate], counts[SnapshotChangeUnchanged], counts[SnapshotChangeSkip]))
+	return strings.Join(lines, "\n")
+}
+
+// ImportSnapshot applies a snapshot to the server, and returns the changes made. Objects are created in the order they
+// depend on each other: roles, plugin settings, config repos, templates, pipeline groups, pipelines with the pipelines
+// they depend on first, environments, and the agents in them. Objects which already exist are left alone if they
+// already match the snapshot, and are otherwise updated to match it, overwriting the changes made on the server since
+// the snapshot was exported. Each update sends the version of the object read just before it, so only an object
+// changed while it is being imported is refused.
+//
+// GoCD creates a pipeline group with its first pipeline, so groups without pipelines which are missing from the server
+// are skipped.
+//
+// With dryRun, the changes are listed without applying them. Importing stops at the first change the server refuses,
+// returning the changes made before it.
+func (c *Client) ImportSnapshot(ctx context.Context, s *Snapshot, dryRun bool) ([]*SnapshotChange, error) {
+	si := &snapshotImport{client: c, dryRun: dryRun}
+	for _, apply := range []func(context.Context, *Snapshot) error{
+		si.roles,
+		si.pluginSettings,
+		si.configRepos,
+		si.templates,
+		si.pipelineGroups,
+		si.pipelines,
+		si.environments,
+		si.agents,
+	} {
+		if err := apply(ctx, s); err != nil {
+			return si.changes, err
+		}
+	}
+	return si.changes, nil
+}
+
+type snapshotImport struct {
+	client  *Client
+	dryRun  bool
+	changes []*SnapshotChange
+}
+
+// apply records a change, and makes it unless this is a dry run.
+func (si *snapshotImport) apply(kind string, name string, action string, change func() error) error {
+	si.changes = append(si.changes, &SnapshotChange{Kind: kind, Name: name, Action: action})
+	if si.dryRun || change == nil {
+		return nil
+	}
+	if err := change(); err != nil {
+		si.changes = si.changes[:len(si.changes)-1]
+		return fmt.Errorf("could not %s %s '%s': %s", action, kind, name, err)
+	}
+	return nil
+}
+
+func (si *snapshotImport) skip(kind string, name string, reason string) {
+	si.changes = append(si.changes, &SnapshotChange{Kind: kind, Name: name, Action: SnapshotChangeSkip, Reason: reason})
+}
+
+// codebeat:disable[ABC]
+func (si *snapshotImport) roles(ctx context.Context, s *Snapshot) error {
+	if len(s.Roles) == 0 {
+		return nil
+	}
+	roles, _, err := si.client.Roles.List(ctx)
+	if err != nil {
+		return err
+	}
+	existing := map[string]*Role{}
+	for _, role := range roles {
+		existing[role.Name] = role
+	}
+
+	for _, role := range s.Roles {
+		role := role
+		current, exists := existing[role.Name]
+		switch {
+		case !exists:
+			err = si.apply("role", role.Name, SnapshotChangeCreate, func() error {
+				_, _, err := si.client.Roles.Create(ctx, role)
+				return err
+			})
+		case snapshotEqual(current, role):
+			err = si.apply("role", role.Name, SnapshotChangeUnchanged, nil)
+		default:
+			err = si.apply("role", role.Name, SnapshotChangeUpdate, func() error {
+				current, _, err := si.client.Roles.Get(ctx, role.Name)
+				if err != nil {
+					return err
+				}
+				desired := *role
+				desired.Version = current.Version
+				_, _, err = si.client.Roles.Update(ctx, role.Name, &desired)
+				return err
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (si *snapshotImport) pluginSettings(ctx context.Context, s *Snapshot) error {
+	for _, settings := range s.PluginSettings {
+		settings := settings
+		current, resp, err := si.client.Plugins.GetSettings(ctx, settings.PluginID)
+		switch {
+		case isNotFound(resp):
+			err = si.apply("plugin settings", settings.PluginID, SnapshotChangeCreate, func() error {
+				_, _, err := si.client.Plugins.CreateSettings(ctx, settings)
+				return err
+			})
+		case err != nil:
+		case snapshotEqual(current, settings):
+			err = si.apply("plugin settings", settings.PluginID, SnapshotChangeUnchanged, nil)
+		default:
+			err = si.apply("plugin settings", settings.PluginID, SnapshotChangeUpdate, func() error {
+				desired := *settings
+				desired.Version = current.Version
+				_, _, err := si.client.Plugins.UpdateSettings(ctx, &desired)
+				return err
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (si *snapshotImport) configRepos(ctx context.Context, s *Snapshot) error {
+	if len(s.ConfigRepos) == 0 {
+		return nil
+	}
+	repos, _, err := si.client.ConfigRepos.List(ctx)
+	if err != nil {
+		return err
+	}
+	existing := map[string]*ConfigRepo{}
+	for _, repo := range repos {
+		existing[repo.ID] = repo
+	}
+
+	for _, repo := range s.ConfigRepos {
+		repo := repo
+		current, exists := existing[repo.ID]
+		switch {
+		case !exists:
+			err = si.apply("config repo", repo.ID, SnapshotChangeCreate, func() error {
+				_, _, err := si.client.ConfigRepos.Create(ctx, repo)
+				return err
+			})
+		case snapshotEqual(current, repo):
+			err = si.apply("config repo", repo.ID, SnapshotChangeUnchanged, nil)
+		default:
+			err = si.apply("config repo", repo.ID, SnapshotChangeUpdate, func() error {
+				current, _, err := si.client.ConfigRepos.Get(ctx, repo.ID)
+				if err != nil {
+					return err
+				}
+				desired := *repo
+				desired.Version = current.Version
+				_, _, err = si.client.ConfigRepos.Update(ctx, repo.ID, &desired)
+				return err
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (si *snapshotImport) templates(ctx context.Context, s *Snapshot) error {
+	if len(s.Templates) == 0 {
+		return nil
+	}
+	templates, _, err := si.client.PipelineTemplates.List(ctx)
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, template := range templates {
+		existing[template.Name] = true
+	}
+
+	for _, template := range s.Templates {
+		template := template
+		if !existing[template.Name] {
+			err = si.apply("template", template.Name, SnapshotChangeCreate, func() error {
+				_, _, err := si.client.PipelineTemplates.Create(ctx, template.Name, template.Stages)
+				return err
+			})
+		} else {
+			err = si.template(ctx, template)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (si *snapshotImport) template(ctx context.Context, template *PipelineTemplate) error {
+	current, _, err := si.client.PipelineTemplates.Get(ctx, template.Name)
+	if err != nil {
+		return err
+	}
+	if snapshotEqual(current.Stages, template.Stages) {
+		return si.apply("template", template.Name, SnapshotChangeUnchanged, nil)
+	}
+	return si.apply("template", template.Name, SnapshotChangeUpdate, func() error {
+		_, _, err := si.client.PipelineTemplates.Update(ctx, template.Name, &PipelineTemplate{
+			Name:    template.Name,
+			Stages:  template.Stages,
+			Version: current.Version,
+		})
+		return err
+	})
+}
+
+// pipelineGroups records the groups created with the pipelines of the snapshot, and skips the groups it can not create.
+func (si *snapshotImport) pipelineGroups(ctx context.Context, s *Snapshot) error {
+	if len(s.PipelineGroups) == 0 {
+		return nil
+	}
+	groups, _, err := si.client.PipelineGroups.List(ctx, "")
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, group := range *groups {
+		existing[group.Name] = true
+	}
+	populated := map[string]bool{}
+	for _, p := range s.Pipelines {
+		populated[p.Group] = true
+	}
+
+	for _, group := range s.PipelineGroups {
+		switch {
+		case existing[group.Name]:
+			err = si.apply("pipeline group", group.Name, SnapshotChangeUnchanged, nil)
+		case populated[group.Name]:
+			// The group is created by creating its first pipeline.
+			err = si.apply("pipeline group", group.Name, SnapshotChangeCreate, nil)
+		default:
+			si.skip("pipeline group", group.Name, "GoCD can only create a pipeline group with a pipeline in it")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (si *snapshotImport) pipelines(ctx context.Context, s *Snapshot) error {
+	if len(s.Pipelines) == 0 {
+		return nil
+	}
+	pipelines, err := SortPipelinesByDependency(s.Pipelines)
+	if err != nil {
+		return err
+	}
+	groups, _, err := si.client.PipelineGroups.List(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pipelines {
+		p := p
+		if groups.GetGroupByPipelineName(p.Name) == nil {
+			err = si.apply("pipeline", p.Name, SnapshotChangeCreate, func() error {
+				_, _, err := si.client.PipelineConfigs.Create(ctx, p.Group, p)
+				return err
+			})
+		} else {
+			err = si.pipeline(ctx, p, groups.GetGroupByPipelineName(p.Name).Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (si *snapshotImport) pipeline(ctx context.Context, p *Pipeline, group string) error {
+	current, _, err := si.client.PipelineConfigs.Get(ctx, p.Name)
+	if err != nil {
+		return err
+	}
+	if current.DefinedInConfigRepo() {
+		si.skip("pipeline", p.Name, "it is defined in a config repo")
+		return nil
+	}
+	if p.Group != group {
+		si.skip("pipeline", p.Name, fmt.Sprintf("it is in group '%s' rather than '%s', and can not be moved", group, p.Group))
+		return nil
+	}
+
+	changes, err := DiffPipelines(current, p)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return si.apply("pipeline", p.Name, SnapshotChangeUnchanged, nil)
+	}
+	return si.apply("pipeline", p.Name, SnapshotChangeUpdate, func() error {
+		desired := *p
+		desired.Version = current.Version
+		_, _, err := si.client.PipelineConfigs.Update(ctx, p.Name, &desired)
+		return err
+	})
+}
+
+// codebeat:enable[ABC]
+
+// codebeat:disable[ABC,CYCLO]
+func (si *snapshotImport) environments(ctx context.Context, s *Snapshot) error {
+	if len(s.Environments) == 0 {
+		return nil
+	}
+	environments, _, err := si.client.Environments.List(ctx)
+	if err != nil {
+		return err
+	}
+	existing := map[string]*Environment{}
+	if environments.Embedded != nil {
+		for _, env := range environments.Embedded.Environments {
+			existing[env.Name] = env
+		}
+	}
+
+	for _, env := range s.Environments {
+		current, exists := existing[env.Name]
+		if !exists {
+			current = &Environment{Name: env.Name}
+		}
+		patch := environmentPatch(current, env)
+
+		action := SnapshotChangeUpdate
+		switch {
+		case !exists:
+			action = SnapshotChangeCreate
+		case patch == nil:
+			action = SnapshotChangeUnchanged
+		}
+
+		name := env.Name
+		err = si.apply("environment", name, action, func() error {
+			if !exists {
+				if _, _, err := si.client.Environments.Create(ctx, name); err != nil {
+					return err
+				}
+			}
+			if patch == nil {
+				return nil
+			}
+			_, _, err := si.client.Environments.Patch(ctx, name, patch)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// codebeat:enable[ABC,CYCLO]
+
+// environmentPatch builds the request turning the environment into the one of the snapshot, or returns nil if they
+// already match. Variables which changed are removed and added again.
+func environmentPatch(current *Environment, env *SnapshotEnvironment) *EnvironmentPatchRequest {
+	pipelines := []string{}
+	for _, p := range current.Pipelines {
+		pipelines = append(pipelines, p.Name)
+	}
+	add, remove := diffNames(pipelines, env.Pipelines)
+	patch := &EnvironmentPatchRequest{
+		Pipelines:            &PatchStringAction{Add: add, Remove: remove},
+		EnvironmentVariables: &EnvironmentVariablesAction{Add: []*EnvironmentVariable{}, Remove: []string{}},
+	}
+
+	variables := map[string]*EnvironmentVariable{}
+	for _, v := range env.EnvironmentVariables {
+		variables[v.Name] = v
+	}
+	for _, v := range current.EnvironmentVariables {
+		if desired, kept := variables[v.Name]; !kept || !reflect.DeepEqual(desired, v) {
+			patch.EnvironmentVariables.Remove = append(patch.EnvironmentVariables.Remove, v.Name)
+		}
+	}
+	existing := map[string]*EnvironmentVariable{}
+	for _, v := range current.EnvironmentVariables {
+		existing[v.Name] = v
+	}
+	for _, v := range env.EnvironmentVariables {
+		if !reflect.DeepEqual(existing[v.Name], v) {
+			patch.EnvironmentVariables.Add = append(patch.EnvironmentVariables.Add, v)
+		}
+	}
+
+	if len(add) == 0 && len(remove) == 0 &&
+		len(patch.EnvironmentVariables.Add) == 0 && len(patch.EnvironmentVariables.Remove) == 0 {
+		return nil
+	}
+	return patch
+}
+
+func (si *snapshotImport) agents(ctx context.Context, s *Snapshot) error {
+	if len(s.Agents) == 0 {
+		return nil
+	}
+	agents, _, err := si.client.Agents.List(ctx)
+	if err != nil {
+		return err
+	}
+	existing := map[string]*Agent{}
+	for _, agent := range agents {
+		existing[agent.UUID] = agent
+	}
+
+	for _, agent := range s.Agents {
+		current, exists := existing[agent.UUID]
+		if !exists {
+			si.skip("agent", agent.UUID, "it is not registered with the server")
+			continue
+		}
+
+		operations := &AgentBulkOperationsUpdate{}
+		if add, remove := diffNames(current.Resources, agent.Resources); len(add) > 0 || len(remove) > 0 {
+			operations.Resources = &AgentBulkOperationUpdate{Add: add, Remove: remove}
+		}
+		if add, remove := diffNames(current.Environments, agent.Environments); len(add) > 0 || len(remove) > 0 {
+			operations.Environments = &AgentBulkOperationUpdate{Add: add, Remove: remove}
+		}
+		if operations.Resources == nil && operations.Environments == nil {
+			err = si.apply("agent", agent.UUID, SnapshotChangeUnchanged, nil)
+		} else {
+			uuid := agent.UUID
+			err = si.apply("agent", uuid, SnapshotChangeUpdate, func() error {
+				_, _, err := si.client.Agents.BulkUpdate(ctx, AgentBulkUpdate{Uuids: []string{uuid}, Operations: operations})
+				return err
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffNames lists the names to add to `current`, and to remove from it, to get `desired`.
+func diffNames(current []string, desired []string) (add []string, remove []string) {
+	for _, name := range desired {
+		if !containsName(current, name) {
+			add = append(add, name)
+		}
+	}
+	for _, name := range current {
+		if !containsName(desired, name) {
+			remove = append(remove, name)
+		}
+	}
+	return add, remove
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotEqual compares the JSON representations of two objects, without the fields owned by the server.
+func snapshotEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(snapshotFields(a), snapshotFields(b))
+}
+
+func snapshotFields(v interface{}) (fields interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	json.Unmarshal(b, &fields)
+	if object, isObject := fields.(map[string]interface{}); isObject {
+		for _, key := range []string{"_links", "_embedded", "version", "template_version"} {
+			delete(object, key)
+		}
+	}
+	return fields
+}
+
+// SortPipelinesByDependency orders pipeline configs so that each pipeline comes after the pipelines its dependency
+// materials refer to, as GoCD refuses pipelines depending on unknown pipelines. Pipelines are otherwise kept in the
+// order they were given, and dependencies on pipelines which are not part of the list are ignored.
+func SortPipelinesByDependency(pipelines []*Pipeline) ([]*Pipeline, error) {
+	byName := map[string]*Pipeline{}
+	for _, p := range pipelines {
+		byName[p.Name] = p
+	}
+
+	sorted := make([]*Pipeline, 0, len(pipelines))
+	state := map[string]int{} // 1 while visiting the upstream pipelines of a pipeline, 2 once it is sorted.
+	var visit func(p *Pipeline, path []string) error
+	visit = func(p *Pipeline, path []string) error {
+		switch state[p.Name] {
+		case 1:
+			return fmt.Errorf("pipelines depend on each other: %s", strings.Join(append(path, p.Name), " -> "))
+		case 2:
+			return nil
+		}
+		state[p.Name] = 1
+		for _, upstream := range upstreamPipelines(p) {
+			if up, known := byName[upstream]; known {
+				if err := visit(up, append(path, p.Name)); err != nil {
+					return err
+				}
+			}
+		}
+		state[p.Name] = 2
+		sorted = append(sorted, p)
+		return nil
+	}
+
+	for _, p := range pipelines {
+		if err := visit(p, nil); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// upstreamPipelines lists the pipelines a pipeline has dependency materials on, sorted.
+func upstreamPipelines(p *Pipeline) (names []string) {
+	for _, m := range p.Materials {
+		if dependency, isDependency := materialAttributeValue(m.Attributes).(MaterialAttributesDependency); isDependency {
+			names = append(names, dependency.Pipeline)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,303 @@
+package gocd_test
+
+import (
+	"context"
+	"github.com/beamly/go-gocd/gocd"
+	"github.com/beamly/go-gocd/gocdtest"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Run("ExportImport", testSnapshotExportImport)
+	t.Run("ConfigRepoPipelines", testSnapshotConfigRepoPipelines)
+	t.Run("MovedPipeline", testSnapshotMovedPipeline)
+	t.Run("PipelineGroups", testSnapshotPipelineGroups)
+	t.Run("ImportError", testSnapshotImportError)
+	t.Run("SortPipelines", testSnapshotSortPipelines)
+	t.Run("ReadMissing", testSnapshotReadMissing)
+}
+
+func snapshotPipeline(name string, upstream ...string) *gocd.Pipeline {
+	p := &gocd.Pipeline{
+		Name: name,
+		Materials: []gocd.Material{{
+			Type:       "git",
+			Attributes: &gocd.MaterialAttributesGit{URL: "https://github.com/example/" + name, Branch: "master"},
+		}},
+		Stages: []*gocd.Stage{{
+			Name: "build",
+			Jobs: []*gocd.Job{{
+				Name:  "compile",
+				Tasks: []*gocd.Task{{Type: "exec", Attributes: &gocd.TaskAttributesExec{Command: "make"}}},
+			}},
+		}},
+	}
+	for _, name := range upstream {
+		p.Materials = append(p.Materials, gocd.Material{
+			Type:       "dependency",
+			Attributes: &gocd.MaterialAttributesDependency{Pipeline: name, Stage: "build"},
+		})
+	}
+	return p
+}
+
+// populateSnapshotServer adds one object of each kind kept in a snapshot to the server.
+func populateSnapshotServer(t *testing.T, server *gocdtest.Server) {
+	client := server.Client()
+	ctx := context.Background()
+
+	server.AddAgent(&gocd.Agent{UUID: "agent-1", Hostname: "agent01"})
+	server.AddPlugin(&gocd.Plugin{ID: "slack"})
+	server.AddPlugin(&gocd.Plugin{ID: "email"})
+
+	_, _, err := client.Roles.Create(ctx, &gocd.Role{
+		Name:       "admins",
+		Type:       "gocd",
+		Attributes: &gocd.RoleAttributesGoCD{Users: []string{"alice"}},
+	})
+	assert.NoError(t, err)
+	_, _, err = client.Plugins.CreateSettings(ctx, &gocd.PluginSettings{
+		PluginID:      "slack",
+		Configuration: []*gocd.ConfigRepoProperty{{Key: "channel", Value: "#builds"}},
+	})
+	assert.NoError(t, err)
+	_, _, err = client.PipelineTemplates.Create(ctx, "build-template", snapshotPipeline("template").Stages)
+	assert.NoError(t, err)
+	_, _, err = client.PipelineConfigs.Create(ctx, "build", snapshotPipeline("app"))
+	assert.NoError(t, err)
+	_, _, err = client.PipelineConfigs.Create(ctx, "deploy", snapshotPipeline("release", "app"))
+	assert.NoError(t, err)
+	_, _, err = client.Environments.Create(ctx, "production")
+	assert.NoError(t, err)
+	_, _, err = client.Environments.Patch(ctx, "production", &gocd.EnvironmentPatchRequest{
+		Pipelines: &gocd.PatchStringAction{Add: []string{"release"}},
+		Agents:    &gocd.PatchStringAction{Add: []string{"agent-1"}},
+		EnvironmentVariables: &gocd.EnvironmentVariablesAction{
+			Add: []*gocd.EnvironmentVariable{{Name: "DEPLOY_ENV", Value: "production"}},
+		},
+	})
+	assert.NoError(t, err)
+	_, _, err = client.Agents.Update(ctx, "agent-1", &gocd.Agent{Resources: []string{"linux"}})
+	assert.NoError(t, err)
+}
+
+func testSnapshotExportImport(t *testing.T) {
+	ctx := context.Background()
+	source := gocdtest.NewServer()
+	defer source.Close()
+	populateSnapshotServer(t, source)
+
+	exported, err := source.Client().ExportSnapshot(ctx)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, exported.PluginSettings, 1)
+	assert.Equal(t, []*gocd.SnapshotAgent{{
+		UUID: "agent-1", Hostname: "agent01", Resources: []string{"linux"}, Environments: []string{"production"},
+	}}, exported.Agents)
+
+	dir, err := ioutil.TempDir("", "snapshot")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, exported.Write(dir))
+	assert.FileExists(t, filepath.Join(dir, "pipelines", "deploy", "release.json"))
+	s, err := gocd.ReadSnapshot(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, exported, s)
+
+	target := gocdtest.NewServer()
+	defer target.Close()
+	target.AddAgent(&gocd.Agent{UUID: "agent-1", Hostname: "agent01"})
+	target.AddPlugin(&gocd.Plugin{ID: "slack"})
+	client := target.Client()
+
+	changes, err := client.ImportSnapshot(ctx, s, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "create role 'admins'\n"+
+		"create plugin settings 'slack'\n"+
+		"create template 'build-template'\n"+
+		"create pipeline group 'build'\n"+
+		"create pipeline group 'deploy'\n"+
+		"create pipeline 'app'\n"+
+		"create pipeline 'release'\n"+
+		"create environment 'production'\n"+
+		"update agent 'agent-1'\n"+
+		"8 to create, 1 to update, 0 unchanged, 0 skipped.", gocd.FormatSnapshotChanges(changes))
+	groups, _, err := client.PipelineGroups.List(ctx, "")
+	assert.NoError(t, err)
+	assert.Empty(t, *groups)
+
+	_, err = client.ImportSnapshot(ctx, s, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+	imported, err := client.ExportSnapshot(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, exported, imported)
+
+	changes, err = client.ImportSnapshot(ctx, s, false)
+	assert.NoError(t, err)
+	for _, change := range changes {
+		assert.Equal(t, gocd.SnapshotChangeUnchanged, change.Action, change.String())
+	}
+
+	s.Roles[0].Attributes.Users = []string{"bob"}
+	s.Agents[0].Resources = []string{"windows"}
+	s.Environments[0].EnvironmentVariables[0].Value = "prod"
+	changes, err = client.ImportSnapshot(ctx, s, false)
+	assert.NoError(t, err)
+	assert.Equal(t, &gocd.SnapshotChange{Kind: "role", Name: "admins", Action: gocd.SnapshotChangeUpdate}, changes[0])
+	imported, err = client.ExportSnapshot(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, imported.Roles[0].Attributes.Users)
+	assert.Equal(t, []string{"windows"}, imported.Agents[0].Resources)
+	assert.Equal(t, "prod", imported.Environments[0].EnvironmentVariables[0].Value)
+}
+
+func testSnapshotConfigRepoPipelines(t *testing.T) {
+	ctx := context.Background()
+	server := gocdtest.NewServer()
+	defer server.Close()
+	remote := snapshotPipeline("remote")
+	remote.Origin = &gocd.PipelineConfigOrigin{Type: "config_repo", File: "my-repo"}
+	server.AddPipeline("build", remote)
+	client := server.Client()
+
+	exported, err := client.ExportSnapshot(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, exported.Pipelines)
+
+	local := snapshotPipeline("remote")
+	local.Group = "build"
+	changes, err := client.ImportSnapshot(ctx, &gocd.Snapshot{Pipelines: []*gocd.Pipeline{local}}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []*gocd.SnapshotChange{{
+		Kind: "pipeline", Name: "remote", Action: gocd.SnapshotChangeSkip, Reason: "it is defined in a config repo",
+	}}, changes)
+}
+
+func testSnapshotMovedPipeline(t *testing.T) {
+	ctx := context.Background()
+	server := gocdtest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	_, _, err := client.PipelineConfigs.Create(ctx, "build", snapshotPipeline("app"))
+	assert.NoError(t, err)
+
+	moved := snapshotPipeline("app")
+	moved.Group = "deploy"
+	moved.LabelTemplate = "${COUNT}-moved"
+	changes, err := client.ImportSnapshot(ctx, &gocd.Snapshot{Pipelines: []*gocd.Pipeline{moved}}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []*gocd.SnapshotChange{{
+		Kind: "pipeline", Name: "app", Action: gocd.SnapshotChangeSkip,
+		Reason: "it is in group 'build' rather than 'deploy', and can not be moved",
+	}}, changes)
+
+	p, _, err := client.PipelineConfigs.Get(ctx, "app")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "${COUNT}-moved", p.LabelTemplate)
+}
+
+func testSnapshotPipelineGroups(t *testing.T) {
+	ctx := context.Background()
+	source := gocdtest.NewServer()
+	defer source.Close()
+	remote := snapshotPipeline("remote")
+	remote.Origin = &gocd.PipelineConfigOrigin{Type: "config_repo", File: "my-repo"}
+	source.AddPipeline("remote", remote)
+	source.AddPipelineGroup("empty")
+
+	exported, err := source.Client().ExportSnapshot(ctx)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []*gocd.SnapshotPipelineGroup{{Name: "empty"}}, exported.PipelineGroups)
+
+	dir, err := ioutil.TempDir("", "snapshot")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, exported.Write(dir))
+	assert.FileExists(t, filepath.Join(dir, "pipeline-groups", "empty.json"))
+	s, err := gocd.ReadSnapshot(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, exported, s)
+
+	target := gocdtest.NewServer()
+	defer target.Close()
+	changes, err := target.Client().ImportSnapshot(ctx, s, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []*gocd.SnapshotChange{{
+		Kind: "pipeline group", Name: "empty", Action: gocd.SnapshotChangeSkip,
+		Reason: "GoCD can only create a pipeline group with a pipeline in it",
+	}}, changes)
+
+	target.AddPipelineGroup("empty")
+	changes, err = target.Client().ImportSnapshot(ctx, s, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []*gocd.SnapshotChange{{
+		Kind: "pipeline group", Name: "empty", Action: gocd.SnapshotChangeUnchanged,
+	}}, changes)
+}
+
+func testSnapshotImportError(t *testing.T) {
+	server := gocdtest.NewServer()
+	defer server.Close()
+
+	invalid := snapshotPipeline("invalid")
+	invalid.Group = "build"
+	invalid.Materials = nil
+	changes, err := server.Client().ImportSnapshot(context.Background(), &gocd.Snapshot{
+		Templates: []*gocd.PipelineTemplate{{Name: "build-template", Stages: snapshotPipeline("template").Stages}},
+		Pipelines: []*gocd.Pipeline{invalid},
+	}, false)
+	assert.Error(t, err)
+	assert.Equal(t, []*gocd.SnapshotChange{{
+		Kind: "template", Name: "build-template", Action: gocd.SnapshotChangeCreate,
+	}}, changes, "the change the server refused is left out")
+}
+
+func testSnapshotSortPipelines(t *testing.T) {
+	sorted, err := gocd.SortPipelinesByDependency([]*gocd.Pipeline{
+		snapshotPipeline("deploy", "test", "package"),
+		snapshotPipeline("test", "build"),
+		snapshotPipeline("build", "external"),
+		snapshotPipeline("package", "build"),
+	})
+	assert.NoError(t, err)
+	var names []string
+	for _, p := range sorted {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"build", "package", "test", "deploy"}, names)
+
+	_, err = gocd.SortPipelinesByDependency([]*gocd.Pipeline{
+		snapshotPipeline("a", "b"),
+		snapshotPipeline("b", "a"),
+	})
+	assert.EqualError(t, err, "pipelines depend on each other: a -> b -> a")
+}
+
+func testSnapshotReadMissing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, err := gocd.ReadSnapshot(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, &gocd.Snapshot{}, s)
+
+	_, err = gocd.ReadSnapshot(filepath.Join(dir, "typo"))
+	assert.True(t, os.IsNotExist(err), "a missing directory is not read as an empty snapshot")
+
+	file := filepath.Join(dir, "file.json")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("{}"), 0644))
+	_, err = gocd.ReadSnapshot(file)
+	assert.EqualError(t, err, "'"+file+"' is not a directory")
+}
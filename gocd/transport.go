@@ -0,0 +1,150 @@
+package gocd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HTTPClient creates an http client for the configuration, with its own transport set up with the TLS, proxy and timeout
+// settings.
+func (c *Configuration) HTTPClient() (*http.Client, error) {
+	transport := newTransport()
+
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if c.ProxyURL != "" {
+		if transport.Proxy, err = c.proxy(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   c.Timeout,
+	}, nil
+}
+
+// tlsConfig builds the TLS configuration from the CA bundle, client certificate, and ssl check settings. If none of
+// these are set, nil is returned so the transport defaults are used.
+func (c *Configuration) tlsConfig() (*tls.Config, error) {
+	skipSslCheck := strings.HasPrefix(c.Server, "https") && c.SkipSslCheck
+	if c.CAFile == "" && c.ClientCert == "" && c.ClientKey == "" && !skipSslCheck {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: skipSslCheck}
+
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read ca_file: %s", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("could not find any PEM certificates in ca_file '%s'", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.ClientCert != "" || c.ClientKey != "" {
+		if c.ClientCert == "" || c.ClientKey == "" {
+			return nil, errors.New("client_cert and client_key must be provided together")
+		}
+		cert, err := tls.LoadX509KeyPair(c.ClientCert, c.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// proxy returns a proxy function sending requests through ProxyURL, except for the hosts matching NoProxy.
+func (c *Configuration) proxy() (func(*http.Request) (*url.URL, error), error) {
+	proxyURL, err := url.Parse(c.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy_url '%s': %s", c.ProxyURL, err)
+	}
+	if proxyURL.Scheme == "" || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy_url '%s': must contain a scheme and a host", c.ProxyURL)
+	}
+
+	noProxy := parseNoProxy(c.NoProxy)
+	return func(req *http.Request) (*url.URL, error) {
+		if noProxy.matches(req.URL.Hostname()) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+// noProxyList describes the hosts, domains, and networks which are not reached through a proxy.
+type noProxyList struct {
+	all      bool
+	hosts    []string
+	networks []*net.IPNet
+}
+
+// parseNoProxy parses a comma separated list of hosts, domains and CIDR ranges, in the same format as the NO_PROXY
+// environment variable. A domain matches itself and all of its sub-domains, and "*" matches every host.
+func parseNoProxy(raw string) *noProxyList {
+	npl := &noProxyList{}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			npl.all = true
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			npl.networks = append(npl.networks, network)
+			continue
+		}
+		if host, _, err := net.SplitHostPort(entry); err == nil {
+			entry = host
+		}
+		npl.hosts = append(npl.hosts, strings.TrimPrefix(entry, "."))
+	}
+	return npl
+}
+
+// matches checks whether the host should bypass the proxy.
+func (npl *noProxyList) matches(host string) bool {
+	if npl.all {
+		return true
+	}
+
+	host = strings.ToLower(host)
+	if ip := net.ParseIP(host); ip != nil {
+		for _, network := range npl.networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+	}
+
+	for _, noProxyHost := range npl.hosts {
+		if host == noProxyHost || strings.HasSuffix(host, "."+noProxyHost) {
+			return true
+		}
+	}
+	return false
+}
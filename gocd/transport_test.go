@@ -0,0 +1,161 @@
+package gocd
+
+import (
+	"encoding/pem"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	t.Run("Default", testTransportDefault)
+	t.Run("CAFile", testTransportCAFile)
+	t.Run("CAFileInvalid", testTransportCAFileInvalid)
+	t.Run("ClientCertIncomplete", testTransportClientCertIncomplete)
+	t.Run("Proxy", testTransportProxy)
+	t.Run("ProxyInvalid", testTransportProxyInvalid)
+	t.Run("NoProxy", testTransportNoProxy)
+	t.Run("Timeout", testTransportTimeout)
+	t.Run("InvalidConfigFailsRequests", testTransportInvalidConfigFailsRequests)
+	t.Run("Env", testTransportEnv)
+}
+
+func testTransportDefault(t *testing.T) {
+	httpClient, err := (&Configuration{Server: "https://my-goserver:8154/go/"}).HTTPClient()
+	assert.NoError(t, err)
+
+	transport := httpClient.Transport.(*http.Transport)
+	assert.Nil(t, transport.TLSClientConfig)
+	assert.Equal(t, time.Duration(0), httpClient.Timeout)
+}
+
+func testTransportCAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "gocd-transport")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NoError(t, ioutil.WriteFile(caFile, ca, 0600))
+
+	httpClient, err := (&Configuration{Server: server.URL}).HTTPClient()
+	assert.NoError(t, err)
+	_, err = httpClient.Get(server.URL)
+	assert.Error(t, err)
+
+	httpClient, err = (&Configuration{Server: server.URL, CAFile: caFile}).HTTPClient()
+	assert.NoError(t, err)
+	resp, err := httpClient.Get(server.URL)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+}
+
+func testTransportCAFileInvalid(t *testing.T) {
+	_, err := (&Configuration{CAFile: "/does/not/exist.pem"}).HTTPClient()
+	assert.Contains(t, err.Error(), "could not read ca_file")
+
+	f, err := ioutil.TempFile("", "gocd-ca")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("not a certificate")
+	f.Close()
+
+	_, err = (&Configuration{CAFile: f.Name()}).HTTPClient()
+	assert.EqualError(t, err, fmt.Sprintf("could not find any PEM certificates in ca_file '%s'", f.Name()))
+}
+
+func testTransportClientCertIncomplete(t *testing.T) {
+	_, err := (&Configuration{ClientCert: "cert.pem"}).HTTPClient()
+	assert.EqualError(t, err, "client_cert and client_key must be provided together")
+
+	_, err = (&Configuration{ClientCert: "/does/not/exist.pem", ClientKey: "/does/not/exist.key"}).HTTPClient()
+	assert.Contains(t, err.Error(), "could not load client certificate")
+}
+
+func testTransportProxy(t *testing.T) {
+	httpClient, err := (&Configuration{
+		ProxyURL: "http://proxy.example.com:3128",
+		NoProxy:  "localhost, .internal.example.com,10.0.0.0/8",
+	}).HTTPClient()
+	assert.NoError(t, err)
+	proxy := httpClient.Transport.(*http.Transport).Proxy
+
+	for host, expected := range map[string]string{
+		"https://goserver.example.com/go/":          "http://proxy.example.com:3128",
+		"https://localhost:8154/go/":                "",
+		"https://goserver.internal.example.com/go/": "",
+		"https://internal.example.com/go/":          "",
+		"https://10.1.2.3:8154/go/":                 "",
+		"https://11.1.2.3:8154/go/":                 "http://proxy.example.com:3128",
+	} {
+		req, _ := http.NewRequest("GET", host, nil)
+		proxyURL, err := proxy(req)
+		assert.NoError(t, err)
+		if expected == "" {
+			assert.Nil(t, proxyURL, host)
+		} else if assert.NotNil(t, proxyURL, host) {
+			assert.Equal(t, expected, proxyURL.String(), host)
+		}
+	}
+}
+
+func testTransportProxyInvalid(t *testing.T) {
+	_, err := (&Configuration{ProxyURL: "proxy.example.com"}).HTTPClient()
+	assert.EqualError(t, err, "invalid proxy_url 'proxy.example.com': must contain a scheme and a host")
+}
+
+func testTransportNoProxy(t *testing.T) {
+	assert.True(t, parseNoProxy("*").matches("anything.example.com"))
+	assert.True(t, parseNoProxy("goserver:8154").matches("goserver"))
+	assert.True(t, parseNoProxy("Example.COM").matches("ci.example.com"))
+	assert.False(t, parseNoProxy("example.com").matches("notexample.com"))
+	assert.False(t, parseNoProxy("").matches("example.com"))
+}
+
+func testTransportTimeout(t *testing.T) {
+	httpClient, err := (&Configuration{Timeout: 30 * time.Second}).HTTPClient()
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, httpClient.Timeout)
+}
+
+func testTransportInvalidConfigFailsRequests(t *testing.T) {
+	c := NewClient(&Configuration{Server: "https://my-goserver:8154/go/", CAFile: "/does/not/exist.pem"}, nil)
+	assert.NotNil(t, c)
+
+	_, err := c.NewRequest("GET", "agents", nil, "")
+	assert.Contains(t, err.Error(), "could not read ca_file")
+}
+
+func testTransportEnv(t *testing.T) {
+	os.Setenv(EnvVarCAFile, "/etc/ssl/ca.pem")
+	os.Setenv(EnvVarProxyURL, "http://proxy:3128")
+	os.Setenv(EnvVarTimeout, "1m")
+	defer func() {
+		os.Unsetenv(EnvVarCAFile)
+		os.Unsetenv(EnvVarProxyURL)
+		os.Unsetenv(EnvVarTimeout)
+	}()
+
+	cfg := &Configuration{CAFile: "/from/file.pem", NoProxy: "localhost"}
+	assert.NoError(t, cfg.loadTransportFromEnv())
+	assert.Equal(t, "/etc/ssl/ca.pem", cfg.CAFile)
+	assert.Equal(t, "http://proxy:3128", cfg.ProxyURL)
+	assert.Equal(t, "localhost", cfg.NoProxy)
+	assert.Equal(t, time.Minute, cfg.Timeout)
+
+	os.Setenv(EnvVarTimeout, "soon")
+	assert.EqualError(t, cfg.loadTransportFromEnv(), `invalid GOCD_TIMEOUT 'soon': time: invalid duration "soon"`)
+}
@@ -0,0 +1,65 @@
+package gocd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// validName matches the names GoCD accepts for pipelines, templates, stages, jobs, and materials.
+var validName = regexp.MustCompile(`^[a-zA-Z0-9_\-][a-zA-Z0-9_\-.]*$`)
+
+const maxNameLength = 255
+
+// ValidationError lists every problem found when validating a resource before it is sent to the GoCD server.
+type ValidationError struct {
+	// Resource describes what was validated, eg: "pipeline 'my-pipeline'".
+	Resource string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s is invalid: %s", e.Resource, strings.Join(e.Problems, "; "))
+}
+
+// problems collects the problems found during a validation.
+type problems []string
+
+func (p *problems) add(format string, a ...interface{}) {
+	*p = append(*p, fmt.Sprintf(format, a...))
+}
+
+// err returns a ValidationError for the resource if any problem was found.
+func (p problems) err(resource string) error {
+	if len(p) == 0 {
+		return nil
+	}
+	return &ValidationError{Resource: resource, Problems: p}
+}
+
+func (p *problems) name(kind string, name string) {
+	if name == "" {
+		p.add("%s name must not be empty", kind)
+		return
+	}
+	if !validName.MatchString(name) || len(name) > maxNameLength {
+		p.add("%s name '%s' must only contain letters, numbers, underscores, hyphens and periods, must not start "+
+			"with a period, and must be at most %d characters long", kind, name, maxNameLength)
+	}
+}
+
+// ValidateBeforeWrite makes the client validate pipelines and templates locally before creating or updating them, so
+// that every problem is reported at once, without a request to the server.
+func (c *Client) ValidateBeforeWrite(enabled bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.validateBeforeWrite = enabled
+}
+
+func (c *Client) validatesBeforeWrite() bool {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.validateBeforeWrite
+}
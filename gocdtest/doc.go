@@ -0,0 +1,58 @@
+// Copyright 2017-2018 Drew J. Sonne. All rights reserved.
+//
+// Use of this source code is governed by a Apache 2.0
+// license that can be found in the LICENSE file.
+
+/*
+Package gocdtest provides utilities for testing code built on top of the gocd package without a running GoCD server.
+
+# Cassettes
+
+A Recorder is an http.RoundTripper which records the interactions with a real GoCD server to a cassette file, and
+replays them afterwards without any network access. Credentials are scrubbed from the cassette before it is written, so
+cassettes can be committed alongside the tests which use them.
+
+	func TestMyTool(t *testing.T) {
+		rec, err := gocdtest.NewRecorder("testdata/my-tool.json", gocdtest.ModeAuto, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rec.Stop()
+
+		client := gocd.NewClient(&gocd.Configuration{
+			Server:   "https://gocd.staging.example.com/go/",
+			Username: os.Getenv("GOCD_USERNAME"),
+			Password: os.Getenv("GOCD_PASSWORD"),
+		}, rec.HTTPClient())
+
+		...
+	}
+
+With ModeAuto, the first run records the session against the server and every later run replays the cassette.
+Re-record a cassette by deleting it, or by using ModeRecord.
+
+# Fake server
+
+A Server is an in-memory fake of the GoCD API. It emulates a given GoCD release, and rejects requests using an Accept
+header that release does not support, updates with a stale ETag, and resources failing validation, as GoCD does.
+
+	server := gocdtest.NewServer()
+	defer server.Close()
+
+	server.AddAgent(&gocd.Agent{UUID: "agent-1", Hostname: "agent01"})
+	env, _, err := server.Client().Environments.Create(ctx, "production")
+
+# Version matrix
+
+A Matrix runs client operations against a Server emulating each GoCD release from 16.1.0 to 20.10.0, and reports the
+Accept header each operation sends to every release, or the releases the gocd package can not use it with:
+
+	report := (&gocdtest.Matrix{}).Run(map[string]gocdtest.Operation{
+		"Roles.List": func(ctx context.Context, client *gocd.Client) error {
+			_, _, err := client.Roles.List(ctx)
+			return err
+		},
+	})
+	fmt.Print(report)
+*/
+package gocdtest
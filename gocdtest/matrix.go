@@ -0,0 +1,263 @@
+package gocdtest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/beamly/go-gocd/gocd"
+)
+
+// Releases lists the GoCD releases from 16.1.0 to 20.10.0, which a Matrix runs operations against by default.
+var Releases = releases(map[int]int{16: 12, 17: 12, 18: 12, 19: 12, 20: 10})
+
+func releases(lastMinor map[int]int) []string {
+	majors := make([]int, 0, len(lastMinor))
+	for major := range lastMinor {
+		majors = append(majors, major)
+	}
+	sort.Ints(majors)
+
+	versions := []string{}
+	for _, major := range majors {
+		for minor := 1; minor <= lastMinor[major]; minor++ {
+			versions = append(versions, fmt.Sprintf("%d.%d.0", major, minor))
+		}
+	}
+	return versions
+}
+
+// Operation is a call made with the gocd client, eg:
+//
+//	func(ctx context.Context, client *gocd.Client) error {
+//		_, _, err := client.PipelineTemplates.List(ctx)
+//		return err
+//	}
+type Operation func(ctx context.Context, client *gocd.Client) error
+
+// Matrix runs operations against a fake server emulating each of a list of GoCD releases, and reports which Accept
+// header every operation sends to each release, or which releases it can not be used with. This makes the impact of
+// upgrading GoCD, or of changing the API versions negotiated by the gocd package, predictable.
+//
+//	report := (&gocdtest.Matrix{}).Run(map[string]gocdtest.Operation{
+//		"PipelineTemplates.List": func(ctx context.Context, client *gocd.Client) error {
+//			_, _, err := client.PipelineTemplates.List(ctx)
+//			return err
+//		},
+//	})
+//	fmt.Print(report)
+type Matrix struct {
+	// Versions of GoCD to emulate. Defaults to Releases.
+	Versions []string
+	// Setup is called with the server for each version before the operations are run, to add the resources they use.
+	Setup func(server *Server)
+}
+
+// Run calls every operation against each version. Each version is emulated by a new Server, so operations can not see
+// the changes made for other versions.
+func (m *Matrix) Run(operations map[string]Operation) *MatrixReport {
+	versions := m.Versions
+	if len(versions) == 0 {
+		versions = Releases
+	}
+	names := make([]string, 0, len(operations))
+	for name := range operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	report := &MatrixReport{}
+	for _, serverVersion := range versions {
+		report.Results = append(report.Results, m.runVersion(serverVersion, names, operations)...)
+	}
+	return report
+}
+
+func (m *Matrix) runVersion(serverVersion string, names []string, operations map[string]Operation) []*MatrixResult {
+	server := NewServer()
+	defer server.Close()
+
+	results := []*MatrixResult{}
+	if err := server.SetVersion(serverVersion); err != nil {
+		for _, name := range names {
+			results = append(results, &MatrixResult{Operation: name, ServerVersion: serverVersion, Err: err})
+		}
+		return results
+	}
+	if m.Setup != nil {
+		m.Setup(server)
+	}
+
+	for _, name := range names {
+		result := &MatrixResult{Operation: name, ServerVersion: serverVersion}
+
+		// Each operation uses its own client, so that it negotiates the server version afresh.
+		client := server.Client()
+		recorder := &acceptRecorder{}
+		client.Use(gocd.BeforeRequest(recorder.record))
+
+		result.Err = operations[name](context.Background(), client)
+		result.Requests, result.Accept = recorder.requests, recorder.accept
+		results = append(results, result)
+	}
+	return results
+}
+
+// acceptRecorder collects the Accept headers sent by a client, ignoring the request for the server version.
+type acceptRecorder struct {
+	mu       sync.Mutex
+	requests int
+	accept   []string
+}
+
+func (ar *acceptRecorder) record(req *http.Request) error {
+	if strings.HasSuffix(strings.TrimSuffix(req.URL.Path, "/"), "/api/version") {
+		return nil
+	}
+
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	ar.requests++
+	accept := req.Header.Get("Accept")
+	for _, seen := range ar.accept {
+		if seen == accept {
+			return nil
+		}
+	}
+	ar.accept = append(ar.accept, accept)
+	return nil
+}
+
+// MatrixResult describes the outcome of an operation against a version of GoCD.
+type MatrixResult struct {
+	Operation     string
+	ServerVersion string
+	// Requests is the number of requests sent by the operation, not counting the request for the server version.
+	Requests int
+	// Accept lists the distinct Accept headers sent by the operation, in the order they were first sent. Requests sent
+	// without an Accept header are listed as an empty string.
+	Accept []string
+	// Err is the error returned by the operation, if any.
+	Err error
+}
+
+// Unsupported is true if the gocd package refused to make the request, as it does not know an API version of the
+// endpoint which the server version supports.
+func (mr *MatrixResult) Unsupported() bool {
+	if mr.Err == nil {
+		return false
+	}
+	message := mr.Err.Error()
+	return strings.Contains(message, "could not find API version tag") ||
+		strings.Contains(message, "could not find api version for server version")
+}
+
+// outcome summarises the result, so that consecutive versions with the same outcome can be grouped.
+func (mr *MatrixResult) outcome() string {
+	if mr.Unsupported() {
+		return "unsupported: " + mr.errorMessage()
+	}
+
+	headers := []string{}
+	for _, accept := range mr.Accept {
+		if accept == "" {
+			accept = "no Accept header"
+		}
+		headers = append(headers, accept)
+	}
+	outcome := strings.Join(headers, ", ")
+	if mr.Requests == 0 {
+		outcome = "no request"
+	}
+	if mr.Err != nil {
+		outcome += " (failed: " + mr.errorMessage() + ")"
+	}
+	return outcome
+}
+
+// errorMessage formats the error on a single line, without the server version, so that it is the same for every
+// version the operation fails in the same way with.
+func (mr *MatrixResult) errorMessage() string {
+	message := strings.Replace(mr.Err.Error(), mr.ServerVersion, "<version>", -1)
+	return strings.Join(strings.Fields(message), " ")
+}
+
+// MatrixReport holds the results of running a Matrix.
+type MatrixReport struct {
+	Results []*MatrixResult
+}
+
+// Result finds the outcome of an operation against a version of GoCD, or nil if it was not run.
+func (r *MatrixReport) Result(operation string, serverVersion string) *MatrixResult {
+	for _, result := range r.Results {
+		if result.Operation == operation && result.ServerVersion == serverVersion {
+			return result
+		}
+	}
+	return nil
+}
+
+// Accept returns the Accept header sent by an operation to a version of GoCD, or an empty string if the operation did
+// not send a request. When the operation sent several requests, the header of the last distinct one is returned.
+func (r *MatrixReport) Accept(operation string, serverVersion string) string {
+	result := r.Result(operation, serverVersion)
+	if result == nil || len(result.Accept) == 0 {
+		return ""
+	}
+	return result.Accept[len(result.Accept)-1]
+}
+
+// Unsupported lists the versions of GoCD the gocd package can not make an operation's requests to.
+func (r *MatrixReport) Unsupported(operation string) []string {
+	versions := []string{}
+	for _, result := range r.Results {
+		if result.Operation == operation && result.Unsupported() {
+			versions = append(versions, result.ServerVersion)
+		}
+	}
+	return versions
+}
+
+// String formats the report as a table, with a row for each range of versions an operation has the same outcome for.
+//
+//	OPERATION               SERVER VERSIONS     OUTCOME
+//	PipelineTemplates.List  16.1.0 - 16.9.0     unsupported: could not find api version for server version '<version>': '/api/admin/templates' requires GoCD >= 16.10.0
+//	PipelineTemplates.List  16.10.0             application/vnd.go.cd.v1+json
+func (r *MatrixReport) String() string {
+	operations := []string{}
+	byOperation := map[string][]*MatrixResult{}
+	for _, result := range r.Results {
+		if _, seen := byOperation[result.Operation]; !seen {
+			operations = append(operations, result.Operation)
+		}
+		byOperation[result.Operation] = append(byOperation[result.Operation], result)
+	}
+	sort.Strings(operations)
+
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "OPERATION\tSERVER VERSIONS\tOUTCOME")
+	for _, operation := range operations {
+		results := byOperation[operation]
+		for start := 0; start < len(results); {
+			end := start
+			for end+1 < len(results) && results[end+1].outcome() == results[start].outcome() {
+				end++
+			}
+
+			versions := results[start].ServerVersion
+			if end > start {
+				versions += " - " + results[end].ServerVersion
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", operation, versions, results[start].outcome())
+			start = end + 1
+		}
+	}
+	w.Flush()
+	return buf.String()
+}
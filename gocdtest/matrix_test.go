@@ -0,0 +1,104 @@
+package gocdtest
+
+import (
+	"context"
+	"github.com/beamly/go-gocd/gocd"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestMatrix(t *testing.T) {
+	t.Run("Releases", testMatrixReleases)
+	t.Run("Accept", testMatrixAccept)
+	t.Run("Unsupported", testMatrixUnsupported)
+	t.Run("Setup", testMatrixSetup)
+	t.Run("InvalidVersion", testMatrixInvalidVersion)
+	t.Run("String", testMatrixString)
+}
+
+var matrixOperations = map[string]Operation{
+	"PipelineTemplates.List": func(ctx context.Context, client *gocd.Client) error {
+		_, _, err := client.PipelineTemplates.List(ctx)
+		return err
+	},
+	"Roles.List": func(ctx context.Context, client *gocd.Client) error {
+		_, _, err := client.Roles.List(ctx)
+		return err
+	},
+}
+
+func testMatrixReleases(t *testing.T) {
+	assert.Len(t, Releases, 58)
+	assert.Equal(t, "16.1.0", Releases[0])
+	assert.Equal(t, "16.12.0", Releases[11])
+	assert.Equal(t, "17.1.0", Releases[12])
+	assert.Equal(t, "20.10.0", Releases[len(Releases)-1])
+}
+
+func testMatrixAccept(t *testing.T) {
+	report := (&Matrix{Versions: []string{"16.10.0", "17.1.0", "19.10.0", "20.2.0"}}).Run(matrixOperations)
+
+	for serverVersion, accept := range map[string]string{
+		"16.10.0": "application/vnd.go.cd.v1+json",
+		"17.1.0":  "application/vnd.go.cd.v3+json",
+		"19.10.0": "application/vnd.go.cd.v5+json",
+		"20.2.0":  "application/vnd.go.cd.v7+json",
+	} {
+		assert.Equal(t, accept, report.Accept("PipelineTemplates.List", serverVersion), serverVersion)
+		assert.NoError(t, report.Result("PipelineTemplates.List", serverVersion).Err, serverVersion)
+	}
+	assert.Equal(t, "application/vnd.go.cd.v3+json", report.Accept("Roles.List", "20.2.0"))
+	assert.Nil(t, report.Result("Roles.List", "15.1.0"))
+}
+
+func testMatrixUnsupported(t *testing.T) {
+	report := (&Matrix{Versions: []string{"16.9.0", "17.4.0", "17.5.0"}}).Run(matrixOperations)
+
+	assert.Equal(t, []string{"16.9.0"}, report.Unsupported("PipelineTemplates.List"))
+	assert.Equal(t, []string{"16.9.0", "17.4.0"}, report.Unsupported("Roles.List"))
+
+	result := report.Result("Roles.List", "17.4.0")
+	assert.True(t, result.Unsupported())
+	assert.Equal(t, 0, result.Requests)
+	assert.Empty(t, report.Accept("Roles.List", "17.4.0"))
+}
+
+func testMatrixSetup(t *testing.T) {
+	report := (&Matrix{
+		Versions: []string{"17.4.0", "19.10.0"},
+		Setup: func(server *Server) {
+			server.AddPipeline("my-group", &gocd.Pipeline{Name: "my-pipeline"})
+		},
+	}).Run(map[string]Operation{
+		"PipelineConfigs.Get": func(ctx context.Context, client *gocd.Client) error {
+			_, _, err := client.PipelineConfigs.Get(ctx, "my-pipeline")
+			return err
+		},
+	})
+
+	assert.NoError(t, report.Result("PipelineConfigs.Get", "17.4.0").Err)
+	assert.Equal(t, "application/vnd.go.cd.v4+json", report.Accept("PipelineConfigs.Get", "17.4.0"))
+	assert.Equal(t, "application/vnd.go.cd.v10+json", report.Accept("PipelineConfigs.Get", "19.10.0"))
+}
+
+func testMatrixInvalidVersion(t *testing.T) {
+	report := (&Matrix{Versions: []string{"not-a-version"}}).Run(matrixOperations)
+
+	result := report.Result("Roles.List", "not-a-version")
+	assert.Error(t, result.Err)
+	assert.False(t, result.Unsupported())
+}
+
+func testMatrixString(t *testing.T) {
+	report := (&Matrix{Versions: []string{"16.11.0", "16.12.0", "17.1.0"}}).Run(map[string]Operation{
+		"PipelineTemplates.List": matrixOperations["PipelineTemplates.List"],
+	})
+
+	lines := strings.Split(strings.TrimSpace(report.String()), "\n")
+	assert.Equal(t, []string{
+		"OPERATION               SERVER VERSIONS    OUTCOME",
+		"PipelineTemplates.List  16.11.0 - 16.12.0  application/vnd.go.cd.v2+json",
+		"PipelineTemplates.List  17.1.0             application/vnd.go.cd.v3+json",
+	}, lines)
+}
@@ -0,0 +1,225 @@
+package gocdtest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Mode describes whether a Recorder sends requests to the server or replays them from a cassette.
+type Mode int
+
+// Recorder modes.
+const (
+	// ModeAuto replays the cassette if it exists, and records a new one otherwise.
+	ModeAuto Mode = iota
+	// ModeRecord always sends requests to the server and overwrites the cassette.
+	ModeRecord
+	// ModeReplay only replays the cassette, failing if it does not exist.
+	ModeReplay
+)
+
+// Matcher checks whether a recorded request matches a request being replayed. Both requests have been scrubbed.
+type Matcher func(req *RecordedRequest, recorded *RecordedRequest) bool
+
+// DefaultMatcher matches requests with the same method, URL, Accept header, and body.
+func DefaultMatcher(req *RecordedRequest, recorded *RecordedRequest) bool {
+	return req.Method == recorded.Method &&
+		req.URL == recorded.URL &&
+		req.Header.Get("Accept") == recorded.Header.Get("Accept") &&
+		req.Body == recorded.Body
+}
+
+// Recorder is an http.RoundTripper recording interactions with a GoCD server to a cassette, or replaying them.
+type Recorder struct {
+	// Matcher selects the recorded interaction to replay for a request. Defaults to DefaultMatcher.
+	Matcher Matcher
+	// Scrubbers are applied to every interaction before it is recorded, and to every request before it is matched.
+	// Defaults to DefaultScrubbers.
+	Scrubbers []Scrubber
+
+	mu        sync.Mutex
+	path      string
+	recording bool
+	transport http.RoundTripper
+	cassette  *Cassette
+	replayed  []bool
+}
+
+// NewRecorder creates a recorder for the cassette at `path`. When recording, requests are sent through `transport`, or
+// http.DefaultTransport if it is nil.
+func NewRecorder(path string, mode Mode, transport http.RoundTripper) (*Recorder, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	r := &Recorder{
+		Matcher:   DefaultMatcher,
+		Scrubbers: DefaultScrubbers(),
+		path:      path,
+		transport: transport,
+		cassette:  &Cassette{},
+	}
+
+	recording := mode == ModeRecord
+	if mode == ModeAuto {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			recording = true
+		}
+	}
+
+	if r.recording = recording; !recording {
+		cassette, err := LoadCassette(path)
+		if err != nil {
+			return nil, fmt.Errorf("gocdtest: could not load cassette: %s", err)
+		}
+		r.cassette = cassette
+		r.replayed = make([]bool, len(cassette.Interactions))
+	}
+
+	return r, nil
+}
+
+// Recording returns true if requests are sent to the server rather than replayed.
+func (r *Recorder) Recording() bool {
+	return r.recording
+}
+
+// HTTPClient returns an http client sending its requests through the recorder, for use with gocd.NewClient.
+func (r *Recorder) HTTPClient() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip records or replays a single request.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recordedReq, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.recording {
+		return r.record(req, recordedReq)
+	}
+	return r.replay(req, recordedReq)
+}
+
+// Stop the recorder, writing the cassette if recording.
+func (r *Recorder) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.recording {
+		return nil
+	}
+	return r.cassette.Save(r.path)
+}
+
+// Unused returns the recorded interactions which have not been replayed.
+func (r *Recorder) Unused() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	unused := []*Interaction{}
+	for i, replayed := range r.replayed {
+		if !replayed {
+			unused = append(unused, r.cassette.Interactions[i])
+		}
+	}
+	return unused
+}
+
+// record sends the request to the server and stores the scrubbed interaction.
+func (r *Recorder) record(req *http.Request, recordedReq *RecordedRequest) (*http.Response, error) {
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	interaction := &Interaction{
+		Request: recordedReq,
+		Response: &RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     cloneHeader(resp.Header),
+			Body:       string(body),
+		},
+	}
+	r.scrub(interaction)
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// replay finds the first matching interaction which has not been replayed yet, and builds a response from it.
+func (r *Recorder) replay(req *http.Request, recordedReq *RecordedRequest) (*http.Response, error) {
+	r.scrub(&Interaction{Request: recordedReq})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.cassette.Interactions {
+		if r.replayed[i] || !r.Matcher(recordedReq, interaction.Request) {
+			continue
+		}
+		r.replayed[i] = true
+		return interaction.Response.response(req), nil
+	}
+	return nil, fmt.Errorf("gocdtest: no recorded interaction for %s %s", recordedReq.Method, recordedReq.URL)
+}
+
+func (r *Recorder) scrub(interaction *Interaction) {
+	for _, scrubber := range r.Scrubbers {
+		scrubber(interaction)
+	}
+}
+
+// recordRequest copies the request, leaving its body readable.
+func recordRequest(req *http.Request) (*RecordedRequest, error) {
+	recorded := &RecordedRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: cloneHeader(req.Header),
+	}
+
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		recorded.Body = string(body)
+	}
+	return recorded, nil
+}
+
+// response builds a net/http.Response from the recorded response.
+func (rr *RecordedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rr.StatusCode, http.StatusText(rr.StatusCode)),
+		StatusCode:    rr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cloneHeader(rr.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(rr.Body))),
+		ContentLength: int64(len(rr.Body)),
+		Request:       req,
+	}
+}
+
+func cloneHeader(header http.Header) http.Header {
+	clone := make(http.Header, len(header))
+	for key, values := range header {
+		clone[key] = append([]string(nil), values...)
+	}
+	return clone
+}
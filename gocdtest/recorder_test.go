@@ -0,0 +1,145 @@
+package gocdtest
+
+import (
+	"context"
+	"fmt"
+	"github.com/beamly/go-gocd/gocd"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Run("RecordAndReplay", testRecorderRecordAndReplay)
+	t.Run("ReplayInOrder", testRecorderReplayInOrder)
+	t.Run("ReplayNoMatch", testRecorderReplayNoMatch)
+	t.Run("ReplayMissingCassette", testRecorderReplayMissingCassette)
+}
+
+// mockServer serves the server version, an agent list and the encryption endpoint, counting the requests received
+// other than for the server version.
+func mockServer(t *testing.T, requests *int) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"version": "18.2.0", "build_number": "5934", "git_sha": "f7fb9f3"}`)
+	})
+	mux.HandleFunc("/api/agents", func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		w.Header().Set("Set-Cookie", "JSESSIONID=session-secret")
+		fmt.Fprint(w, `{"_embedded": {"agents": [{"uuid": "adb9540a-b954-4571-9d9b-2f330739d4da", "hostname": "agent01"}]}}`)
+	})
+	mux.HandleFunc("/api/admin/encrypt", func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		b, _ := ioutil.ReadAll(r.Body)
+		assert.Contains(t, string(b), "plain-secret")
+		fmt.Fprint(w, `{"encrypted_value": "aSdiFgRRZ6A="}`)
+	})
+	return httptest.NewServer(mux)
+}
+
+func tempCassette(t *testing.T) (path string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "gocdtest")
+	assert.NoError(t, err)
+	return filepath.Join(dir, "cassettes", "session.json"), func() { os.RemoveAll(dir) }
+}
+
+func runSession(t *testing.T, serverURL string, httpClient *http.Client) {
+	client := gocd.NewClient(&gocd.Configuration{
+		Server:   serverURL,
+		Username: "admin",
+		Password: "admin-password",
+	}, httpClient)
+
+	agents, _, err := client.Agents.List(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, agents, 1) {
+		assert.Equal(t, "agent01", agents[0].Hostname)
+	}
+
+	_, _, err = client.Encryption.Encrypt(context.Background(), "plain-secret")
+	assert.NoError(t, err)
+}
+
+func testRecorderRecordAndReplay(t *testing.T) {
+	path, cleanup := tempCassette(t)
+	defer cleanup()
+
+	requests := 0
+	server := mockServer(t, &requests)
+
+	rec, err := NewRecorder(path, ModeAuto, nil)
+	assert.NoError(t, err)
+	assert.True(t, rec.Recording())
+	runSession(t, server.URL, rec.HTTPClient())
+	assert.NoError(t, rec.Stop())
+	assert.Equal(t, 2, requests)
+
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	for _, secret := range []string{"admin-password", "session-secret", "aSdiFgRRZ6A=", "plain-secret"} {
+		assert.NotContains(t, string(b), secret)
+	}
+	assert.Contains(t, string(b), Scrubbed)
+
+	// Once the server is gone, the session is replayed from the cassette.
+	server.Close()
+
+	rec, err = NewRecorder(path, ModeAuto, nil)
+	assert.NoError(t, err)
+	assert.False(t, rec.Recording())
+	runSession(t, server.URL, rec.HTTPClient())
+	assert.NoError(t, rec.Stop())
+	assert.Empty(t, rec.Unused())
+	assert.Equal(t, 2, requests)
+}
+
+func testRecorderReplayInOrder(t *testing.T) {
+	path, cleanup := tempCassette(t)
+	defer cleanup()
+
+	cassette := &Cassette{}
+	for _, body := range []string{"first", "second"} {
+		cassette.Interactions = append(cassette.Interactions, &Interaction{
+			Request:  &RecordedRequest{Method: "GET", URL: "https://ci.example.com/go/api/version"},
+			Response: &RecordedResponse{StatusCode: 200, Body: body},
+		})
+	}
+	assert.NoError(t, cassette.Save(path))
+
+	rec, err := NewRecorder(path, ModeReplay, nil)
+	assert.NoError(t, err)
+	assert.Len(t, rec.Unused(), 2)
+
+	for _, expected := range []string{"first", "second"} {
+		resp, err := rec.HTTPClient().Get("https://ci.example.com/go/api/version")
+		if assert.NoError(t, err) {
+			b, _ := ioutil.ReadAll(resp.Body)
+			assert.Equal(t, expected, string(b))
+		}
+	}
+
+	_, err = rec.HTTPClient().Get("https://ci.example.com/go/api/version")
+	assert.Error(t, err)
+}
+
+func testRecorderReplayNoMatch(t *testing.T) {
+	path, cleanup := tempCassette(t)
+	defer cleanup()
+	assert.NoError(t, (&Cassette{}).Save(path))
+
+	rec, err := NewRecorder(path, ModeReplay, nil)
+	assert.NoError(t, err)
+
+	_, err = rec.HTTPClient().Get("https://ci.example.com/go/api/agents")
+	assert.True(t, strings.HasSuffix(err.Error(), "gocdtest: no recorded interaction for GET https://ci.example.com/go/api/agents"))
+}
+
+func testRecorderReplayMissingCassette(t *testing.T) {
+	_, err := NewRecorder("/does/not/exist.json", ModeReplay, nil)
+	assert.Contains(t, err.Error(), "gocdtest: could not load cassette")
+}
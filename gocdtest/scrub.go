@@ -0,0 +1,154 @@
+package gocdtest
+
+import (
+	"encoding/json"
+	"github.com/beamly/go-gocd/internal/redact"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Scrubbed replaces credentials in recorded interactions.
+const Scrubbed = "[SCRUBBED]"
+
+// Scrubber removes sensitive data from an interaction. The response is nil when a request is scrubbed for matching.
+type Scrubber func(interaction *Interaction)
+
+// DefaultScrubbers remove credential headers, passwords and encrypted values in JSON and XML bodies, the values of
+// secure variables, the plaintext sent to the encryption endpoint, and the credentials in URLs. They hide the same
+// secrets as the debug logs of the gocd package.
+func DefaultScrubbers() []Scrubber {
+	return []Scrubber{
+		ScrubHeaders("Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"),
+		ScrubJSONFields("password", "encrypted_password", "encrypted_value"),
+		ScrubXML(),
+		ScrubRequestBodies("/api/admin/encrypt"),
+		ScrubURLCredentials(),
+		ScrubSecureVariables(),
+	}
+}
+
+// ScrubHeaders replaces the values of the named headers in requests and responses.
+func ScrubHeaders(names ...string) Scrubber {
+	return func(interaction *Interaction) {
+		for _, name := range names {
+			scrubHeader(interaction.Request.Header, name)
+			if interaction.Response != nil {
+				scrubHeader(interaction.Response.Header, name)
+			}
+		}
+	}
+}
+
+// ScrubJSONFields replaces the values of the named fields, and of the `value` field in objects with `"secure": true`,
+// in JSON request and response bodies.
+func ScrubJSONFields(names ...string) Scrubber {
+	fields := map[string]bool{}
+	for _, name := range names {
+		fields[name] = true
+	}
+	return func(interaction *Interaction) {
+		interaction.Request.Body = scrubJSON(interaction.Request.Body, fields)
+		if interaction.Response != nil {
+			interaction.Response.Body = scrubJSON(interaction.Response.Body, fields)
+		}
+	}
+}
+
+// ScrubXML replaces passwords and encrypted values in cruise-config.xml request and response bodies, eg: the
+// `encryptedPassword` attribute of materials.
+func ScrubXML() Scrubber {
+	return func(interaction *Interaction) {
+		interaction.Request.Body = redact.XML(interaction.Request.Body, Scrubbed)
+		if interaction.Response != nil {
+			interaction.Response.Body = redact.XML(interaction.Response.Body, Scrubbed)
+		}
+	}
+}
+
+// ScrubRequestBodies replaces the whole body of requests whose URL path ends with one of the suffixes.
+func ScrubRequestBodies(pathSuffixes ...string) Scrubber {
+	return func(interaction *Interaction) {
+		u, err := url.Parse(interaction.Request.URL)
+		if err != nil || interaction.Request.Body == "" {
+			return
+		}
+		for _, suffix := range pathSuffixes {
+			if strings.HasSuffix(u.Path, suffix) {
+				interaction.Request.Body = Scrubbed
+				return
+			}
+		}
+	}
+}
+
+// ScrubURLCredentials removes user info from the URLs of requests.
+func ScrubURLCredentials() Scrubber {
+	return func(interaction *Interaction) {
+		if u, err := url.Parse(interaction.Request.URL); err == nil && u.User != nil {
+			u.User = nil
+			interaction.Request.URL = u.String()
+		}
+	}
+}
+
+// ScrubSecureVariables replaces the values of the `secure_variables[...]` query parameters in the URLs of requests, as
+// sent when scheduling pipelines on servers without the JSON API.
+func ScrubSecureVariables() Scrubber {
+	return func(interaction *Interaction) {
+		interaction.Request.URL = redact.URL(interaction.Request.URL, Scrubbed)
+	}
+}
+
+// ScrubString replaces every occurrence of `secret` in URLs, headers, and bodies with `replacement`. It can be used to
+// hide hostnames or tokens which are not covered by the default scrubbers.
+func ScrubString(secret string, replacement string) Scrubber {
+	replace := func(s string) string {
+		return strings.Replace(s, secret, replacement, -1)
+	}
+	return func(interaction *Interaction) {
+		if secret == "" {
+			return
+		}
+		interaction.Request.URL = replace(interaction.Request.URL)
+		interaction.Request.Body = replace(interaction.Request.Body)
+		replaceHeader(interaction.Request.Header, replace)
+		if interaction.Response != nil {
+			interaction.Response.Body = replace(interaction.Response.Body)
+			replaceHeader(interaction.Response.Header, replace)
+		}
+	}
+}
+
+func scrubHeader(header http.Header, name string) {
+	if values, hasHeader := header[http.CanonicalHeaderKey(name)]; hasHeader {
+		for i := range values {
+			values[i] = Scrubbed
+		}
+	}
+}
+
+func replaceHeader(header http.Header, replace func(string) string) {
+	for _, values := range header {
+		for i, value := range values {
+			values[i] = replace(value)
+		}
+	}
+}
+
+// scrubJSON replaces secrets in a JSON document. Bodies which are not JSON are returned unchanged.
+func scrubJSON(body string, fields map[string]bool) string {
+	var decoded interface{}
+	if body == "" || json.Unmarshal([]byte(body), &decoded) != nil {
+		return body
+	}
+	if !redact.JSON(decoded, fields, Scrubbed) {
+		return body
+	}
+
+	b, err := json.Marshal(decoded)
+	if err != nil {
+		return body
+	}
+	return string(b)
+}
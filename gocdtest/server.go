@@ -0,0 +1,373 @@
+package gocdtest
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/beamly/go-gocd/gocd"
+	"github.com/hashicorp/go-version"
+)
+
+// DefaultServerVersion is the version of GoCD emulated by a Server, unless another one is set with SetVersion.
+const DefaultServerVersion = "19.10.0"
+
+// Error messages returned by the fake server, matching those returned by GoCD.
+const (
+	messageNotFound       = "Either the resource you requested was not found, or you are not authorized to perform this action."
+	messageIncorrectURL   = "The url you are trying to reach appears to be incorrect."
+	messageMethodNotAllow = "The method you are trying to use is not allowed on this resource."
+	messageStaleETag      = "Someone has modified the configuration for %s '%s'. Please update your copy of the config with the changes and try again."
+	messageAlreadyExists  = "Failed to add %s. The %s '%s' already exists."
+	messageValidation     = "Validations failed for %s '%s'. Error(s): [%s]. Please correct and resubmit."
+	messageDeleted        = "The %s '%s' was deleted successfully."
+)
+
+// Server is an in-memory fake of the GoCD API, for testing code built on the gocd package. It serves agents, pipeline
+// configs, pipeline groups, templates, environments, roles, config repos, plugins and their settings, and the server
+// version.
+//
+// Requests must use the Accept header the gocd package negotiates for the emulated server version, updates must send
+// the ETag of the resource they modify in the If-Match header, and resources failing validation are rejected with the
+// same status codes as GoCD.
+//
+//	server := gocdtest.NewServer()
+//	defer server.Close()
+//
+//	client := server.Client()
+//	pipeline, _, err := client.PipelineConfigs.Create(ctx, "my-group", &gocd.Pipeline{...})
+type Server struct {
+	// URL of the GoCD server, including the "/go/" suffix.
+	URL string
+
+	server *httptest.Server
+
+	mu             sync.Mutex
+	version        *gocd.ServerVersion
+	agents         *store
+	pipelines      *store
+	pipelineGroups []string
+	templates      *store
+	environments   *store
+	roles          *store
+	configRepos    *store
+	plugins        *store
+	pluginSettings *store
+}
+
+// NewServer starts a fake GoCD server with no resources, emulating DefaultServerVersion.
+func NewServer() *Server {
+	s := &Server{
+		agents:         newStore(),
+		pipelines:      newStore(),
+		templates:      newStore(),
+		environments:   newStore(),
+		roles:          newStore(),
+		configRepos:    newStore(),
+		plugins:        newStore(),
+		pluginSettings: newStore(),
+	}
+	if err := s.SetVersion(DefaultServerVersion); err != nil {
+		panic(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/go/", http.StripPrefix("/go", http.HandlerFunc(s.serveAPI)))
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL + "/go/"
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Client creates a gocd client for the server.
+func (s *Server) Client() *gocd.Client {
+	return gocd.NewClient(&gocd.Configuration{Server: s.URL}, s.server.Client())
+}
+
+// SetVersion changes the version of GoCD emulated by the server, eg: "18.2.0". Clients cache the server version, so
+// this should be called before the first request.
+func (s *Server) SetVersion(serverVersion string) error {
+	parts, err := version.NewVersion(serverVersion)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = &gocd.ServerVersion{
+		Version:      serverVersion,
+		VersionParts: parts,
+		BuildNumber:  "1",
+		GitSha:       "0000000000000000000000000000000000000000",
+		FullVersion:  serverVersion + " (1-0000000000000000000000000000000000000000)",
+		CommitURL:    "https://github.com/gocd/gocd/commits/0000000000000000000000000000000000000000",
+	}
+	return nil
+}
+
+// AddAgent registers an agent with the server. Agents can not be created through the API, as they register
+// themselves with GoCD.
+func (s *Server) AddAgent(agent *gocd.Agent) {
+	doc, err := toDocument(agent)
+	if err != nil {
+		panic(err)
+	}
+	if _, hasState := doc["agent_config_state"]; !hasState {
+		doc["agent_config_state"] = "Enabled"
+	}
+	delete(doc, "environments")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agents.put(agent.UUID, doc)
+}
+
+// AddPlugin installs a plugin on the server. Plugins can not be installed through the API.
+func (s *Server) AddPlugin(plugin *gocd.Plugin) {
+	doc, err := toDocument(plugin)
+	if err != nil {
+		panic(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.plugins.put(plugin.ID, doc)
+}
+
+// AddPipeline stores a pipeline config without validating it. This can be used to add pipelines defined in config
+// repos, by setting their Origin, as those can not be created through the API.
+func (s *Server) AddPipeline(group string, pipeline *gocd.Pipeline) {
+	doc, err := toDocument(pipeline)
+	if err != nil {
+		panic(err)
+	}
+	doc["group"] = group
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pipelines.put(pipeline.Name, doc)
+}
+
+// AddPipelineGroup adds a pipeline group without pipelines. Groups are otherwise created with their first pipeline, as
+// the API can not create them.
+func (s *Server) AddPipelineGroup(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pipelineGroups = append(s.pipelineGroups, name)
+}
+
+// serveAPI checks the Accept header against the emulated server version before routing the request.
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	apiVersion, err := s.apiVersion(path)
+	if err != nil || r.Header.Get("Accept") != apiVersion {
+		s.writeMessage(w, http.StatusNotFound, messageIncorrectURL)
+		return
+	}
+
+	if path == "/api/version" {
+		s.serveVersion(w, r)
+		return
+	}
+
+	if strings.HasPrefix(path, "/api/agents") {
+		s.serveAgents(w, r, path)
+		return
+	}
+
+	if path == "/api/config/pipeline_groups" {
+		s.servePipelineGroups(w, r)
+		return
+	}
+
+	if strings.HasPrefix(path, "/api/admin/plugin_info") {
+		s.servePlugins(w, r, path)
+		return
+	}
+
+	for _, kind := range s.kinds() {
+		if path == kind.collection {
+			s.serveCollection(w, r, kind)
+			return
+		}
+		if id := strings.TrimPrefix(path, kind.collection+"/"); id != path && !strings.Contains(id, "/") {
+			s.serveResource(w, r, kind, id)
+			return
+		}
+	}
+
+	s.writeMessage(w, http.StatusNotFound, messageIncorrectURL)
+}
+
+// apiVersion finds the Accept header expected for a path. Collections without their own entry in the version lookup,
+// such as "/api/admin/pipelines", use the version of the resources they contain.
+func (s *Server) apiVersion(path string) (string, error) {
+	apiVersion, err := s.version.GetAPIVersionForPath(path)
+	if err != nil {
+		for _, kind := range s.kinds() {
+			if path == kind.collection {
+				return s.version.GetAPIVersionForPath(path + "/" + kind.key)
+			}
+		}
+	}
+	return apiVersion, err
+}
+
+func (s *Server) serveVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		s.writeMessage(w, http.StatusMethodNotAllowed, messageMethodNotAllow)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"_links":       s.links("/api/version"),
+		"version":      s.version.Version,
+		"build_number": s.version.BuildNumber,
+		"git_sha":      s.version.GitSha,
+		"full_version": s.version.FullVersion,
+		"commit_url":   s.version.CommitURL,
+	})
+}
+
+// links builds the HAL links for a resource.
+func (s *Server) links(path string) map[string]interface{} {
+	return map[string]interface{}{
+		"self": map[string]string{"href": strings.TrimSuffix(s.URL, "/") + path},
+	}
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func (s *Server) writeMessage(w http.ResponseWriter, status int, message string) {
+	s.writeJSON(w, status, map[string]string{"message": message})
+}
+
+// writeValidationErrors responds with a 422, describing the errors in the same format as GoCD.
+func (s *Server) writeValidationErrors(w http.ResponseWriter, kind *kind, id string, doc document, errs fieldErrors) {
+	messages := []string{}
+	for _, field := range errs.fields() {
+		messages = append(messages, errs[field]...)
+	}
+
+	data := doc.clone()
+	data["errors"] = errs
+	s.writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+		"message": fmt.Sprintf(messageValidation, kind.singular, id, strings.Join(messages, ", ")),
+		"data":    data,
+	})
+}
+
+// fieldErrors describes validation errors, keyed by field name.
+type fieldErrors map[string][]string
+
+func (fe fieldErrors) add(field string, format string, args ...interface{}) {
+	fe[field] = append(fe[field], fmt.Sprintf(format, args...))
+}
+
+func (fe fieldErrors) fields() []string {
+	fields := make([]string, 0, len(fe))
+	for field := range fe {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// document is the JSON representation of a resource, stored without its links or version.
+type document map[string]interface{}
+
+func toDocument(v interface{}) (document, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	doc := document{}
+	if err = json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (d document) clone() document {
+	clone, _ := toDocument(d)
+	return clone
+}
+
+// etag is derived from the content of the document, so that it changes whenever the document does.
+func (d document) etag() string {
+	b, _ := json.Marshal(d)
+	return fmt.Sprintf("%x", sha1.Sum(b))
+}
+
+func (d document) string(key string) string {
+	value, _ := d[key].(string)
+	return value
+}
+
+func (d document) list(key string) []interface{} {
+	value, _ := d[key].([]interface{})
+	return value
+}
+
+// names returns the "name" (or other key) of every object in a list.
+func (d document) names(key string, nameKey string) []string {
+	names := []string{}
+	for _, item := range d.list(key) {
+		if object, isObject := item.(map[string]interface{}); isObject {
+			if name, isString := object[nameKey].(string); isString {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// store holds documents by identifier.
+type store struct {
+	docs map[string]document
+}
+
+func newStore() *store {
+	return &store{docs: map[string]document{}}
+}
+
+func (st *store) get(id string) (doc document, ok bool) {
+	doc, ok = st.docs[id]
+	return
+}
+
+func (st *store) put(id string, doc document) {
+	delete(doc, "_links")
+	delete(doc, "version")
+	delete(doc, "errors")
+	st.docs[id] = doc
+}
+
+func (st *store) delete(id string) {
+	delete(st.docs, id)
+}
+
+// ids returns the identifiers of every document, sorted.
+func (st *store) ids() []string {
+	ids := make([]string, 0, len(st.docs))
+	for id := range st.docs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,247 @@
+package gocdtest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// serveAgents handles the agent endpoints. Agents are registered with AddAgent, and the environments of an agent are
+// derived from the environments it has been added to.
+func (s *Server) serveAgents(w http.ResponseWriter, r *http.Request, path string) {
+	segments := strings.Split(strings.TrimPrefix(path, "/api/agents"), "/")
+
+	switch {
+	case len(segments) == 1:
+		s.serveAgentCollection(w, r)
+	case len(segments) == 2:
+		s.serveAgent(w, r, segments[1])
+	case segments[2] == "job_run_history":
+		if _, exists := s.agents.get(segments[1]); !exists {
+			s.writeMessage(w, http.StatusNotFound, messageNotFound)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"jobs":       []interface{}{},
+			"pagination": map[string]int{"offset": 0, "total": 0, "page_size": 10},
+		})
+	default:
+		s.writeMessage(w, http.StatusNotFound, messageIncorrectURL)
+	}
+}
+
+func (s *Server) serveAgentCollection(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		agents := []interface{}{}
+		for _, uuid := range s.agents.ids() {
+			agents = append(agents, s.agent(uuid))
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"_links":    s.links("/api/agents"),
+			"_embedded": map[string]interface{}{"agents": agents},
+		})
+	case "PATCH":
+		s.bulkUpdateAgents(w, r)
+	case "DELETE":
+		s.bulkDeleteAgents(w, r)
+	default:
+		s.writeMessage(w, http.StatusMethodNotAllowed, messageMethodNotAllow)
+	}
+}
+
+func (s *Server) serveAgent(w http.ResponseWriter, r *http.Request, uuid string) {
+	existing, exists := s.agents.get(uuid)
+	if !exists {
+		s.writeMessage(w, http.StatusNotFound, messageNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		s.writeJSON(w, http.StatusOK, s.agent(uuid))
+	case "PATCH":
+		patch, ok := s.readDocument(w, r)
+		if !ok {
+			return
+		}
+
+		agent := existing.clone()
+		for _, field := range []string{"hostname", "agent_config_state"} {
+			if value := patch.string(field); value != "" {
+				agent[field] = value
+			}
+		}
+		if resources, hasResources := patch["resources"]; hasResources {
+			agent["resources"] = resourceList(resources)
+		}
+		if !s.validAgentConfigState(w, agent.string("agent_config_state")) {
+			return
+		}
+		if environments, hasEnvironments := patch["environments"]; hasEnvironments {
+			if !s.setAgentEnvironments(w, uuid, stringList(environments)) {
+				return
+			}
+		}
+
+		s.agents.put(uuid, agent)
+		s.writeJSON(w, http.StatusOK, s.agent(uuid))
+	case "DELETE":
+		if existing.string("agent_config_state") != "Disabled" {
+			s.writeMessage(w, http.StatusNotAcceptable, fmt.Sprintf("Failed to delete agent %s as agent is not disabled.", uuid))
+			return
+		}
+		s.deleteAgents([]string{uuid})
+		s.writeMessage(w, http.StatusOK, "Deleted 1 agent(s).")
+	default:
+		s.writeMessage(w, http.StatusMethodNotAllowed, messageMethodNotAllow)
+	}
+}
+
+func (s *Server) bulkUpdateAgents(w http.ResponseWriter, r *http.Request) {
+	update, ok := s.readDocument(w, r)
+	if !ok {
+		return
+	}
+	uuids := stringList(update["uuids"])
+	if !s.agentsExist(w, uuids) {
+		return
+	}
+
+	state := update.string("agent_config_state")
+	if state != "" && !s.validAgentConfigState(w, state) {
+		return
+	}
+
+	operations, _ := update["operations"].(map[string]interface{})
+	for _, uuid := range uuids {
+		agent, _ := s.agents.get(uuid)
+		if state != "" {
+			agent["agent_config_state"] = state
+		}
+		if resources, hasResources := operations["resources"]; hasResources {
+			agent["resources"] = stringsToList(patchNames(stringList(agent["resources"]), resources))
+		}
+		if environments, hasEnvironments := operations["environments"]; hasEnvironments {
+			if !s.setAgentEnvironments(w, uuid, patchNames(s.agentEnvironments(uuid), environments)) {
+				return
+			}
+		}
+	}
+
+	s.writeMessage(w, http.StatusOK, fmt.Sprintf("Updated agent(s) with uuid(s): [%s].", strings.Join(uuids, ", ")))
+}
+
+func (s *Server) bulkDeleteAgents(w http.ResponseWriter, r *http.Request) {
+	request, ok := s.readDocument(w, r)
+	if !ok {
+		return
+	}
+	uuids := stringList(request["uuids"])
+	if !s.agentsExist(w, uuids) {
+		return
+	}
+
+	for _, uuid := range uuids {
+		if agent, _ := s.agents.get(uuid); agent.string("agent_config_state") != "Disabled" {
+			s.writeMessage(w, http.StatusNotAcceptable, "Could not delete any agents, as one or more agents might not be disabled or are still building.")
+			return
+		}
+	}
+
+	s.deleteAgents(uuids)
+	s.writeMessage(w, http.StatusOK, fmt.Sprintf("Deleted %d agent(s).", len(uuids)))
+}
+
+// agent builds the representation of an agent, including its environments.
+func (s *Server) agent(uuid string) document {
+	doc, _ := s.agents.get(uuid)
+	agent := doc.clone()
+	agent["_links"] = s.links("/api/agents/" + uuid)
+	agent["environments"] = stringsToList(s.agentEnvironments(uuid))
+	return agent
+}
+
+// agentEnvironments lists the environments an agent has been added to.
+func (s *Server) agentEnvironments(uuid string) []string {
+	environments := []string{}
+	for _, name := range s.environments.ids() {
+		env, _ := s.environments.get(name)
+		if containsString(env.names("agents", "uuid"), uuid) {
+			environments = append(environments, name)
+		}
+	}
+	return environments
+}
+
+// setAgentEnvironments adds the agent to, and removes it from, environments so that it is part of exactly those listed.
+func (s *Server) setAgentEnvironments(w http.ResponseWriter, uuid string, environments []string) bool {
+	for _, name := range environments {
+		if _, exists := s.environments.get(name); !exists {
+			s.writeMessage(w, http.StatusBadRequest, fmt.Sprintf("Environment '%s' does not exist.", name))
+			return false
+		}
+	}
+
+	for _, name := range s.environments.ids() {
+		env, _ := s.environments.get(name)
+		agents := env.names("agents", "uuid")
+		action := map[string]interface{}{"remove": []interface{}{uuid}}
+		if containsString(environments, name) {
+			action = map[string]interface{}{"add": []interface{}{uuid}}
+		}
+		env["agents"] = references(patchNames(agents, action), "uuid")
+	}
+	return true
+}
+
+func (s *Server) deleteAgents(uuids []string) {
+	for _, uuid := range uuids {
+		s.setAgentEnvironments(nil, uuid, nil)
+		s.agents.delete(uuid)
+	}
+}
+
+func (s *Server) agentsExist(w http.ResponseWriter, uuids []string) bool {
+	missing := []string{}
+	for _, uuid := range uuids {
+		if _, exists := s.agents.get(uuid); !exists {
+			missing = append(missing, uuid)
+		}
+	}
+	if len(missing) > 0 {
+		s.writeMessage(w, http.StatusBadRequest, fmt.Sprintf("Agent(s) with uuid(s): [%s] not found.", strings.Join(missing, ", ")))
+		return false
+	}
+	return true
+}
+
+func (s *Server) validAgentConfigState(w http.ResponseWriter, state string) bool {
+	if state != "Enabled" && state != "Disabled" && state != "Pending" {
+		s.writeMessage(w, http.StatusBadRequest, fmt.Sprintf("The value of `agent_config_state` can be one of `Enabled`, `Disabled` or null, but was '%s'.", state))
+		return false
+	}
+	return true
+}
+
+// resourceList accepts resources as a list, or as a comma separated string.
+func resourceList(resources interface{}) []interface{} {
+	if str, isString := resources.(string); isString {
+		list := []string{}
+		for _, resource := range strings.Split(str, ",") {
+			if resource = strings.TrimSpace(resource); resource != "" {
+				list = append(list, resource)
+			}
+		}
+		return stringsToList(list)
+	}
+	return stringsToList(stringList(resources))
+}
+
+func stringsToList(strs []string) []interface{} {
+	list := make([]interface{}, len(strs))
+	for i, str := range strs {
+		list[i] = str
+	}
+	return list
+}
@@ -0,0 +1,47 @@
+package gocdtest
+
+import (
+	"net/http"
+	"strings"
+)
+
+// servePlugins lists the plugins added with AddPlugin, and describes a single one.
+func (s *Server) servePlugins(w http.ResponseWriter, r *http.Request, path string) {
+	if r.Method != "GET" {
+		s.writeMessage(w, http.StatusMethodNotAllowed, messageMethodNotAllow)
+		return
+	}
+
+	if id := strings.TrimPrefix(path, "/api/admin/plugin_info/"); id != path {
+		plugin, exists := s.plugins.get(id)
+		if !exists {
+			s.writeMessage(w, http.StatusNotFound, messageNotFound)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, s.plugin(id, plugin))
+		return
+	}
+
+	plugins := []interface{}{}
+	for _, id := range s.plugins.ids() {
+		plugin, _ := s.plugins.get(id)
+		plugins = append(plugins, s.plugin(id, plugin))
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"_links":    s.links("/api/admin/plugin_info"),
+		"_embedded": map[string]interface{}{"plugin_info": plugins},
+	})
+}
+
+func (s *Server) plugin(id string, doc document) document {
+	plugin := doc.clone()
+	plugin["_links"] = s.links("/api/admin/plugin_info/" + id)
+	return plugin
+}
+
+func (s *Server) validatePluginSettings(doc document, errs fieldErrors) {
+	id := doc.string("plugin_id")
+	if _, exists := s.plugins.get(id); !exists {
+		errs.add("plugin_id", "Plugin with id '%s' is not found.", id)
+	}
+}
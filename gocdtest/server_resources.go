@@ -0,0 +1,293 @@
+package gocdtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// kind describes how a type of resource is served.
+type kind struct {
+	singular   string // singular is used in messages, eg: "pipeline".
+	collection string // collection is the path of the collection, eg: "/api/admin/pipelines".
+	embedded   string // embedded is the key of the list of resources in the collection, or empty if it can't be listed.
+	key        string // key is the field identifying a resource, eg: "name".
+	store      *store
+
+	// unwrap extracts the resource from the body of a create request.
+	unwrap func(body document, errs fieldErrors) document
+	// normalize converts the resource to the representation it is stored in.
+	normalize func(doc document)
+	// validate checks a resource before it is stored.
+	validate func(doc document, errs fieldErrors)
+	// patch applies a PATCH request to a resource. Resources without it can not be patched.
+	patch func(doc document, patch document)
+	// readOnly returns a message if the resource can not be modified through the API.
+	readOnly func(doc document) string
+	// inUse returns a message if the resource can not be deleted.
+	inUse func(id string) string
+	// summary builds the representation of a resource in the collection.
+	summary func(id string, doc document) document
+}
+
+func (s *Server) kinds() []*kind {
+	return []*kind{
+		{
+			singular:   "pipeline",
+			collection: "/api/admin/pipelines",
+			key:        "name",
+			store:      s.pipelines,
+			unwrap:     unwrapPipeline,
+			normalize:  normalizePipeline,
+			validate:   s.validatePipeline,
+			readOnly:   remotePipelineMessage,
+			inUse:      s.pipelineInUse,
+		},
+		{
+			singular:   "template",
+			collection: "/api/admin/templates",
+			embedded:   "templates",
+			key:        "name",
+			store:      s.templates,
+			validate:   s.validateTemplate,
+			inUse:      s.templateInUse,
+			summary:    s.templateSummary,
+		},
+		{
+			singular:   "environment",
+			collection: "/api/admin/environments",
+			embedded:   "environments",
+			key:        "name",
+			store:      s.environments,
+			normalize:  normalizeEnvironment,
+			validate:   s.validateEnvironment,
+			patch:      patchEnvironment,
+		},
+		{
+			singular:   "role",
+			collection: "/api/admin/security/roles",
+			embedded:   "roles",
+			key:        "name",
+			store:      s.roles,
+			validate:   s.validateRole,
+		},
+		{
+			singular:   "config repo",
+			collection: "/api/admin/config_repos",
+			embedded:   "config_repos",
+			key:        "id",
+			store:      s.configRepos,
+			validate:   s.validateConfigRepo,
+		},
+		{
+			singular:   "plugin settings",
+			collection: "/api/admin/plugin_settings",
+			key:        "plugin_id",
+			store:      s.pluginSettings,
+			validate:   s.validatePluginSettings,
+		},
+	}
+}
+
+// serveCollection lists and creates resources.
+func (s *Server) serveCollection(w http.ResponseWriter, r *http.Request, kind *kind) {
+	switch {
+	case r.Method == "GET" && kind.embedded != "":
+		items := []interface{}{}
+		for _, id := range kind.store.ids() {
+			items = append(items, s.collectionItem(kind, id))
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"_links":    s.links(kind.collection),
+			"_embedded": map[string]interface{}{kind.embedded: items},
+		})
+	case r.Method == "POST":
+		s.createResource(w, r, kind)
+	default:
+		s.writeMessage(w, http.StatusMethodNotAllowed, messageMethodNotAllow)
+	}
+}
+
+func (s *Server) collectionItem(kind *kind, id string) document {
+	doc, _ := kind.store.get(id)
+	if kind.summary != nil {
+		return kind.summary(id, doc)
+	}
+	return s.resource(kind, id, doc)
+}
+
+// serveResource reads, updates, and deletes a single resource.
+func (s *Server) serveResource(w http.ResponseWriter, r *http.Request, kind *kind, id string) {
+	existing, exists := kind.store.get(id)
+	if !exists {
+		s.writeMessage(w, http.StatusNotFound, messageNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		s.writeResource(w, http.StatusOK, kind, id, existing)
+	case "PUT":
+		s.updateResource(w, r, kind, id, existing)
+	case "PATCH":
+		s.patchResource(w, r, kind, id, existing)
+	case "DELETE":
+		if kind.inUse != nil {
+			if message := kind.inUse(id); message != "" {
+				s.writeMessage(w, http.StatusUnprocessableEntity, message)
+				return
+			}
+		}
+		kind.store.delete(id)
+		s.writeMessage(w, http.StatusOK, fmt.Sprintf(messageDeleted, kind.singular, id))
+	default:
+		s.writeMessage(w, http.StatusMethodNotAllowed, messageMethodNotAllow)
+	}
+}
+
+func (s *Server) createResource(w http.ResponseWriter, r *http.Request, kind *kind) {
+	doc, ok := s.readDocument(w, r)
+	if !ok {
+		return
+	}
+
+	errs := fieldErrors{}
+	if kind.unwrap != nil {
+		doc = kind.unwrap(doc, errs)
+	}
+	id := doc.string(kind.key)
+	if _, exists := kind.store.get(id); exists && id != "" {
+		s.writeMessage(w, http.StatusUnprocessableEntity, fmt.Sprintf(messageAlreadyExists, kind.singular, kind.singular, id))
+		return
+	}
+
+	s.storeResource(w, kind, id, doc, errs)
+}
+
+func (s *Server) updateResource(w http.ResponseWriter, r *http.Request, kind *kind, id string, existing document) {
+	if !s.checkWritable(w, kind, existing) || !s.checkETag(w, r, kind, id, existing) {
+		return
+	}
+
+	doc, ok := s.readDocument(w, r)
+	if !ok {
+		return
+	}
+	if bodyID := doc.string(kind.key); bodyID != id {
+		s.writeMessage(w, http.StatusUnprocessableEntity, fmt.Sprintf("Renaming of %ss is not supported by this API.", kind.singular))
+		return
+	}
+	if group, hasGroup := existing["group"]; hasGroup && doc.string("group") == "" {
+		doc["group"] = group
+	}
+
+	s.storeResource(w, kind, id, doc, fieldErrors{})
+}
+
+func (s *Server) patchResource(w http.ResponseWriter, r *http.Request, kind *kind, id string, existing document) {
+	if kind.patch == nil {
+		s.writeMessage(w, http.StatusMethodNotAllowed, messageMethodNotAllow)
+		return
+	}
+	if !s.checkWritable(w, kind, existing) {
+		return
+	}
+
+	patch, ok := s.readDocument(w, r)
+	if !ok {
+		return
+	}
+	doc := existing.clone()
+	kind.patch(doc, patch)
+
+	s.storeResource(w, kind, id, doc, fieldErrors{})
+}
+
+// storeResource validates and stores a created or updated resource, and responds with it.
+func (s *Server) storeResource(w http.ResponseWriter, kind *kind, id string, doc document, errs fieldErrors) {
+	if kind.normalize != nil {
+		kind.normalize(doc)
+	}
+	kind.validate(doc, errs)
+	if len(errs) > 0 {
+		s.writeValidationErrors(w, kind, id, doc, errs)
+		return
+	}
+
+	kind.store.put(id, doc)
+	s.writeResource(w, http.StatusOK, kind, id, doc)
+}
+
+// checkETag ensures the If-Match header of the request matches the ETag of the resource.
+func (s *Server) checkETag(w http.ResponseWriter, r *http.Request, kind *kind, id string, existing document) bool {
+	ifMatch := strings.Trim(strings.TrimPrefix(r.Header.Get("If-Match"), "W/"), `"`)
+	if ifMatch != existing.etag() {
+		s.writeMessage(w, http.StatusPreconditionFailed, fmt.Sprintf(messageStaleETag, kind.singular, id))
+		return false
+	}
+	return true
+}
+
+func (s *Server) checkWritable(w http.ResponseWriter, kind *kind, existing document) bool {
+	if kind.readOnly != nil {
+		if message := kind.readOnly(existing); message != "" {
+			s.writeMessage(w, http.StatusUnprocessableEntity, message)
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) writeResource(w http.ResponseWriter, status int, kind *kind, id string, doc document) {
+	w.Header().Set("ETag", fmt.Sprintf(`"%s"`, doc.etag()))
+	s.writeJSON(w, status, s.resource(kind, id, doc))
+}
+
+// resource builds the representation of a stored resource.
+func (s *Server) resource(kind *kind, id string, doc document) document {
+	resource := doc.clone()
+	resource["_links"] = s.links(kind.collection + "/" + id)
+	return resource
+}
+
+func (s *Server) readDocument(w http.ResponseWriter, r *http.Request) (doc document, ok bool) {
+	doc = document{}
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		s.writeMessage(w, http.StatusBadRequest, fmt.Sprintf("Error parsing the request body: %s", err))
+		return nil, false
+	}
+	return doc, true
+}
+
+// servePipelineGroups lists the groups of the pipeline configs, with the name of each pipeline in them, followed by the
+// groups added by AddPipelineGroup without pipelines.
+func (s *Server) servePipelineGroups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		s.writeMessage(w, http.StatusMethodNotAllowed, messageMethodNotAllow)
+		return
+	}
+
+	groups := []string{}
+	pipelines := map[string][]interface{}{}
+	for _, name := range s.pipelines.ids() {
+		pipeline, _ := s.pipelines.get(name)
+		group := pipeline.string("group")
+		if _, seen := pipelines[group]; !seen {
+			groups = append(groups, group)
+		}
+		pipelines[group] = append(pipelines[group], map[string]interface{}{"name": name})
+	}
+	for _, group := range s.pipelineGroups {
+		if _, seen := pipelines[group]; !seen {
+			groups = append(groups, group)
+			pipelines[group] = []interface{}{}
+		}
+	}
+
+	list := []interface{}{}
+	for _, group := range groups {
+		list = append(list, map[string]interface{}{"name": group, "pipelines": pipelines[group]})
+	}
+	s.writeJSON(w, http.StatusOK, list)
+}
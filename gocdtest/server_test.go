@@ -0,0 +1,388 @@
+package gocdtest
+
+import (
+	"context"
+	"github.com/beamly/go-gocd/gocd"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	t.Run("Version", testServerVersion)
+	t.Run("AcceptVersion", testServerAcceptVersion)
+	t.Run("PipelineLifecycle", testServerPipelineLifecycle)
+	t.Run("PipelineValidation", testServerPipelineValidation)
+	t.Run("RemotePipeline", testServerRemotePipeline)
+	t.Run("Environments", testServerEnvironments)
+	t.Run("Agents", testServerAgents)
+	t.Run("Roles", testServerRoles)
+	t.Run("ConfigRepos", testServerConfigRepos)
+	t.Run("PipelineGroups", testServerPipelineGroups)
+	t.Run("PluginSettings", testServerPluginSettings)
+}
+
+func testPipeline(name string) *gocd.Pipeline {
+	return &gocd.Pipeline{
+		Name: name,
+		Materials: []gocd.Material{{
+			Type:       "git",
+			Attributes: &gocd.MaterialAttributesGit{URL: "https://github.com/gocd/gocd", Branch: "master"},
+		}},
+		Stages: testStages(),
+	}
+}
+
+func testStages() []*gocd.Stage {
+	return []*gocd.Stage{{
+		Name: "build",
+		Jobs: []*gocd.Job{{
+			Name:  "compile",
+			Tasks: []*gocd.Task{{Type: "exec", Attributes: &gocd.TaskAttributesExec{Command: "make"}}},
+		}},
+	}}
+}
+
+func testServerVersion(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	v, _, err := server.Client().ServerVersion.Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultServerVersion, v.Version)
+
+	assert.Error(t, server.SetVersion("not-a-version"))
+}
+
+func testServerAcceptVersion(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"api/admin/templates", nil)
+	req.Header.Set("Accept", "application/vnd.go.cd.v1+json")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	assert.NoError(t, server.SetVersion("18.2.0"))
+	_, _, err = server.Client().PipelineTemplates.List(context.Background())
+	assert.NoError(t, err)
+}
+
+func testServerPipelineLifecycle(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	_, _, err := client.PipelineTemplates.Create(ctx, "build-template", testStages())
+	assert.NoError(t, err)
+
+	pipeline := testPipeline("my-pipeline")
+	pipeline.Stages = nil
+	pipeline.Template = "build-template"
+	_, _, err = client.PipelineConfigs.Create(ctx, "my-group", pipeline)
+	assert.NoError(t, err)
+
+	_, _, err = client.PipelineConfigs.Create(ctx, "my-group", pipeline)
+	assert.Contains(t, err.Error(), "Failed to add pipeline. The pipeline 'my-pipeline' already exists.")
+
+	templates, _, err := client.PipelineTemplates.List(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, templates, 1) {
+		assert.Equal(t, "my-pipeline", templates[0].Embedded.Pipelines[0].Name)
+	}
+
+	fetched, _, err := client.PipelineConfigs.Get(ctx, "my-pipeline")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, fetched.Version)
+	assert.Equal(t, "my-group", fetched.Group)
+
+	stale := *fetched
+	stale.Version = "stale"
+	_, resp, err := client.PipelineConfigs.Update(ctx, "my-pipeline", &stale)
+	assert.Contains(t, err.Error(), "Someone has modified the configuration for pipeline 'my-pipeline'.")
+	assert.Equal(t, http.StatusPreconditionFailed, resp.HTTP.StatusCode)
+
+	fetched.LabelTemplate = "${COUNT}-${git}"
+	updated, _, err := client.PipelineConfigs.Update(ctx, "my-pipeline", fetched)
+	assert.NoError(t, err)
+	assert.Equal(t, "${COUNT}-${git}", updated.LabelTemplate)
+	assert.NotEqual(t, fetched.Version, updated.Version)
+
+	_, resp, err = client.PipelineTemplates.Delete(ctx, "build-template")
+	assert.Contains(t, err.Error(), "Cannot delete the template 'build-template' as it is used by pipeline(s): '[my-pipeline]'.")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.HTTP.StatusCode)
+
+	_, _, err = client.PipelineConfigs.Delete(ctx, "my-pipeline")
+	assert.NoError(t, err)
+	_, _, err = client.PipelineTemplates.Delete(ctx, "build-template")
+	assert.NoError(t, err)
+
+	_, resp, err = client.PipelineConfigs.Get(ctx, "my-pipeline")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.HTTP.StatusCode)
+}
+
+func testServerPipelineValidation(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	pipeline := testPipeline(".invalid")
+	pipeline.Materials = nil
+	pipeline.Stages = append(pipeline.Stages, testStages()...)
+	_, resp, err := client.PipelineConfigs.Create(ctx, "", pipeline)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.HTTP.StatusCode)
+	for _, message := range []string{
+		"Validations failed for pipeline '.invalid'",
+		"Pipeline group must be specified for creating a pipeline.",
+		"Invalid pipeline name '.invalid'.",
+		"A pipeline must have at least one material.",
+		"You have defined multiple stages called 'build'.",
+	} {
+		assert.Contains(t, err.Error(), message)
+	}
+
+	pipeline = testPipeline("uses-missing-template")
+	pipeline.Stages = nil
+	pipeline.Template = "missing"
+	_, _, err = client.PipelineConfigs.Create(ctx, "my-group", pipeline)
+	assert.Contains(t, err.Error(), "Pipeline 'uses-missing-template' refers to non-existent template 'missing'.")
+}
+
+func testServerRemotePipeline(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	pipeline := testPipeline("remote")
+	pipeline.Origin = &gocd.PipelineConfigOrigin{Type: "config_repo", File: "my-repo"}
+	server.AddPipeline("my-group", pipeline)
+
+	fetched, _, err := client.PipelineConfigs.Get(ctx, "remote")
+	assert.NoError(t, err)
+	assert.Equal(t, "config_repo", fetched.Origin.Type)
+
+	_, _, err = client.PipelineConfigs.Update(ctx, "remote", fetched)
+	assert.Contains(t, err.Error(), "Can not operate on pipeline 'remote' as it is defined remotely in 'my-repo'.")
+}
+
+func testServerEnvironments(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	server.AddAgent(&gocd.Agent{UUID: "agent-1", Hostname: "agent01"})
+	_, _, err := client.PipelineConfigs.Create(ctx, "my-group", testPipeline("my-pipeline"))
+	assert.NoError(t, err)
+
+	_, _, err = client.Environments.Create(ctx, "production")
+	assert.NoError(t, err)
+
+	env, _, err := client.Environments.Patch(ctx, "production", &gocd.EnvironmentPatchRequest{
+		Pipelines: &gocd.PatchStringAction{Add: []string{"my-pipeline"}},
+		Agents:    &gocd.PatchStringAction{Add: []string{"agent-1"}},
+		EnvironmentVariables: &gocd.EnvironmentVariablesAction{
+			Add: []*gocd.EnvironmentVariable{{Name: "DEPLOY_ENV", Value: "production"}},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "my-pipeline", env.Pipelines[0].Name)
+	assert.Equal(t, "agent-1", env.Agents[0].UUID)
+	assert.Equal(t, "DEPLOY_ENV", env.EnvironmentVariables[0].Name)
+
+	agent, _, err := client.Agents.Get(ctx, "agent-1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"production"}, agent.Environments)
+
+	_, _, err = client.Environments.Patch(ctx, "production", &gocd.EnvironmentPatchRequest{
+		Pipelines: &gocd.PatchStringAction{Add: []string{"unknown"}},
+	})
+	assert.Contains(t, err.Error(), "Environment 'production' refers to an unknown pipeline 'unknown'.")
+
+	_, _, err = client.PipelineConfigs.Delete(ctx, "my-pipeline")
+	assert.Contains(t, err.Error(), "Cannot delete pipeline 'my-pipeline' as it is present in environment 'production'.")
+
+	envs, _, err := client.Environments.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, envs.Embedded.Environments, 1)
+
+	_, _, err = client.Environments.Delete(ctx, "production")
+	assert.NoError(t, err)
+}
+
+func testServerAgents(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	server.AddAgent(&gocd.Agent{UUID: "agent-1", Hostname: "agent01"})
+	server.AddAgent(&gocd.Agent{UUID: "agent-2", Hostname: "agent02"})
+
+	agents, _, err := client.Agents.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, agents, 2)
+
+	agent, _, err := client.Agents.Update(ctx, "agent-1", &gocd.Agent{Resources: []string{"linux", "java"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"linux", "java"}, agent.Resources)
+	assert.Equal(t, "Enabled", agent.AgentConfigState)
+
+	_, resp, err := client.Agents.Delete(ctx, "agent-1")
+	assert.Contains(t, err.Error(), "Failed to delete agent agent-1 as agent is not disabled.")
+	assert.Equal(t, http.StatusNotAcceptable, resp.HTTP.StatusCode)
+
+	_, _, err = client.Agents.BulkUpdate(ctx, gocd.AgentBulkUpdate{
+		Uuids:            []string{"agent-1", "agent-2"},
+		AgentConfigState: "Disabled",
+	})
+	assert.NoError(t, err)
+
+	_, _, err = client.Agents.BulkUpdate(ctx, gocd.AgentBulkUpdate{Uuids: []string{"missing"}})
+	assert.Contains(t, err.Error(), "Agent(s) with uuid(s): [missing] not found.")
+
+	message, _, err := client.Agents.BulkDelete(ctx, []string{"agent-1", "agent-2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Deleted 2 agent(s).", message)
+
+	agents, _, err = client.Agents.List(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, agents)
+}
+
+func testServerRoles(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	_, _, err := client.Roles.Create(ctx, &gocd.Role{
+		Name:       "admins",
+		Type:       "gocd",
+		Attributes: &gocd.RoleAttributesGoCD{Users: []string{"alice"}},
+	})
+	assert.NoError(t, err)
+
+	_, _, err = client.Roles.Create(ctx, &gocd.Role{Name: "invalid", Type: "ldap"})
+	assert.Contains(t, err.Error(), "Role type must be one of 'gocd' or 'plugin'.")
+
+	role, _, err := client.Roles.Get(ctx, "admins")
+	assert.NoError(t, err)
+	role.Attributes.Users = append(role.Attributes.Users, "bob")
+	role, _, err = client.Roles.Update(ctx, "admins", role)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, role.Attributes.Users)
+
+	roles, _, err := client.Roles.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, roles, 1)
+}
+
+func testServerConfigRepos(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	_, _, err := client.ConfigRepos.Create(ctx, &gocd.ConfigRepo{
+		ID:       "my-repo",
+		PluginID: "yaml.config.plugin",
+		Material: gocd.Material{
+			Type:       "git",
+			Attributes: &gocd.MaterialAttributesGit{URL: "https://github.com/example/pipelines"},
+		},
+	})
+	assert.NoError(t, err)
+
+	_, _, err = client.ConfigRepos.Create(ctx, &gocd.ConfigRepo{ID: "missing-plugin", Material: gocd.Material{Type: "git"}})
+	assert.Contains(t, err.Error(), "Configuration repository plugin id must not be blank.")
+	assert.Contains(t, err.Error(), "URL cannot be blank.")
+
+	repo, _, err := client.ConfigRepos.Get(ctx, "my-repo")
+	assert.NoError(t, err)
+	assert.Equal(t, "yaml.config.plugin", repo.PluginID)
+
+	repos, _, err := client.ConfigRepos.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, repos, 1)
+
+	_, _, err = client.ConfigRepos.Delete(ctx, "my-repo")
+	assert.NoError(t, err)
+}
+
+func testServerPipelineGroups(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	for group, name := range map[string]string{"build": "app", "deploy": "release"} {
+		_, _, err := client.PipelineConfigs.Create(ctx, group, testPipeline(name))
+		assert.NoError(t, err)
+	}
+	server.AddPipeline("build", testPipeline("lib"))
+	server.AddPipelineGroup("build")
+	server.AddPipelineGroup("empty")
+
+	groups, _, err := client.PipelineGroups.List(ctx, "")
+	assert.NoError(t, err)
+	if assert.Len(t, *groups, 3) {
+		assert.Equal(t, "build", (*groups)[0].Name)
+		assert.Equal(t, []string{"app", "lib"}, pipelineNames((*groups)[0].Pipelines))
+		assert.Equal(t, "deploy", (*groups)[1].Name)
+		assert.Equal(t, []string{"release"}, pipelineNames((*groups)[1].Pipelines))
+		assert.Equal(t, "empty", (*groups)[2].Name)
+		assert.Empty(t, (*groups)[2].Pipelines)
+	}
+}
+
+func pipelineNames(pipelines []*gocd.Pipeline) (names []string) {
+	for _, p := range pipelines {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func testServerPluginSettings(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	server.AddPlugin(&gocd.Plugin{ID: "slack"})
+	plugins, _, err := client.Plugins.List(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, plugins.Embedded.PluginInfo, 1) {
+		assert.Equal(t, "slack", plugins.Embedded.PluginInfo[0].ID)
+	}
+
+	_, resp, err := client.Plugins.GetSettings(ctx, "slack")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.HTTP.StatusCode)
+
+	_, _, err = client.Plugins.CreateSettings(ctx, &gocd.PluginSettings{PluginID: "email"})
+	assert.Contains(t, err.Error(), "Plugin with id 'email' is not found.")
+
+	created, _, err := client.Plugins.CreateSettings(ctx, &gocd.PluginSettings{
+		PluginID:      "slack",
+		Configuration: []*gocd.ConfigRepoProperty{{Key: "channel", Value: "#builds"}},
+	})
+	assert.NoError(t, err)
+
+	settings, _, err := client.Plugins.GetSettings(ctx, "slack")
+	assert.NoError(t, err)
+	assert.Equal(t, created.Version, settings.Version)
+
+	settings.Configuration[0].Value = "#deploys"
+	_, _, err = client.Plugins.UpdateSettings(ctx, settings)
+	assert.NoError(t, err)
+
+	_, _, err = client.Plugins.UpdateSettings(ctx, settings)
+	assert.Contains(t, err.Error(), "Someone has modified the configuration for plugin settings 'slack'.")
+}
@@ -0,0 +1,342 @@
+package gocdtest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// validName matches the names GoCD accepts for pipelines, stages, jobs, templates, environments, and roles.
+var validName = regexp.MustCompile(`^[a-zA-Z0-9_\-][a-zA-Z0-9_\-.]*$`)
+
+const maxNameLength = 255
+
+func validateName(errs fieldErrors, field string, kind string, name string) {
+	if name == "" {
+		errs.add(field, "%s%s name can not be blank.", strings.ToUpper(kind[:1]), kind[1:])
+		return
+	}
+	if !validName.MatchString(name) || len(name) > maxNameLength {
+		errs.add(field, "Invalid %s name '%s'. This must be alphanumeric and can contain underscores, hyphens and "+
+			"periods (however, it cannot start with a period). The maximum allowed length is %d characters.",
+			kind, name, maxNameLength)
+	}
+}
+
+// unwrapPipeline extracts the pipeline from a create request, which wraps it with its group.
+func unwrapPipeline(body document, errs fieldErrors) document {
+	pipeline, _ := body["pipeline"].(map[string]interface{})
+	doc := document(pipeline)
+	if doc == nil {
+		doc = document{}
+	}
+
+	group := body.string("group")
+	if group == "" {
+		errs.add("group", "Pipeline group must be specified for creating a pipeline.")
+	}
+	doc["group"] = group
+	return doc
+}
+
+func (s *Server) validatePipeline(doc document, errs fieldErrors) {
+	name := doc.string("name")
+	validateName(errs, "name", "pipeline", name)
+
+	materials := doc.list("materials")
+	if len(materials) == 0 {
+		errs.add("materials", "A pipeline must have at least one material.")
+	}
+	for _, material := range materials {
+		m, _ := material.(map[string]interface{})
+		if document(m).string("type") == "" {
+			errs.add("materials", "Material type must be specified.")
+		}
+	}
+
+	template := doc.string("template")
+	stages := doc.list("stages")
+	switch {
+	case template != "" && len(stages) > 0:
+		errs.add("stages", "Cannot add stages to pipeline '%s' which already references template '%s'.", name, template)
+	case template != "":
+		if _, exists := s.templates.get(template); !exists {
+			errs.add("template", "Pipeline '%s' refers to non-existent template '%s'.", name, template)
+		}
+	case len(stages) == 0:
+		errs.add("stages", "A pipeline must have at least one stage.")
+	}
+	validateStages(doc, errs)
+}
+
+func (s *Server) validateTemplate(doc document, errs fieldErrors) {
+	validateName(errs, "name", "template", doc.string("name"))
+	if len(doc.list("stages")) == 0 {
+		errs.add("stages", "A template must have at least one stage.")
+	}
+	validateStages(doc, errs)
+}
+
+// validateStages checks that stages and the jobs in them have valid and unique names.
+func validateStages(doc document, errs fieldErrors) {
+	stageNames := map[string]bool{}
+	for _, stage := range doc.list("stages") {
+		s, _ := stage.(map[string]interface{})
+		stageDoc := document(s)
+		name := stageDoc.string("name")
+
+		validateName(errs, "stages", "stage", name)
+		if stageNames[strings.ToLower(name)] {
+			errs.add("stages", "You have defined multiple stages called '%s'. Stage names are case-insensitive and must be unique.", name)
+		}
+		stageNames[strings.ToLower(name)] = true
+
+		jobs := stageDoc.list("jobs")
+		if len(jobs) == 0 {
+			errs.add("stages", "Stage '%s' must have at least one job.", name)
+		}
+		jobNames := map[string]bool{}
+		for _, jobName := range stageDoc.names("jobs", "name") {
+			validateName(errs, "stages", "job", jobName)
+			if jobNames[strings.ToLower(jobName)] {
+				errs.add("stages", "You have defined multiple jobs called '%s' in stage '%s'. Job names are case-insensitive and must be unique.", jobName, name)
+			}
+			jobNames[strings.ToLower(jobName)] = true
+		}
+	}
+}
+
+// remotePipelineMessage prevents pipelines defined in config repos from being modified.
+func remotePipelineMessage(doc document) string {
+	origin, _ := doc["origin"].(map[string]interface{})
+	if document(origin).string("type") != "config_repo" {
+		return ""
+	}
+	location := document(origin).string("id")
+	if location == "" {
+		location = document(origin).string("file")
+	}
+	return fmt.Sprintf("Can not operate on pipeline '%s' as it is defined remotely in '%s'.", doc.string("name"), location)
+}
+
+// normalizePipeline records pipelines created through the API as defined in the GoCD config.
+func normalizePipeline(doc document) {
+	if _, hasOrigin := doc["origin"].(map[string]interface{}); !hasOrigin {
+		doc["origin"] = map[string]interface{}{"type": "gocd", "file": "cruise-config.xml"}
+	}
+}
+
+// pipelineInUse prevents pipelines from being deleted while they are part of an environment, or other pipelines
+// depend on them.
+func (s *Server) pipelineInUse(name string) string {
+	for _, envName := range s.environments.ids() {
+		env, _ := s.environments.get(envName)
+		if containsString(env.names("pipelines", "name"), name) {
+			return fmt.Sprintf("Cannot delete pipeline '%s' as it is present in environment '%s'.", name, envName)
+		}
+	}
+
+	for _, downstream := range s.pipelines.ids() {
+		pipeline, _ := s.pipelines.get(downstream)
+		for _, material := range pipeline.list("materials") {
+			m, _ := material.(map[string]interface{})
+			attributes, _ := m["attributes"].(map[string]interface{})
+			if document(m).string("type") == "dependency" && document(attributes).string("pipeline") == name {
+				return fmt.Sprintf("Cannot delete pipeline '%s' as pipeline '%s' depends on it.", name, downstream)
+			}
+		}
+	}
+	return ""
+}
+
+// pipelinesUsingTemplate lists the pipelines referencing a template.
+func (s *Server) pipelinesUsingTemplate(template string) []string {
+	pipelines := []string{}
+	for _, name := range s.pipelines.ids() {
+		pipeline, _ := s.pipelines.get(name)
+		if pipeline.string("template") == template {
+			pipelines = append(pipelines, name)
+		}
+	}
+	return pipelines
+}
+
+func (s *Server) templateInUse(name string) string {
+	if pipelines := s.pipelinesUsingTemplate(name); len(pipelines) > 0 {
+		return fmt.Sprintf("Cannot delete the template '%s' as it is used by pipeline(s): '[%s]'.", name, strings.Join(pipelines, ", "))
+	}
+	return ""
+}
+
+// templateSummary lists a template with the pipelines using it, as GoCD does.
+func (s *Server) templateSummary(name string, doc document) document {
+	pipelines := []interface{}{}
+	for _, pipeline := range s.pipelinesUsingTemplate(name) {
+		pipelines = append(pipelines, map[string]interface{}{
+			"_links": s.links("/api/admin/pipelines/" + pipeline),
+			"name":   pipeline,
+		})
+	}
+	return document{
+		"_links":    s.links("/api/admin/templates/" + name),
+		"name":      name,
+		"_embedded": map[string]interface{}{"pipelines": pipelines},
+	}
+}
+
+// normalizeEnvironment stores pipelines and agents by reference only.
+func normalizeEnvironment(doc document) {
+	pipelines := []interface{}{}
+	for _, name := range doc.names("pipelines", "name") {
+		pipelines = append(pipelines, map[string]interface{}{"name": name})
+	}
+	agents := []interface{}{}
+	for _, uuid := range doc.names("agents", "uuid") {
+		agents = append(agents, map[string]interface{}{"uuid": uuid})
+	}
+	doc["pipelines"] = pipelines
+	doc["agents"] = agents
+	if _, hasVariables := doc["environment_variables"]; !hasVariables {
+		doc["environment_variables"] = []interface{}{}
+	}
+}
+
+func (s *Server) validateEnvironment(doc document, errs fieldErrors) {
+	name := doc.string("name")
+	validateName(errs, "name", "environment", name)
+
+	for _, pipeline := range doc.names("pipelines", "name") {
+		if _, exists := s.pipelines.get(pipeline); !exists {
+			errs.add("pipelines", "Environment '%s' refers to an unknown pipeline '%s'.", name, pipeline)
+			continue
+		}
+		for _, other := range s.environments.ids() {
+			env, _ := s.environments.get(other)
+			if other != name && containsString(env.names("pipelines", "name"), pipeline) {
+				errs.add("pipelines", "Associating pipeline(s) which is already part of %s environment.", other)
+			}
+		}
+	}
+
+	for _, uuid := range doc.names("agents", "uuid") {
+		if _, exists := s.agents.get(uuid); !exists {
+			errs.add("agents", "Environment '%s' has an invalid agent uuid '%s'.", name, uuid)
+		}
+	}
+
+	variables := map[string]bool{}
+	for _, variable := range doc.names("environment_variables", "name") {
+		if variables[variable] {
+			errs.add("environment_variables", "Environment Variable name '%s' is not unique for environment '%s'.", variable, name)
+		}
+		variables[variable] = true
+	}
+}
+
+// patchEnvironment adds and removes pipelines, agents, and environment variables.
+func patchEnvironment(doc document, patch document) {
+	pipelines := patchNames(doc.names("pipelines", "name"), patch["pipelines"])
+	agents := patchNames(doc.names("agents", "uuid"), patch["agents"])
+
+	doc["pipelines"] = references(pipelines, "name")
+	doc["agents"] = references(agents, "uuid")
+
+	action, _ := patch["environment_variables"].(map[string]interface{})
+	remove := map[string]bool{}
+	for _, name := range stringList(action["remove"]) {
+		remove[name] = true
+	}
+	for _, variable := range document(action).list("add") {
+		if v, isObject := variable.(map[string]interface{}); isObject {
+			remove[document(v).string("name")] = true
+		}
+	}
+
+	variables := []interface{}{}
+	for _, variable := range doc.list("environment_variables") {
+		if v, isObject := variable.(map[string]interface{}); !isObject || !remove[document(v).string("name")] {
+			variables = append(variables, variable)
+		}
+	}
+	doc["environment_variables"] = append(variables, document(action).list("add")...)
+}
+
+func (s *Server) validateRole(doc document, errs fieldErrors) {
+	validateName(errs, "name", "role", doc.string("name"))
+
+	attributes, _ := doc["attributes"].(map[string]interface{})
+	switch doc.string("type") {
+	case "gocd":
+	case "plugin":
+		if document(attributes).string("auth_config_id") == "" {
+			errs.add("auth_config_id", "Role '%s' must have an auth_config_id.", doc.string("name"))
+		}
+	default:
+		errs.add("type", "Role type must be one of 'gocd' or 'plugin'.")
+	}
+}
+
+func (s *Server) validateConfigRepo(doc document, errs fieldErrors) {
+	validateName(errs, "id", "config repo", doc.string("id"))
+	if doc.string("plugin_id") == "" {
+		errs.add("plugin_id", "Configuration repository plugin id must not be blank.")
+	}
+
+	material, _ := doc["material"].(map[string]interface{})
+	attributes, _ := material["attributes"].(map[string]interface{})
+	if document(material).string("type") == "" {
+		errs.add("material", "Material type must be specified.")
+	}
+	if url := document(attributes).string("url"); url == "" && document(material).string("type") != "plugin" {
+		errs.add("material", "URL cannot be blank.")
+	}
+}
+
+// patchNames adds and removes names according to a {"add": [...], "remove": [...]} action.
+func patchNames(names []string, action interface{}) []string {
+	a, _ := action.(map[string]interface{})
+	for _, name := range stringList(a["add"]) {
+		if !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	remove := stringList(a["remove"])
+	kept := []string{}
+	for _, name := range names {
+		if !containsString(remove, name) {
+			kept = append(kept, name)
+		}
+	}
+	sort.Strings(kept)
+	return kept
+}
+
+func references(names []string, key string) []interface{} {
+	refs := []interface{}{}
+	for _, name := range names {
+		refs = append(refs, map[string]interface{}{key: name})
+	}
+	return refs
+}
+
+func stringList(value interface{}) []string {
+	strs := []string{}
+	list, _ := value.([]interface{})
+	for _, item := range list {
+		if str, isString := item.(string); isString {
+			strs = append(strs, str)
+		}
+	}
+	return strs
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
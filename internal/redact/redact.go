@@ -0,0 +1,55 @@
+// Package redact finds the secrets in GoCD requests and responses. It is shared by the debug logging of the gocd
+// package and the scrubbers of the gocdtest package, so that secrets hidden from one are hidden from the other.
+package redact
+
+import (
+	"regexp"
+	"strings"
+)
+
+// xmlSecrets matches secret attributes and elements in cruise-config.xml.
+var xmlSecrets = regexp.MustCompile(`(?i)((?:password|encryptedPassword|encryptedValue)=")[^"]*(")|(<encryptedValue>)[^<]*(</encryptedValue>)`)
+
+// querySecrets matches the values of secure variables in the query of a URL, as sent when scheduling pipelines on
+// servers without the JSON API.
+var querySecrets = regexp.MustCompile(`(?i)((?:^|[?&])secure_variables(?:\[|%5B)[^=&#]*=)[^&#]*`)
+
+// JSON walks a decoded JSON document, replacing the values of the named keys, and of the `value` key in objects with
+// `"secure": true`. Empty values are kept, so that it remains visible that no secret was set. It returns true if
+// anything was replaced.
+func JSON(value interface{}, keys map[string]bool, replacement string) (replaced bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		secure, _ := v["secure"].(bool)
+		for key, child := range v {
+			if keys[key] || (secure && key == "value") {
+				if child != nil && child != "" && child != replacement {
+					v[key] = replacement
+					replaced = true
+				}
+				continue
+			}
+			replaced = JSON(child, keys, replacement) || replaced
+		}
+	case []interface{}:
+		for _, child := range v {
+			replaced = JSON(child, keys, replacement) || replaced
+		}
+	}
+	return
+}
+
+// XML replaces passwords and encrypted values in cruise-config.xml.
+func XML(body string, replacement string) string {
+	return xmlSecrets.ReplaceAllString(body, "${1}${3}"+escapeReplacement(replacement)+"${2}${4}")
+}
+
+// URL replaces the values of secure variables in a URL or path.
+func URL(u string, replacement string) string {
+	return querySecrets.ReplaceAllString(u, "${1}"+escapeReplacement(replacement))
+}
+
+// escapeReplacement keeps the replacement from being expanded as a regular expression template.
+func escapeReplacement(replacement string) string {
+	return strings.Replace(replacement, "$", "$$", -1)
+}
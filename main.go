@@ -56,6 +56,36 @@ func buildCli() *cli.App {
 			Name:   "skip_ssl_check",
 			EnvVar: gocd.EnvVarSkipSsl,
 		},
+		cli.StringFlag{
+			Name:   "ca_file",
+			Usage:  "PEM encoded certificate authorities to trust when connecting to the server",
+			EnvVar: gocd.EnvVarCAFile,
+		},
+		cli.StringFlag{
+			Name:   "client_cert",
+			Usage:  "PEM encoded client certificate for mutual TLS",
+			EnvVar: gocd.EnvVarClientCert,
+		},
+		cli.StringFlag{
+			Name:   "client_key",
+			Usage:  "PEM encoded client key for mutual TLS",
+			EnvVar: gocd.EnvVarClientKey,
+		},
+		cli.StringFlag{
+			Name:   "proxy_url",
+			Usage:  "Proxy to connect to the server through",
+			EnvVar: gocd.EnvVarProxyURL,
+		},
+		cli.StringFlag{
+			Name:   "no_proxy",
+			Usage:  "Comma separated list of hosts, domains, and CIDR ranges not to proxy",
+			EnvVar: gocd.EnvVarNoProxy,
+		},
+		cli.DurationFlag{
+			Name:   "timeout",
+			Usage:  "Timeout for each request to the server, eg: 30s",
+			EnvVar: gocd.EnvVarTimeout,
+		},
 	}
 
 	sort.Sort(cli.CommandsByName(app.Commands))